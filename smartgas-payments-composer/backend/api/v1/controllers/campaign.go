@@ -2,10 +2,12 @@ package controllers
 
 import (
 	"errors"
+	"math"
 	"net/http"
 	"smartgas-payment/internal/dto"
 	"smartgas-payment/internal/lang"
 	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
 	"smartgas-payment/internal/repository"
 	"smartgas-payment/internal/schemas"
 	"smartgas-payment/internal/utils"
@@ -126,7 +128,7 @@ func (cc *campaignController) Create(c *gin.Context) {
 			)
 			return
 		} else if utils.CheckMysqlErrCode(err, 1452) {
-			c.JSON(http.StatusNotAcceptable, dto.GeneralMessage{Detail: lang.NotAcceptable + "gas_station_id"})
+			c.JSON(http.StatusNotAcceptable, dto.GeneralMessage{Detail: lang.NotAcceptable + "gas_station_id or level_id"})
 			return
 		}
 		// Logging error in sentry
@@ -241,6 +243,19 @@ func (cc *campaignController) GetCampaignDetail(c *gin.Context) {
 
 	copier.Copy(&campaignResponse, campaign)
 
+	if campaign.BudgetAmount != nil {
+		remaining := *campaign.BudgetAmount - campaign.SpentAmount
+		if remaining < 0 {
+			remaining = money.Zero
+		}
+		campaignResponse.RemainingAmount = &remaining
+	}
+
+	if campaign.BudgetLiters != nil {
+		remaining := math.Max(*campaign.BudgetLiters-campaign.SpentLiters, 0)
+		campaignResponse.RemainingLiters = &remaining
+	}
+
 	c.JSON(http.StatusOK, &campaignResponse)
 }
 
@@ -16,6 +16,13 @@ var ControllersSet = wire.NewSet(
 	ProvideSettingController,
 	ProvideCampaignController,
 	ProvideElegibityController,
+	ProvideReconciliationController,
+	ProvideStripeEventController,
+	ProvideDisputeController,
+	ProvideReportController,
+	ProvideSettlementController,
+	ProvideFraudController,
+	ProvidePromoCodeController,
 
 	wire.Bind(new(UserController), new(*userController)),
 	wire.Bind(new(IAUthController), new(*AuthController)),
@@ -28,4 +35,11 @@ var ControllersSet = wire.NewSet(
 	wire.Bind(new(SettingController), new(*settingController)),
 	wire.Bind(new(CampaignController), new(*campaignController)),
 	wire.Bind(new(ElegibilityController), new(*elegibilityController)),
+	wire.Bind(new(ReconciliationController), new(*reconciliationController)),
+	wire.Bind(new(StripeEventController), new(*stripeEventController)),
+	wire.Bind(new(DisputeController), new(*disputeController)),
+	wire.Bind(new(ReportController), new(*reportController)),
+	wire.Bind(new(SettlementController), new(*settlementController)),
+	wire.Bind(new(FraudController), new(*fraudController)),
+	wire.Bind(new(PromoCodeController), new(*promoCodeController)),
 )
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"net/http"
 	"smartgas-payment/config"
+	"smartgas-payment/internal/discounts"
 	"smartgas-payment/internal/dto"
 	"smartgas-payment/internal/lang"
 	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/payments"
 	"smartgas-payment/internal/repository"
 	"smartgas-payment/internal/schemas"
 	"smartgas-payment/internal/services"
@@ -24,6 +26,10 @@ type CustomerController interface {
 	DeleteCard(*gin.Context)
 	ListAll(*gin.Context)
 	GetElegibilityLevel(*gin.Context)
+	ListPayments(*gin.Context)
+	CreateSetupIntent(*gin.Context)
+	GetDefaultPaymentMethod(*gin.Context)
+	SetDefaultPaymentMethod(*gin.Context)
 }
 
 type customerController struct {
@@ -33,6 +39,8 @@ type customerController struct {
 	settingsRepo    repository.SettingRepository
 	repository      repository.CustomerRepository
 	elegibilityRepo repository.ElegibilityRepository
+	paymentRepo     repository.PaymentRepository
+	discountEngine  discounts.Engine
 }
 
 func ProvideCustomerController(
@@ -42,6 +50,8 @@ func ProvideCustomerController(
 	settingsRepo repository.SettingRepository,
 	repository repository.CustomerRepository,
 	elegibilityRepo repository.ElegibilityRepository,
+	paymentRepo repository.PaymentRepository,
+	discountEngine discounts.Engine,
 ) *customerController {
 	return &customerController{
 		stripeService:   stripeService,
@@ -50,6 +60,8 @@ func ProvideCustomerController(
 		settingsRepo:    settingsRepo,
 		repository:      repository,
 		elegibilityRepo: elegibilityRepo,
+		paymentRepo:     paymentRepo,
+		discountEngine:  discountEngine,
 	}
 }
 
@@ -166,9 +178,150 @@ func (cc *customerController) ListPaymenthMethods(c *gin.Context) {
 
 	copier.Copy(&paymentMethodsResponse, paymentMethods)
 
+	defaultPaymentMethod, err := cc.stripeService.GetDefaultPaymentMethod(customer.StripeCustomerID)
+	if err != nil {
+		// Logging error in sentry, cards are listed without the default one
+		opts := &utils.TrackErrorOpts{
+			Context:  map[string]map[string]any{"Payment Provider": {"name": "stripe"}},
+			Tags:     map[string]string{"auth_type": "customer"},
+			Customer: customer,
+		}
+		utils.TrackError(c, err, opts)
+	}
+
+	if defaultPaymentMethod != nil {
+		for i := range paymentMethodsResponse {
+			paymentMethodsResponse[i].IsDefault = paymentMethodsResponse[i].ID == defaultPaymentMethod.ID
+		}
+	}
+
 	c.JSON(http.StatusOK, paymentMethodsResponse)
 }
 
+// @Summary Save a customer card
+// @Description Create a stripe SetupIntent to save a card without charging it, the client confirms it with the client secret
+// @Tags Customers
+// @Produce json
+// @Router /api/v1/customers/payment-methods/setup-intent [POST]
+// @Param Authorization header string true "Token"
+// @Success 201 {object} dto.CustomerSetupIntentResponse "Setup intent"
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (cc *customerController) CreateSetupIntent(c *gin.Context) {
+	customer := c.MustGet("customer").(*models.Customer)
+
+	setupIntent, err := cc.stripeService.CreateSetupIntent(customer.StripeCustomerID)
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Context:  map[string]map[string]any{"Payment Provider": {"name": "stripe"}},
+			Tags:     map[string]string{"auth_type": "customer"},
+			Customer: customer,
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.CustomerSetupIntentResponse{
+		ID:           setupIntent.ID,
+		ClientSecret: setupIntent.ClientSecret,
+	})
+}
+
+// @Summary Customer default card
+// @Description Get the card used by one tap loads
+// @Tags Customers
+// @Produce json
+// @Router /api/v1/customers/payment-methods/default [GET]
+// @Param Authorization header string true "Token"
+// @Success 200 {object} schemas.PaymentMethod "Default card"
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "The customer has no default card"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (cc *customerController) GetDefaultPaymentMethod(c *gin.Context) {
+	customer := c.MustGet("customer").(*models.Customer)
+
+	paymentMethod, err := cc.stripeService.GetDefaultPaymentMethod(customer.StripeCustomerID)
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Context:  map[string]map[string]any{"Payment Provider": {"name": "stripe"}},
+			Tags:     map[string]string{"auth_type": "customer"},
+			Customer: customer,
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	if paymentMethod == nil {
+		c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NoDefaultPaymentMethod})
+		return
+	}
+
+	var response schemas.PaymentMethod
+
+	copier.Copy(&response, paymentMethod)
+	response.IsDefault = true
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Set customer default card
+// @Description Set the card used by one tap loads, it must be saved already
+// @Tags Customers
+// @Accept json
+// @Produce json
+// @Router /api/v1/customers/payment-methods/default [PUT]
+// @Param Authorization header string true "Token"
+// @Param request body dto.CustomerDefaultPaymentMethodRequest true "Card"
+// @Success 200 {object} dto.GeneralMessage "Updated"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "The card does not belong to the customer"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (cc *customerController) SetDefaultPaymentMethod(c *gin.Context) {
+	customer := c.MustGet("customer").(*models.Customer)
+
+	var body dto.CustomerDefaultPaymentMethodRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			utils.MapValidatorError[dto.CustomerDefaultPaymentMethodRequest](err),
+		)
+		return
+	}
+
+	owned := false
+	for _, pm := range cc.stripeService.ListPaymenthMethodsByCustomer(customer.StripeCustomerID) {
+		if pm.ID == body.PaymentMethodID {
+			owned = true
+			break
+		}
+	}
+
+	if !owned {
+		c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.PaymentMethodNotFound})
+		return
+	}
+
+	err := cc.stripeService.SetDefaultPaymentMethod(customer.StripeCustomerID, body.PaymentMethodID)
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Context:  map[string]map[string]any{"Payment Provider": {"name": "stripe"}},
+			Tags:     map[string]string{"auth_type": "customer"},
+			Customer: customer,
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.GeneralMessage{Detail: lang.RecordUpdated})
+}
+
 // @Summary List all customers
 // @Description List of all customers
 // @Tags Customers
@@ -239,30 +392,117 @@ func (cc *customerController) GetElegibilityLevel(c *gin.Context) {
 
 	copier.Copy(&levelResponse, cusLevel.Level)
 
-	applicablePromotionType, err := cc.settingsRepo.GetByName("applicable_promotion_type")
+	policy, err := cc.discountEngine.Policy()
 	if err != nil {
-		var csmErr error
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			csmErr = errors.New("Applicable promotion type not found")
-		} else {
-			csmErr = err
-		}
 		// Logging error in sentry
 		opts := &utils.TrackErrorOpts{
 			Customer: customer,
 			Tags:     map[string]string{"auth_type": "customer"},
 		}
-		utils.TrackError(c, csmErr, opts)
-	}
-
-	promotionTypeInDB := "none"
-	if applicablePromotionType != nil {
-		promotionTypeInDB = applicablePromotionType.Value
+		utils.TrackError(c, err, opts)
 	}
 
-	if promotionTypeInDB == "elegibility" {
+	if policy != nil && policy.Enabled(discounts.SourceElegibility) {
 		levelResponse.LevelsEnabled = true
 	}
 
 	c.JSON(http.StatusOK, levelResponse)
 }
+
+// @Summary Customer payments
+// @Description Get the loads of the customer, newest first. Use next_cursor of a page as cursor to get the next one
+// @Tags Customers
+// @Produce json
+// @Router /api/v1/customers/payments [GET]
+// @Param Authorization header string true "Token"
+// @Param param query dto.CursorPaginateRequest false "Pagination"
+// @Param filters query dto.CustomerPaymentListQueryRequest false "Filters"
+// @Success 200 {object} dto.CursorPaginationResponse{data=[]dto.CustomerPaymentResponse} "Customer payments"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (cc *customerController) ListPayments(c *gin.Context) {
+	var pagination dto.CursorPaginateRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.CursorPaginateRequest](err))
+		return
+	}
+
+	var params dto.CustomerPaymentListQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.CustomerPaymentListQueryRequest](err))
+		return
+	}
+
+	filters := map[string]any{}
+
+	if params.From != "" {
+		from, _ := time.ParseInLocation("2006-01-02", params.From, time.Local)
+		filters["from"] = from
+	}
+
+	if params.To != "" {
+		to, _ := time.ParseInLocation("2006-01-02", params.To, time.Local)
+		// Including the whole day
+		filters["to"] = to.AddDate(0, 0, 1)
+	}
+
+	if params.From != "" && params.To != "" && params.To < params.From {
+		c.JSON(http.StatusBadRequest, dto.GeneralMessage{Detail: lang.InvalidDateRange})
+		return
+	}
+
+	if params.GasStationID != "" {
+		filters["gas_station_id"] = params.GasStationID
+	}
+
+	customer := c.MustGet("customer").(*models.Customer)
+
+	var paginationSchema schemas.CursorPagination
+
+	copier.Copy(&paginationSchema, &pagination)
+
+	customerPayments, err := cc.paymentRepo.ListForCustomer(customer.ID, &paginationSchema, filters)
+	if err != nil {
+		if errors.Is(err, schemas.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, dto.GeneralMessage{Detail: lang.InvalidCursor})
+			return
+		}
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Customer: customer,
+			Tags:     map[string]string{"auth_type": "customer"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	response := make([]dto.CustomerPaymentResponse, 0, len(customerPayments))
+
+	for _, payment := range customerPayments {
+		var paymentResponse dto.CustomerPaymentResponse
+
+		copier.Copy(&paymentResponse, payment)
+
+		lastEvent := ""
+		// Events are preloaded newest first
+		if len(payment.Events) > 0 {
+			lastEvent = payment.Events[0].Type
+			paymentResponse.LastEvent = &dto.CustomerPaymentEventResponse{
+				Type:      lastEvent,
+				CreatedAt: payment.Events[0].CreatedAt,
+			}
+		}
+
+		paymentResponse.Invoiceable = payments.IsInvoiceable(payment.Invoiced, payment.Status, lastEvent)
+
+		response = append(response, paymentResponse)
+	}
+
+	c.JSON(http.StatusOK, dto.CursorPaginationResponse{
+		Limit:      paginationSchema.GetLimit(),
+		NextCursor: paginationSchema.NextCursor,
+		Data:       response,
+	})
+}
@@ -0,0 +1,228 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"smartgas-payment/internal/dto"
+	"smartgas-payment/internal/injectors"
+	"smartgas-payment/internal/lang"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/services"
+	"smartgas-payment/internal/utils"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/stripe/stripe-go/v72"
+)
+
+type customerCtrlTest struct {
+	suite.Suite
+	stripeService *services.MockStripeService
+	testRequest   *utils.TestRequest
+	cards         []*stripe.PaymentMethod
+}
+
+func (suite *customerCtrlTest) SetupSuite() {
+	setup, _ := injectors.InitializeServerWithMocks()
+
+	suite.testRequest = &utils.TestRequest{
+		Router: setup.Router,
+	}
+
+	customer := &models.Customer{
+		ID:               uuid.New(),
+		StripeCustomerID: "cus_cards",
+		SwitCustomerID:   "swit_cards",
+	}
+	setup.ExtCustomerService.On("Verify", "customer-token").Return(&schemas.Customer{}, nil)
+	setup.CustomerRepositoryMock.On("GetCustomerOrCreate", mock.Anything).Return(customer, false, nil)
+	setup.CustomerRepositoryMock.On("UpdateByID", customer.ID, mock.Anything).Return(true, nil)
+
+	suite.cards = []*stripe.PaymentMethod{
+		{ID: "pm_visa", Card: &stripe.PaymentMethodCard{Last4: "4242", Brand: stripe.PaymentMethodCardBrandVisa}},
+		{ID: "pm_master", Card: &stripe.PaymentMethodCard{Last4: "4444", Brand: stripe.PaymentMethodCardBrandMastercard}},
+	}
+
+	suite.stripeService = setup.StripeServiceMock
+	suite.stripeService.On("ListPaymenthMethodsByCustomer", "cus_cards").Return(suite.cards)
+
+	suite.testRequest.SetBearerToken("Token customer-token")
+}
+
+// resetStripeService clears the calls of the stripe mock so every case sets its own
+func (suite *customerCtrlTest) resetStripeService() {
+	suite.stripeService.ExpectedCalls = nil
+	suite.stripeService.Calls = nil
+	suite.stripeService.On("ListPaymenthMethodsByCustomer", "cus_cards").Return(suite.cards)
+}
+
+func (suite *customerCtrlTest) TestCreateSetupIntent() {
+	url := "/api/v1/customers/payment-methods/setup-intent"
+
+	testcases := []struct {
+		Name               string
+		Err                error
+		ExpectedStatusCode int
+		ExpectedResponse   any
+	}{
+		{
+			Name:               "TestCustomerController_SetupIntentCreated",
+			ExpectedStatusCode: http.StatusCreated,
+			ExpectedResponse: dto.CustomerSetupIntentResponse{
+				ID:           "seti_test",
+				ClientSecret: "seti_test_secret",
+			},
+		},
+		{
+			Name:               "TestCustomerController_SetupIntentFailed",
+			Err:                errors.New("stripe unavailable"),
+			ExpectedStatusCode: http.StatusInternalServerError,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.InternalServerError},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.resetStripeService()
+
+			var setupIntent *stripe.SetupIntent
+			if tc.Err == nil {
+				setupIntent = &stripe.SetupIntent{ID: "seti_test", ClientSecret: "seti_test_secret"}
+			}
+			suite.stripeService.On("CreateSetupIntent", "cus_cards").Return(setupIntent, tc.Err).Once()
+
+			res := suite.testRequest.Post(url, nil)
+
+			suite.Equal(tc.ExpectedStatusCode, res.Code, utils.PrintExpectedValues(tc.ExpectedStatusCode, res.Code))
+
+			expected, _ := json.Marshal(tc.ExpectedResponse)
+			suite.Equal(string(expected), res.Body.String(), utils.PrintExpectedValues(string(expected), res.Body.String()))
+		})
+	}
+}
+
+func (suite *customerCtrlTest) TestDefaultPaymentMethod() {
+	url := "/api/v1/customers/payment-methods/default"
+
+	defaultCard := schemas.PaymentMethod{ID: "pm_master", IsDefault: true}
+	defaultCard.Card.Last4 = "4444"
+	defaultCard.Card.Brand = string(stripe.PaymentMethodCardBrandMastercard)
+
+	testcases := []struct {
+		Name               string
+		Default            *stripe.PaymentMethod
+		ExpectedStatusCode int
+		ExpectedResponse   any
+	}{
+		{
+			Name:               "TestCustomerController_GetDefault",
+			Default:            suite.cards[1],
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   defaultCard,
+		},
+		{
+			Name:               "TestCustomerController_GetNoDefault",
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.NoDefaultPaymentMethod},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.resetStripeService()
+			suite.stripeService.On("GetDefaultPaymentMethod", "cus_cards").Return(tc.Default, nil).Once()
+
+			res := suite.testRequest.Get(url, nil)
+
+			suite.Equal(tc.ExpectedStatusCode, res.Code, utils.PrintExpectedValues(tc.ExpectedStatusCode, res.Code))
+
+			expected, _ := json.Marshal(tc.ExpectedResponse)
+			suite.Equal(string(expected), res.Body.String(), utils.PrintExpectedValues(string(expected), res.Body.String()))
+		})
+	}
+}
+
+func (suite *customerCtrlTest) TestSetDefaultPaymentMethod() {
+	url := "/api/v1/customers/payment-methods/default"
+
+	testcases := []struct {
+		Name               string
+		Body               any
+		Updated            bool
+		ExpectedStatusCode int
+		ExpectedResponse   any
+	}{
+		{
+			Name:               "TestCustomerController_SetDefault",
+			Body:               dto.CustomerDefaultPaymentMethodRequest{PaymentMethodID: "pm_master"},
+			Updated:            true,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.RecordUpdated},
+		},
+		{
+			// Cards of other customers can not be set
+			Name:               "TestCustomerController_SetDefaultNotOwned",
+			Body:               dto.CustomerDefaultPaymentMethodRequest{PaymentMethodID: "pm_other"},
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.PaymentMethodNotFound},
+		},
+		{
+			Name:               "TestCustomerController_SetDefaultMissingCard",
+			Body:               dto.CustomerDefaultPaymentMethodRequest{},
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedResponse: []dto.BadRequestMessage{{
+				Field:   "payment_method_id",
+				Message: "required",
+			}},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.resetStripeService()
+			suite.stripeService.On("SetDefaultPaymentMethod", "cus_cards", "pm_master").Return(nil).Maybe()
+
+			res := suite.testRequest.Put(url, tc.Body)
+
+			suite.Equal(tc.ExpectedStatusCode, res.Code, utils.PrintExpectedValues(tc.ExpectedStatusCode, res.Code))
+
+			expected, _ := json.Marshal(tc.ExpectedResponse)
+			suite.Equal(string(expected), res.Body.String(), utils.PrintExpectedValues(string(expected), res.Body.String()))
+
+			if tc.Updated {
+				suite.stripeService.AssertCalled(suite.T(), "SetDefaultPaymentMethod", "cus_cards", "pm_master")
+			} else {
+				suite.stripeService.AssertNotCalled(suite.T(), "SetDefaultPaymentMethod", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func (suite *customerCtrlTest) TestListPaymentMethodsDefault() {
+	suite.resetStripeService()
+	suite.stripeService.On("GetDefaultPaymentMethod", "cus_cards").Return(suite.cards[1], nil).Once()
+
+	res := suite.testRequest.Get("/api/v1/customers/payment-methods", nil)
+
+	suite.Equal(http.StatusOK, res.Code, utils.PrintExpectedValues(http.StatusOK, res.Code))
+
+	var response dto.ListCustomerPaymentMethodResponse
+	suite.Nil(json.Unmarshal(res.Body.Bytes(), &response))
+	suite.Len(response, 2)
+	suite.False(response[0].IsDefault)
+	suite.True(response[1].IsDefault)
+}
+
+func TestCustomerController(t *testing.T) {
+	suite.Run(t, new(customerCtrlTest))
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"net/http"
+	"smartgas-payment/internal/dto"
+	"smartgas-payment/internal/lang"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jinzhu/copier"
+)
+
+type DisputeController interface {
+	List(*gin.Context)
+}
+
+type disputeController struct {
+	repository repository.DisputeRepository
+}
+
+func ProvideDisputeController(repository repository.DisputeRepository) *disputeController {
+	return &disputeController{
+		repository: repository,
+	}
+}
+
+// @Summary Dispute List
+// @Description Get paginated chargebacks opened on stripe payments
+// @Tags Disputes
+// @Produce json
+// @Router /api/v1/disputes [GET]
+// @Security Bearer
+// @Param param query dto.PaginateRequest true "Pagination"
+// @Param filters query dto.DisputeListQueryRequest false "Filters"
+// @Success 200 {object} dto.PaginationResponse{data=[]dto.DisputeResponse} "Disputes List"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (dc *disputeController) List(c *gin.Context) {
+	var pagination dto.PaginateRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaginateRequest](err))
+		return
+	}
+
+	var params dto.DisputeListQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.DisputeListQueryRequest](err))
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	var paginationSchema schemas.Pagination
+
+	copier.Copy(&paginationSchema, &pagination)
+
+	filters := map[string]any{}
+
+	if params.Status != "" {
+		filters["status"] = params.Status
+	}
+
+	if params.PaymentID != "" {
+		paymentID, _ := uuid.Parse(params.PaymentID)
+		filters["payment_id"] = paymentID
+	}
+
+	utils.AddStationsFilter(user, filters)
+
+	disputes, err := dc.repository.List(&paginationSchema, filters)
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: user,
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	response := make([]dto.DisputeResponse, 0)
+
+	copier.Copy(&response, &disputes)
+
+	var paginationResponse dto.PaginationResponse
+
+	copier.Copy(&paginationResponse, &paginationSchema)
+
+	paginationResponse.Data = response
+
+	c.JSON(http.StatusOK, paginationResponse)
+}
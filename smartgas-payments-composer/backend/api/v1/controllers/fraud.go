@@ -0,0 +1,569 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"smartgas-payment/internal/dto"
+	"smartgas-payment/internal/lang"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+type FraudController interface {
+	ListRules(*gin.Context)
+	GetRule(*gin.Context)
+	CreateRule(*gin.Context)
+	UpdateRule(*gin.Context)
+	DeleteRule(*gin.Context)
+	ListBlocks(*gin.Context)
+	CreateBlock(*gin.Context)
+	DeleteBlock(*gin.Context)
+	ListDecisions(*gin.Context)
+}
+
+type fraudController struct {
+	repository repository.FraudRepository
+}
+
+func ProvideFraudController(repository repository.FraudRepository) *fraudController {
+	return &fraudController{
+		repository: repository,
+	}
+}
+
+func fraudGasStationResponse(gasStation *models.GasStation) *dto.FraudGasStationResponse {
+	if gasStation == nil {
+		return nil
+	}
+
+	return &dto.FraudGasStationResponse{ID: gasStation.ID, Name: gasStation.Name}
+}
+
+func fraudRuleResponse(rule *models.FraudRule) dto.FraudRuleResponse {
+	var response dto.FraudRuleResponse
+
+	copier.Copy(&response, rule)
+
+	response.GasStation = fraudGasStationResponse(rule.GasStation)
+
+	return response
+}
+
+// fraudRuleFromRequest copies the body to the rule, the limit not used by the rule
+// type is set to zero
+func fraudRuleFromRequest(body *dto.FraudRuleRequest, rule *models.FraudRule) {
+	rule.Type = body.Type
+	rule.Active = body.Active
+	rule.GasStationID = nil
+	rule.MaxAmount = 0
+	rule.MaxCount = 0
+
+	if body.GasStationID != "" {
+		gasStationID, _ := uuid.Parse(body.GasStationID)
+		rule.GasStationID = &gasStationID
+	}
+
+	switch body.Type {
+	case models.FraudMaxAmountPerLoad, models.FraudMaxAmountPerDay:
+		rule.MaxAmount = body.MaxAmount
+	default:
+		rule.MaxCount = body.MaxCount
+	}
+}
+
+func (fc *fraudController) trackError(c *gin.Context, err error) {
+	// Logging error in sentry
+	opts := &utils.TrackErrorOpts{
+		Admin: c.MustGet("user").(*models.User),
+		Tags:  map[string]string{"auth_type": "admin"},
+	}
+	utils.TrackError(c, err, opts)
+	c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+}
+
+// @Summary Fraud Rule List
+// @Description Get paginated limits checked before reserving the funds of a load
+// @Tags Fraud
+// @Produce json
+// @Router /api/v1/fraud/rules [GET]
+// @Security Bearer
+// @Param param query dto.PaginateRequest true "Pagination"
+// @Param filters query dto.FraudRuleListQueryRequest false "Filters"
+// @Success 200 {object} dto.PaginationResponse{data=[]dto.FraudRuleResponse} "Fraud rules"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (fc *fraudController) ListRules(c *gin.Context) {
+	var pagination dto.PaginateRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaginateRequest](err))
+		return
+	}
+
+	var params dto.FraudRuleListQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.FraudRuleListQueryRequest](err))
+		return
+	}
+
+	var paginationSchema schemas.Pagination
+
+	copier.Copy(&paginationSchema, &pagination)
+
+	filters := map[string]any{}
+
+	if params.Type != "" {
+		filters["type"] = params.Type
+	}
+
+	if params.GasStationID != "" {
+		filters["gas_station_id"] = params.GasStationID
+	}
+
+	rules, err := fc.repository.ListRules(&paginationSchema, filters)
+	if err != nil {
+		fc.trackError(c, err)
+		return
+	}
+
+	response := make([]dto.FraudRuleResponse, 0, len(rules))
+
+	for _, rule := range rules {
+		response = append(response, fraudRuleResponse(rule))
+	}
+
+	var paginationResponse dto.PaginationResponse
+
+	copier.Copy(&paginationResponse, &paginationSchema)
+
+	paginationResponse.Data = response
+
+	c.JSON(http.StatusOK, paginationResponse)
+}
+
+// getRule binds the path and returns the rule, otherwise the response is written
+// and nil is returned
+func (fc *fraudController) getRule(c *gin.Context) *models.FraudRule {
+	var path dto.FraudPathRequest
+	if err := c.ShouldBindUri(&path); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.FraudPathRequest](err))
+		return nil
+	}
+
+	id, _ := uuid.Parse(path.ID)
+
+	rule, err := fc.repository.GetRuleByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NotFoundRecord})
+			return nil
+		}
+		fc.trackError(c, err)
+		return nil
+	}
+
+	return rule
+}
+
+// @Summary Fraud Rule Detail
+// @Description Get a limit checked before reserving the funds of a load
+// @Tags Fraud
+// @Produce json
+// @Router /api/v1/fraud/rules/{id} [GET]
+// @Security Bearer
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Success 200 {object} dto.FraudRuleResponse "Fraud rule"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (fc *fraudController) GetRule(c *gin.Context) {
+	rule := fc.getRule(c)
+	if rule == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, fraudRuleResponse(rule))
+}
+
+// @Summary Create Fraud Rule
+// @Description Add a limit for the loads of every station or of one station. Every limit reached rejects the load
+// @Tags Fraud
+// @Accept json
+// @Produce json
+// @Router /api/v1/fraud/rules [POST]
+// @Security Bearer
+// @Param data body dto.FraudRuleRequest true "Fraud rule"
+// @Success 201 {object} dto.FraudRuleResponse "Fraud rule"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 406 {object} dto.GeneralMessage "Gas station not exists"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (fc *fraudController) CreateRule(c *gin.Context) {
+	var body dto.FraudRuleRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.FraudRuleRequest](err))
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	rule := &models.FraudRule{CreatedByID: &user.ID}
+
+	fraudRuleFromRequest(&body, rule)
+
+	if err := fc.repository.CreateRule(rule); err != nil {
+		if utils.CheckMysqlErrCode(err, 1452) {
+			c.JSON(http.StatusNotAcceptable, dto.GeneralMessage{Detail: lang.NotAcceptable + "gas_station_id"})
+			return
+		}
+		fc.trackError(c, err)
+		return
+	}
+
+	rule, err := fc.repository.GetRuleByID(rule.ID)
+	if err != nil {
+		fc.trackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, fraudRuleResponse(rule))
+}
+
+// @Summary Update Fraud Rule
+// @Description Update a limit checked before reserving the funds of a load
+// @Tags Fraud
+// @Accept json
+// @Produce json
+// @Router /api/v1/fraud/rules/{id} [PUT]
+// @Security Bearer
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Param data body dto.FraudRuleRequest true "Fraud rule"
+// @Success 200 {object} dto.FraudRuleResponse "Fraud rule"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 406 {object} dto.GeneralMessage "Gas station not exists"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (fc *fraudController) UpdateRule(c *gin.Context) {
+	var body dto.FraudRuleRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.FraudRuleRequest](err))
+		return
+	}
+
+	rule := fc.getRule(c)
+	if rule == nil {
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	fraudRuleFromRequest(&body, rule)
+	rule.UpdatedByID = &user.ID
+
+	if err := fc.repository.UpdateRule(rule); err != nil {
+		if utils.CheckMysqlErrCode(err, 1452) {
+			c.JSON(http.StatusNotAcceptable, dto.GeneralMessage{Detail: lang.NotAcceptable + "gas_station_id"})
+			return
+		}
+		fc.trackError(c, err)
+		return
+	}
+
+	rule, err := fc.repository.GetRuleByID(rule.ID)
+	if err != nil {
+		fc.trackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fraudRuleResponse(rule))
+}
+
+// @Summary Delete Fraud Rule
+// @Description Delete a limit, past decisions keep their reason
+// @Tags Fraud
+// @Produce json
+// @Router /api/v1/fraud/rules/{id} [DELETE]
+// @Security Bearer
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Success 200 {object} dto.GeneralMessage "Deleted"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (fc *fraudController) DeleteRule(c *gin.Context) {
+	var path dto.FraudPathRequest
+	if err := c.ShouldBindUri(&path); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.FraudPathRequest](err))
+		return
+	}
+
+	id, _ := uuid.Parse(path.ID)
+
+	deleted, err := fc.repository.DeleteRule(id)
+	if err != nil {
+		fc.trackError(c, err)
+		return
+	}
+
+	if !deleted {
+		c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NotFoundRecord})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.GeneralMessage{Detail: "Deleted"})
+}
+
+// @Summary Fraud Block List
+// @Description Get paginated customers, cards and gift cards that can not reserve funds
+// @Tags Fraud
+// @Produce json
+// @Router /api/v1/fraud/blocks [GET]
+// @Security Bearer
+// @Param param query dto.PaginateRequest true "Pagination"
+// @Param filters query dto.FraudBlockListQueryRequest false "Filters"
+// @Success 200 {object} dto.PaginationResponse{data=[]dto.FraudBlockResponse} "Fraud blocks"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (fc *fraudController) ListBlocks(c *gin.Context) {
+	var pagination dto.PaginateRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaginateRequest](err))
+		return
+	}
+
+	var params dto.FraudBlockListQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.FraudBlockListQueryRequest](err))
+		return
+	}
+
+	var paginationSchema schemas.Pagination
+
+	copier.Copy(&paginationSchema, &pagination)
+
+	filters := map[string]any{}
+
+	if params.Type != "" {
+		filters["type"] = params.Type
+	}
+
+	if params.Value != "" {
+		filters["value"] = params.Value
+	}
+
+	blocks, err := fc.repository.ListBlocks(&paginationSchema, filters)
+	if err != nil {
+		fc.trackError(c, err)
+		return
+	}
+
+	response := make([]dto.FraudBlockResponse, 0)
+
+	copier.Copy(&response, &blocks)
+
+	var paginationResponse dto.PaginationResponse
+
+	copier.Copy(&paginationResponse, &paginationSchema)
+
+	paginationResponse.Data = response
+
+	c.JSON(http.StatusOK, paginationResponse)
+}
+
+// @Summary Create Fraud Block
+// @Description Block a customer, card or gift card, its loads are rejected before reserving funds
+// @Tags Fraud
+// @Accept json
+// @Produce json
+// @Router /api/v1/fraud/blocks [POST]
+// @Security Bearer
+// @Param data body dto.FraudBlockRequest true "Fraud block"
+// @Success 201 {object} dto.FraudBlockResponse "Fraud block"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 406 {object} dto.GeneralMessage "Customer blocks need a customer id"
+// @Failure 409 {object} dto.GeneralMessage "Already blocked"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (fc *fraudController) CreateBlock(c *gin.Context) {
+	var body dto.FraudBlockRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.FraudBlockRequest](err))
+		return
+	}
+
+	if body.Type == models.FraudBlockCustomer {
+		if _, err := uuid.Parse(body.Value); err != nil {
+			c.JSON(http.StatusNotAcceptable, dto.GeneralMessage{Detail: lang.NotAcceptable + "value"})
+			return
+		}
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	block := &models.FraudBlock{
+		Type:        body.Type,
+		Value:       body.Value,
+		Reason:      body.Reason,
+		CreatedByID: &user.ID,
+	}
+
+	if err := fc.repository.CreateBlock(block); err != nil {
+		if utils.CheckDuplicatedEntry(err) {
+			c.JSON(http.StatusConflict, dto.GeneralMessage{Detail: lang.DuplicatedEntry + "value"})
+			return
+		}
+		fc.trackError(c, err)
+		return
+	}
+
+	var response dto.FraudBlockResponse
+
+	copier.Copy(&response, block)
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// @Summary Delete Fraud Block
+// @Description Unblock a customer, card or gift card
+// @Tags Fraud
+// @Produce json
+// @Router /api/v1/fraud/blocks/{id} [DELETE]
+// @Security Bearer
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Success 200 {object} dto.GeneralMessage "Deleted"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (fc *fraudController) DeleteBlock(c *gin.Context) {
+	var path dto.FraudPathRequest
+	if err := c.ShouldBindUri(&path); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.FraudPathRequest](err))
+		return
+	}
+
+	id, _ := uuid.Parse(path.ID)
+
+	deleted, err := fc.repository.DeleteBlock(id)
+	if err != nil {
+		fc.trackError(c, err)
+		return
+	}
+
+	if !deleted {
+		c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NotFoundRecord})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.GeneralMessage{Detail: "Deleted"})
+}
+
+// @Summary Fraud Decision List
+// @Description Get paginated decisions taken before reserving the funds of loads, newest first
+// @Tags Fraud
+// @Produce json
+// @Router /api/v1/fraud/decisions [GET]
+// @Security Bearer
+// @Param param query dto.PaginateRequest true "Pagination"
+// @Param filters query dto.FraudDecisionListQueryRequest false "Filters, dates are days in local time and both are included"
+// @Success 200 {object} dto.PaginationResponse{data=[]dto.FraudDecisionResponse} "Fraud decisions"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (fc *fraudController) ListDecisions(c *gin.Context) {
+	var pagination dto.PaginateRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaginateRequest](err))
+		return
+	}
+
+	var params dto.FraudDecisionListQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.FraudDecisionListQueryRequest](err))
+		return
+	}
+
+	if params.From != "" && params.To != "" && params.To < params.From {
+		c.JSON(http.StatusBadRequest, dto.GeneralMessage{Detail: lang.InvalidDateRange})
+		return
+	}
+
+	var paginationSchema schemas.Pagination
+
+	copier.Copy(&paginationSchema, &pagination)
+
+	user := c.MustGet("user").(*models.User)
+
+	filters := map[string]any{}
+
+	if params.Allowed != "" {
+		filters["allowed"] = params.Allowed == "true"
+	}
+
+	if params.Reason != "" {
+		filters["reason"] = params.Reason
+	}
+
+	if params.CustomerID != "" {
+		filters["customer_id"] = params.CustomerID
+	}
+
+	if params.GasStationID != "" {
+		filters["gas_station_id"] = params.GasStationID
+	}
+
+	if params.From != "" {
+		from, _ := time.ParseInLocation("2006-01-02", params.From, time.Local)
+		filters["from"] = from
+	}
+
+	if params.To != "" {
+		to, _ := time.ParseInLocation("2006-01-02", params.To, time.Local)
+		// Including the whole day
+		filters["to"] = to.AddDate(0, 0, 1)
+	}
+
+	utils.AddStationsFilter(user, filters)
+
+	decisions, err := fc.repository.ListDecisions(&paginationSchema, filters)
+	if err != nil {
+		fc.trackError(c, err)
+		return
+	}
+
+	response := make([]dto.FraudDecisionResponse, 0, len(decisions))
+
+	for _, decision := range decisions {
+		var item dto.FraudDecisionResponse
+
+		copier.Copy(&item, decision)
+
+		item.GasStation = fraudGasStationResponse(decision.GasStation)
+		item.Customer = nil
+		if decision.Customer != nil {
+			item.Customer = &dto.FraudCustomerResponse{}
+			copier.Copy(item.Customer, decision.Customer)
+		}
+
+		response = append(response, item)
+	}
+
+	var paginationResponse dto.PaginationResponse
+
+	copier.Copy(&paginationResponse, &paginationSchema)
+
+	paginationResponse.Data = response
+
+	c.JSON(http.StatusOK, paginationResponse)
+}
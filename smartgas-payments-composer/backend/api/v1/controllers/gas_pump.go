@@ -3,6 +3,7 @@ package controllers
 import (
 	"errors"
 	"net/http"
+	"smartgas-payment/internal/discounts"
 	"smartgas-payment/internal/dto"
 	"smartgas-payment/internal/lang"
 	"smartgas-payment/internal/models"
@@ -10,7 +11,6 @@ import (
 	"smartgas-payment/internal/schemas"
 	"smartgas-payment/internal/tasks"
 	"smartgas-payment/internal/utils"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -29,21 +29,18 @@ type GasPumpController interface {
 type gasPumpController struct {
 	repository          repository.GasPumpRepository
 	synchronizationTask tasks.SynchronizationTask
-	campaignRepository  repository.CampaignRepository
-	settingsRepo        repository.SettingRepository
+	discountEngine      discounts.Engine
 }
 
 func ProvideGasPumpProvider(
 	repository repository.GasPumpRepository,
 	synchronizationTask tasks.SynchronizationTask,
-	campaignRepository repository.CampaignRepository,
-	settingsRepo repository.SettingRepository,
+	discountEngine discounts.Engine,
 ) *gasPumpController {
 	return &gasPumpController{
 		repository:          repository,
 		synchronizationTask: synchronizationTask,
-		campaignRepository:  campaignRepository,
-		settingsRepo:        settingsRepo,
+		discountEngine:      discountEngine,
 	}
 }
 
@@ -315,64 +312,35 @@ func (gp *gasPumpController) GetDetailForCustomer(c *gin.Context) {
 
 	var gasPump dto.GasPumpGetDetailForCustomerResponse
 
-	applicablePromotionType, err := gp.settingsRepo.GetByName("applicable_promotion_type")
+	result, err := gp.discountEngine.Evaluate(discounts.Input{
+		Customer:   customer,
+		GasStation: station.GasStation,
+		Preview:    true,
+	})
 	if err != nil {
-		var csmErr error
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			csmErr = errors.New("Applicable promotion type not found")
-		} else {
-			csmErr = err
-		}
 		// Logging error in sentry
 		opts := &utils.TrackErrorOpts{
 			Customer: customer,
 			Tags:     map[string]string{"auth_type": "customer"},
 		}
-		utils.TrackError(c, csmErr, opts)
-	}
-
-	promotionTypeInDB := "none"
-	// Check applicable promotion
-	gasPump.DiscountType = "none"
-
-	if applicablePromotionType != nil {
-		promotionTypeInDB = applicablePromotionType.Value
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
 	}
 
-	if promotionTypeInDB == "campaign" {
-		// Checking valid promotions
-		campaign, err := gp.campaignRepository.GetApplicableCampaign(
-			time.Now(),
-			station.GasStation.ID,
-		)
-		if err != nil {
-			if !errors.Is(err, gorm.ErrRecordNotFound) {
-				// Logging error in sentry
-				opts := &utils.TrackErrorOpts{
-					Customer: customer,
-					Tags:     map[string]string{"auth_type": "customer"},
-				}
-				utils.TrackError(c, err, opts)
-				c.JSON(
-					http.StatusInternalServerError,
-					dto.GeneralMessage{Detail: lang.InternalServerError},
-				)
-				return
-			}
-		}
-
-		if campaign != nil {
-			gasPump.DiscountType = "campaign"
-			gasPump.Campaign = &struct {
-				Name     string  "json:\"name\""
-				Discount float64 "json:\"discount\""
-			}{
-				Discount: *campaign.Discount,
-				Name:     campaign.Name,
-			}
+	gasPump.DiscountType = result.Type
+	gasPump.DiscountPerLiter = result.DiscountPerLiter
+	gasPump.Stacking = result.Stacking
+	copier.Copy(&gasPump.Discounts, result.Discounts)
+
+	if result.CampaignID() != nil {
+		gasPump.Campaign = &struct {
+			Name     string  "json:\"name\""
+			Discount float64 "json:\"discount\""
+		}{
+			Discount: *result.Campaign.Discount,
+			Name:     result.Campaign.Name,
 		}
-	} else if promotionTypeInDB == "elegibility" {
-		gasPump.DiscountType = "elegibility"
 	}
 
 	copier.Copy(&gasPump, &station)
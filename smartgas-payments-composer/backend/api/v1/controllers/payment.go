@@ -1,17 +1,21 @@
 package controllers
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
 	"io"
 	"net/http"
 	"smartgas-payment/config"
+	"smartgas-payment/internal/discounts"
 	"smartgas-payment/internal/dto"
 	"smartgas-payment/internal/lang"
 	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/payments"
 	"smartgas-payment/internal/repository"
 	"smartgas-payment/internal/schemas"
 	"smartgas-payment/internal/services"
+	"smartgas-payment/internal/tasks"
 	"smartgas-payment/internal/utils"
 	"strings"
 	"time"
@@ -20,20 +24,20 @@ import (
 
 	"github.com/getsentry/sentry-go"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/jinzhu/copier"
-	"github.com/stripe/stripe-go/v72"
-	"github.com/stripe/stripe-go/v72/charge"
 	"github.com/stripe/stripe-go/v72/webhook"
 	"gorm.io/gorm"
 )
 
 const (
-	minChargeAmount float32 = 10.0
+	// $10.00 MXN
+	minChargeAmount money.Amount = 1000
 )
 
-var paymentWebsocket = internalWebsocket.InitChannels()
+var paymentWebsocket = internalWebsocket.PaymentChannels
 
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
@@ -44,6 +48,9 @@ var upgrader = websocket.Upgrader{
 
 type PaymentController interface {
 	List(*gin.Context)
+	Export(*gin.Context)
+	GetByID(*gin.Context)
+	Quote(*gin.Context)
 	CreateIntent(*gin.Context)
 	StripeWebhook(*gin.Context)
 	AddEvent(*gin.Context)
@@ -53,6 +60,8 @@ type PaymentController interface {
 	SignInvoice(*gin.Context)
 	ResendInvoice(c *gin.Context)
 	GetInvoicePDF(c *gin.Context)
+	GetReceipt(c *gin.Context)
+	GetReceiptOperation(c *gin.Context)
 	DoPaymentAction(c *gin.Context)
 	CreateIntentOperation(c *gin.Context)
 }
@@ -60,46 +69,49 @@ type PaymentController interface {
 type paymentController struct {
 	repository        repository.PaymentRepository
 	gasPumpRepository repository.GasPumpRepository
-	stripeService     services.StripeService
-	switService       services.SwitService
+	providers         services.PaymentProviderRegistry
 	config            config.Config
 	socioSmartService services.SocioSmartService
 	invoicingService  services.InvoicingService
-	mailService       services.MailService
+	receiptService    services.ReceiptService
+	outboxTask        tasks.OutboxTask
+	stripeWebhookTask tasks.StripeWebhookTask
+	fraudTask         tasks.FraudTask
+	refundRepository  repository.RefundRepository
 	settingsRepo      repository.SettingRepository
-	campaignRepo      repository.CampaignRepository
-	elegibilityRepo   repository.ElegibilityRepository
-	debitService      services.DebitService
+	discountEngine    discounts.Engine
 	customerRepo      repository.CustomerRepository
 }
 
 func ProvidePaymentController(repository repository.PaymentRepository,
 	gasPumpRepository repository.GasPumpRepository,
-	stripeService services.StripeService,
+	providers services.PaymentProviderRegistry,
 	config config.Config,
 	socioSmartService services.SocioSmartService,
-	switService services.SwitService,
 	invoicingService services.InvoicingService,
-	mailService services.MailService,
+	receiptService services.ReceiptService,
+	outboxTask tasks.OutboxTask,
+	stripeWebhookTask tasks.StripeWebhookTask,
+	fraudTask tasks.FraudTask,
+	refundRepository repository.RefundRepository,
 	settingsRepo repository.SettingRepository,
-	campaignRepo repository.CampaignRepository,
-	elegibilityRepo repository.ElegibilityRepository,
-	debitService services.DebitService,
+	discountEngine discounts.Engine,
 	customerRepo repository.CustomerRepository,
 ) *paymentController {
 	return &paymentController{
 		repository:        repository,
 		gasPumpRepository: gasPumpRepository,
-		stripeService:     stripeService,
+		providers:         providers,
 		config:            config,
 		socioSmartService: socioSmartService,
-		switService:       switService,
 		invoicingService:  invoicingService,
-		mailService:       mailService,
+		receiptService:    receiptService,
+		outboxTask:        outboxTask,
+		stripeWebhookTask: stripeWebhookTask,
+		fraudTask:         fraudTask,
+		refundRepository:  refundRepository,
 		settingsRepo:      settingsRepo,
-		campaignRepo:      campaignRepo,
-		elegibilityRepo:   elegibilityRepo,
-		debitService:      debitService,
+		discountEngine:    discountEngine,
 		customerRepo:      customerRepo,
 	}
 }
@@ -111,7 +123,7 @@ func ProvidePaymentController(repository repository.PaymentRepository,
 // @Router /api/v1/payments [GET]
 // @Security Bearer
 // @Param param query dto.PaginateRequest true "Pagination"
-// @Param search query string false "Lookup in payments"
+// @Param filters query dto.PaymentListQueryRequest false "Filters and sorting, dates are days in local time and both are included"
 // @Success 200 {object} dto.PaginationResponse{data=[]dto.PaymentListResponse} "Payments paginated"
 // @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
 // @Failure 401 {object} dto.GeneralMessage "Unauthorized"
@@ -123,15 +135,25 @@ func (pc *paymentController) List(c *gin.Context) {
 		return
 	}
 
+	var params dto.PaymentListQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaymentListQueryRequest](err))
+		return
+	}
+
+	filters, invalid := paymentListFilters(&params)
+	if invalid != "" {
+		c.JSON(http.StatusBadRequest, dto.GeneralMessage{Detail: invalid})
+		return
+	}
+
 	user := c.MustGet("user").(*models.User)
 
 	var paginationSchema schemas.Pagination
 
 	copier.Copy(&paginationSchema, &pagination)
 
-	search := c.Query("search")
-
-	filters := map[string]any{"search": "%" + search + "%"}
+	paginationSchema.Sort = paymentListSort(&params)
 
 	utils.AddStationsFilter(user, filters)
 
@@ -161,6 +183,272 @@ func (pc *paymentController) List(c *gin.Context) {
 	c.JSON(http.StatusOK, paginationResponse)
 }
 
+// paymentListSort returns the order of the list query, both values are limited by
+// the validation of the request. Payments are ordered by id last so rows sharing the
+// sorted value keep their order between pages
+func paymentListSort(params *dto.PaymentListQueryRequest) string {
+	sortBy, sortOrder := "created_at", "desc"
+	if params.SortBy != "" {
+		sortBy = params.SortBy
+	}
+	if params.SortOrder != "" {
+		sortOrder = params.SortOrder
+	}
+
+	return "payments." + sortBy + " " + sortOrder + ", payments.id " + sortOrder
+}
+
+// paymentExportHeader are the columns of the payments export
+var paymentExportHeader = []any{
+	"id", "created_at", "status", "last_event", "payment_provider", "external_transaction_id",
+	"gas_station", "gas_pump", "customer", "fuel_type", "charge_type", "price", "total_liter",
+	"amount", "real_amount_reported", "refunded_amount", "charge_fee", "discount_per_liter",
+	"discount_type", "gm_points", "invoiced", "invoice_uuid",
+}
+
+// @Summary Payment Export
+// @Description Download the payments matching the list filters as csv or xlsx. csv files are streamed while they are built, xlsx files are sent once complete and are limited to 100000 rows
+// @Tags Payments
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Router /api/v1/payments/export [GET]
+// @Security Bearer
+// @Param filters query dto.PaymentListQueryRequest false "Same filters and sorting of the list"
+// @Param format query dto.PaymentExportQueryRequest false "File format, csv by default"
+// @Success 200 {file} file "Payments file"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 422 {object} dto.GeneralMessage "More rows than allowed for xlsx files"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (pc *paymentController) Export(c *gin.Context) {
+	var params dto.PaymentListQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaymentListQueryRequest](err))
+		return
+	}
+
+	var exportParams dto.PaymentExportQueryRequest
+	if err := c.ShouldBindQuery(&exportParams); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaymentExportQueryRequest](err))
+		return
+	}
+
+	filters, invalid := paymentListFilters(&params)
+	if invalid != "" {
+		c.JSON(http.StatusBadRequest, dto.GeneralMessage{Detail: invalid})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	utils.AddStationsFilter(user, filters)
+
+	format := exportParams.Format
+	if format == "" {
+		format = "csv"
+	}
+
+	writer, err := utils.NewTabularWriter(format, c.Writer)
+
+	opts := &utils.TrackErrorOpts{
+		Admin: user,
+		Tags:  map[string]string{"auth_type": "admin"},
+	}
+
+	if err != nil {
+		// Logging error in sentry
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	// Nothing reaches the client before the first rows, errors can still be answered
+	if err := writer.WriteRow(paymentExportHeader...); err != nil {
+		// Logging error in sentry
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	filename := "payments-" + time.Now().Format("20060102-150405") + "." + format
+
+	c.Header("Content-Type", utils.TabularContentTypes[format])
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	err = pc.repository.Export(filters, paymentListSort(&params), func(row *repository.PaymentExportRow) error {
+		return writer.WriteRow(
+			row.ID, row.CreatedAt, row.Status, row.LastEvent, row.PaymentProvider,
+			row.ExternalTransactionID, row.GasStationName, row.GasPumpNumber, row.CustomerName,
+			row.FuelType, row.ChargeType, row.Price, row.TotalLiter, row.Amount,
+			row.RealAmountReported, row.RefundedAmount, row.ChargeFee, row.DiscountPerLiter,
+			row.DiscountType, row.GMPoints, row.Invoiced, row.InvoiceID,
+		)
+	})
+
+	if err == nil {
+		err = writer.Close()
+	}
+
+	// xlsx files are written when closed, so nothing was sent
+	if errors.Is(err, utils.ErrTooManyRows) {
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		c.JSON(http.StatusUnprocessableEntity, dto.GeneralMessage{Detail: lang.ExportTooLarge})
+		return
+	}
+
+	// The status was already sent, the client gets a truncated file
+	if err != nil {
+		// Logging error in sentry
+		utils.TrackError(c, err, opts)
+		c.Abort()
+	}
+}
+
+// paymentListFilters maps the list query to the filters of the payment repository,
+// when the ranges are invalid the message explaining why is returned instead
+func paymentListFilters(params *dto.PaymentListQueryRequest) (map[string]any, string) {
+	filters := map[string]any{"search": "%" + params.Search + "%"}
+
+	values := map[string]string{
+		"status":           params.Status,
+		"last_event":       params.LastEvent,
+		"payment_provider": params.PaymentProvider,
+		"fuel_type":        params.FuelType,
+		"discount_type":    params.DiscountType,
+		"employee_id":      params.EmployeeID,
+	}
+	for key, value := range values {
+		if value != "" {
+			filters[key] = value
+		}
+	}
+
+	ids := map[string]string{
+		"gas_station_id": params.GasStationID,
+		"gas_pump_id":    params.GasPumpID,
+		"customer_id":    params.CustomerID,
+	}
+	for key, value := range ids {
+		if value != "" {
+			id, _ := uuid.Parse(value)
+			filters[key] = id
+		}
+	}
+
+	if params.FromOperations != nil {
+		filters["from_operations"] = *params.FromOperations
+	}
+
+	if params.Invoiced != nil {
+		filters["invoiced"] = *params.Invoiced
+	}
+
+	// Amounts are pesos, parsed without going through floats
+	var minAmount money.Amount
+	if params.MinAmount != "" {
+		amount, err := money.Parse(params.MinAmount)
+		if err != nil || amount < money.Zero {
+			return nil, lang.InvalidAmountRange
+		}
+		minAmount = amount
+		filters["min_amount"] = amount
+	}
+	if params.MaxAmount != "" {
+		amount, err := money.Parse(params.MaxAmount)
+		if err != nil || amount < minAmount {
+			return nil, lang.InvalidAmountRange
+		}
+		filters["max_amount"] = amount
+	}
+
+	// Dates were validated by the request, to includes its whole day
+	var from, to time.Time
+	if params.From != "" {
+		from, _ = time.ParseInLocation("2006-01-02", params.From, time.Local)
+		filters["from"] = from
+	}
+	if params.To != "" {
+		to, _ = time.ParseInLocation("2006-01-02", params.To, time.Local)
+		to = to.AddDate(0, 0, 1)
+		filters["to"] = to
+	}
+
+	if params.From != "" && params.To != "" && !from.Before(to) {
+		return nil, lang.InvalidDateRange
+	}
+
+	return filters, ""
+}
+
+// @Summary Payment Detail
+// @Description Payment with its customer, pump, discount, refunds and every event with the application or user that produced it
+// @Tags Payments
+// @Produce json
+// @Router /api/v1/payments/{id} [GET]
+// @Security Bearer
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Success 200 {object} dto.PaymentDetailResponse "Payment detail"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (pc *paymentController) GetByID(c *gin.Context) {
+	var path dto.PaymentDetailPath
+	if err := c.ShouldBindUri(&path); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaymentDetailPath](err))
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	id, _ := uuid.Parse(path.ID)
+
+	filters := map[string]any{}
+
+	utils.AddStationsFilter(user, filters)
+
+	opts := &utils.TrackErrorOpts{
+		Admin: user,
+		Tags:  map[string]string{"auth_type": "admin"},
+	}
+
+	payment, err := pc.repository.GetByIDDetailed(id, filters)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NotFoundRecord})
+			return
+		}
+		// Logging error in sentry
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	refunds, err := pc.refundRepository.ListByPaymentID(payment.ID)
+	if err != nil {
+		// Logging error in sentry
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	var response dto.PaymentDetailResponse
+
+	copier.Copy(&response, payment)
+
+	response.Refunds = make([]dto.RefundResponse, 0)
+
+	copier.Copy(&response.Refunds, &refunds)
+
+	if response.Events == nil {
+		response.Events = make([]dto.PaymentEventResponse, 0)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // @Summary Create Payment intent from operation app
 // @Description Creaate Payment intent from opertion app with employee credentials
 // @Tags Payments
@@ -170,12 +458,16 @@ func (pc *paymentController) List(c *gin.Context) {
 // @Param X-GAS-STATION-ID header string true "Gas Station ID"
 // @Param X-EMPLOYEE-ID header string true "Employee ID"
 // @Param X-EMPLOYEE-NIP header string true "Employee NIP"
+// @Param Idempotency-Key header string false "Key to safely retry the request"
 // @Success 201 {object} dto.PaymentCrateIntentResponse "Payment intent information"
 // @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
 // @Failure 401 {object} dto.GeneralMessage "Unauthorized"
 // @Failure 402 {object} dto.GeneralMessage "Payment Required, Unsufficient funds"
+// @Failure 403 {object} dto.GeneralMessage "Rejected by the fraud rules, the customer or card is blocked or a limit was reached"
 // @Failure 404 {object} dto.GeneralMessage "Whether gas station or pump not found"
 // @Failure 406 {object} dto.GeneralMessage "Not fuel type in gas pump"
+// @Failure 409 {object} dto.GeneralMessage "Request with the same Idempotency-Key in progress"
+// @Failure 422 {object} dto.GeneralMessage "Idempotency-Key used with a different request"
 // @Failure 500 {object} dto.GeneralMessage "Internal server error"
 func (pc *paymentController) CreateIntentOperation(c *gin.Context) {
 	var body dto.CreatePaymentIntentOperationRequest
@@ -246,25 +538,44 @@ func (pc *paymentController) CreateIntentOperation(c *gin.Context) {
 		return
 	}
 
-	status := "pending"
-	var opts services.DebitReserveFundsOpts
-	if body.ChargeType == "customer" {
-		opts = services.DebitReserveFundsOpts{
-			Amount: body.Amount,
-			// ExternalCustomerID:  customer.ExternalID,
-			ExternalCustomerID:  body.ExternalCustomerID,
-			ExternalLegalNameID: gasPump.GasStation.LegalNameID,
-		}
-	} else {
-		opts = services.DebitReserveFundsOpts{
-			Amount:              body.Amount,
-			ExternalLegalNameID: gasPump.GasStation.LegalNameID,
-			CardKey:             body.CardKey,
+	provider, err := pc.providers.Get("debit")
+	if err != nil {
+		opts := &utils.TrackErrorOpts{
+			Tags: map[string]string{"auth_type": "employee_authentication"},
 		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	fraudCheck := tasks.FraudCheck{
+		Source:          tasks.FraudSourceOperation,
+		Customer:        customer,
+		GasStation:      gasPump.GasStation,
+		PaymentProvider: provider.Name(),
+		Amount:          body.Amount,
+	}
+	if body.ChargeType != "customer" {
+		fraudCheck.GiftCardKey = body.CardKey
 	}
-	transID, err := pc.debitService.ReserveFunds(opts)
+	trackOpts := &utils.TrackErrorOpts{
+		Tags: map[string]string{"auth_type": "employee_authentication"},
+	}
+	if !pc.checkFraud(c, fraudCheck, trackOpts) {
+		return
+	}
+
+	opts := services.ReserveOpts{
+		Amount:              body.Amount,
+		Customer:            customer,
+		ExternalLegalNameID: gasPump.GasStation.LegalNameID,
+	}
+	if body.ChargeType != "customer" {
+		opts.CardKey = body.CardKey
+	}
+	reservation, err := provider.Reserve(opts)
 	if err != nil {
-		if errors.Is(err, services.DebitUnsufficientFunds) {
+		if errors.Is(err, services.ErrInsufficientFunds) {
 			c.JSON(
 				http.StatusPaymentRequired,
 				dto.GeneralMessage{Detail: "Unsufficient funds or invalid card data"},
@@ -286,41 +597,25 @@ func (pc *paymentController) CreateIntentOperation(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
 		return
 	}
-	// Logging error in sentry
-	status = "paid"
-	liters := body.Amount / float32(price)
+	liters := body.Amount.Liters(price)
 	// TODO: Save record in DB here
 	payment := models.Payment{
-		ExternalTransactionID: transID,
+		ExternalTransactionID: reservation.TransactionID,
 		FuelType:              body.FuelType,
-		Amount:                float32(body.Amount),
-		TotalLiter:            liters,
+		Amount:                body.Amount,
+		TotalLiter:            float32(liters),
 		Price:                 price,
 		ChargeType:            "by_total",
 		GasPump:               gasPump,
 		Customer:              customer,
-		PaymentProvider:       "debit",
+		PaymentProvider:       provider.Name(),
 		Events: []models.PaymentEvent{
 			{Type: "funds_reserved"},
-		}, // Creating pending event
-		Status:          status,
+		},
 		SetByEmployeeID: &employeeID,
 		FromOperations:  utils.BoolAddr(true),
 		GiftCardKey:     utils.StringAddr(body.CardKey),
 	}
-	err = pc.repository.CreatePaymentIntent(&payment)
-	if err != nil {
-		// TODO: Log in sentry as well as the stripe cancelation error
-		pc.debitService.CancelReservation(transID)
-		// Logging error in sentry
-		opts := &utils.TrackErrorOpts{
-			Tags: map[string]string{"auth_type": "employee_authentication"},
-		}
-		utils.TrackError(c, err, opts)
-		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
-		return
-	}
-
 	setting, err := pc.settingsRepo.GetByName("gas_pump_status")
 	if err != nil {
 		var csmErr error
@@ -344,80 +639,124 @@ func (pc *paymentController) CreateIntentOperation(c *gin.Context) {
 		gasPumpEnabled = true
 	}
 
+	// PRE-SET gas pump once the payment is stored, on failure the payment is given back
+	var messages []*models.OutboxMessage
 	if gasPumpEnabled {
-		// PRE-SET gas pump
-		opts := services.SetGasPumpOptions{
-			Number:    payment.GasPump.Number,
-			Ip:        payment.GasPump.GasStation.Ip,
-			FuelType:  payment.FuelType,
-			Amount:    payment.Amount,
-			PaymentID: payment.ID,
-			Discount:  0,
+		messages = append(messages, tasks.NewSetGasPumpMessage(payment.ID))
+	}
+
+	err = pc.repository.CreatePaymentIntent(&payment, messages, pc.fraudTask.Guard(fraudCheck))
+	if err != nil {
+		// TODO: Log in sentry as well as the stripe cancelation error
+		provider.Cancel(reservation.TransactionID)
+		// Another load of the customer reached the limits meanwhile
+		var rejected *tasks.FraudRejectedError
+		if errors.As(err, &rejected) {
+			rejectLoad(c, rejected.Decision)
+			return
+		}
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Tags: map[string]string{"auth_type": "employee_authentication"},
 		}
-		data, err := pc.socioSmartService.SetGasPump(opts)
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
 
-		status := "Error"
+	pc.outboxTask.Dispatch(messages...)
 
-		if data != nil && data.Status == 0 {
-			status = "This pump has already a preset"
-		}
+	c.JSON(http.StatusCreated, dto.PaymentCrateIntentOperationResponse{
+		Amount:     body.Amount,
+		TotalLiter: liters,
+		ID:         payment.ID,
+	})
+}
 
-		if err != nil || data.Status == 0 {
-			// Logging error in sentry
-			optsTE := &utils.TrackErrorOpts{
-				Customer: customer,
-				Tags:     map[string]string{"auth_type": "customer"},
-				Context: map[string]map[string]any{
-					"Response": {"Status": status},
-					"Data": {
-						"gas_station":     payment.GasPump.GasStation.Name,
-						"gas_station_id":  payment.GasPump.GasStationID,
-						"gas_pump_number": payment.GasPump.Number,
-						"gas_pump_id":     payment.GasPumpID,
-					},
-				},
-			}
-			utils.TrackError(c, err, optsTE)
-			// -1 means refund entire transaction
-			// TODO: Log error in sentry
-			pc.debitService.CancelReservation(payment.ExternalTransactionID)
+// @Summary Quote a load
+// @Description Price per liter, discount and liters of a load, create intent charges the quoted terms while the quote token is valid
+// @Tags Payments
+// @Produce json
+// @Router /api/v1/payments/quote [POST]
+// @Param Authorization header string true "Token"
+// @Param body body dto.PaymentQuoteRequest true "Load to quote"
+// @Success 200 {object} dto.PaymentQuoteResponse "Quoted terms of the load"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Gas pump not found"
+// @Failure 406 {object} dto.GeneralMessage "Not fuel type in gas pump or promo code not applicable"
+// @Failure 409 {object} dto.GeneralMessage "Promo code without uses left"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (pc *paymentController) Quote(c *gin.Context) {
+	var body dto.PaymentQuoteRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaymentQuoteRequest](err))
+		return
+	}
 
-			event := &models.PaymentEvent{
-				PaymentID: payment.ID,
-				// TODO: add new status
-				Type: "internal_cancellation",
-			}
+	customer := c.MustGet("customer").(*models.Customer)
+	trackOpts := &utils.TrackErrorOpts{
+		Customer: customer,
+		Tags:     map[string]string{"auth_type": "customer"},
+	}
 
-			pc.repository.CreateEvent(event)
-			c.JSON(
-				http.StatusInternalServerError,
-				dto.GeneralMessage{Detail: "not able to preset pump"},
-			)
-			return
-		} else {
-			event := &models.PaymentEvent{
-				PaymentID: payment.ID,
-				Type:      "pump_ready",
-			}
+	gasPumpID, _ := uuid.Parse(body.GasPumpID)
 
-			err = pc.repository.CreateEvent(event)
-			if err != nil {
-				// TODO: Log error in sentry
-				// Logging error in sentry
-				opts := &utils.TrackErrorOpts{
-					Customer: customer,
-					Tags:     map[string]string{"auth_type": "customer"},
-				}
-				utils.TrackError(c, err, opts)
-			}
+	gasPump, err := pc.gasPumpRepository.GetByID(gasPumpID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NotFoundRecord})
+			return
 		}
+		// Logging error in sentry
+		utils.TrackError(c, err, trackOpts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
 	}
 
-	c.JSON(http.StatusCreated, dto.PaymentCrateIntentOperationResponse{
-		Amount:     float64(body.Amount),
-		TotalLiter: float64(liters),
-		ID:         payment.ID,
-	})
+	quote, result := pc.quoteLoad(c, gasPump, discounts.Input{
+		Customer:        customer,
+		FuelType:        body.FuelType,
+		PaymentProvider: body.PaymentProvider,
+		ChargeType:      body.ChargeType,
+		Amount:          body.Amount,
+		TotalLiter:      money.RoundLiters(float64(body.TotalLiter)),
+		PromoCode:       body.PromoCode,
+	}, trackOpts)
+	if quote == nil {
+		return
+	}
+
+	expiresAt := time.Now().Add(time.Minute * time.Duration(pc.config.QuoteExpMinutes))
+	claims := schemas.QuoteClaims{Quote: *quote}
+	claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+
+	token, err := claims.ClaimToken()
+	if err != nil {
+		// Logging error in sentry
+		utils.TrackError(c, err, trackOpts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	response := dto.PaymentQuoteResponse{
+		Price:            quote.Price,
+		DiscountPerLiter: quote.DiscountPerLiter,
+		DiscountType:     quote.DiscountType,
+		CampaignID:       quote.CampaignID,
+		LevelID:          quote.LevelID,
+		PromoCodeID:      quote.PromoCodeID,
+		Amount:           quote.Amount,
+		TotalLiter:       quote.TotalLiter,
+		Stacking:         result.Stacking,
+		Capped:           result.Capped,
+		QuoteToken:       token,
+		ExpiresAt:        expiresAt,
+	}
+
+	copier.Copy(&response.Discounts, result.Discounts)
+
+	c.JSON(http.StatusOK, response)
 }
 
 // @Summary Create Payment intent
@@ -427,11 +766,16 @@ func (pc *paymentController) CreateIntentOperation(c *gin.Context) {
 // @Router /api/v1/payments/create-intent [POST]
 // @Param Authorization header string true "Token"
 // @Param body body dto.CreatePaymentIntentRequest true "Create payment intent to charge fuel"
+// @Param Idempotency-Key header string false "Key to safely retry the request"
 // @Success 201 {object} dto.PaymentCrateIntentResponse "Payment intent information"
 // @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
 // @Failure 401 {object} dto.GeneralMessage "Unauthorized"
 // @Failure 402 {object} dto.GeneralMessage "Payment Required, Unsufficient funds"
-// @Failure 406 {object} dto.GeneralMessage "Not fuel type in gas pump"
+// @Failure 403 {object} dto.GeneralMessage "Rejected by the fraud rules, the customer or card is blocked or a limit was reached"
+// @Failure 406 {object} dto.GeneralMessage "Not fuel type in gas pump or promo code not applicable"
+// @Failure 409 {object} dto.GeneralMessage "Request with the same Idempotency-Key in progress or promo code without uses left"
+// @Failure 410 {object} dto.GeneralMessage "Quote expired"
+// @Failure 422 {object} dto.GeneralMessage "Idempotency-Key used with a different request"
 // @Failure 500 {object} dto.GeneralMessage "Internal server error"
 func (pc *paymentController) CreateIntent(c *gin.Context) {
 	// Logic here!
@@ -465,231 +809,34 @@ func (pc *paymentController) CreateIntent(c *gin.Context) {
 
 	}
 
-	// APPLY DISCOUNT
-	var discount float64
-	discountType := "none"
+	trackOpts := &utils.TrackErrorOpts{
+		Customer: customer,
+		Tags:     map[string]string{"auth_type": "customer"},
+	}
+
+	var quote *schemas.Quote
+	if body.QuoteToken != "" {
+		quote = pc.quotedLoad(c, body, customer, gasPump)
+	} else {
+		quote, _ = pc.quoteLoad(c, gasPump, discounts.Input{
+			Customer:        customer,
+			FuelType:        body.FuelType,
+			PaymentProvider: body.PaymentProvider,
+			ChargeType:      body.ChargeType,
+			Amount:          body.Amount,
+			TotalLiter:      money.RoundLiters(float64(body.TotalLiter)),
+			PromoCode:       body.PromoCode,
+		}, trackOpts)
+	}
+	if quote == nil {
+		return
+	}
+
+	amount := quote.Amount
+	liters := quote.TotalLiter
 
-	applicablePromotionType, err := pc.settingsRepo.GetByName("applicable_promotion_type")
+	provider, err := pc.providers.Get(body.PaymentProvider)
 	if err != nil {
-		var csmErr error
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			csmErr = errors.New("Applicable promotion type not found")
-		} else {
-			csmErr = err
-		}
-		// Logging error in sentry
-		opts := &utils.TrackErrorOpts{
-			Customer: customer,
-			Tags:     map[string]string{"auth_type": "customer"},
-		}
-		utils.TrackError(c, csmErr, opts)
-	}
-
-	promotionTypeInDB := "none"
-	if applicablePromotionType != nil {
-		promotionTypeInDB = applicablePromotionType.Value
-	}
-	discountType = promotionTypeInDB
-
-	// Checking valid Campaign
-	var campaign *models.Campaign
-	var cusLevel *models.CustomerLevel
-	if promotionTypeInDB == "campaign" {
-		campaign, err = pc.campaignRepo.GetApplicableCampaign(time.Now(), *gasPump.GasStationID)
-		if err != nil {
-			if !errors.Is(err, gorm.ErrRecordNotFound) {
-				// Logging error in sentry
-				opts := &utils.TrackErrorOpts{
-					Customer: customer,
-					Tags:     map[string]string{"auth_type": "customer"},
-				}
-				utils.TrackError(c, err, opts)
-				c.JSON(
-					http.StatusInternalServerError,
-					dto.GeneralMessage{Detail: lang.InternalServerError},
-				)
-				return
-			}
-		}
-
-		// Check applicable promotion
-		if campaign != nil {
-			discount = *campaign.Discount
-		} else {
-			discountType = "none"
-		}
-	} else if promotionTypeInDB == "elegibility" {
-		// TODO: check if applicable time zone should be UTC or America/Mazatlan
-		loc, _ := time.LoadLocation("America/Mazatlan")
-		n := time.Now().In(loc)
-		cusLevel, err = pc.elegibilityRepo.GetCustomerLevelByCriterias(map[string]any{
-			"customer_id":    customer.ID,
-			"validity_month": n.Month(),
-			"validity_year":  n.Year(),
-		})
-		if err != nil {
-			if !errors.Is(err, gorm.ErrRecordNotFound) {
-				// Logging error in sentry
-				opts := &utils.TrackErrorOpts{
-					Customer: customer,
-					Tags:     map[string]string{"auth_type": "customer"},
-				}
-				utils.TrackError(c, err, opts)
-				c.JSON(
-					http.StatusInternalServerError,
-					dto.GeneralMessage{Detail: lang.InternalServerError},
-				)
-				return
-			}
-		}
-		if cusLevel != nil {
-			discount = *cusLevel.Level.Discount
-		} else {
-			discountType = "none"
-		}
-
-	}
-
-	var price float64
-
-	if body.FuelType == "regular" {
-		price = *gasPump.RegularPrice - discount
-	} else if body.FuelType == "premium" {
-		price = *gasPump.PremiumPrice - discount
-	} else {
-		price = *gasPump.DieselPrice - discount
-	}
-
-	if price <= 0 {
-		c.JSON(http.StatusNotAcceptable, dto.GeneralMessage{Detail: lang.NotFuelInPump})
-		return
-	}
-
-	var amount float64
-	var liters float64
-
-	if body.ChargeType == "by_liter" {
-		liters = float64(body.TotalLiter)
-		amount = float64(body.TotalLiter) * price
-	} else {
-		liters = float64(body.Amount) / price
-		amount = float64(body.Amount)
-	}
-
-	if amount < float64(minChargeAmount) {
-		amount = float64(minChargeAmount)
-	}
-
-	transID := ""
-	var pi *stripe.PaymentIntent
-	status := "pending"
-	if body.PaymentProvider == "stripe" {
-		pi, err = pc.stripeService.CreatePaymentIntent(amount, customer.StripeCustomerID)
-		if err != nil {
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Customer: customer,
-				Tags:     map[string]string{"auth_type": "customer"},
-			}
-			utils.TrackError(c, err, opts)
-			c.JSON(
-				http.StatusInternalServerError,
-				dto.GeneralMessage{Detail: lang.InternalServerError},
-			)
-			return
-		}
-		transID = pi.ID
-	} else if body.PaymentProvider == "swit" {
-		opts := services.ReserveFundsOpts{
-			CustomerID: customer.SwitCustomerID,
-			SourceID:   body.SourceID,
-			Cvv:        body.Cvv,
-			Last4:      body.Last4,
-			Amount:     float32(amount),
-		}
-		transID, err = pc.switService.ReserveFunds(opts)
-		if err != nil {
-			if err == services.ErrProccesingPayment {
-				c.JSON(http.StatusPaymentRequired, dto.GeneralMessage{Detail: "Unsufficient funds or invalid card data"})
-				return
-			}
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Customer: customer,
-				Tags:     map[string]string{"auth_type": "customer"},
-			}
-			utils.TrackError(c, err, opts)
-			c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
-			return
-
-		}
-		status = "paid"
-	} else if body.PaymentProvider == "debit" {
-		opts := services.DebitReserveFundsOpts{
-			Amount:              float32(amount),
-			ExternalCustomerID:  customer.ExternalID,
-			ExternalLegalNameID: gasPump.GasStation.LegalNameID,
-		}
-		transID, err = pc.debitService.ReserveFunds(opts)
-		if err != nil {
-			if errors.Is(err, services.DebitUnsufficientFunds) {
-				c.JSON(http.StatusPaymentRequired, dto.GeneralMessage{Detail: "Unsufficient funds or invalid card data"})
-				return
-			}
-			opts := &utils.TrackErrorOpts{
-				Customer: customer,
-				Tags:     map[string]string{"auth_type": "customer"},
-			}
-			utils.TrackError(c, err, opts)
-			c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
-			return
-		}
-		// Logging error in sentry
-		status = "paid"
-	}
-
-	// TODO: Save record in DB here
-	payment := models.Payment{
-		ExternalTransactionID: transID,
-		FuelType:              body.FuelType,
-		Amount:                float32(amount),
-		TotalLiter:            float32(liters),
-		Price:                 price,
-		ChargeType:            body.ChargeType,
-		GasPump:               gasPump,
-		Customer:              customer,
-		PaymentProvider:       body.PaymentProvider,
-		// Events:                []models.PaymentEvent{{}}, // Creating pending event
-		Status:           status,
-		DiscountPerLiter: discount,
-		DiscountType:     discountType,
-	}
-
-	if body.PaymentProvider == "stripe" {
-		payment.Events = []models.PaymentEvent{{}}
-	} else if body.PaymentProvider == "swit" || body.PaymentProvider == "debit" {
-		payment.Events = []models.PaymentEvent{{Type: "funds_reserved"}}
-	}
-
-	// Check Discount type i n order to save it in DB
-	if discountType == "campaign" && campaign != nil {
-		payment.CampaignID = &campaign.ID
-	} else if discountType == "elegibility" && cusLevel != nil {
-		// Elegibility
-		payment.LevelID = cusLevel.LevelID
-	}
-
-	err = pc.repository.CreatePaymentIntent(&payment)
-	if err != nil {
-		// TODO: Log in sentry as well as the stripe cancelation error
-		if body.PaymentProvider == "stripe" {
-			pc.stripeService.CancelPaymentIntent(transID)
-		} else if body.PaymentProvider == "swit" {
-			// TODO: apply cancelation
-			pc.switService.CancelFundReservation(transID)
-		} else if body.PaymentProvider == "debit" {
-			pc.debitService.CancelReservation(transID)
-		}
 		// Logging error in sentry
 		opts := &utils.TrackErrorOpts{
 			Customer: customer,
@@ -700,506 +847,200 @@ func (pc *paymentController) CreateIntent(c *gin.Context) {
 		return
 	}
 
-	response := dto.PaymentCrateIntentResponse{
-		// ClientSecret: pi.ClientSecret,
-		Amount:     amount,
-		TotalLiter: liters,
-		ID:         payment.ID,
+	fraudCheck := tasks.FraudCheck{
+		Source:                  tasks.FraudSourceCustomer,
+		Customer:                customer,
+		GasStation:              gasPump.GasStation,
+		PaymentProvider:         body.PaymentProvider,
+		Amount:                  amount,
+		CardID:                  body.SourceID,
+		UseDefaultPaymentMethod: body.UseDefaultPaymentMethod,
 	}
-
-	if body.PaymentProvider == "stripe" {
-		response.ClientSecret = pi.ClientSecret
+	if !pc.checkFraud(c, fraudCheck, trackOpts) {
+		return
 	}
 
-	// Setup pump in swit
-	setting, err := pc.settingsRepo.GetByName("gas_pump_status")
+	manualCapture, err := pc.stripeManualCapture(body.PaymentProvider, gasPump.GasStation)
 	if err != nil {
-		var csmErr error
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			csmErr = errors.New("Gas Pump Status not setted")
-		} else {
-			csmErr = err
-		}
-		// Logging error in sentry
+		// Logging error in sentry, charging up front as before
 		opts := &utils.TrackErrorOpts{
 			Customer: customer,
 			Tags:     map[string]string{"auth_type": "customer"},
 		}
-		utils.TrackError(c, csmErr, opts)
-
-	}
-
-	gasPumpEnabled := false
-
-	if setting != nil && setting.Value == "enabled" {
-		gasPumpEnabled = true
-	}
-
-	if gasPumpEnabled && (body.PaymentProvider == "swit" || body.PaymentProvider == "debit") {
-		// PRE-SET gas pump
-		opts := services.SetGasPumpOptions{
-			Number:    payment.GasPump.Number,
-			Ip:        payment.GasPump.GasStation.Ip,
-			FuelType:  payment.FuelType,
-			Amount:    payment.Amount,
-			PaymentID: payment.ID,
-			Discount:  discount,
-		}
-		data, err := pc.socioSmartService.SetGasPump(opts)
-
-		status := "Error"
-
-		if data != nil && data.Status == 0 {
-			status = "This pump has already a preset"
-		}
-
-		if err != nil || data.Status == 0 {
-			// Logging error in sentry
-			optsTE := &utils.TrackErrorOpts{
-				Customer: customer,
-				Tags:     map[string]string{"auth_type": "customer"},
-				Context: map[string]map[string]any{
-					"Response": {"Status": status},
-					"Data": {
-						"gas_station":     payment.GasPump.GasStation.Name,
-						"gas_station_id":  payment.GasPump.GasStationID,
-						"gas_pump_number": payment.GasPump.Number,
-						"gas_pump_id":     payment.GasPumpID,
-						"customer":        payment.Customer.FirstName + " " + payment.Customer.FirstLastName + " " + payment.Customer.SecondLastName,
-						"customer_id":     payment.CustomerID,
-						"customer_email":  payment.Customer.Email,
-						"discount":        opts.Discount,
-					},
-				},
-			}
-			utils.TrackError(c, err, optsTE)
-			// -1 means refund entire transaction
-			// TODO: Log error in sentry
-			if body.PaymentProvider == "swit" {
-				pc.switService.CancelFundReservation(payment.ExternalTransactionID)
-			} else if body.PaymentProvider == "debit" {
-				pc.debitService.CancelReservation(payment.ExternalTransactionID)
-			}
-
-			event := &models.PaymentEvent{
-				PaymentID: payment.ID,
-				// TODO: add new status
-				Type: "internal_cancellation",
-			}
-
-			pc.repository.CreateEvent(event)
-			// Send email
-
-			requestFuelSchemaMail := &schemas.FuelRequest{}
-			requestFuelSchemaMail.FillData(&payment)
-			requestFuelSchemaMail.RefundedAmount = payment.Amount
-			requestFuelSchemaMail.Error = true
-
-			opts := services.SendMailOpts{
-				Data:         requestFuelSchemaMail,
-				Description:  "Hubo un error al intentar hacer tu carga",
-				TemplatePath: "fuel_request.html",
-				To:           payment.Customer.Email,
-			}
-
-			pc.mailService.SendMail(opts)
-		} else {
-			event := &models.PaymentEvent{
-				PaymentID: payment.ID,
-				Type:      "pump_ready",
-			}
-
-			err = pc.repository.CreateEvent(event)
-			if err != nil {
-				// TODO: Log error in sentry
-				// Logging error in sentry
-				opts := &utils.TrackErrorOpts{
-					Customer: customer,
-					Tags:     map[string]string{"auth_type": "customer"},
-				}
-				utils.TrackError(c, err, opts)
-			}
-
-			channel := paymentWebsocket.GetChannel(payment.ID.String())
-			channel.BroadcastJson(dto.PaymentWebsocketNotification{Status: "pump_ready"})
-		}
-	}
-
-	c.JSON(http.StatusCreated, response)
-}
-
-func (pc *paymentController) StripeWebhook(c *gin.Context) {
-	payload, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		// Logging error in sentry
-		opts := &utils.TrackErrorOpts{
-			Tags: map[string]string{"webhook": "stripe"},
-		}
 		utils.TrackError(c, err, opts)
-		c.Status(http.StatusServiceUnavailable)
-		return
 	}
-	signatureHeader := c.GetHeader("Stripe-Signature")
 
-	event, err := webhook.ConstructEvent(payload, signatureHeader, pc.config.StripeWebhookSecret)
+	reservation, err := provider.Reserve(services.ReserveOpts{
+		Amount:                  amount,
+		Customer:                customer,
+		ExternalLegalNameID:     gasPump.GasStation.LegalNameID,
+		SourceID:                body.SourceID,
+		Cvv:                     body.Cvv,
+		Last4:                   body.Last4,
+		ManualCapture:           manualCapture,
+		UseDefaultPaymentMethod: body.UseDefaultPaymentMethod,
+	})
 	if err != nil {
-		// Logging error in sentry
-		opts := &utils.TrackErrorOpts{
-			Tags: map[string]string{"webhook": "stripe"},
-		}
-		utils.TrackError(c, err, opts)
-		c.Status(http.StatusBadRequest)
-		return
-	}
-
-	switch event.Type {
-	case "payment_intent.payment_failed":
-		var paymentIntent stripe.PaymentIntent
-		err := json.Unmarshal(event.Data.Raw, &paymentIntent)
-		if err != nil {
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-			// TODO: Log in sentry
-			c.Status(http.StatusBadRequest)
-			return
-		}
-
-		payment, err := pc.repository.GetPaymentByStripePaymentIntentID(paymentIntent.ID)
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				// TODO: Log in sentry that the payment has not been found
-				c.Status(http.StatusNotFound)
-				return
-			}
-			// TODO: Log in sentry that there is an internal server error
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-			c.Status(http.StatusInternalServerError)
-			return
-		}
-
-		payment.Status = "failed"
-
-		updated, err := pc.repository.UpdateByID(payment.ID, payment)
-		if err != nil {
-			// TODO: Log in sentry that there is an internal server error
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-			c.Status(http.StatusInternalServerError)
-			return
-		}
-
-		if !updated {
-			// TODO: Log in sentry that there is not payment with that id
-			// c.Status(http.StatusNotFound)
-			// return
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, errors.New("There is not payment with this id to update"), opts)
-		}
-
-		event := &models.PaymentEvent{
-			PaymentID: payment.ID,
-			Type:      "failed",
-		}
-
-		err = pc.repository.CreateEvent(event)
-		if err != nil {
-			// TODO: LOg in sentryerr
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-		}
-
-	case "payment_intent.succeeded":
-		var paymentIntent stripe.PaymentIntent
-		err := json.Unmarshal(event.Data.Raw, &paymentIntent)
-		// activate pump
-		if err != nil {
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-			// TODO: Log in sentry
-			c.Status(http.StatusBadRequest)
-			return
-		}
-
-		payment, err := pc.repository.GetPaymentByStripePaymentIntentID(paymentIntent.ID)
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				// TODO: Log in sentry that the payment has not been found
-				c.Status(http.StatusNotFound)
-				return
-			}
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-			// TODO: Log in sentry that there is an internal server error
-			c.Status(http.StatusInternalServerError)
-			return
-		}
-
-		payment.Status = "paid"
-
-		updated, err := pc.repository.UpdateByID(payment.ID, payment)
-		if err != nil {
-			// TODO: Should refund
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-			c.Status(http.StatusInternalServerError)
-			return
-		}
-
-		if !updated {
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-			// TODO: Should refund
-			c.Status(http.StatusNotFound)
-			return
-		}
-
-		// logging event
-		event := &models.PaymentEvent{
-			PaymentID: payment.ID,
-			Type:      "paid",
-		}
-
-		err = pc.repository.CreateEvent(event)
-		if err != nil {
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-			// TODO: LOg in sentry
-		}
-
-		channel := paymentWebsocket.GetChannel(payment.ID.String())
-
-		channel.BroadcastJson(dto.PaymentWebsocketNotification{Status: "paid"})
-
-		// Setup
-		setting, err := pc.settingsRepo.GetByName("gas_pump_status")
-		if err != nil {
-			var csmErr error
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				csmErr = errors.New("Gas Pump Status not setted")
-			} else {
-				csmErr = err
-			}
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, csmErr, opts)
-
-		}
-
-		gasPumpEnabled := false
-
-		if setting != nil && setting.Value == "enabled" {
-			gasPumpEnabled = true
-		}
-
-		if gasPumpEnabled { // PRE-SET gas pump
-			opts := services.SetGasPumpOptions{
-				Number:    payment.GasPump.Number,
-				Ip:        payment.GasPump.GasStation.Ip,
-				FuelType:  payment.FuelType,
-				Amount:    payment.Amount,
-				PaymentID: payment.ID,
-				Discount:  payment.DiscountPerLiter,
-			}
-			data, err := pc.socioSmartService.SetGasPump(opts)
-			status := "Error"
-
-			if data != nil && data.Status == 0 {
-				status = "This pump has already a preset"
-			}
-			// Logging error in sentry
-			optsTE := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-				Context: map[string]map[string]any{
-					"Response": {"Status": status},
-					"Data": {
-						"gas_station":     payment.GasPump.GasStation.Name,
-						"gas_station_id":  payment.GasPump.GasStationID,
-						"gas_pump_number": payment.GasPump.Number,
-						"gas_pump_id":     payment.GasPumpID,
-						"customer":        payment.Customer.FirstName + " " + payment.Customer.FirstLastName + " " + payment.Customer.SecondLastName,
-						"customer_id":     payment.CustomerID,
-						"customer_email":  payment.Customer.Email,
-						"discount":        opts.Discount,
-					},
-				},
-			}
-			utils.TrackError(c, err, optsTE)
-
-			if err != nil || data.Status == 0 {
-				// -1 means refund entire transaction
-				// TODO: Log error in sentry
-				pc.stripeService.MakeARefund(payment.ExternalTransactionID, -2)
-
-				requestFuelSchemaMail := &schemas.FuelRequest{}
-				requestFuelSchemaMail.FillData(payment)
-				requestFuelSchemaMail.RefundedAmount = payment.Amount
-				requestFuelSchemaMail.Error = true
-
-				opts := services.SendMailOpts{
-					Data:         requestFuelSchemaMail,
-					Description:  "Hubo un error al intentar hacer tu carga",
-					TemplatePath: "fuel_request.html",
-					To:           payment.Customer.Email,
-				}
-
-				pc.mailService.SendMail(opts)
-			} else {
-				event := &models.PaymentEvent{
-					PaymentID: payment.ID,
-					Type:      "pump_ready",
-				}
-
-				err = pc.repository.CreateEvent(event)
-				if err != nil {
-					// TODO: Log error in sentry
-					opts := &utils.TrackErrorOpts{
-						Tags: map[string]string{"webhook": "stripe"},
-					}
-					utils.TrackError(c, err, opts)
-				}
-				channel.BroadcastJson(dto.PaymentWebsocketNotification{Status: "pump_ready"})
-			}
-		}
-
-		// Notify user
-
-	case "charge.refunded":
-		var chargeBody stripe.Charge
-		err := json.Unmarshal(event.Data.Raw, &chargeBody)
-		if err != nil {
-			// TODO: Log in sentry
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-			c.Status(http.StatusBadRequest)
-			return
-		}
-
-		params := stripe.ChargeParams{}
-		params.AddExpand("refunds")
-
-		cha, err := charge.Get(chargeBody.ID, &params)
-		if err != nil {
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-			c.Status(http.StatusNotFound)
+		if errors.Is(err, services.ErrInsufficientFunds) {
+			c.JSON(http.StatusPaymentRequired, dto.GeneralMessage{Detail: "Unsufficient funds or invalid card data"})
 			return
 		}
+		if errors.Is(err, services.ErrNoDefaultPaymentMethod) {
+			c.JSON(http.StatusNotAcceptable, dto.GeneralMessage{Detail: lang.NoDefaultPaymentMethod})
+			return
+		}
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Customer: customer,
+			Tags:     map[string]string{"auth_type": "customer"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
 
-		status := "partial_refund"
+	// TODO: Save record in DB here
+	payment := models.Payment{
+		ExternalTransactionID: reservation.TransactionID,
+		FuelType:              body.FuelType,
+		Amount:                amount,
+		TotalLiter:            float32(liters),
+		Price:                 quote.Price,
+		ChargeType:            body.ChargeType,
+		GasPump:               gasPump,
+		Customer:              customer,
+		PaymentProvider:       body.PaymentProvider,
+		DiscountPerLiter:      quote.DiscountPerLiter,
+		DiscountType:          quote.DiscountType,
+		CampaignID:            quote.CampaignID,
+		LevelID:               quote.LevelID,
+		PromoCodeID:           quote.PromoCodeID,
+	}
 
-		if len(cha.Refunds.Data) > 0 {
-			re := cha.Refunds.Data[0]
+	if reservation.ManualCapture {
+		payment.CaptureMethod = models.CaptureManual
+	}
 
-			if sta, ok := re.Metadata["status"]; ok {
-				status = sta
-			}
-		}
+	if reservation.Reserved {
+		payment.Events = []models.PaymentEvent{{Type: "funds_reserved"}}
+	} else {
+		payment.Events = []models.PaymentEvent{{Type: "pending"}}
+	}
 
-		payment, err := pc.repository.GetPaymentByStripePaymentIntentID(chargeBody.PaymentIntent.ID)
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				// TODO: Log in sentry that the payment has not been found
-				c.Status(http.StatusNotFound)
-				return
-			}
-			// TODO: Log in sentry that there is an internal server error
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-			c.Status(http.StatusInternalServerError)
-			return
+	// Setup pump in swit
+	setting, err := pc.settingsRepo.GetByName("gas_pump_status")
+	if err != nil {
+		var csmErr error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			csmErr = errors.New("Gas Pump Status not setted")
+		} else {
+			csmErr = err
 		}
-
-		event := &models.PaymentEvent{
-			PaymentID: payment.ID,
-			Type:      status,
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Customer: customer,
+			Tags:     map[string]string{"auth_type": "customer"},
 		}
+		utils.TrackError(c, csmErr, opts)
 
-		err = pc.repository.CreateEvent(event)
-		if err != nil {
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-			c.JSON(
-				http.StatusInternalServerError,
-				dto.GeneralMessage{Detail: lang.InternalServerError},
-			)
-			return
-		}
+	}
 
-		if status == "manual_action" {
-			pc.repository.CreateEvent(&models.PaymentEvent{
-				Type:      "partial_refund",
-				PaymentID: payment.ID,
-			})
-		}
+	gasPumpEnabled := false
 
-		amount := float32(chargeBody.AmountRefunded / 100)
-		decimals := float32(chargeBody.AmountRefunded%100) / 100
+	if setting != nil && setting.Value == "enabled" {
+		gasPumpEnabled = true
+	}
 
-		payment.RefundedAmount = float32(amount + decimals)
+	// PRE-SET gas pump once the payment is stored, on failure the payment is given back.
+	// Providers confirming the payment later preset the pump on their webhook
+	var messages []*models.OutboxMessage
+	if gasPumpEnabled && reservation.Reserved {
+		messages = append(messages, tasks.NewSetGasPumpMessage(payment.ID))
+	}
 
-		_, err = pc.repository.UpdateByID(payment.ID, payment)
-		if err != nil {
-			// TODO: Log error in sentry
-			opts := &utils.TrackErrorOpts{
-				Tags: map[string]string{"webhook": "stripe"},
-			}
-			utils.TrackError(c, err, opts)
-			c.JSON(
-				http.StatusInternalServerError,
-				dto.GeneralMessage{Detail: lang.InternalServerError},
-			)
+	err = pc.repository.CreatePaymentIntent(&payment, messages, pc.fraudTask.Guard(fraudCheck))
+	if err != nil {
+		// TODO: Log in sentry as well as the provider cancelation error
+		provider.Cancel(reservation.TransactionID)
+		// Another load of the customer reached the limits meanwhile
+		var rejected *tasks.FraudRejectedError
+		if errors.As(err, &rejected) {
+			rejectLoad(c, rejected.Decision)
+			return
+		}
+		// Used up by another load since it was quoted
+		if errors.Is(err, repository.ErrPromoCodeUsed) {
+			c.JSON(http.StatusConflict, dto.GeneralMessage{Detail: lang.PromoCodeUsed})
 			return
 		}
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Customer: customer,
+			Tags:     map[string]string{"auth_type": "customer"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
 
-		// TODO: Notify user that money were refunded
+	response := dto.PaymentCrateIntentResponse{
+		ClientSecret:   reservation.ClientSecret,
+		Amount:         amount,
+		TotalLiter:     liters,
+		ID:             payment.ID,
+		RequiresAction: reservation.RequiresAction,
+	}
 
-		channel := paymentWebsocket.GetChannel(payment.ID.String())
+	pc.outboxTask.Dispatch(messages...)
 
-		channel.BroadcastJson(dto.PaymentWebsocketNotification{Status: status})
+	c.JSON(http.StatusCreated, response)
+}
 
-		paymentWebsocket.DeleteChannel(payment.ID.String())
+func (pc *paymentController) StripeWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Tags: map[string]string{"webhook": "stripe"},
+		}
+		utils.TrackError(c, err, opts)
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+	signatureHeader := c.GetHeader("Stripe-Signature")
+
+	event, err := webhook.ConstructEvent(payload, signatureHeader, pc.config.StripeWebhookSecret)
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Tags: map[string]string{"webhook": "stripe"},
+		}
+		utils.TrackError(c, err, opts)
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	// Stored before processing, failures are kept to be replayed
+	_, err = pc.stripeWebhookTask.Receive(event, payload)
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Tags: map[string]string{"webhook": "stripe"},
+		}
+		utils.TrackError(c, err, opts)
 
+		switch {
+		case errors.Is(err, tasks.ErrStripeEventPayload):
+			c.Status(http.StatusBadRequest)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.Status(http.StatusNotFound)
+		case errors.Is(err, payments.ErrInvalidTransition), errors.Is(err, tasks.ErrStripeEventProcessing):
+			// Stripe delivers it again later
+			c.Status(http.StatusConflict)
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
 	}
 
 	c.Status(http.StatusOK)
@@ -1214,10 +1055,13 @@ func (pc *paymentController) StripeWebhook(c *gin.Context) {
 // @Param body body dto.AddEventBodyRequest true "Add event for payment"
 // @Param APP-KEY header string true "App Key"
 // @Param API-KEY header string true "Api Key"
+// @Param Idempotency-Key header string false "Key to safely retry the request"
 // @Success 201 {object} dto.GeneralMessage "OK"
 // @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
 // @Failure 401 {object} dto.GeneralMessage "Unauthorized"
-// @Failure 406 {object} dto.GeneralMessage "Already Served"
+// @Failure 406 {object} dto.GeneralMessage "Amount charged greater than paid"
+// @Failure 409 {object} dto.GeneralMessage "Event not allowed after the last one, i.e. already served"
+// @Failure 422 {object} dto.GeneralMessage "Idempotency-Key used with a different request"
 // @Failure 402 {object} dto.GeneralMessage "Payment Required"
 // @Failure 404 {object} dto.GeneralMessage "Payment id not found in db"
 // @Failure 500 {object} dto.GeneralMessage "Internal server error"
@@ -1302,36 +1146,30 @@ func (pc *paymentController) AddEvent(c *gin.Context) {
 		}
 	}
 
+	lastEvent := ""
 	if e != nil {
-		if e.Type == "served" || e.Type == "partial_refund" {
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Application: authorizedApp,
-				Tags:        map[string]string{"auth_type": "application"},
-				Level:       sentry.LevelInfo,
-			}
-			utils.TrackError(c, errors.New(lang.PaymentAlreadyServed), opts)
-			c.JSON(http.StatusNotAcceptable, dto.GeneralMessage{Detail: lang.PaymentAlreadyServed})
-			return
-		}
+		lastEvent = e.Type
+	}
 
-		if e.Type == "internal_cancellation" {
-			msg := "Impossible preset gas pump"
+	if err := payments.ValidateTransition(lastEvent, body.Type); err != nil {
+		msg := lang.PaymentTransitionNotAllowed
+		if lastEvent == "served" || lastEvent == "partial_refund" {
+			msg = lang.PaymentAlreadyServed
+		}
 
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Application: authorizedApp,
-				Tags:        map[string]string{"auth_type": "application"},
-				Level:       sentry.LevelInfo,
-			}
-			utils.TrackError(c, errors.New(msg), opts)
-			c.JSON(http.StatusNotAcceptable, dto.GeneralMessage{Detail: msg})
-			return
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Application: authorizedApp,
+			Tags:        map[string]string{"auth_type": "application"},
+			Level:       sentry.LevelInfo,
 		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusConflict, dto.GeneralMessage{Detail: msg})
+		return
 	}
 	realAmountCharged := body.AmountCharged
 	// Charge that will be necessary in order to complete $10 MXN
-	var chargeFee float32
+	var chargeFee money.Amount
 
 	if body.Type == "served" {
 		if realAmountCharged > payment.Amount {
@@ -1354,148 +1192,55 @@ func (pc *paymentController) AddEvent(c *gin.Context) {
 			realAmountCharged = minChargeAmount
 		}
 
+		// Stored along with the served event
 		payment.RealAmountReported = realAmountCharged
 		payment.ChargeFee = chargeFee
-
-		_, err = pc.repository.UpdateByID(payment.ID, payment)
-		if err != nil {
-			// TODO: Log error in sentry
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Application: authorizedApp,
-				Tags:        map[string]string{"auth_type": "application"},
-				Level:       sentry.LevelInfo,
-			}
-			utils.TrackError(c, err, opts)
-			c.JSON(
-				http.StatusInternalServerError,
-				dto.GeneralMessage{Detail: lang.InternalServerError},
-			)
-			return
-		}
-
-	}
-
-	event := &models.PaymentEvent{
-		PaymentID:               payment.ID,
-		Type:                    body.Type,
-		AuthorizedApplicationID: &authorizedApp.ID,
-	}
-
-	err = pc.repository.CreateEvent(event)
-	if err != nil {
-		// Logging error in sentry
-		opts := &utils.TrackErrorOpts{
-			Application: authorizedApp,
-			Tags:        map[string]string{"auth_type": "application"},
-			Level:       sentry.LevelInfo,
-		}
-		utils.TrackError(c, err, opts)
-		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
-		return
 	}
 
 	// TODO: check totals to refund
 	difference := payment.Amount - realAmountCharged
-
-	closeChannel := true
-	// checking if some money should be returned
-	if body.Type == "served" && difference > 0 {
-		closeChannel = false
-		//  Refund money stripe
-
-		if payment.PaymentProvider == "stripe" {
-
-			_, err = pc.stripeService.MakeARefund(
-				payment.ExternalTransactionID,
-				float64(difference),
-			)
-			if err != nil {
-				// TODO: Log error in sentry
-				// Logging error in sentry
-				opts := &utils.TrackErrorOpts{
-					Application: authorizedApp,
-					Tags:        map[string]string{"auth_type": "application"},
-					Level:       sentry.LevelInfo,
-				}
-				utils.TrackError(c, err, opts)
-				c.JSON(
-					http.StatusInternalServerError,
-					dto.GeneralMessage{Detail: lang.InternalServerError},
-				)
-				return
-			}
-		}
+	// Manually captured payments only capture the served amount, nothing is refunded
+	if payment.CaptureMethod == models.CaptureManual {
+		difference = money.Zero
 	}
 
-	if (payment.PaymentProvider == "swit" || payment.PaymentProvider == "debit") &&
-		body.Type == "served" {
-		if difference > 0 {
-			event := &models.PaymentEvent{
-				PaymentID: payment.ID,
-				Type:      "partial_refund",
-			}
-			pc.repository.CreateEvent(event)
+	// Side effects are stored along with the event and run once it is committed, the
+	// capture records the difference given back
+	var messages []*models.OutboxMessage
+	var updates map[string]any
 
-			payment.RefundedAmount = difference
+	if body.Type == "served" {
+		updates = map[string]any{
+			"real_amount_reported": realAmountCharged,
+			"charge_fee":           chargeFee,
+		}
 
-			pc.repository.UpdateByID(payment.ID, payment)
+		messages = append(
+			messages,
+			tasks.NewCapturePaymentMessage(payment.ID, payment.Amount, realAmountCharged),
+		)
 
+		if payment.CampaignID != nil {
+			messages = append(messages, tasks.NewCampaignUsageMessage(payment.ID))
 		}
-		var err error
-		if payment.PaymentProvider == "swit" {
-			err = pc.switService.ConfirmFundReservation(
-				payment.ExternalTransactionID,
-				realAmountCharged,
-			)
-		} else if payment.PaymentProvider == "debit" {
-			err = pc.debitService.PaymentConfirmation(payment.ExternalTransactionID, realAmountCharged)
-		}
-		if err != nil {
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Application: authorizedApp,
-				Tags:        map[string]string{"auth_type": "application"},
-			}
-			utils.TrackError(c, err, opts)
-		}
-	}
 
-	// POints in GM
-
-	if body.Type == "served" {
+		// POints in GM and email
 		if !*payment.FromOperations {
-			points, err := pc.socioSmartService.AccumPoints(payment)
-			if err != nil {
-				// TODO: Report in sentry
-				// Logging error in sentry
-				opts := &utils.TrackErrorOpts{
-					Application: authorizedApp,
-					Tags:        map[string]string{"auth_type": "application"},
-				}
-				utils.TrackError(c, err, opts)
-			}
-
-			payment.GMPoints = points.Amount
-			payment.GMID = points.Id
-
-			pc.repository.UpdateByID(payment.ID, payment)
-
-			// Send email
-
 			requestFuelSchemaMail := &schemas.FuelRequest{}
 			requestFuelSchemaMail.FillData(payment)
 			requestFuelSchemaMail.RefundedAmount = difference
 
-			opts := services.SendMailOpts{
-				Data:         requestFuelSchemaMail,
-				Description:  "Tu carga ha sido completada",
-				TemplatePath: "fuel_request.html",
-				To:           payment.Customer.Email,
-			}
-
-			pc.mailService.SendMail(opts)
+			messages = append(
+				messages,
+				tasks.NewAccumPointsMessage(payment.ID),
+				tasks.NewFuelRequestReceiptMailMessage(
+					payment,
+					"Tu carga ha sido completada",
+					requestFuelSchemaMail,
+				),
+			)
 		}
+
 		// Setup
 		setting, err := pc.settingsRepo.GetByName("gas_pump_status")
 		if err != nil {
@@ -1513,33 +1258,42 @@ func (pc *paymentController) AddEvent(c *gin.Context) {
 
 		}
 
-		gasPumpEnabled := false
-
+		// Post ticket to GM
 		if setting != nil && setting.Value == "enabled" {
-			gasPumpEnabled = true
+			messages = append(messages, tasks.NewReportTransactionMessage(payment.ID))
 		}
+	}
 
-		if gasPumpEnabled {
-			// Post ticket to GM
-
-			optsTrans := services.ReportTransactionOpts{
-				Ip:     payment.GasPump.GasStation.Ip,
-				Number: payment.GasPump.Number,
-			}
-			err := pc.socioSmartService.ReportTransaction(optsTrans)
-			if err != nil {
-				// Logging error in sentry
-				opts := &utils.TrackErrorOpts{
-					Application: authorizedApp,
-					Tags:        map[string]string{"auth_type": "application"},
-					Level:       sentry.LevelError,
-				}
-				utils.TrackError(c, err, opts)
+	event := &models.PaymentEvent{
+		PaymentID:               payment.ID,
+		Type:                    body.Type,
+		AuthorizedApplicationID: &authorizedApp.ID,
+	}
 
-			}
+	err = pc.repository.CreateEventWithUpdates(event, updates, messages...)
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Application: authorizedApp,
+			Tags:        map[string]string{"auth_type": "application"},
+			Level:       sentry.LevelInfo,
+		}
+		utils.TrackError(c, err, opts)
+		if errors.Is(err, payments.ErrInvalidTransition) {
+			c.JSON(
+				http.StatusConflict,
+				dto.GeneralMessage{Detail: lang.PaymentTransitionNotAllowed},
+			)
+			return
 		}
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	// The channel is kept open to notify the difference given back
+	closeChannel := body.Type != "served" || difference <= 0
 
-	}
+	pc.outboxTask.Dispatch(messages...)
 
 	// Real time notification
 	channel := paymentWebsocket.GetChannel(payment.ID.String())
@@ -1744,7 +1498,8 @@ func (pc *paymentController) SignInvoice(c *gin.Context) {
 		return
 	}
 
-	if payment.Events[0].Type != "served" && payment.Events[0].Type != "partial_refund" {
+	// Events are preloaded newest first
+	if !payments.IsFinished(payment.Status, payment.Events[0].Type) {
 		c.JSON(
 			http.StatusNotAcceptable,
 			dto.GeneralMessage{Detail: "Load not finished or payment required"},
@@ -1877,8 +1632,138 @@ func (pc *paymentController) GetInvoicePDF(c *gin.Context) {
 	c.JSON(http.StatusOK, dto.GetInvoicePDFResponse{UrlPDF: url})
 }
 
+// @Summary Payment receipt
+// @Description Non fiscal receipt (ticket) of a served load
+// @Tags Payments
+// @Produce application/pdf
+// @Router /api/v1/payments/{id}/receipt [GET]
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Param Authorization header string true "Token"
+// @Success 200 {file} file "PDF"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 406 {object} dto.GeneralMessage "Load not finished"
+// @Failure 500 {object} dto.GeneralMessage "Internal Server Error"
+func (pc *paymentController) GetReceipt(c *gin.Context) {
+	var path dto.PaymentDetailCustomerPath
+	if err := c.ShouldBindUri(&path); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaymentDetailCustomerPath](err))
+		return
+	}
+	id, _ := uuid.Parse(path.ID)
+
+	customer := c.MustGet("customer").(*models.Customer)
+
+	opts := &utils.TrackErrorOpts{
+		Customer: customer,
+		Tags:     map[string]string{"auth_type": "customer"},
+	}
+
+	payment, err := pc.repository.GetByIDForCustomer(id, customer.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NotFoundRecord})
+			return
+		}
+		// Logging error in sentry
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	payment.Customer = customer
+
+	lastEvent := ""
+	// Events are preloaded newest first
+	if len(payment.Events) > 0 {
+		lastEvent = payment.Events[0].Type
+	}
+
+	pc.writeReceipt(c, payment, lastEvent, opts)
+}
+
+// @Summary Payment receipt for operations
+// @Description Non fiscal receipt (ticket) of a load served in the station of the employee
+// @Tags Payments
+// @Produce application/pdf
+// @Router /api/v1/payments/{id}/receipt-operation [GET]
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Param X-GAS-STATION-ID header string true "Gas Station ID"
+// @Param X-EMPLOYEE-ID header string true "Employee ID"
+// @Param X-EMPLOYEE-NIP header string true "Employee NIP"
+// @Success 200 {file} file "PDF"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized employee"
+// @Failure 404 {object} dto.GeneralMessage "Not found in the station of the employee"
+// @Failure 406 {object} dto.GeneralMessage "Load not finished"
+// @Failure 500 {object} dto.GeneralMessage "Internal Server Error"
+func (pc *paymentController) GetReceiptOperation(c *gin.Context) {
+	var path dto.PaymentDetailPath
+	if err := c.ShouldBindUri(&path); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaymentDetailPath](err))
+		return
+	}
+	id, _ := uuid.Parse(path.ID)
+
+	gasStation := c.MustGet("gas_station").(*models.GasStation)
+
+	opts := &utils.TrackErrorOpts{
+		Tags: map[string]string{"auth_type": "employee_authentication"},
+	}
+
+	filters := map[string]any{"stations": []string{gasStation.ID.String()}}
+
+	payment, err := pc.repository.GetByIDDetailed(id, filters)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NotFoundRecord})
+			return
+		}
+		// Logging error in sentry
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	lastEvent := ""
+	// Events are preloaded oldest first
+	if len(payment.Events) > 0 {
+		lastEvent = payment.Events[len(payment.Events)-1].Type
+	}
+
+	pc.writeReceipt(c, payment, lastEvent, opts)
+}
+
+// writeReceipt responds the receipt of a finished load as PDF
+func (pc *paymentController) writeReceipt(
+	c *gin.Context,
+	payment *models.Payment,
+	lastEvent string,
+	opts *utils.TrackErrorOpts,
+) {
+	if !payments.IsFinished(payment.Status, lastEvent) {
+		c.JSON(http.StatusNotAcceptable, dto.GeneralMessage{Detail: lang.ReceiptNotAvailable})
+		return
+	}
+
+	data := &schemas.FuelRequest{}
+	data.FillData(payment)
+
+	var receipt bytes.Buffer
+	if err := pc.receiptService.Render(data, &receipt); err != nil {
+		// Logging error in sentry
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+tasks.ReceiptFilename(payment)+`"`)
+	c.Data(http.StatusOK, "application/pdf", receipt.Bytes())
+}
+
 // @Summary Do payment action
-// @Description Manual action for payment
+// @Description Manual action for payment. Refunds of served loads can be partial, the amount left to refund is given back when amount is omitted
 // @Tags Payments
 // @Produce json
 // @Router /api/v1/payments/actions/{id} [POST]
@@ -1887,6 +1772,8 @@ func (pc *paymentController) GetInvoicePDF(c *gin.Context) {
 // @Param body body dto.DoPaymentActionRequest true "Do action for payment"
 // @Success 200 {object} dto.GeneralMessage "done"
 // @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 409 {object} dto.GeneralMessage "Action not allowed in current payment state"
+// @Failure 422 {object} dto.GeneralMessage "Amount greater than what is left to refund or partial refund not supported"
 // @Failure 401 {object} dto.GeneralMessage "Unauthorized"
 // @Failure 500 {object} dto.GeneralMessage "Internal Server Error"
 func (pc *paymentController) DoPaymentAction(c *gin.Context) {
@@ -1945,75 +1832,25 @@ func (pc *paymentController) DoPaymentAction(c *gin.Context) {
 		return
 	}
 
-	if e.Type != "paid" && e.Type != "funds_reserved" {
+	nextEvent := "pump_ready"
+	if body.Action == "refund" {
+		// Loads not served yet are canceled, the served ones are refunded partially
+		nextEvent = "manual_action"
+		if !payments.CanTransition(e.Type, nextEvent) {
+			nextEvent = "partial_refund"
+		}
+	}
+
+	if !payments.CanTransition(e.Type, nextEvent) {
 		c.JSON(
-			http.StatusPreconditionFailed,
-			dto.GeneralMessage{Detail: "payment status not valid"},
+			http.StatusConflict,
+			dto.GeneralMessage{Detail: lang.PaymentTransitionNotAllowed},
 		)
 		return
 	}
 
 	if body.Action == "refund" {
-		var err error
-		if payment.PaymentProvider == "stripe" {
-			_, err = pc.stripeService.MakeARefund(payment.ExternalTransactionID, -3)
-		} else if payment.PaymentProvider == "swit" {
-			err = pc.switService.CancelFundReservation(payment.ExternalTransactionID)
-		} else if payment.PaymentProvider == "debit" {
-			err = pc.debitService.CancelReservation(payment.ExternalTransactionID)
-		}
-
-		// TODO: Log error in sentry
-
-		if err != nil {
-			// Logging error in sentry
-			opts := &utils.TrackErrorOpts{
-				Admin: user,
-				Tags: map[string]string{
-					"auth_type":        "admin",
-					"payment_provider": payment.PaymentProvider,
-				},
-				Level: sentry.LevelInfo,
-			}
-			utils.TrackError(c, err, opts)
-			c.JSON(
-				http.StatusInternalServerError,
-				dto.GeneralMessage{Detail: lang.InternalServerError},
-			)
-			return
-		}
-
-		if !*payment.FromOperations {
-			requestFuelSchemaMail := &schemas.FuelRequest{}
-			requestFuelSchemaMail.FillData(payment)
-			requestFuelSchemaMail.RefundedAmount = payment.Amount
-			requestFuelSchemaMail.Error = true
-
-			opts := services.SendMailOpts{
-				Data:         requestFuelSchemaMail,
-				Description:  "Hubo un error al intentar hacer tu carga (manual)",
-				TemplatePath: "fuel_request.html",
-				To:           payment.Customer.Email,
-			}
-
-			pc.mailService.SendMail(opts)
-
-		}
-
-		if payment.PaymentProvider != "stripe" {
-			pc.repository.CreateEvent(
-				&models.PaymentEvent{PaymentID: payment.ID, Type: "manual_action"},
-			)
-			pc.repository.CreateEvent(
-				&models.PaymentEvent{PaymentID: payment.ID, Type: "partial_refund"},
-			)
-		}
-
-		payment.RefundedAmount = payment.Amount
-
-		pc.repository.UpdateByID(payment.ID, payment)
-
-		c.JSON(http.StatusOK, dto.GeneralMessage{Detail: "ok"})
+		pc.refundPayment(c, payment, nextEvent == "manual_action", &body, user)
 		return
 	} else {
 		channel := paymentWebsocket.GetChannel(payment.ID.String())
@@ -2042,6 +1879,9 @@ func (pc *paymentController) DoPaymentAction(c *gin.Context) {
 			gasPumpEnabled = true
 		}
 
+		// The preset stays synchronous here, unlike the set_gas_pump outbox message: the
+		// admin is answered whether the pump took it, and a failure leaves the payment as
+		// is to be retried instead of giving it back
 		if gasPumpEnabled { // PRE-SET gas pump
 			opts := services.SetGasPumpOptions{
 				Number:    payment.GasPump.Number,
@@ -2086,6 +1926,7 @@ func (pc *paymentController) DoPaymentAction(c *gin.Context) {
 				event := &models.PaymentEvent{
 					PaymentID: payment.ID,
 					Type:      "pump_ready",
+					UserID:    &user.ID,
 				}
 
 				err = pc.repository.CreateEvent(event)
@@ -2107,3 +1948,370 @@ func (pc *paymentController) DoPaymentAction(c *gin.Context) {
 		}
 	}
 }
+
+// refundPayment gives back body.Amount (everything left when zero) recording who asked
+// for it and why. Loads not served are canceled, so they are refunded entirely
+func (pc *paymentController) refundPayment(
+	c *gin.Context,
+	payment *models.Payment,
+	cancelLoad bool,
+	body *dto.DoPaymentActionRequest,
+	user *models.User,
+) {
+	opts := &utils.TrackErrorOpts{
+		Admin: user,
+		Tags: map[string]string{
+			"auth_type":        "admin",
+			"payment_provider": payment.PaymentProvider,
+		},
+	}
+
+	provider, err := pc.providers.Get(payment.PaymentProvider)
+	if err != nil {
+		// Logging error in sentry
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	captured := payment.RealAmountReported
+	if cancelLoad {
+		captured = payment.Amount
+
+		if body.Amount > 0 && body.Amount != captured {
+			c.JSON(http.StatusUnprocessableEntity, dto.GeneralMessage{Detail: lang.RefundMustBeTotal})
+			return
+		}
+	}
+
+	refund := &models.Refund{
+		PaymentID:       payment.ID,
+		UserID:          &user.ID,
+		Amount:          body.Amount,
+		Reason:          body.Reason,
+		PaymentProvider: payment.PaymentProvider,
+	}
+
+	if err := pc.refundRepository.Reserve(refund, captured); err != nil {
+		if errors.Is(err, payments.ErrRefundExceedsCaptured) {
+			c.JSON(http.StatusUnprocessableEntity, dto.GeneralMessage{Detail: lang.RefundExceedsCaptured})
+			return
+		}
+		// Logging error in sentry
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	if cancelLoad {
+		err = pc.refundEntirePayment(provider, payment, "manual_action", refund.Reason)
+	} else {
+		err = pc.refundPartOfPayment(provider, payment, refund.Amount, refund.Reason)
+	}
+
+	if err != nil {
+		refund.Status = models.RefundFailed
+		refund.Error = err.Error()
+		if len(refund.Error) > 1000 {
+			refund.Error = refund.Error[:1000]
+		}
+		pc.refundRepository.Finish(refund)
+
+		if errors.Is(err, services.ErrOperationNotSupported) {
+			c.JSON(
+				http.StatusUnprocessableEntity,
+				dto.GeneralMessage{Detail: lang.PartialRefundNotSupported},
+			)
+			return
+		}
+		// Logging error in sentry
+		opts.Level = sentry.LevelInfo
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	refund.Status = models.RefundDone
+	if err := pc.refundRepository.Finish(refund); err != nil {
+		// Logging error in sentry
+		utils.TrackError(c, err, opts)
+	}
+
+	// Async refunds are recorded by the provider webhook, authorizations released are not
+	if !provider.RefundsConfirmedAsync() || cancelLoad && payment.CaptureMethod == models.CaptureManual {
+		if err := pc.recordRefund(payment, refund.Amount, cancelLoad, user); err != nil {
+			// The money was given back, the refund is left to be reconciled
+			utils.TrackError(c, err, opts)
+			c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+			return
+		}
+	}
+
+	if payment.Customer != nil && !*payment.FromOperations {
+		requestFuelSchemaMail := &schemas.FuelRequest{}
+		requestFuelSchemaMail.FillData(payment)
+		requestFuelSchemaMail.RefundedAmount = refund.Amount
+		requestFuelSchemaMail.Error = cancelLoad
+
+		description := "Se realizó un reembolso de tu carga"
+		if cancelLoad {
+			description = "Hubo un error al intentar hacer tu carga (manual)"
+		}
+
+		mail := tasks.NewFuelRequestMailMessage(payment, description, requestFuelSchemaMail)
+
+		if err := pc.outboxTask.Enqueue(mail); err != nil {
+			// Logging error in sentry
+			utils.TrackError(c, err, opts)
+		}
+	}
+
+	c.JSON(http.StatusOK, dto.GeneralMessage{Detail: "ok"})
+}
+
+// checkFraud runs the fraud rules before reserving the funds of a load, when it is
+// rejected (or they can not be checked) the response is written and false is returned
+func (pc *paymentController) checkFraud(
+	c *gin.Context,
+	check tasks.FraudCheck,
+	trackOpts *utils.TrackErrorOpts,
+) bool {
+	decision, err := pc.fraudTask.Evaluate(check)
+	if err != nil {
+		// Logging error in sentry
+		utils.TrackError(c, err, trackOpts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return false
+	}
+
+	if decision.Allowed {
+		return true
+	}
+
+	rejectLoad(c, decision)
+
+	return false
+}
+
+// rejectLoad writes the response of a load rejected by the fraud rules
+func rejectLoad(c *gin.Context, decision *models.FraudDecision) {
+	switch decision.Reason {
+	case models.FraudBlockCustomer, models.FraudBlockCard, models.FraudBlockGiftCard:
+		c.JSON(http.StatusForbidden, dto.GeneralMessage{Detail: lang.FraudBlocked})
+	default:
+		c.JSON(http.StatusForbidden, dto.GeneralMessage{Detail: lang.FraudLimitExceeded + decision.Reason})
+	}
+}
+
+// quoteLoad prices the load with the discounts that apply, when it can not be priced
+// the response is written and a nil quote is returned
+func (pc *paymentController) quoteLoad(
+	c *gin.Context,
+	gasPump *models.GasPump,
+	load discounts.Input,
+	trackOpts *utils.TrackErrorOpts,
+) (*schemas.Quote, *discounts.Result) {
+	if load.FuelType == "regular" {
+		load.PumpPrice = *gasPump.RegularPrice
+	} else if load.FuelType == "premium" {
+		load.PumpPrice = *gasPump.PremiumPrice
+	} else {
+		load.PumpPrice = *gasPump.DieselPrice
+	}
+
+	load.GasStation = gasPump.GasStation
+
+	result, err := pc.discountEngine.Evaluate(load)
+	if err != nil {
+		if errors.Is(err, discounts.ErrPromoCodeNotApplicable) {
+			c.JSON(http.StatusNotAcceptable, dto.GeneralMessage{Detail: lang.PromoCodeNotApplicable})
+			return nil, nil
+		}
+		if errors.Is(err, repository.ErrPromoCodeUsed) {
+			c.JSON(http.StatusConflict, dto.GeneralMessage{Detail: lang.PromoCodeUsed})
+			return nil, nil
+		}
+		// Logging error in sentry
+		utils.TrackError(c, err, trackOpts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return nil, nil
+	}
+
+	discount := result.DiscountPerLiter
+	price := load.PumpPrice - discount
+
+	if price <= 0 {
+		c.JSON(http.StatusNotAcceptable, dto.GeneralMessage{Detail: lang.NotFuelInPump})
+		return nil, nil
+	}
+
+	var amount money.Amount
+	var liters float64
+
+	if load.ChargeType == "by_liter" {
+		liters = load.TotalLiter
+		amount = money.ForLiters(liters, price)
+	} else {
+		liters = load.Amount.Liters(price)
+		amount = load.Amount
+	}
+
+	if amount < minChargeAmount {
+		amount = minChargeAmount
+	}
+
+	quote := &schemas.Quote{
+		GasPumpID:        gasPump.ID,
+		CustomerID:       load.Customer.ID,
+		FuelType:         load.FuelType,
+		PaymentProvider:  load.PaymentProvider,
+		ChargeType:       load.ChargeType,
+		Price:            price,
+		DiscountPerLiter: discount,
+		DiscountType:     result.Type,
+		CampaignID:       result.CampaignID(),
+		LevelID:          result.LevelID(),
+		PromoCode:        models.NormalizePromoCode(load.PromoCode),
+		PromoCodeID:      result.PromoCodeID(),
+		Amount:           amount,
+		TotalLiter:       liters,
+	}
+
+	return quote, result
+}
+
+// quotedLoad returns the terms of the quote sent to create intent, when it expired or
+// does not match the requested load the response is written and nil is returned
+func (pc *paymentController) quotedLoad(
+	c *gin.Context,
+	body dto.CreatePaymentIntentRequest,
+	customer *models.Customer,
+	gasPump *models.GasPump,
+) *schemas.Quote {
+	claims, err := utils.ParseQuoteToken(body.QuoteToken)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			c.JSON(http.StatusGone, dto.GeneralMessage{Detail: lang.QuoteExpired})
+			return nil
+		}
+		c.JSON(http.StatusBadRequest, dto.GeneralMessage{Detail: lang.InvalidQuote})
+		return nil
+	}
+
+	quote := claims.Quote
+	matches := quote.CustomerID == customer.ID &&
+		quote.GasPumpID == gasPump.ID &&
+		quote.FuelType == body.FuelType &&
+		quote.PaymentProvider == body.PaymentProvider &&
+		quote.ChargeType == body.ChargeType &&
+		quote.PromoCode == models.NormalizePromoCode(body.PromoCode)
+
+	if body.ChargeType == "by_liter" {
+		matches = matches && quote.TotalLiter == money.RoundLiters(float64(body.TotalLiter))
+	} else {
+		matches = matches && quote.Amount == body.Amount
+	}
+
+	if !matches {
+		c.JSON(http.StatusBadRequest, dto.GeneralMessage{Detail: lang.InvalidQuote})
+		return nil
+	}
+
+	return &quote
+}
+
+// stripeManualCapture reports whether stripe payments of the station only authorize
+// the amount and capture the served one. It is read from the setting
+// stripe_capture_method_<station external id>, stripe_capture_method otherwise
+func (pc *paymentController) stripeManualCapture(
+	paymentProvider string,
+	gasStation *models.GasStation,
+) (bool, error) {
+	if paymentProvider != "stripe" {
+		return false, nil
+	}
+
+	for _, name := range []string{
+		"stripe_capture_method_" + gasStation.ExternalID,
+		"stripe_capture_method",
+	} {
+		setting, err := pc.settingsRepo.GetByName(name)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return false, err
+		}
+
+		if setting.Value != "" {
+			return setting.Value == models.CaptureManual, nil
+		}
+	}
+
+	return false, nil
+}
+
+// refundPartOfPayment refunds amount of a served load through its provider
+func (pc *paymentController) refundPartOfPayment(
+	provider services.PaymentProvider,
+	payment *models.Payment,
+	amount money.Amount,
+	reason string,
+) error {
+	return provider.Refund(services.RefundOpts{
+		TransactionID: payment.ExternalTransactionID,
+		Amount:        amount,
+		Event:         "partial_refund",
+		Reason:        reason,
+	})
+}
+
+// refundEntirePayment gives back the whole payment through its provider, event is
+// recorded by providers confirming refunds later and reason is kept by the ones supporting it
+func (pc *paymentController) refundEntirePayment(
+	provider services.PaymentProvider,
+	payment *models.Payment,
+	event string,
+	reason string,
+) error {
+	// Loads not served were only authorized, releasing the authorization is immediate
+	if payment.CaptureMethod == models.CaptureManual {
+		return provider.Cancel(payment.ExternalTransactionID)
+	}
+
+	return provider.Refund(services.RefundOpts{
+		TransactionID: payment.ExternalTransactionID,
+		Event:         event,
+		Reason:        reason,
+	})
+}
+
+// recordRefund stores the refund events of a payment given back synchronously, adding
+// amount to what was refunded in the same transaction as the partial_refund event
+func (pc *paymentController) recordRefund(
+	payment *models.Payment,
+	amount money.Amount,
+	cancelLoad bool,
+	user *models.User,
+) error {
+	if cancelLoad {
+		err := pc.repository.CreateEvent(
+			&models.PaymentEvent{PaymentID: payment.ID, Type: "manual_action", UserID: &user.ID},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := pc.repository.CreateEventWithUpdates(
+		&models.PaymentEvent{PaymentID: payment.ID, Type: "partial_refund", UserID: &user.ID},
+		map[string]any{"refunded_amount": gorm.Expr("refunded_amount + ?", amount)},
+	)
+	if err != nil {
+		return err
+	}
+
+	payment.RefundedAmount += amount
+
+	return nil
+}
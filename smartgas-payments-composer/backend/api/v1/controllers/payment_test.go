@@ -0,0 +1,1055 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"smartgas-payment/internal/dto"
+	"smartgas-payment/internal/enums"
+	"smartgas-payment/internal/injectors"
+	"smartgas-payment/internal/lang"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/payments"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/services"
+	"smartgas-payment/internal/tasks"
+	"smartgas-payment/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type paymentCtrlTest struct {
+	suite.Suite
+	repository         *repository.MockPaymentRepository
+	userRepository     *repository.MockUserRepository
+	refundRepository   *repository.MockRefundRepository
+	customerRepository *repository.MockCustomerRepository
+	gasPumpRepository  *repository.MockGasPumpRepository
+	settingRepository  *repository.MockSettingRepository
+	customerService    *services.MockCustomerService
+	providers          *services.MockPaymentProviderRegistry
+	receiptService     *services.MockReceiptService
+	outboxTask         *tasks.MockOutboxTask
+	fraudTask          *tasks.MockFraudTask
+	stripeProvider     *services.MockPaymentProvider
+	switProvider       *services.MockPaymentProvider
+	debitProvider      *services.MockPaymentProvider
+	testRequest        *utils.TestRequest
+	userID             uuid.UUID
+	validToken         string
+	operatorToken      string
+	noPermissionToken  string
+	operatorStation    *models.GasStation
+	customer           *models.Customer
+	gasPump            *models.GasPump
+}
+
+func (suite *paymentCtrlTest) SetupSuite() {
+	setup, _ := injectors.InitializeServerWithMocks()
+
+	suite.repository = setup.PaymentRepositoryMock
+	suite.userRepository = setup.UserRepositoryMock
+	suite.refundRepository = setup.RefundRepositoryMock
+	suite.customerRepository = setup.CustomerRepositoryMock
+	suite.gasPumpRepository = setup.GasPumpRepositoryMock
+	suite.settingRepository = setup.SettingRepositoryMock
+	suite.customerService = setup.ExtCustomerService
+	suite.providers = setup.PaymentProviderRegistryMock
+	suite.receiptService = setup.ReceiptServiceMock
+	suite.outboxTask = setup.OutboxTaskMock
+	suite.fraudTask = setup.FraudTaskMock
+
+	suite.testRequest = &utils.TestRequest{
+		Router: setup.Router,
+	}
+
+	suite.userID = uuid.New()
+
+	user := &models.User{
+		ID:      suite.userID,
+		IsAdmin: utils.BoolAddr(true),
+	}
+
+	// Station operators only see the payments of their stations
+	suite.operatorStation = &models.GasStation{ID: uuid.New()}
+	operator := &models.User{
+		ID:          uuid.New(),
+		IsAdmin:     utils.BoolAddr(false),
+		Permissions: []*models.Permission{{Name: string(enums.ViewPayments)}},
+		GasStations: []*models.GasStation{suite.operatorStation},
+	}
+	noPermission := &models.User{
+		ID:      uuid.New(),
+		IsAdmin: utils.BoolAddr(false),
+	}
+
+	suite.userRepository.On("GetUserByID", suite.userID).Return(user, nil)
+	suite.userRepository.On("GetUserByID", operator.ID).Return(operator, nil)
+	suite.userRepository.On("GetUserByID", noPermission.ID).Return(noPermission, nil)
+	suite.userRepository.On("GetUserByID", mock.AnythingOfType("uuid.UUID")).Return(nil, gorm.ErrRecordNotFound)
+
+	claims := &schemas.JwtClaims{
+		Sub: suite.userID,
+	}
+
+	suite.validToken, _ = claims.ClaimToken()
+
+	claims.Sub = operator.ID
+	suite.operatorToken, _ = claims.ClaimToken()
+
+	claims.Sub = noPermission.ID
+	suite.noPermissionToken, _ = claims.ClaimToken()
+
+	suite.stripeProvider = &services.MockPaymentProvider{}
+	suite.stripeProvider.On("RefundsConfirmedAsync").Return(true).Maybe()
+
+	suite.switProvider = &services.MockPaymentProvider{}
+	suite.switProvider.On("RefundsConfirmedAsync").Return(false).Maybe()
+
+	suite.debitProvider = &services.MockPaymentProvider{}
+	suite.debitProvider.On("RefundsConfirmedAsync").Return(false).Maybe()
+
+	suite.providers.On("Get", "stripe").Return(suite.stripeProvider, nil)
+	suite.providers.On("Get", "swit").Return(suite.switProvider, nil)
+	suite.providers.On("Get", "debit").Return(suite.debitProvider, nil)
+
+	suite.customer = &models.Customer{
+		ID:               uuid.New(),
+		StripeCustomerID: "cus_test",
+		SwitCustomerID:   "swit_test",
+	}
+	suite.customerService.On("Verify", "customer-token").Return(&schemas.Customer{}, nil)
+	suite.customerRepository.On("GetCustomerOrCreate", mock.Anything).Return(suite.customer, false, nil)
+	suite.customerRepository.On("UpdateByID", suite.customer.ID, mock.Anything).Return(true, nil)
+
+	suite.gasPump = &models.GasPump{
+		ID:     uuid.New(),
+		Number: "01",
+		GasStation: &models.GasStation{
+			ID:       uuid.New(),
+			Timezone: "America/Mazatlan",
+		},
+	}
+	suite.gasPumpRepository.On("GetByID", suite.gasPump.ID).Return(suite.gasPump, nil)
+}
+
+// quote is the quote of a swit load of amount for the customer
+func (suite *paymentCtrlTest) quote(amount money.Amount) schemas.QuoteClaims {
+	return schemas.QuoteClaims{
+		Quote: schemas.Quote{
+			GasPumpID:       suite.gasPump.ID,
+			CustomerID:      suite.customer.ID,
+			FuelType:        "regular",
+			PaymentProvider: "swit",
+			ChargeType:      "by_total",
+			Price:           23.5,
+			Amount:          amount,
+			TotalLiter:      amount.Liters(23.5),
+		},
+	}
+}
+
+// intent is a swit load of amount quoted for the customer
+func (suite *paymentCtrlTest) intent(amount money.Amount) dto.CreatePaymentIntentRequest {
+	token, _ := suite.quote(amount).ClaimToken()
+
+	return dto.CreatePaymentIntentRequest{
+		FuelType:        "regular",
+		Amount:          amount,
+		ChargeType:      "by_total",
+		GasPumpID:       suite.gasPump.ID.String(),
+		PaymentProvider: "swit",
+		SourceID:        "card_test",
+		Last4:           "4242",
+		Cvv:             "123",
+		QuoteToken:      token,
+	}
+}
+
+// fraudDecision is what the fraud rules decide for loads of amount
+func (suite *paymentCtrlTest) fraudDecision(amount money.Amount, reason string) {
+	suite.fraudTask.On("Evaluate", mock.MatchedBy(func(check tasks.FraudCheck) bool {
+		return check.Amount == amount
+	})).Return(&models.FraudDecision{Allowed: reason == "", Reason: reason}, nil)
+	suite.fraudTask.On("Guard", mock.MatchedBy(func(check tasks.FraudCheck) bool {
+		return check.Amount == amount
+	})).Return(repository.PaymentGuard(nil)).Maybe()
+}
+
+// payment registers a payment of provider whose last event is lastEvent
+func (suite *paymentCtrlTest) payment(provider string, lastEvent string) *models.Payment {
+	payment := &models.Payment{
+		ID:                    uuid.New(),
+		Amount:                money.FromFloat(500),
+		RealAmountReported:    money.FromFloat(400),
+		PaymentProvider:       provider,
+		ExternalTransactionID: "tr_" + provider,
+		CaptureMethod:         models.CaptureAutomatic,
+		FromOperations:        utils.BoolAddr(false),
+	}
+
+	suite.repository.On("GetByIDPreloaded", payment.ID).Return(payment, nil)
+	suite.repository.On("GetLastEventByPaymentID", payment.ID).
+		Return(&models.PaymentEvent{PaymentID: payment.ID, Type: lastEvent}, nil)
+
+	return payment
+}
+
+func (suite *paymentCtrlTest) TestDoPaymentActionRefund() {
+	url := "/api/v1/payments/actions/"
+
+	servedStripe := suite.payment("stripe", "served")
+	suite.refundRepository.On("Reserve", mock.MatchedBy(func(refund *models.Refund) bool {
+		if refund.PaymentID != servedStripe.ID {
+			return false
+		}
+		refund.Amount = money.FromFloat(100)
+		return true
+	}), servedStripe.RealAmountReported).Return(nil)
+	suite.stripeProvider.On("Refund", services.RefundOpts{
+		TransactionID: servedStripe.ExternalTransactionID,
+		Amount:        money.FromFloat(100),
+		Event:         "partial_refund",
+		Reason:        "Bomba despachó de menos",
+	}).Return(nil).Once()
+
+	exceeded := suite.payment("stripe", "partial_refund")
+	suite.refundRepository.On("Reserve", mock.MatchedBy(func(refund *models.Refund) bool {
+		return refund.PaymentID == exceeded.ID
+	}), exceeded.RealAmountReported).Return(payments.ErrRefundExceedsCaptured)
+
+	// Swit and Debit give back what is left when no amount is sent
+	servedSwit := suite.payment("swit", "served")
+	servedDebit := suite.payment("debit", "served")
+	failedDebit := suite.payment("debit", "partial_refund")
+	failedDebit.ExternalTransactionID = "tr_debit_failed"
+	for _, served := range []*models.Payment{servedSwit, servedDebit, failedDebit} {
+		served := served
+		suite.refundRepository.On("Reserve", mock.MatchedBy(func(refund *models.Refund) bool {
+			if refund.PaymentID != served.ID {
+				return false
+			}
+			refund.Amount = served.RealAmountReported - money.FromFloat(50)
+			return true
+		}), served.RealAmountReported).Return(nil)
+	}
+	suite.switProvider.On("Refund", services.RefundOpts{
+		TransactionID: servedSwit.ExternalTransactionID,
+		Amount:        money.FromFloat(350),
+		Event:         "partial_refund",
+		Reason:        "Bomba despachó de menos",
+	}).Return(nil).Once()
+	suite.debitProvider.On("Refund", services.RefundOpts{
+		TransactionID: servedDebit.ExternalTransactionID,
+		Amount:        money.FromFloat(350),
+		Event:         "partial_refund",
+		Reason:        "Bomba despachó de menos",
+	}).Return(nil).Once()
+	suite.debitProvider.On("Refund", services.RefundOpts{
+		TransactionID: failedDebit.ExternalTransactionID,
+		Amount:        money.FromFloat(350),
+		Event:         "partial_refund",
+		Reason:        "Bomba despachó de menos",
+	}).Return(services.DebitRefundGreaterError).Once()
+	for _, served := range []*models.Payment{servedSwit, servedDebit} {
+		served := served
+		suite.repository.On(
+			"CreateEventWithUpdates",
+			mock.MatchedBy(func(event *models.PaymentEvent) bool {
+				return event.PaymentID == served.ID && event.Type == "partial_refund"
+			}),
+			map[string]any{"refunded_amount": gorm.Expr("refunded_amount + ?", money.FromFloat(350))},
+		).Return(nil).Once()
+	}
+
+	paidSwit := suite.payment("swit", "paid")
+	suite.refundRepository.On("Reserve", mock.MatchedBy(func(refund *models.Refund) bool {
+		if refund.PaymentID != paidSwit.ID {
+			return false
+		}
+		refund.Amount = paidSwit.Amount
+		return true
+	}), paidSwit.Amount).Return(nil)
+	suite.switProvider.On("Refund", services.RefundOpts{
+		TransactionID: paidSwit.ExternalTransactionID,
+		Event:         "manual_action",
+		Reason:        "Bomba despachó de menos",
+	}).Return(nil).Once()
+	suite.repository.On("CreateEvent", mock.MatchedBy(func(event *models.PaymentEvent) bool {
+		return event.PaymentID == paidSwit.ID && event.Type == "manual_action"
+	})).Return(nil).Once()
+	suite.repository.On(
+		"CreateEventWithUpdates",
+		mock.MatchedBy(func(event *models.PaymentEvent) bool {
+			return event.PaymentID == paidSwit.ID && event.Type == "partial_refund"
+		}),
+		mock.MatchedBy(func(updates map[string]any) bool {
+			_, ok := updates["refunded_amount"]
+			return ok
+		}),
+	).Return(nil).Once()
+
+	suite.refundRepository.On("Finish", mock.AnythingOfType("*models.Refund")).Return(nil)
+
+	testcases := []struct {
+		Name               string
+		Url                string
+		Body               any
+		ExpectedStatusCode int
+		ExpectedResponse   any
+	}{
+		{
+			Name: "TestPaymentController_RefundServedStripe",
+			Url:  url + servedStripe.ID.String(),
+			Body: dto.DoPaymentActionRequest{
+				Action: "refund",
+				Amount: money.FromFloat(100),
+				Reason: "Bomba despachó de menos",
+			},
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   dto.GeneralMessage{Detail: "ok"},
+		},
+		{
+			Name: "TestPaymentController_RefundExceedsCaptured",
+			Url:  url + exceeded.ID.String(),
+			Body: dto.DoPaymentActionRequest{
+				Action: "refund",
+				Amount: money.FromFloat(1000),
+				Reason: "Bomba despachó de menos",
+			},
+			ExpectedStatusCode: http.StatusUnprocessableEntity,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.RefundExceedsCaptured},
+		},
+		{
+			Name: "TestPaymentController_RefundServedSwit",
+			Url:  url + servedSwit.ID.String(),
+			Body: dto.DoPaymentActionRequest{
+				Action: "refund",
+				Reason: "Bomba despachó de menos",
+			},
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   dto.GeneralMessage{Detail: "ok"},
+		},
+		{
+			Name: "TestPaymentController_RefundServedDebit",
+			Url:  url + servedDebit.ID.String(),
+			Body: dto.DoPaymentActionRequest{
+				Action: "refund",
+				Reason: "Bomba despachó de menos",
+			},
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   dto.GeneralMessage{Detail: "ok"},
+		},
+		{
+			Name: "TestPaymentController_RefundServedDebitFailed",
+			Url:  url + failedDebit.ID.String(),
+			Body: dto.DoPaymentActionRequest{
+				Action: "refund",
+				Reason: "Bomba despachó de menos",
+			},
+			ExpectedStatusCode: http.StatusInternalServerError,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.InternalServerError},
+		},
+		{
+			Name: "TestPaymentController_RefundNotServedMustBeTotal",
+			Url:  url + paidSwit.ID.String(),
+			Body: dto.DoPaymentActionRequest{
+				Action: "refund",
+				Amount: money.FromFloat(100),
+				Reason: "Bomba despachó de menos",
+			},
+			ExpectedStatusCode: http.StatusUnprocessableEntity,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.RefundMustBeTotal},
+		},
+		{
+			Name: "TestPaymentController_RefundNotServed",
+			Url:  url + paidSwit.ID.String(),
+			Body: dto.DoPaymentActionRequest{
+				Action: "refund",
+				Reason: "Bomba despachó de menos",
+			},
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse:   dto.GeneralMessage{Detail: "ok"},
+		},
+	}
+
+	suite.testRequest.SetBearerToken("Bearer " + suite.validToken)
+	defer suite.testRequest.SetBearerToken("")
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			res := suite.testRequest.Post(tc.Url, tc.Body)
+
+			suite.Equal(tc.ExpectedStatusCode, res.Code, utils.PrintExpectedValues(tc.ExpectedStatusCode, res.Code))
+
+			expected, _ := json.Marshal(tc.ExpectedResponse)
+			suite.Equal(string(expected), res.Body.String(), utils.PrintExpectedValues(string(expected), res.Body.String()))
+		})
+	}
+
+	suite.stripeProvider.AssertExpectations(suite.T())
+	suite.switProvider.AssertExpectations(suite.T())
+	suite.debitProvider.AssertExpectations(suite.T())
+	suite.repository.AssertExpectations(suite.T())
+	suite.refundRepository.AssertCalled(suite.T(), "Finish", mock.MatchedBy(func(refund *models.Refund) bool {
+		return refund.PaymentID == failedDebit.ID && refund.Status == models.RefundFailed
+	}))
+	suite.repository.AssertNotCalled(suite.T(), "CreateEventWithUpdates", mock.MatchedBy(func(event *models.PaymentEvent) bool {
+		return event.PaymentID == failedDebit.ID
+	}), mock.Anything)
+}
+
+func (suite *paymentCtrlTest) TestCreateIntentFraud() {
+	url := "/api/v1/payments/create-intent"
+
+	rejected := money.FromFloat(300)
+	suite.fraudDecision(rejected, models.FraudMaxLoadsPerDay)
+
+	// Another load of the customer was created between the check and the payment
+	raced := money.FromFloat(400)
+	suite.fraudDecision(raced, "")
+	suite.switProvider.On("Reserve", mock.MatchedBy(func(opts services.ReserveOpts) bool {
+		return opts.Amount == raced
+	})).Return(&services.ReserveResult{TransactionID: "tr_raced", Reserved: true}, nil).Once()
+	suite.repository.On("CreatePaymentIntent", mock.MatchedBy(func(payment *models.Payment) bool {
+		return payment.Amount == raced
+	}), mock.Anything, mock.Anything).Return(&tasks.FraudRejectedError{
+		Decision: &models.FraudDecision{Reason: models.FraudMaxAmountPerDay},
+	}).Once()
+	suite.switProvider.On("Cancel", "tr_raced").Return(nil).Once()
+	suite.settingRepository.On("GetByName", "gas_pump_status").
+		Return(&models.Setting{Name: "gas_pump_status", Value: "disabled"}, nil).Once()
+
+	testcases := []struct {
+		Name               string
+		Body               any
+		ExpectedStatusCode int
+		ExpectedResponse   any
+	}{
+		{
+			Name:               "TestPaymentController_FraudRejectedBeforeReserve",
+			Body:               suite.intent(rejected),
+			ExpectedStatusCode: http.StatusForbidden,
+			ExpectedResponse: dto.GeneralMessage{
+				Detail: lang.FraudLimitExceeded + models.FraudMaxLoadsPerDay,
+			},
+		},
+		{
+			Name:               "TestPaymentController_FraudRejectedWhenCreated",
+			Body:               suite.intent(raced),
+			ExpectedStatusCode: http.StatusForbidden,
+			ExpectedResponse: dto.GeneralMessage{
+				Detail: lang.FraudLimitExceeded + models.FraudMaxAmountPerDay,
+			},
+		},
+	}
+
+	suite.testRequest.SetBearerToken("Token customer-token")
+	defer suite.testRequest.SetBearerToken("")
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			res := suite.testRequest.Post(url, tc.Body)
+
+			suite.Equal(tc.ExpectedStatusCode, res.Code, utils.PrintExpectedValues(tc.ExpectedStatusCode, res.Code))
+
+			expected, _ := json.Marshal(tc.ExpectedResponse)
+			suite.Equal(string(expected), res.Body.String(), utils.PrintExpectedValues(string(expected), res.Body.String()))
+		})
+	}
+
+	suite.switProvider.AssertNotCalled(suite.T(), "Reserve", mock.MatchedBy(func(opts services.ReserveOpts) bool {
+		return opts.Amount == rejected
+	}))
+	suite.switProvider.AssertExpectations(suite.T())
+}
+
+func (suite *paymentCtrlTest) TestCreateIntentPresetQueued() {
+	url := "/api/v1/payments/create-intent"
+
+	amount := money.FromFloat(370)
+	suite.fraudDecision(amount, "")
+	suite.switProvider.On("Reserve", mock.MatchedBy(func(opts services.ReserveOpts) bool {
+		return opts.Amount == amount
+	})).Return(&services.ReserveResult{TransactionID: "tr_preset", Reserved: true}, nil).Once()
+	suite.settingRepository.On("GetByName", "gas_pump_status").
+		Return(&models.Setting{Name: "gas_pump_status", Value: "enabled"}, nil).Once()
+
+	// The preset is stored along with the payment and left to the outbox, the request
+	// never calls the pump itself
+	isPreset := mock.MatchedBy(func(message *models.OutboxMessage) bool {
+		return message.Type == models.OutboxSetGasPump
+	})
+	suite.repository.On("CreatePaymentIntent", mock.MatchedBy(func(payment *models.Payment) bool {
+		return payment.Amount == amount
+	}), mock.MatchedBy(func(messages []*models.OutboxMessage) bool {
+		return len(messages) == 1 && messages[0].Type == models.OutboxSetGasPump
+	}), mock.Anything).Return(nil).Once()
+	suite.outboxTask.On("Dispatch", isPreset).Return().Once()
+
+	suite.testRequest.SetBearerToken("Token customer-token")
+	defer suite.testRequest.SetBearerToken("")
+
+	res := suite.testRequest.Post(url, suite.intent(amount))
+
+	suite.Equal(http.StatusCreated, res.Code, utils.PrintExpectedValues(http.StatusCreated, res.Code))
+	suite.outboxTask.AssertCalled(suite.T(), "Dispatch", isPreset)
+}
+
+func (suite *paymentCtrlTest) TestCreateIntentQuote() {
+	url := "/api/v1/payments/create-intent"
+
+	quoted := money.FromFloat(350)
+	suite.fraudDecision(quoted, "")
+	suite.switProvider.On("Reserve", mock.MatchedBy(func(opts services.ReserveOpts) bool {
+		return opts.Amount == quoted
+	})).Return(&services.ReserveResult{TransactionID: "tr_quoted", Reserved: true}, nil).Once()
+	// The load is charged at the quoted terms, not priced again
+	suite.repository.On("CreatePaymentIntent", mock.MatchedBy(func(payment *models.Payment) bool {
+		return payment.Amount == quoted &&
+			payment.Price == 23.5 &&
+			payment.DiscountPerLiter == 1.5 &&
+			payment.DiscountType == "campaign"
+	}), []*models.OutboxMessage(nil), mock.Anything).Return(nil).Once()
+	suite.settingRepository.On("GetByName", "gas_pump_status").
+		Return(&models.Setting{Name: "gas_pump_status", Value: "disabled"}, nil).Once()
+	suite.outboxTask.On("Dispatch").Return().Once()
+
+	valid := suite.intent(quoted)
+	claims := suite.quote(quoted)
+	claims.DiscountPerLiter = 1.5
+	claims.DiscountType = "campaign"
+	valid.QuoteToken, _ = claims.ClaimToken()
+
+	expired := suite.intent(money.FromFloat(410))
+	claims = suite.quote(expired.Amount)
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	expired.QuoteToken, _ = claims.ClaimToken()
+
+	tampered := suite.intent(money.FromFloat(420))
+	tampered.QuoteToken += "x"
+
+	otherAmount := suite.intent(money.FromFloat(430))
+	otherAmount.Amount = money.FromFloat(1000)
+
+	otherFuelType := suite.intent(money.FromFloat(440))
+	otherFuelType.FuelType = "premium"
+
+	otherCustomer := suite.intent(money.FromFloat(450))
+	claims = suite.quote(otherCustomer.Amount)
+	claims.CustomerID = uuid.New()
+	otherCustomer.QuoteToken, _ = claims.ClaimToken()
+
+	testcases := []struct {
+		Name               string
+		Body               any
+		ExpectedStatusCode int
+		ExpectedResponse   any
+	}{
+		{
+			Name:               "TestPaymentController_QuoteCharged",
+			Body:               valid,
+			ExpectedStatusCode: http.StatusCreated,
+			ExpectedResponse: dto.PaymentCrateIntentResponse{
+				Amount:     quoted,
+				TotalLiter: quoted.Liters(23.5),
+			},
+		},
+		{
+			Name:               "TestPaymentController_QuoteExpired",
+			Body:               expired,
+			ExpectedStatusCode: http.StatusGone,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.QuoteExpired},
+		},
+		{
+			Name:               "TestPaymentController_QuoteTampered",
+			Body:               tampered,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.InvalidQuote},
+		},
+		{
+			Name:               "TestPaymentController_QuoteOfOtherAmount",
+			Body:               otherAmount,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.InvalidQuote},
+		},
+		{
+			Name:               "TestPaymentController_QuoteOfOtherFuelType",
+			Body:               otherFuelType,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.InvalidQuote},
+		},
+		{
+			Name:               "TestPaymentController_QuoteOfOtherCustomer",
+			Body:               otherCustomer,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.InvalidQuote},
+		},
+	}
+
+	suite.testRequest.SetBearerToken("Token customer-token")
+	defer suite.testRequest.SetBearerToken("")
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			res := suite.testRequest.Post(url, tc.Body)
+
+			suite.Equal(tc.ExpectedStatusCode, res.Code, utils.PrintExpectedValues(tc.ExpectedStatusCode, res.Code))
+
+			expected, _ := json.Marshal(tc.ExpectedResponse)
+			suite.Equal(string(expected), res.Body.String(), utils.PrintExpectedValues(string(expected), res.Body.String()))
+		})
+	}
+
+	// Rejected quotes never reach the fraud rules nor the provider
+	suite.fraudTask.AssertNotCalled(suite.T(), "Evaluate", mock.MatchedBy(func(check tasks.FraudCheck) bool {
+		return check.Amount >= expired.Amount && check.Amount <= otherCustomer.Amount
+	}))
+	suite.switProvider.AssertExpectations(suite.T())
+	suite.repository.AssertExpectations(suite.T())
+}
+
+func (suite *paymentCtrlTest) TestListAmountFilters() {
+	url := "/api/v1/payments?page=1&limit=10"
+
+	suite.repository.On("List", mock.Anything, mock.MatchedBy(func(filters map[string]any) bool {
+		return filters["min_amount"] == money.FromCents(10010) && filters["max_amount"] == money.FromCents(20000)
+	})).Return([]*models.Payment{}, nil).Once()
+
+	testcases := []struct {
+		Name               string
+		Url                string
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "TestPaymentController_ListAmountsInPesos",
+			Url:                url + "&min_amount=100.10&max_amount=200",
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "TestPaymentController_ListMaxBelowMin",
+			Url:                url + "&min_amount=200&max_amount=100.10",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "TestPaymentController_ListNegativeAmount",
+			Url:                url + "&min_amount=-5",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "TestPaymentController_ListAmountNotNumeric",
+			Url:                url + "&max_amount=abc",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	suite.testRequest.SetBearerToken("Bearer " + suite.validToken)
+	defer suite.testRequest.SetBearerToken("")
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			res := suite.testRequest.Get(tc.Url, nil)
+
+			suite.Equal(tc.ExpectedStatusCode, res.Code, utils.PrintExpectedValues(tc.ExpectedStatusCode, res.Code))
+		})
+	}
+
+	suite.repository.AssertExpectations(suite.T())
+}
+
+func (suite *paymentCtrlTest) TestGetByIDStationScoping() {
+	visible := &models.Payment{
+		ID:     uuid.New(),
+		Amount: money.FromFloat(500),
+		Status: payments.StatusPaid,
+		Events: []models.PaymentEvent{{Type: "funds_reserved"}},
+	}
+	// Paid at a station the operator does not manage
+	hidden := uuid.New()
+
+	stationsFilter := map[string]any{"stations": []string{suite.operatorStation.ID.String()}}
+
+	suite.repository.On("GetByIDDetailed", visible.ID, map[string]any{}).Return(visible, nil)
+	suite.repository.On("GetByIDDetailed", visible.ID, stationsFilter).Return(visible, nil)
+	suite.repository.On("GetByIDDetailed", hidden, stationsFilter).Return(nil, gorm.ErrRecordNotFound)
+	suite.refundRepository.On("ListByPaymentID", visible.ID).Return([]*models.Refund{}, nil)
+
+	testcases := []struct {
+		Name               string
+		Token              string
+		ID                 string
+		ExpectedStatusCode int
+		ExpectedFilters    map[string]any
+	}{
+		{
+			Name:               "TestPaymentController_DetailAdmin",
+			Token:              suite.validToken,
+			ID:                 visible.ID.String(),
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedFilters:    map[string]any{},
+		},
+		{
+			Name:               "TestPaymentController_DetailOwnStation",
+			Token:              suite.operatorToken,
+			ID:                 visible.ID.String(),
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedFilters:    stationsFilter,
+		},
+		{
+			Name:               "TestPaymentController_DetailOtherStation",
+			Token:              suite.operatorToken,
+			ID:                 hidden.String(),
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedFilters:    stationsFilter,
+		},
+		{
+			Name:               "TestPaymentController_DetailWithoutPermission",
+			Token:              suite.noPermissionToken,
+			ID:                 visible.ID.String(),
+			ExpectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			Name:               "TestPaymentController_DetailInvalidID",
+			Token:              suite.validToken,
+			ID:                 "not-an-id",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	defer suite.testRequest.SetBearerToken("")
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.testRequest.SetBearerToken("Bearer " + tc.Token)
+
+			res := suite.testRequest.Get("/api/v1/payments/"+tc.ID, nil)
+
+			suite.Equal(tc.ExpectedStatusCode, res.Code, utils.PrintExpectedValues(tc.ExpectedStatusCode, res.Code))
+
+			if tc.ExpectedFilters != nil {
+				id, _ := uuid.Parse(tc.ID)
+				suite.repository.AssertCalled(suite.T(), "GetByIDDetailed", id, tc.ExpectedFilters)
+			}
+
+			if tc.ExpectedStatusCode == http.StatusOK {
+				var response dto.PaymentDetailResponse
+				suite.Nil(json.Unmarshal(res.Body.Bytes(), &response))
+				suite.Equal(visible.ID, response.ID)
+				suite.Len(response.Events, 1)
+				suite.NotNil(response.Refunds)
+			}
+		})
+	}
+
+	// Never looked up without the stations of the operator
+	suite.repository.AssertNotCalled(suite.T(), "GetByIDDetailed", hidden, map[string]any{})
+}
+
+func (suite *paymentCtrlTest) TestExportSort() {
+	testcases := []struct {
+		Name         string
+		Query        string
+		ExpectedSort string
+	}{
+		{
+			Name:         "TestPaymentController_SortDefault",
+			ExpectedSort: "payments.created_at desc, payments.id desc",
+		},
+		{
+			Name:         "TestPaymentController_SortByAmount",
+			Query:        "&sort_by=amount&sort_order=asc",
+			ExpectedSort: "payments.amount asc, payments.id asc",
+		},
+		{
+			Name:         "TestPaymentController_SortByStatus",
+			Query:        "&sort_by=status",
+			ExpectedSort: "payments.status desc, payments.id desc",
+		},
+	}
+
+	suite.testRequest.SetBearerToken("Bearer " + suite.validToken)
+	defer suite.testRequest.SetBearerToken("")
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			// Ties are broken by id, in the same direction
+			suite.repository.On("Export", mock.Anything, tc.ExpectedSort, mock.Anything).Return(nil).Once()
+
+			res := suite.testRequest.Get("/api/v1/payments/export?format=csv"+tc.Query, nil)
+
+			suite.Equal(http.StatusOK, res.Code, utils.PrintExpectedValues(http.StatusOK, res.Code))
+			suite.repository.AssertCalled(suite.T(), "Export", mock.Anything, tc.ExpectedSort, mock.Anything)
+		})
+	}
+}
+
+func (suite *paymentCtrlTest) TestExportXLSXTooLarge() {
+	suite.repository.On("Export", mock.Anything, mock.Anything, mock.Anything).
+		Return(func(_ any, _ string, write func(*repository.PaymentExportRow) error) error {
+			for {
+				if err := write(&repository.PaymentExportRow{ID: uuid.NewString()}); err != nil {
+					return err
+				}
+			}
+		}).
+		Once()
+
+	suite.testRequest.SetBearerToken("Bearer " + suite.validToken)
+	defer suite.testRequest.SetBearerToken("")
+
+	res := suite.testRequest.Get("/api/v1/payments/export?format=xlsx", nil)
+
+	suite.Equal(http.StatusUnprocessableEntity, res.Code)
+	suite.Empty(res.Header().Get("Content-Disposition"))
+
+	expected, _ := json.Marshal(dto.GeneralMessage{Detail: lang.ExportTooLarge})
+	suite.Equal(string(expected), res.Body.String())
+}
+
+// oneTapIntent is a stripe load of amount charged to the default card of the customer
+func (suite *paymentCtrlTest) oneTapIntent(amount money.Amount) dto.CreatePaymentIntentRequest {
+	claims := suite.quote(amount)
+	claims.PaymentProvider = "stripe"
+
+	body := suite.intent(amount)
+	body.PaymentProvider = "stripe"
+	body.SourceID, body.Last4, body.Cvv = "", "", ""
+	body.UseDefaultPaymentMethod = true
+	body.QuoteToken, _ = claims.ClaimToken()
+
+	return body
+}
+
+func (suite *paymentCtrlTest) TestCreateIntentDefaultPaymentMethod() {
+	url := "/api/v1/payments/create-intent"
+
+	confirmed, authenticated, noDefault := money.FromFloat(610), money.FromFloat(620), money.FromFloat(630)
+
+	suite.settingRepository.On("GetByName", "stripe_capture_method_"+suite.gasPump.GasStation.ExternalID).
+		Return(nil, gorm.ErrRecordNotFound)
+	suite.settingRepository.On("GetByName", "stripe_capture_method").Return(nil, gorm.ErrRecordNotFound)
+
+	oneTap := func(amount money.Amount) any {
+		return mock.MatchedBy(func(opts services.ReserveOpts) bool {
+			return opts.Amount == amount && opts.UseDefaultPaymentMethod
+		})
+	}
+	for _, amount := range []money.Amount{confirmed, authenticated, noDefault} {
+		suite.fraudDecision(amount, "")
+	}
+	suite.stripeProvider.On("Reserve", oneTap(confirmed)).
+		Return(&services.ReserveResult{TransactionID: "pi_confirmed", ClientSecret: "pi_confirmed_secret"}, nil).Once()
+	// 3D Secure asked by the bank, the client authenticates it with the client secret
+	suite.stripeProvider.On("Reserve", oneTap(authenticated)).
+		Return(&services.ReserveResult{
+			TransactionID:  "pi_authenticated",
+			ClientSecret:   "pi_authenticated_secret",
+			RequiresAction: true,
+		}, nil).Once()
+	suite.stripeProvider.On("Reserve", oneTap(noDefault)).Return(nil, services.ErrNoDefaultPaymentMethod).Once()
+
+	suite.repository.On("CreatePaymentIntent", mock.MatchedBy(func(payment *models.Payment) bool {
+		return payment.Amount == confirmed || payment.Amount == authenticated
+	}), []*models.OutboxMessage(nil), mock.Anything).Return(nil).Twice()
+	suite.settingRepository.On("GetByName", "gas_pump_status").
+		Return(&models.Setting{Name: "gas_pump_status", Value: "disabled"}, nil).Twice()
+	suite.outboxTask.On("Dispatch").Return().Twice()
+
+	switOneTap := suite.intent(money.FromFloat(640))
+	switOneTap.UseDefaultPaymentMethod = true
+
+	testcases := []struct {
+		Name               string
+		Body               any
+		ExpectedStatusCode int
+		ExpectedResponse   any
+	}{
+		{
+			Name:               "TestPaymentController_OneTapConfirmed",
+			Body:               suite.oneTapIntent(confirmed),
+			ExpectedStatusCode: http.StatusCreated,
+			ExpectedResponse: dto.PaymentCrateIntentResponse{
+				ClientSecret: "pi_confirmed_secret",
+				Amount:       confirmed,
+				TotalLiter:   confirmed.Liters(23.5),
+			},
+		},
+		{
+			Name:               "TestPaymentController_OneTapRequiresAction",
+			Body:               suite.oneTapIntent(authenticated),
+			ExpectedStatusCode: http.StatusCreated,
+			ExpectedResponse: dto.PaymentCrateIntentResponse{
+				ClientSecret:   "pi_authenticated_secret",
+				Amount:         authenticated,
+				TotalLiter:     authenticated.Liters(23.5),
+				RequiresAction: true,
+			},
+		},
+		{
+			Name:               "TestPaymentController_OneTapNoDefault",
+			Body:               suite.oneTapIntent(noDefault),
+			ExpectedStatusCode: http.StatusNotAcceptable,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.NoDefaultPaymentMethod},
+		},
+		{
+			// Only stripe keeps default cards
+			Name:               "TestPaymentController_OneTapNotStripe",
+			Body:               switOneTap,
+			ExpectedStatusCode: http.StatusBadRequest,
+			ExpectedResponse: []dto.BadRequestMessage{{
+				Field:   "use_default_payment_method",
+				Message: "excluded_unless",
+			}},
+		},
+	}
+
+	suite.testRequest.SetBearerToken("Token customer-token")
+	defer suite.testRequest.SetBearerToken("")
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			res := suite.testRequest.Post(url, tc.Body)
+
+			suite.Equal(tc.ExpectedStatusCode, res.Code, utils.PrintExpectedValues(tc.ExpectedStatusCode, res.Code))
+
+			expected, _ := json.Marshal(tc.ExpectedResponse)
+			suite.Equal(string(expected), res.Body.String(), utils.PrintExpectedValues(string(expected), res.Body.String()))
+		})
+	}
+
+	suite.stripeProvider.AssertExpectations(suite.T())
+	suite.repository.AssertNotCalled(suite.T(), "CreatePaymentIntent", mock.MatchedBy(func(payment *models.Payment) bool {
+		return payment.Amount == noDefault
+	}), mock.Anything, mock.Anything)
+}
+
+func (suite *paymentCtrlTest) TestGetReceipt() {
+	testcases := []struct {
+		Name               string
+		LastEvent          string
+		Status             string
+		NotFound           bool
+		RenderErr          error
+		ExpectedStatusCode int
+		ExpectedResponse   any
+	}{
+		{
+			Name:               "TestPaymentController_ReceiptServed",
+			Status:             payments.StatusPaid,
+			LastEvent:          "served",
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "TestPaymentController_ReceiptPartiallyRefunded",
+			Status:             payments.StatusPaid,
+			LastEvent:          "partial_refund",
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "TestPaymentController_ReceiptNotServed",
+			Status:             payments.StatusPaid,
+			LastEvent:          "serving",
+			ExpectedStatusCode: http.StatusNotAcceptable,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.ReceiptNotAvailable},
+		},
+		{
+			Name:               "TestPaymentController_ReceiptCanceled",
+			Status:             payments.StatusCanceled,
+			LastEvent:          "canceled",
+			ExpectedStatusCode: http.StatusNotAcceptable,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.ReceiptNotAvailable},
+		},
+		{
+			// Payments of other customers are not found either
+			Name:               "TestPaymentController_ReceiptNotFound",
+			NotFound:           true,
+			ExpectedStatusCode: http.StatusNotFound,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.NotFoundRecord},
+		},
+		{
+			Name:               "TestPaymentController_ReceiptRenderFailed",
+			Status:             payments.StatusPaid,
+			LastEvent:          "served",
+			RenderErr:          errors.New("font not found"),
+			ExpectedStatusCode: http.StatusInternalServerError,
+			ExpectedResponse:   dto.GeneralMessage{Detail: lang.InternalServerError},
+		},
+	}
+
+	suite.testRequest.SetBearerToken("Token customer-token")
+	defer suite.testRequest.SetBearerToken("")
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			payment := &models.Payment{
+				ID:                 uuid.New(),
+				CustomerID:         &suite.customer.ID,
+				Status:             tc.Status,
+				Amount:             money.FromFloat(500),
+				RealAmountReported: money.FromFloat(400),
+				GasPump:            suite.gasPump,
+				// Newest first
+				Events: []models.PaymentEvent{{Type: tc.LastEvent}, {Type: "paid"}},
+			}
+
+			if tc.NotFound {
+				suite.repository.On("GetByIDForCustomer", payment.ID, suite.customer.ID).
+					Return(nil, gorm.ErrRecordNotFound).Once()
+			} else {
+				suite.repository.On("GetByIDForCustomer", payment.ID, suite.customer.ID).Return(payment, nil).Once()
+			}
+
+			suite.receiptService.On("Render", mock.MatchedBy(func(data *schemas.FuelRequest) bool {
+				return data.TransactionID == payment.ID.String()
+			}), mock.Anything).Run(func(args mock.Arguments) {
+				args.Get(1).(io.Writer).Write([]byte("%PDF-1.3 receipt"))
+			}).Return(tc.RenderErr).Maybe()
+
+			res := suite.testRequest.Get("/api/v1/payments/"+payment.ID.String()+"/receipt", nil)
+
+			suite.Equal(tc.ExpectedStatusCode, res.Code, utils.PrintExpectedValues(tc.ExpectedStatusCode, res.Code))
+
+			if tc.ExpectedResponse == nil {
+				suite.Equal("application/pdf", res.Header().Get("Content-Type"))
+				suite.Equal(
+					`attachment; filename="`+tasks.ReceiptFilename(payment)+`"`,
+					res.Header().Get("Content-Disposition"),
+				)
+				suite.Equal("%PDF-1.3 receipt", res.Body.String())
+				return
+			}
+
+			expected, _ := json.Marshal(tc.ExpectedResponse)
+			suite.Equal(string(expected), res.Body.String(), utils.PrintExpectedValues(string(expected), res.Body.String()))
+		})
+	}
+}
+
+func TestPaymentController(t *testing.T) {
+	suite.Run(t, new(paymentCtrlTest))
+}
@@ -0,0 +1,444 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"smartgas-payment/internal/dto"
+	"smartgas-payment/internal/lang"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+const (
+	// Letters and digits not mistaken for each other, 32 of them so every byte maps
+	// evenly to one
+	promoCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	promoCodeLength   = 8
+)
+
+type PromoCodeController interface {
+	List(*gin.Context)
+	Get(*gin.Context)
+	Create(*gin.Context)
+	CreateBatch(*gin.Context)
+	Update(*gin.Context)
+	ListRedemptions(*gin.Context)
+}
+
+type promoCodeController struct {
+	repository repository.PromoCodeRepository
+}
+
+func ProvidePromoCodeController(repository repository.PromoCodeRepository) *promoCodeController {
+	return &promoCodeController{
+		repository: repository,
+	}
+}
+
+// generatePromoCode is a random code after the prefix
+func generatePromoCode(prefix string) (string, error) {
+	random := make([]byte, promoCodeLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	for i, b := range random {
+		random[i] = promoCodeAlphabet[int(b)%len(promoCodeAlphabet)]
+	}
+
+	return models.NormalizePromoCode(prefix) + string(random), nil
+}
+
+func promoCodeResponse(code *models.PromoCode) dto.PromoCodeResponse {
+	var response dto.PromoCodeResponse
+
+	copier.Copy(&response, code)
+
+	return response
+}
+
+func (pcc *promoCodeController) trackError(c *gin.Context, err error) {
+	// Logging error in sentry
+	opts := &utils.TrackErrorOpts{
+		Admin: c.MustGet("user").(*models.User),
+		Tags:  map[string]string{"auth_type": "admin"},
+	}
+	utils.TrackError(c, err, opts)
+	c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+}
+
+// createError writes the response of codes that could not be stored
+func (pcc *promoCodeController) createError(c *gin.Context, err error) {
+	if utils.CheckDuplicatedEntry(err) {
+		c.JSON(http.StatusConflict, dto.GeneralMessage{Detail: lang.PromoCodeExists})
+		return
+	}
+	if utils.CheckMysqlErrCode(err, 1452) {
+		c.JSON(http.StatusNotAcceptable, dto.GeneralMessage{Detail: lang.NotAcceptable + "campaign_id or customer_id"})
+		return
+	}
+	pcc.trackError(c, err)
+}
+
+// @Summary Promo Code List
+// @Description Get paginated promo codes
+// @Tags Promo Codes
+// @Produce json
+// @Router /api/v1/promo-codes [GET]
+// @Security Bearer
+// @Param param query dto.PaginateRequest true "Pagination"
+// @Param filters query dto.PromoCodeListQueryRequest false "Filters"
+// @Success 200 {object} dto.PaginationResponse{data=[]dto.PromoCodeResponse} "Promo codes"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (pcc *promoCodeController) List(c *gin.Context) {
+	var pagination dto.PaginateRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaginateRequest](err))
+		return
+	}
+
+	var params dto.PromoCodeListQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PromoCodeListQueryRequest](err))
+		return
+	}
+
+	var paginationSchema schemas.Pagination
+
+	copier.Copy(&paginationSchema, &pagination)
+
+	filters := map[string]any{}
+
+	if params.Search != "" {
+		filters["search"] = "%" + models.NormalizePromoCode(params.Search) + "%"
+	}
+
+	if params.Type != "" {
+		filters["type"] = params.Type
+	}
+
+	if params.CampaignID != "" {
+		filters["campaign_id"] = params.CampaignID
+	}
+
+	if params.BatchID != "" {
+		filters["batch_id"] = params.BatchID
+	}
+
+	if params.CustomerID != "" {
+		filters["customer_id"] = params.CustomerID
+	}
+
+	codes, err := pcc.repository.List(&paginationSchema, filters)
+	if err != nil {
+		pcc.trackError(c, err)
+		return
+	}
+
+	response := make([]dto.PromoCodeResponse, 0, len(codes))
+
+	for _, code := range codes {
+		response = append(response, promoCodeResponse(code))
+	}
+
+	var paginationResponse dto.PaginationResponse
+
+	copier.Copy(&paginationResponse, &paginationSchema)
+
+	paginationResponse.Data = response
+
+	c.JSON(http.StatusOK, paginationResponse)
+}
+
+// getCode binds the path and returns the code, otherwise the response is written
+// and nil is returned
+func (pcc *promoCodeController) getCode(c *gin.Context) *models.PromoCode {
+	var path dto.PromoCodePathRequest
+	if err := c.ShouldBindUri(&path); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PromoCodePathRequest](err))
+		return nil
+	}
+
+	id, _ := uuid.Parse(path.ID)
+
+	code, err := pcc.repository.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NotFoundRecord})
+			return nil
+		}
+		pcc.trackError(c, err)
+		return nil
+	}
+
+	return code
+}
+
+// @Summary Promo Code Detail
+// @Description Get a promo code and how many times it was used
+// @Tags Promo Codes
+// @Produce json
+// @Router /api/v1/promo-codes/{id} [GET]
+// @Security Bearer
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Success 200 {object} dto.PromoCodeResponse "Promo code"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (pcc *promoCodeController) Get(c *gin.Context) {
+	code := pcc.getCode(c)
+	if code == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, promoCodeResponse(code))
+}
+
+// @Summary Create Promo Code
+// @Description Add a code giving the discount of a campaign, single use codes are used once by anyone
+// @Tags Promo Codes
+// @Accept json
+// @Produce json
+// @Router /api/v1/promo-codes [POST]
+// @Security Bearer
+// @Param data body dto.PromoCodeCreateRequest true "Promo code"
+// @Success 201 {object} dto.PromoCodeResponse "Promo code"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 406 {object} dto.GeneralMessage "Campaign or customer not exists"
+// @Failure 409 {object} dto.GeneralMessage "Code already exists"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (pcc *promoCodeController) Create(c *gin.Context) {
+	var body dto.PromoCodeCreateRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PromoCodeCreateRequest](err))
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	campaignID, _ := uuid.Parse(body.CampaignID)
+
+	code := &models.PromoCode{
+		Code:               body.Code,
+		Type:               body.Type,
+		CampaignID:         campaignID,
+		MaxUses:            body.MaxUses,
+		MaxUsesPerCustomer: body.MaxUsesPerCustomer,
+		Active:             body.Active,
+		CreatedByID:        &user.ID,
+	}
+
+	if body.Type == models.PromoCodeSingleUse {
+		code.MaxUses = utils.IntAddr(1)
+	}
+
+	if body.CustomerID != "" {
+		customerID, _ := uuid.Parse(body.CustomerID)
+		code.CustomerID = &customerID
+	}
+
+	if err := pcc.repository.Create(code); err != nil {
+		pcc.createError(c, err)
+		return
+	}
+
+	code, err := pcc.repository.GetByID(code.ID)
+	if err != nil {
+		pcc.trackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, promoCodeResponse(code))
+}
+
+// @Summary Create Promo Code Batch
+// @Description Add random codes used once each giving the discount of a campaign, one for each customer when customer_ids is sent
+// @Tags Promo Codes
+// @Accept json
+// @Produce json
+// @Router /api/v1/promo-codes/batch [POST]
+// @Security Bearer
+// @Param data body dto.PromoCodeBatchRequest true "Promo code batch"
+// @Success 201 {object} dto.PromoCodeBatchResponse "Codes created"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 406 {object} dto.GeneralMessage "Campaign or customer not exists"
+// @Failure 409 {object} dto.GeneralMessage "A generated code already exists, retry"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (pcc *promoCodeController) CreateBatch(c *gin.Context) {
+	var body dto.PromoCodeBatchRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PromoCodeBatchRequest](err))
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	campaignID, _ := uuid.Parse(body.CampaignID)
+	batchID := uuid.New()
+
+	customerIDs := make([]*uuid.UUID, 0, len(body.CustomerIDs))
+	for _, id := range body.CustomerIDs {
+		customerID, _ := uuid.Parse(id)
+		customerIDs = append(customerIDs, &customerID)
+	}
+
+	if len(customerIDs) == 0 {
+		customerIDs = make([]*uuid.UUID, body.Quantity)
+	}
+
+	codes := make([]*models.PromoCode, 0, len(customerIDs))
+	response := dto.PromoCodeBatchResponse{
+		BatchID: batchID,
+		Codes:   make([]dto.PromoCodeBatchCodeResponse, 0, len(customerIDs)),
+	}
+
+	for _, customerID := range customerIDs {
+		value, err := generatePromoCode(body.Prefix)
+		if err != nil {
+			pcc.trackError(c, err)
+			return
+		}
+
+		codes = append(codes, &models.PromoCode{
+			Code:        value,
+			Type:        models.PromoCodeBatch,
+			CampaignID:  campaignID,
+			BatchID:     &batchID,
+			CustomerID:  customerID,
+			MaxUses:     utils.IntAddr(1),
+			Active:      body.Active,
+			CreatedByID: &user.ID,
+		})
+		response.Codes = append(response.Codes, dto.PromoCodeBatchCodeResponse{
+			Code:       value,
+			CustomerID: customerID,
+		})
+	}
+
+	if err := pcc.repository.Create(codes...); err != nil {
+		pcc.createError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// @Summary Update Promo Code
+// @Description Update the limits of a promo code or deactivate it, uses already reserved are kept
+// @Tags Promo Codes
+// @Accept json
+// @Produce json
+// @Router /api/v1/promo-codes/{id} [PUT]
+// @Security Bearer
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Param data body dto.PromoCodeUpdateRequest true "Promo code"
+// @Success 200 {object} dto.PromoCodeResponse "Promo code"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (pcc *promoCodeController) Update(c *gin.Context) {
+	var body dto.PromoCodeUpdateRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PromoCodeUpdateRequest](err))
+		return
+	}
+
+	code := pcc.getCode(c)
+	if code == nil {
+		return
+	}
+
+	if code.Type == models.PromoCodeMultiUse {
+		code.MaxUses = body.MaxUses
+	}
+	code.MaxUsesPerCustomer = body.MaxUsesPerCustomer
+	code.Active = body.Active
+
+	if err := pcc.repository.Update(code); err != nil {
+		pcc.trackError(c, err)
+		return
+	}
+
+	code, err := pcc.repository.GetByID(code.ID)
+	if err != nil {
+		pcc.trackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promoCodeResponse(code))
+}
+
+// @Summary Promo Code Redemptions
+// @Description Get paginated payments that used a promo code, reserved ones are not served yet and released ones were canceled
+// @Tags Promo Codes
+// @Produce json
+// @Router /api/v1/promo-codes/{id}/redemptions [GET]
+// @Security Bearer
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Param param query dto.PaginateRequest true "Pagination"
+// @Param filters query dto.PromoRedemptionListQueryRequest false "Filters"
+// @Success 200 {object} dto.PaginationResponse{data=[]dto.PromoRedemptionResponse} "Redemptions"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (pcc *promoCodeController) ListRedemptions(c *gin.Context) {
+	var pagination dto.PaginateRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaginateRequest](err))
+		return
+	}
+
+	var params dto.PromoRedemptionListQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PromoRedemptionListQueryRequest](err))
+		return
+	}
+
+	code := pcc.getCode(c)
+	if code == nil {
+		return
+	}
+
+	var paginationSchema schemas.Pagination
+
+	copier.Copy(&paginationSchema, &pagination)
+
+	filters := map[string]any{"promo_code_id": code.ID}
+
+	if params.Status != "" {
+		filters["status"] = params.Status
+	}
+
+	redemptions, err := pcc.repository.ListRedemptions(&paginationSchema, filters)
+	if err != nil {
+		pcc.trackError(c, err)
+		return
+	}
+
+	response := make([]dto.PromoRedemptionResponse, 0)
+
+	copier.Copy(&response, &redemptions)
+
+	var paginationResponse dto.PaginationResponse
+
+	copier.Copy(&paginationResponse, &paginationSchema)
+
+	paginationResponse.Data = response
+
+	c.JSON(http.StatusOK, paginationResponse)
+}
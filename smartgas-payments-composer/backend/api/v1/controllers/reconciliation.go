@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"smartgas-payment/internal/dto"
+	"smartgas-payment/internal/lang"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/tasks"
+	"smartgas-payment/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+type ReconciliationController interface {
+	Run(*gin.Context)
+	List(*gin.Context)
+	GetByID(*gin.Context)
+	ListDiscrepancies(*gin.Context)
+}
+
+type reconciliationController struct {
+	repository repository.ReconciliationRepository
+	task       tasks.ReconciliationTask
+}
+
+func ProvideReconciliationController(
+	repository repository.ReconciliationRepository,
+	task tasks.ReconciliationTask,
+) *reconciliationController {
+	return &reconciliationController{
+		repository: repository,
+		task:       task,
+	}
+}
+
+// @Summary Run Reconciliation
+// @Description Match the payments created between two dates against the payment providers.
+// @Description Swit and Debit have no way to list their transactions, only Stripe payments are reconciled
+// @Tags Reconciliation
+// @Produce json
+// @Accept json
+// @Router /api/v1/reconciliations [POST]
+// @Security Bearer
+// @Param params body dto.ReconciliationRunRequest true "Date range, both days included"
+// @Success 201 {object} dto.ReconciliationResponse "Reconciliation report"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 423 {object} dto.GeneralMessage "There is a reconciliation already running"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (rc *reconciliationController) Run(c *gin.Context) {
+	var body dto.ReconciliationRunRequest
+
+	if err := c.ShouldBind(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.ReconciliationRunRequest](err))
+		return
+	}
+
+	from, to, err := utils.ParseDateRange(body.From, body.To)
+	if err != nil || !from.Before(to) {
+		c.JSON(http.StatusBadRequest, dto.GeneralMessage{Detail: lang.InvalidDateRange})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	reconciliation, err := rc.task.Reconcile(from, to)
+	if err != nil {
+		if errors.Is(err, tasks.RunningError) {
+			c.JSON(http.StatusLocked, dto.GeneralMessage{Detail: err.Error()})
+			return
+		}
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: user,
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	var response dto.ReconciliationResponse
+
+	copier.Copy(&response, reconciliation)
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// @Summary Reconciliation List
+// @Description Get paginated reconciliations
+// @Tags Reconciliation
+// @Produce json
+// @Router /api/v1/reconciliations [GET]
+// @Security Bearer
+// @Param param query dto.PaginateRequest true "Pagination"
+// @Success 200 {object} dto.PaginationResponse{data=[]dto.ReconciliationResponse} "Reconciliations List"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (rc *reconciliationController) List(c *gin.Context) {
+	var pagination dto.PaginateRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaginateRequest](err))
+		return
+	}
+
+	var paginationSchema schemas.Pagination
+
+	copier.Copy(&paginationSchema, &pagination)
+
+	reconciliations, err := rc.repository.List(&paginationSchema, map[string]any{})
+
+	user := c.MustGet("user").(*models.User)
+
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: user,
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	response := make([]dto.ReconciliationResponse, 0)
+
+	copier.Copy(&response, &reconciliations)
+
+	var paginationResponse dto.PaginationResponse
+
+	copier.Copy(&paginationResponse, &paginationSchema)
+
+	paginationResponse.Data = response
+
+	c.JSON(http.StatusOK, paginationResponse)
+}
+
+// @Summary Reconciliation Detail
+// @Description Get a reconciliation report
+// @Tags Reconciliation
+// @Produce json
+// @Router /api/v1/reconciliations/{id} [GET]
+// @Security Bearer
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Success 200 {object} dto.ReconciliationResponse "Reconciliation report"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (rc *reconciliationController) GetByID(c *gin.Context) {
+	var path dto.ReconciliationPathRequest
+	if err := c.ShouldBindUri(&path); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.ReconciliationPathRequest](err))
+		return
+	}
+
+	id, _ := uuid.Parse(path.ID)
+
+	reconciliation, err := rc.repository.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NotFoundRecord})
+			return
+		}
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: c.MustGet("user").(*models.User),
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	var response dto.ReconciliationResponse
+
+	copier.Copy(&response, reconciliation)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Reconciliation Discrepancies
+// @Description Get paginated discrepancies found by a reconciliation
+// @Tags Reconciliation
+// @Produce json
+// @Router /api/v1/reconciliations/{id}/discrepancies [GET]
+// @Security Bearer
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Param param query dto.PaginateRequest true "Pagination"
+// @Param filters query dto.ReconciliationDiscrepancyListQueryRequest false "Filters"
+// @Success 200 {object} dto.PaginationResponse{data=[]dto.ReconciliationDiscrepancyResponse} "Discrepancies List"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (rc *reconciliationController) ListDiscrepancies(c *gin.Context) {
+	var pagination dto.PaginateRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaginateRequest](err))
+		return
+	}
+
+	var path dto.ReconciliationPathRequest
+	if err := c.ShouldBindUri(&path); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.ReconciliationPathRequest](err))
+		return
+	}
+
+	var params dto.ReconciliationDiscrepancyListQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			utils.MapValidatorError[dto.ReconciliationDiscrepancyListQueryRequest](err),
+		)
+		return
+	}
+
+	var paginationSchema schemas.Pagination
+
+	copier.Copy(&paginationSchema, &pagination)
+
+	id, _ := uuid.Parse(path.ID)
+
+	filters := map[string]any{"reconciliation_id": id}
+
+	if params.Type != "" {
+		filters["type"] = params.Type
+	}
+
+	if params.PaymentProvider != "" {
+		filters["payment_provider"] = params.PaymentProvider
+	}
+
+	discrepancies, err := rc.repository.ListDiscrepancies(&paginationSchema, filters)
+
+	user := c.MustGet("user").(*models.User)
+
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: user,
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	response := make([]dto.ReconciliationDiscrepancyResponse, 0)
+
+	copier.Copy(&response, &discrepancies)
+
+	var paginationResponse dto.PaginationResponse
+
+	copier.Copy(&paginationResponse, &paginationSchema)
+
+	paginationResponse.Data = response
+
+	c.JSON(http.StatusOK, paginationResponse)
+}
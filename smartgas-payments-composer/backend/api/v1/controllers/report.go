@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"net/http"
+	"smartgas-payment/internal/dto"
+	"smartgas-payment/internal/lang"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/reports"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ReportController interface {
+	Sales(*gin.Context)
+}
+
+type reportController struct {
+	repository repository.ReportRepository
+}
+
+func ProvideReportController(repository repository.ReportRepository) *reportController {
+	return &reportController{
+		repository: repository,
+	}
+}
+
+// @Summary Sales Report
+// @Description Totals of the payments served, grouped by the dimensions requested and by day, week or month in the timezone of each station. Only the stations of the user are included
+// @Tags Reports
+// @Produce json
+// @Router /api/v1/reports/sales [GET]
+// @Security Bearer
+// @Param params query dto.SalesReportQueryRequest true "Date range (both days included, in the timezone of each station), period, dimensions and filters"
+// @Success 200 {object} dto.SalesReportResponse "Sales report"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (rc *reportController) Sales(c *gin.Context) {
+	var params dto.SalesReportQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.SalesReportQueryRequest](err))
+		return
+	}
+
+	from, to, err := utils.ParseDateRange(params.From, params.To)
+	if err != nil || !from.Before(to) {
+		c.JSON(http.StatusBadRequest, dto.GeneralMessage{Detail: lang.InvalidDateRange})
+		return
+	}
+
+	if to.After(from.AddDate(1, 0, 1)) {
+		c.JSON(http.StatusBadRequest, dto.GeneralMessage{Detail: lang.ReportRangeTooLong})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	filters := map[string]any{"from": from, "to": to}
+
+	if params.GasStationID != "" {
+		gasStationID, _ := uuid.Parse(params.GasStationID)
+		filters["gas_station_id"] = gasStationID
+	}
+
+	if params.FuelType != "" {
+		filters["fuel_type"] = params.FuelType
+	}
+
+	if params.PaymentProvider != "" {
+		filters["payment_provider"] = params.PaymentProvider
+	}
+
+	utils.AddStationsFilter(user, filters)
+
+	rows, err := rc.repository.Sales(filters, params.GroupBy, params.Period != "")
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: user,
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	groups := reports.GroupSales(rows, params.Period)
+
+	response := dto.SalesReportResponse{
+		From:    params.From,
+		To:      params.To,
+		Period:  params.Period,
+		GroupBy: params.GroupBy,
+		Totals:  salesTotalsResponse(reports.SumSales(groups)),
+		Groups:  make([]dto.SalesGroupResponse, 0, len(groups)),
+	}
+
+	if response.GroupBy == nil {
+		response.GroupBy = make([]string, 0)
+	}
+
+	for _, group := range groups {
+		groupResponse := dto.SalesGroupResponse{
+			GasStationID:        group.GasStationID,
+			GasStationName:      group.GasStationName,
+			GasPumpID:           group.GasPumpID,
+			GasPumpNumber:       group.GasPumpNumber,
+			FuelType:            group.FuelType,
+			PaymentProvider:     group.PaymentProvider,
+			SalesTotalsResponse: salesTotalsResponse(group.SalesTotals),
+		}
+
+		if group.Period != nil {
+			groupResponse.Period = group.Period.Format(reports.DateLayout)
+		}
+
+		response.Groups = append(response.Groups, groupResponse)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func salesTotalsResponse(totals reports.SalesTotals) dto.SalesTotalsResponse {
+	return dto.SalesTotalsResponse{
+		Transactions: totals.Transactions,
+		Amount:       totals.Amount,
+		Liters:       money.RoundLiters(totals.Liters),
+		Refunds:      totals.Refunds,
+		ChargeFees:   totals.ChargeFees,
+		Discounts:    totals.Discounts,
+	}
+}
@@ -0,0 +1,370 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"smartgas-payment/internal/dto"
+	"smartgas-payment/internal/lang"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/tasks"
+	"smartgas-payment/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+type SettlementController interface {
+	Generate(*gin.Context)
+	List(*gin.Context)
+	GetByID(*gin.Context)
+	ListLines(*gin.Context)
+	Close(*gin.Context)
+}
+
+type settlementController struct {
+	repository repository.SettlementRepository
+	task       tasks.SettlementTask
+}
+
+func ProvideSettlementController(
+	repository repository.SettlementRepository,
+	task tasks.SettlementTask,
+) *settlementController {
+	return &settlementController{
+		repository: repository,
+		task:       task,
+	}
+}
+
+func settlementResponse(settlement *models.Settlement) dto.SettlementResponse {
+	var response dto.SettlementResponse
+
+	copier.Copy(&response, settlement)
+
+	response.From = settlement.From.Format("2006-01-02")
+	response.To = settlement.To.AddDate(0, 0, -1).Format("2006-01-02")
+
+	return response
+}
+
+// getSettlement binds the path and returns the settlement when the user can see it,
+// otherwise the response is written and nil is returned
+func (sc *settlementController) getSettlement(c *gin.Context) *models.Settlement {
+	var path dto.SettlementPathRequest
+	if err := c.ShouldBindUri(&path); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.SettlementPathRequest](err))
+		return nil
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	id, _ := uuid.Parse(path.ID)
+
+	settlement, err := sc.repository.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NotFoundRecord})
+			return nil
+		}
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: user,
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return nil
+	}
+
+	if !utils.CanSeeLegalName(user, settlement.LegalNameID) {
+		c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NotFoundRecord})
+		return nil
+	}
+
+	return settlement
+}
+
+// @Summary Generate Settlement
+// @Description Compute what a legal name receives for a period: captured amounts minus refunds and provider fees. Open settlements of the same period are computed again, refunds of payments settled in closed periods are added as adjustments
+// @Tags Settlements
+// @Produce json
+// @Accept json
+// @Router /api/v1/settlements [POST]
+// @Security Bearer
+// @Param params body dto.SettlementGenerateRequest true "Legal name and period, both days included"
+// @Success 201 {object} dto.SettlementResponse "Settlement"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 403 {object} dto.GeneralMessage "The legal name is not of the stations of the user"
+// @Failure 409 {object} dto.GeneralMessage "The settlement is closed or the period overlaps another one"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (sc *settlementController) Generate(c *gin.Context) {
+	var body dto.SettlementGenerateRequest
+
+	if err := c.ShouldBind(&body); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.SettlementGenerateRequest](err))
+		return
+	}
+
+	from, to, err := utils.ParseDateRange(body.From, body.To)
+	if err != nil || !from.Before(to) {
+		c.JSON(http.StatusBadRequest, dto.GeneralMessage{Detail: lang.InvalidDateRange})
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	if !utils.CanSeeLegalName(user, body.LegalNameID) {
+		c.JSON(http.StatusForbidden, dto.GeneralMessage{Detail: lang.NotEnoughPermissions})
+		return
+	}
+
+	settlement, err := sc.task.Generate(body.LegalNameID, from, to)
+	if err != nil {
+		if errors.Is(err, repository.ErrSettlementClosed) {
+			c.JSON(http.StatusConflict, dto.GeneralMessage{Detail: lang.SettlementClosed})
+			return
+		}
+		if errors.Is(err, tasks.ErrSettlementOverlaps) {
+			c.JSON(http.StatusConflict, dto.GeneralMessage{Detail: lang.SettlementOverlaps})
+			return
+		}
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: user,
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	c.JSON(http.StatusCreated, settlementResponse(settlement))
+}
+
+// @Summary Settlement List
+// @Description Get paginated settlements of the legal names of the stations of the user
+// @Tags Settlements
+// @Produce json
+// @Router /api/v1/settlements [GET]
+// @Security Bearer
+// @Param param query dto.PaginateRequest true "Pagination"
+// @Param filters query dto.SettlementListQueryRequest false "Filters"
+// @Success 200 {object} dto.PaginationResponse{data=[]dto.SettlementResponse} "Settlements List"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (sc *settlementController) List(c *gin.Context) {
+	var pagination dto.PaginateRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaginateRequest](err))
+		return
+	}
+
+	var params dto.SettlementListQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.SettlementListQueryRequest](err))
+		return
+	}
+
+	var paginationSchema schemas.Pagination
+
+	copier.Copy(&paginationSchema, &pagination)
+
+	user := c.MustGet("user").(*models.User)
+
+	filters := map[string]any{}
+
+	if params.LegalNameID != "" {
+		filters["legal_name_id"] = params.LegalNameID
+	}
+
+	if params.Status != "" {
+		filters["status"] = params.Status
+	}
+
+	if !*user.IsAdmin {
+		filters["legal_names"] = utils.GatherLegalNameIds(user)
+	}
+
+	settlements, err := sc.repository.List(&paginationSchema, filters)
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: user,
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	response := make([]dto.SettlementResponse, 0, len(settlements))
+
+	for _, settlement := range settlements {
+		response = append(response, settlementResponse(settlement))
+	}
+
+	var paginationResponse dto.PaginationResponse
+
+	copier.Copy(&paginationResponse, &paginationSchema)
+
+	paginationResponse.Data = response
+
+	c.JSON(http.StatusOK, paginationResponse)
+}
+
+// @Summary Settlement Detail
+// @Description Get a settlement with its totals by payment provider
+// @Tags Settlements
+// @Produce json
+// @Router /api/v1/settlements/{id} [GET]
+// @Security Bearer
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Success 200 {object} dto.SettlementDetailResponse "Settlement"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (sc *settlementController) GetByID(c *gin.Context) {
+	settlement := sc.getSettlement(c)
+	if settlement == nil {
+		return
+	}
+
+	totals, err := sc.repository.TotalsByProvider(settlement.ID)
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: c.MustGet("user").(*models.User),
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	response := dto.SettlementDetailResponse{
+		SettlementResponse: settlementResponse(settlement),
+		Providers:          make([]dto.SettlementProviderTotalsResponse, 0, len(totals)),
+	}
+
+	copier.Copy(&response.Providers, &totals)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Settlement Lines
+// @Description Get paginated payments and adjustments included in a settlement
+// @Tags Settlements
+// @Produce json
+// @Router /api/v1/settlements/{id}/lines [GET]
+// @Security Bearer
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Param param query dto.PaginateRequest true "Pagination"
+// @Param filters query dto.SettlementLineListQueryRequest false "Filters"
+// @Success 200 {object} dto.PaginationResponse{data=[]dto.SettlementLineResponse} "Settlement lines"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (sc *settlementController) ListLines(c *gin.Context) {
+	var pagination dto.PaginateRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaginateRequest](err))
+		return
+	}
+
+	var params dto.SettlementLineListQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.SettlementLineListQueryRequest](err))
+		return
+	}
+
+	settlement := sc.getSettlement(c)
+	if settlement == nil {
+		return
+	}
+
+	var paginationSchema schemas.Pagination
+
+	copier.Copy(&paginationSchema, &pagination)
+
+	filters := map[string]any{"settlement_id": settlement.ID}
+
+	if params.Type != "" {
+		filters["type"] = params.Type
+	}
+
+	if params.PaymentProvider != "" {
+		filters["payment_provider"] = params.PaymentProvider
+	}
+
+	lines, err := sc.repository.ListLines(&paginationSchema, filters)
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: c.MustGet("user").(*models.User),
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	response := make([]dto.SettlementLineResponse, 0)
+
+	copier.Copy(&response, &lines)
+
+	var paginationResponse dto.PaginationResponse
+
+	copier.Copy(&paginationResponse, &paginationSchema)
+
+	paginationResponse.Data = response
+
+	c.JSON(http.StatusOK, paginationResponse)
+}
+
+// @Summary Close Settlement
+// @Description Compute a settlement for the last time and lock it, later refunds of its payments become adjustments of the next settlements
+// @Tags Settlements
+// @Produce json
+// @Router /api/v1/settlements/{id}/close [POST]
+// @Security Bearer
+// @Param id path string true "uuid4 id" minLength(36) maxLength(36)
+// @Success 200 {object} dto.SettlementResponse "Closed settlement"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 409 {object} dto.GeneralMessage "The settlement is already closed"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (sc *settlementController) Close(c *gin.Context) {
+	settlement := sc.getSettlement(c)
+	if settlement == nil {
+		return
+	}
+
+	user := c.MustGet("user").(*models.User)
+
+	settlement, err := sc.task.Close(settlement.ID, user)
+	if err != nil {
+		if errors.Is(err, repository.ErrSettlementClosed) {
+			c.JSON(http.StatusConflict, dto.GeneralMessage{Detail: lang.SettlementClosed})
+			return
+		}
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: user,
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	c.JSON(http.StatusOK, settlementResponse(settlement))
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"smartgas-payment/internal/dto"
+	"smartgas-payment/internal/lang"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/tasks"
+	"smartgas-payment/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+	"gorm.io/gorm"
+)
+
+type StripeEventController interface {
+	List(*gin.Context)
+	GetByID(*gin.Context)
+	Replay(*gin.Context)
+}
+
+type stripeEventController struct {
+	repository        repository.StripeEventRepository
+	stripeWebhookTask tasks.StripeWebhookTask
+}
+
+func ProvideStripeEventController(
+	repository repository.StripeEventRepository,
+	stripeWebhookTask tasks.StripeWebhookTask,
+) *stripeEventController {
+	return &stripeEventController{
+		repository:        repository,
+		stripeWebhookTask: stripeWebhookTask,
+	}
+}
+
+// @Summary Stripe Event List
+// @Description Get paginated stripe webhook events received
+// @Tags Stripe Events
+// @Produce json
+// @Router /api/v1/stripe-events [GET]
+// @Security Bearer
+// @Param param query dto.PaginateRequest true "Pagination"
+// @Param filters query dto.StripeEventListQueryRequest false "Filters"
+// @Success 200 {object} dto.PaginationResponse{data=[]dto.StripeEventResponse} "Stripe Events List"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (sec *stripeEventController) List(c *gin.Context) {
+	var pagination dto.PaginateRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.PaginateRequest](err))
+		return
+	}
+
+	var params dto.StripeEventListQueryRequest
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.StripeEventListQueryRequest](err))
+		return
+	}
+
+	var paginationSchema schemas.Pagination
+
+	copier.Copy(&paginationSchema, &pagination)
+
+	filters := map[string]any{}
+
+	if params.Status != "" {
+		filters["status"] = params.Status
+	}
+
+	if params.Type != "" {
+		filters["type"] = params.Type
+	}
+
+	events, err := sec.repository.List(&paginationSchema, filters)
+
+	user := c.MustGet("user").(*models.User)
+
+	if err != nil {
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: user,
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	response := make([]dto.StripeEventResponse, 0)
+
+	copier.Copy(&response, &events)
+
+	var paginationResponse dto.PaginationResponse
+
+	copier.Copy(&paginationResponse, &paginationSchema)
+
+	paginationResponse.Data = response
+
+	c.JSON(http.StatusOK, paginationResponse)
+}
+
+// @Summary Stripe Event Detail
+// @Description Get a stripe webhook event with its payload
+// @Tags Stripe Events
+// @Produce json
+// @Router /api/v1/stripe-events/{id} [GET]
+// @Security Bearer
+// @Param id path string true "stripe event id"
+// @Success 200 {object} dto.StripeEventDetailResponse "Stripe Event"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (sec *stripeEventController) GetByID(c *gin.Context) {
+	var path dto.StripeEventPathRequest
+	if err := c.ShouldBindUri(&path); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.StripeEventPathRequest](err))
+		return
+	}
+
+	event, err := sec.repository.GetByID(path.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NotFoundRecord})
+			return
+		}
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: c.MustGet("user").(*models.User),
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	var response dto.StripeEventDetailResponse
+
+	copier.Copy(&response.StripeEventResponse, event)
+
+	if json.Valid([]byte(event.Payload)) {
+		response.Payload = json.RawMessage(event.Payload)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Replay Stripe Event
+// @Description Process again a failed or pending stripe webhook event, the outcome is returned in its status and error
+// @Tags Stripe Events
+// @Produce json
+// @Router /api/v1/stripe-events/{id}/replay [POST]
+// @Security Bearer
+// @Param id path string true "stripe event id"
+// @Success 200 {object} dto.StripeEventResponse "Stripe Event after the replay"
+// @Failure 400 {array} dto.BadRequestMessage "Bad Request, failed on body, form, query..."
+// @Failure 401 {object} dto.GeneralMessage "Unauthorized"
+// @Failure 404 {object} dto.GeneralMessage "Not found"
+// @Failure 409 {object} dto.GeneralMessage "The event was already processed or is being processed"
+// @Failure 500 {object} dto.GeneralMessage "Internal server error"
+func (sec *stripeEventController) Replay(c *gin.Context) {
+	var path dto.StripeEventPathRequest
+	if err := c.ShouldBindUri(&path); err != nil {
+		c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.StripeEventPathRequest](err))
+		return
+	}
+
+	event, err := sec.stripeWebhookTask.Replay(path.ID)
+
+	switch {
+	case errors.Is(err, tasks.ErrStripeEventNotReplayable):
+		c.JSON(http.StatusConflict, dto.GeneralMessage{Detail: lang.StripeEventNotReplayable})
+		return
+	case errors.Is(err, tasks.ErrStripeEventProcessing):
+		c.JSON(http.StatusConflict, dto.GeneralMessage{Detail: lang.StripeEventProcessing})
+		return
+	case event == nil && errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, dto.GeneralMessage{Detail: lang.NotFoundRecord})
+		return
+	case event == nil:
+		// Logging error in sentry
+		opts := &utils.TrackErrorOpts{
+			Admin: c.MustGet("user").(*models.User),
+			Tags:  map[string]string{"auth_type": "admin"},
+		}
+		utils.TrackError(c, err, opts)
+		c.JSON(http.StatusInternalServerError, dto.GeneralMessage{Detail: lang.InternalServerError})
+		return
+	}
+
+	// Processing errors are stored in the event
+	var response dto.StripeEventResponse
+
+	copier.Copy(&response, event)
+
+	c.JSON(http.StatusOK, response)
+}
@@ -37,6 +37,21 @@ func (cr *CustomerRoutes) Setup(group *gin.RouterGroup) {
 		cr.customerAuthMiddleware.Middleware(),
 		cr.controller.ListPaymenthMethods,
 	)
+	router.POST(
+		"/payment-methods/setup-intent",
+		cr.customerAuthMiddleware.Middleware(),
+		cr.controller.CreateSetupIntent,
+	)
+	router.GET(
+		"/payment-methods/default",
+		cr.customerAuthMiddleware.Middleware(),
+		cr.controller.GetDefaultPaymentMethod,
+	)
+	router.PUT(
+		"/payment-methods/default",
+		cr.customerAuthMiddleware.Middleware(),
+		cr.controller.SetDefaultPaymentMethod,
+	)
 	router.DELETE(
 		"/payment-methods/:card_id",
 		cr.customerAuthMiddleware.Middleware(),
@@ -48,6 +63,11 @@ func (cr *CustomerRoutes) Setup(group *gin.RouterGroup) {
 		cr.customerAuthMiddleware.Middleware(),
 		cr.controller.ListPaymenthMethodsSwit,
 	)
+	router.GET(
+		"/payments",
+		cr.customerAuthMiddleware.Middleware(),
+		cr.controller.ListPayments,
+	)
 	router.GET("/all",
 		cr.authMiddleware.Middleware(viewAllCustomersPerms),
 		cr.controller.ListAll,
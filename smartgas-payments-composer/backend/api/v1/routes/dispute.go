@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"smartgas-payment/api/v1/controllers"
+	"smartgas-payment/internal/enums"
+	"smartgas-payment/internal/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DisputeRoutes struct {
+	controller     controllers.DisputeController
+	authMiddleware *middlewares.AuthMiddleware
+}
+
+func ProvideDisputeRoutes(
+	controller controllers.DisputeController,
+	authMiddleware *middlewares.AuthMiddleware,
+) *DisputeRoutes {
+	return &DisputeRoutes{
+		authMiddleware: authMiddleware,
+		controller:     controller,
+	}
+}
+
+func (dr *DisputeRoutes) Setup(group *gin.RouterGroup) {
+	router := group.Group("/disputes")
+
+	viewOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.ViewPayments,
+	}
+
+	router.GET("", dr.authMiddleware.Middleware(viewOpts), dr.controller.List)
+}
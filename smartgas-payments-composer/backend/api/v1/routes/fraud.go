@@ -0,0 +1,50 @@
+package routes
+
+import (
+	"smartgas-payment/api/v1/controllers"
+	"smartgas-payment/internal/enums"
+	"smartgas-payment/internal/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FraudRoutes struct {
+	controller     controllers.FraudController
+	authMiddleware *middlewares.AuthMiddleware
+}
+
+func ProvideFraudRoutes(
+	controller controllers.FraudController,
+	authMiddleware *middlewares.AuthMiddleware,
+) *FraudRoutes {
+	return &FraudRoutes{
+		authMiddleware: authMiddleware,
+		controller:     controller,
+	}
+}
+
+func (fr *FraudRoutes) Setup(group *gin.RouterGroup) {
+	router := group.Group("/fraud")
+
+	viewOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.ViewFraudRules,
+	}
+
+	editOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.EditFraudRules,
+	}
+
+	decisionsOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.ViewFraudDecisions,
+	}
+
+	router.GET("/rules", fr.authMiddleware.Middleware(viewOpts), fr.controller.ListRules)
+	router.POST("/rules", fr.authMiddleware.Middleware(editOpts), fr.controller.CreateRule)
+	router.GET("/rules/:id", fr.authMiddleware.Middleware(viewOpts), fr.controller.GetRule)
+	router.PUT("/rules/:id", fr.authMiddleware.Middleware(editOpts), fr.controller.UpdateRule)
+	router.DELETE("/rules/:id", fr.authMiddleware.Middleware(editOpts), fr.controller.DeleteRule)
+	router.GET("/blocks", fr.authMiddleware.Middleware(viewOpts), fr.controller.ListBlocks)
+	router.POST("/blocks", fr.authMiddleware.Middleware(editOpts), fr.controller.CreateBlock)
+	router.DELETE("/blocks/:id", fr.authMiddleware.Middleware(editOpts), fr.controller.DeleteBlock)
+	router.GET("/decisions", fr.authMiddleware.Middleware(decisionsOpts), fr.controller.ListDecisions)
+}
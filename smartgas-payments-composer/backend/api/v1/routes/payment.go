@@ -12,6 +12,7 @@ type PaymentRoutes struct {
 	customerAuthMiddleware *middlewares.CustomerAuthMiddleware
 	authMiddleware         *middlewares.AuthMiddleware
 	securityMiddleware     *middlewares.SecurityMiddleware
+	idempotencyMiddleware  *middlewares.IdempotencyMiddleware
 	controller             controllers.PaymentController
 }
 
@@ -20,12 +21,14 @@ func ProvidePaymenRoutes(
 	controller controllers.PaymentController,
 	authMiddleware *middlewares.AuthMiddleware,
 	securityMiddleware *middlewares.SecurityMiddleware,
+	idempotencyMiddleware *middlewares.IdempotencyMiddleware,
 ) *PaymentRoutes {
 	return &PaymentRoutes{
 		controller:             controller,
 		customerAuthMiddleware: customerAuthMiddleware,
 		authMiddleware:         authMiddleware,
 		securityMiddleware:     securityMiddleware,
+		idempotencyMiddleware:  idempotencyMiddleware,
 	}
 }
 
@@ -40,20 +43,35 @@ func (pr *PaymentRoutes) Setup(group *gin.RouterGroup) {
 		RequiredPermission: enums.CanDoActionsPayments,
 	}
 
+	router.POST("/quote", pr.customerAuthMiddleware.Middleware(), pr.controller.Quote)
 	router.POST(
 		"/create-intent",
 		pr.customerAuthMiddleware.Middleware(),
+		pr.idempotencyMiddleware.Middleware(),
 		pr.controller.CreateIntent,
 	)
 	router.POST("/stripe-webhook", pr.controller.StripeWebhook)
 	router.GET("", pr.authMiddleware.Middleware(viewOpts), pr.controller.List)
-	router.POST("/:id/events", pr.securityMiddleware.Middleware(), pr.controller.AddEvent)
+	router.GET("/export", pr.authMiddleware.Middleware(viewOpts), pr.controller.Export)
+	router.GET("/:id", pr.authMiddleware.Middleware(viewOpts), pr.controller.GetByID)
+	router.POST(
+		"/:id/events",
+		pr.securityMiddleware.Middleware(),
+		pr.idempotencyMiddleware.Middleware(),
+		pr.controller.AddEvent,
+	)
 	router.GET(
 		"/:id/customer-detail",
 		pr.customerAuthMiddleware.Middleware(),
 		pr.controller.GetByIDForCustomer,
 	)
 	router.GET("/:id/customer-detail-ws", pr.controller.PaymentNotifierWS)
+	router.GET("/:id/receipt", pr.customerAuthMiddleware.Middleware(), pr.controller.GetReceipt)
+	router.GET(
+		"/:id/receipt-operation",
+		pr.securityMiddleware.SmartGasEmployeeMiddleware(),
+		pr.controller.GetReceiptOperation,
+	)
 	router.GET(
 		"/provider",
 		pr.customerAuthMiddleware.Middleware(),
@@ -78,6 +96,7 @@ func (pr *PaymentRoutes) Setup(group *gin.RouterGroup) {
 	router.POST(
 		"/create-intent-operation",
 		pr.securityMiddleware.SmartGasEmployeeMiddleware(),
+		pr.idempotencyMiddleware.Middleware(),
 		pr.controller.CreateIntentOperation,
 	)
 }
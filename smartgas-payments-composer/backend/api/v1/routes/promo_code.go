@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"smartgas-payment/api/v1/controllers"
+	"smartgas-payment/internal/enums"
+	"smartgas-payment/internal/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromoCodeRoutes struct {
+	controller     controllers.PromoCodeController
+	authMiddleware *middlewares.AuthMiddleware
+}
+
+func ProvidePromoCodeRoutes(
+	controller controllers.PromoCodeController,
+	authMiddleware *middlewares.AuthMiddleware,
+) *PromoCodeRoutes {
+	return &PromoCodeRoutes{
+		authMiddleware: authMiddleware,
+		controller:     controller,
+	}
+}
+
+func (pcr *PromoCodeRoutes) Setup(group *gin.RouterGroup) {
+	router := group.Group("/promo-codes")
+
+	viewOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.ViewPromoCodes,
+	}
+
+	editOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.EditPromoCodes,
+	}
+
+	router.GET("", pcr.authMiddleware.Middleware(viewOpts), pcr.controller.List)
+	router.POST("", pcr.authMiddleware.Middleware(editOpts), pcr.controller.Create)
+	router.POST("/batch", pcr.authMiddleware.Middleware(editOpts), pcr.controller.CreateBatch)
+	router.GET("/:id", pcr.authMiddleware.Middleware(viewOpts), pcr.controller.Get)
+	router.PUT("/:id", pcr.authMiddleware.Middleware(editOpts), pcr.controller.Update)
+	router.GET(
+		"/:id/redemptions",
+		pcr.authMiddleware.Middleware(viewOpts),
+		pcr.controller.ListRedemptions,
+	)
+}
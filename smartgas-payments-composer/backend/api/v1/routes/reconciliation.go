@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"smartgas-payment/api/v1/controllers"
+	"smartgas-payment/internal/enums"
+	"smartgas-payment/internal/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReconciliationRoutes struct {
+	controller     controllers.ReconciliationController
+	authMiddleware *middlewares.AuthMiddleware
+}
+
+func ProvideReconciliationRoutes(
+	controller controllers.ReconciliationController,
+	authMiddleware *middlewares.AuthMiddleware,
+) *ReconciliationRoutes {
+	return &ReconciliationRoutes{
+		authMiddleware: authMiddleware,
+		controller:     controller,
+	}
+}
+
+func (rr *ReconciliationRoutes) Setup(group *gin.RouterGroup) {
+	router := group.Group("/reconciliations")
+
+	viewReconciliationsOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.ViewReconciliations,
+	}
+
+	addReconciliationOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.AddReconciliation,
+	}
+
+	router.GET("", rr.authMiddleware.Middleware(viewReconciliationsOpts), rr.controller.List)
+	router.GET("/:id", rr.authMiddleware.Middleware(viewReconciliationsOpts), rr.controller.GetByID)
+	router.GET(
+		"/:id/discrepancies",
+		rr.authMiddleware.Middleware(viewReconciliationsOpts),
+		rr.controller.ListDiscrepancies,
+	)
+	router.POST("", rr.authMiddleware.Middleware(addReconciliationOpts), rr.controller.Run)
+}
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"smartgas-payment/api/v1/controllers"
+	"smartgas-payment/internal/enums"
+	"smartgas-payment/internal/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportRoutes struct {
+	controller     controllers.ReportController
+	authMiddleware *middlewares.AuthMiddleware
+}
+
+func ProvideReportRoutes(
+	controller controllers.ReportController,
+	authMiddleware *middlewares.AuthMiddleware,
+) *ReportRoutes {
+	return &ReportRoutes{
+		authMiddleware: authMiddleware,
+		controller:     controller,
+	}
+}
+
+func (rr *ReportRoutes) Setup(group *gin.RouterGroup) {
+	router := group.Group("/reports")
+
+	viewOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.ViewReports,
+	}
+
+	router.GET("/sales", rr.authMiddleware.Middleware(viewOpts), rr.controller.Sales)
+}
@@ -20,6 +20,13 @@ var RoutesSet = wire.NewSet(
 	ProvideSettingRoutes,
 	ProvideCampaingRoutes,
 	ProvideElebilityRoutes,
+	ProvideReconciliationRoutes,
+	ProvideStripeEventRoutes,
+	ProvideDisputeRoutes,
+	ProvideReportRoutes,
+	ProvideSettlementRoutes,
+	ProvideFraudRoutes,
+	ProvidePromoCodeRoutes,
 )
 
 type Route interface {
@@ -38,6 +45,13 @@ func ProvideV1Routes(
 	settingRoutes *SettingRoutes,
 	promotionRoutes *CampaignRoutes,
 	elegibilityRoutes *ElebilityRoutes,
+	reconciliationRoutes *ReconciliationRoutes,
+	stripeEventRoutes *StripeEventRoutes,
+	disputeRoutes *DisputeRoutes,
+	reportRoutes *ReportRoutes,
+	settlementRoutes *SettlementRoutes,
+	fraudRoutes *FraudRoutes,
+	promoCodeRoutes *PromoCodeRoutes,
 ) Routes {
 	return Routes{
 		userRoutes,
@@ -51,5 +65,12 @@ func ProvideV1Routes(
 		settingRoutes,
 		promotionRoutes,
 		elegibilityRoutes,
+		reconciliationRoutes,
+		stripeEventRoutes,
+		disputeRoutes,
+		reportRoutes,
+		settlementRoutes,
+		fraudRoutes,
+		promoCodeRoutes,
 	}
 }
@@ -0,0 +1,46 @@
+package routes
+
+import (
+	"smartgas-payment/api/v1/controllers"
+	"smartgas-payment/internal/enums"
+	"smartgas-payment/internal/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SettlementRoutes struct {
+	controller     controllers.SettlementController
+	authMiddleware *middlewares.AuthMiddleware
+}
+
+func ProvideSettlementRoutes(
+	controller controllers.SettlementController,
+	authMiddleware *middlewares.AuthMiddleware,
+) *SettlementRoutes {
+	return &SettlementRoutes{
+		authMiddleware: authMiddleware,
+		controller:     controller,
+	}
+}
+
+func (sr *SettlementRoutes) Setup(group *gin.RouterGroup) {
+	router := group.Group("/settlements")
+
+	viewOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.ViewSettlements,
+	}
+
+	addOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.AddSettlement,
+	}
+
+	closeOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.CloseSettlement,
+	}
+
+	router.GET("", sr.authMiddleware.Middleware(viewOpts), sr.controller.List)
+	router.POST("", sr.authMiddleware.Middleware(addOpts), sr.controller.Generate)
+	router.GET("/:id", sr.authMiddleware.Middleware(viewOpts), sr.controller.GetByID)
+	router.GET("/:id/lines", sr.authMiddleware.Middleware(viewOpts), sr.controller.ListLines)
+	router.POST("/:id/close", sr.authMiddleware.Middleware(closeOpts), sr.controller.Close)
+}
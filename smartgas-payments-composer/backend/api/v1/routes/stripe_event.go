@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"smartgas-payment/api/v1/controllers"
+	"smartgas-payment/internal/enums"
+	"smartgas-payment/internal/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StripeEventRoutes struct {
+	controller     controllers.StripeEventController
+	authMiddleware *middlewares.AuthMiddleware
+}
+
+func ProvideStripeEventRoutes(
+	controller controllers.StripeEventController,
+	authMiddleware *middlewares.AuthMiddleware,
+) *StripeEventRoutes {
+	return &StripeEventRoutes{
+		authMiddleware: authMiddleware,
+		controller:     controller,
+	}
+}
+
+func (ser *StripeEventRoutes) Setup(group *gin.RouterGroup) {
+	router := group.Group("/stripe-events")
+
+	viewStripeEventsOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.ViewStripeEvents,
+	}
+
+	replayStripeEventOpts := middlewares.AuthMiddlewareOptions{
+		RequiredPermission: enums.ReplayStripeEvent,
+	}
+
+	router.GET("", ser.authMiddleware.Middleware(viewStripeEventsOpts), ser.controller.List)
+	router.GET("/:id", ser.authMiddleware.Middleware(viewStripeEventsOpts), ser.controller.GetByID)
+	router.POST("/:id/replay", ser.authMiddleware.Middleware(replayStripeEventOpts), ser.controller.Replay)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"log"
+	"smartgas-payment/internal/injectors"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	outboxInterval time.Duration
+	outboxBatch    int
+	outboxOnce     bool
+)
+
+// outboxWorkerCmd represents the outboxWorker command
+var outboxWorkerCmd = &cobra.Command{
+	Use:   "outboxWorker",
+	Short: "Process pending payment side effects",
+	Long: `Runs the payment side effects (captures, refunds, points, emails,
+pump presets) stored in the outbox that were not completed when the
+payment event was recorded, retrying them with backoff.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		outboxTask, err := injectors.InitializeOutboxTask()
+		if err != nil {
+			panic(err)
+		}
+
+		log.Println("Init outbox worker")
+
+		for {
+			processed, err := outboxTask.ProcessPending(outboxBatch)
+			if err != nil {
+				log.Println("Outbox: error listing messages", err)
+			} else if processed > 0 {
+				log.Println("Outbox: processed messages", processed)
+			}
+
+			if outboxOnce {
+				return
+			}
+
+			// A full batch means there may be more waiting
+			if processed < outboxBatch {
+				time.Sleep(outboxInterval)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(outboxWorkerCmd)
+
+	outboxWorkerCmd.Flags().DurationVar(&outboxInterval, "interval", time.Second*10, "Time between polls")
+	outboxWorkerCmd.Flags().IntVar(&outboxBatch, "batch", 50, "Messages processed per poll")
+	outboxWorkerCmd.Flags().BoolVar(&outboxOnce, "once", false, "Process a single batch and exit")
+}
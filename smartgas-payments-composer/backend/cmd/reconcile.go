@@ -0,0 +1,68 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"log"
+	"smartgas-payment/internal/injectors"
+	"smartgas-payment/internal/utils"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	reconcileFrom string
+	reconcileTo   string
+)
+
+// reconcileCmd represents the reconcile command
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Match local payments against the payment providers",
+	Long: `Compares the payments created between --from and --to (both days included,
+yesterday by default) with the transactions reported by every payment provider
+and stores a report with the discrepancies found. Swit and Debit have no way to
+list their transactions, so only Stripe payments are reconciled.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+		if reconcileFrom == "" {
+			reconcileFrom = yesterday
+		}
+		if reconcileTo == "" {
+			reconcileTo = reconcileFrom
+		}
+
+		from, to, err := utils.ParseDateRange(reconcileFrom, reconcileTo)
+		if err != nil {
+			log.Fatalln("Reconciliation: invalid dates", err)
+		}
+
+		reconciliationTask, err := injectors.InitializeReconciliationTask()
+		if err != nil {
+			panic(err)
+		}
+
+		reconciliation, err := reconciliationTask.Reconcile(from, to)
+		if err != nil {
+			log.Fatalln("Reconciliation: error reconciling", err)
+		}
+
+		log.Printf(
+			"Reconciliation %s: %d matched, %d discrepancies\n",
+			reconciliation.ID, reconciliation.Matched, len(reconciliation.Discrepancies),
+		)
+
+		if reconciliation.Errors != "" {
+			log.Println("Reconciliation: errors", reconciliation.Errors)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+
+	reconcileCmd.Flags().StringVar(&reconcileFrom, "from", "", "First day, YYYY-MM-DD (yesterday by default)")
+	reconcileCmd.Flags().StringVar(&reconcileTo, "to", "", "Last day, YYYY-MM-DD (same as --from by default)")
+}
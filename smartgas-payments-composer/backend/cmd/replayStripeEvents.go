@@ -0,0 +1,92 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"log"
+	"smartgas-payment/internal/injectors"
+	"smartgas-payment/internal/models"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayStripeEventID string
+	replayStripeLimit   int
+	replayStripeList    bool
+)
+
+// replayStripeEventsCmd represents the replayStripeEvents command
+var replayStripeEventsCmd = &cobra.Command{
+	Use:   "replayStripeEvents",
+	Short: "List and replay failed stripe webhook events",
+	Long: `Processes again the stripe webhook events that failed, through the same
+logic used by the webhook. Use --id to replay a single event (failed or stuck
+pending) or --list to only print the failed events.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if replayStripeList {
+			repository, err := injectors.InitializeStripeEventRepository()
+			if err != nil {
+				panic(err)
+			}
+
+			events, err := repository.ListByStatus(models.StripeEventFailed, replayStripeLimit)
+			if err != nil {
+				log.Fatalln("Stripe events: error listing failed events", err)
+			}
+
+			for _, event := range events {
+				logStripeEvent(event)
+			}
+
+			log.Printf("Stripe events: %d failed\n", len(events))
+			return
+		}
+
+		stripeWebhookTask, err := injectors.InitializeStripeWebhookTask()
+		if err != nil {
+			panic(err)
+		}
+
+		if replayStripeEventID != "" {
+			event, err := stripeWebhookTask.Replay(replayStripeEventID)
+			if event == nil {
+				log.Fatalln("Stripe events: error replaying", replayStripeEventID, err)
+			}
+
+			logStripeEvent(event)
+			return
+		}
+
+		events, err := stripeWebhookTask.ReplayFailed(replayStripeLimit)
+		if err != nil {
+			log.Fatalln("Stripe events: error listing failed events", err)
+		}
+
+		processed := 0
+		for _, event := range events {
+			logStripeEvent(event)
+			if event.Status == models.StripeEventProcessed {
+				processed++
+			}
+		}
+
+		log.Printf("Stripe events: %d replayed, %d processed\n", len(events), processed)
+	},
+}
+
+func logStripeEvent(event *models.StripeEvent) {
+	log.Printf(
+		"Stripe events: %s %s %s (attempts %d) %s\n",
+		event.ID, event.Type, event.Status, event.Attempts, event.Error,
+	)
+}
+
+func init() {
+	rootCmd.AddCommand(replayStripeEventsCmd)
+
+	replayStripeEventsCmd.Flags().StringVar(&replayStripeEventID, "id", "", "Replay only this event")
+	replayStripeEventsCmd.Flags().IntVar(&replayStripeLimit, "limit", 100, "Failed events replayed or listed")
+	replayStripeEventsCmd.Flags().BoolVar(&replayStripeList, "list", false, "List the failed events without replaying them")
+}
@@ -0,0 +1,101 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"log"
+	"smartgas-payment/internal/injectors"
+	"smartgas-payment/internal/tasks"
+	"time"
+
+	"github.com/go-co-op/gocron"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sweepEveryMinutes int
+	sweepOnce         bool
+)
+
+// scheduleSweepCmd represents the scheduleSweep command
+var scheduleSweepCmd = &cobra.Command{
+	Use:   "scheduleSweep",
+	Short: "Give back the funds of abandoned loads",
+	Long: `Looks for payments stuck in funds_reserved, paid or pump_ready longer than
+the configured timeouts (SWEEPER_*_MINUTES), cancels or refunds them through
+their provider, records an internal_cancellation and notifies the customer.
+Payments left pending or requiring action are canceled along with their intent.
+Authorizations about to expire are captured when the load was served and
+idempotency keys older than a day are deleted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		sweeperTask, err := injectors.InitializeReservationSweeperTask()
+		if err != nil {
+			panic(err)
+		}
+
+		if sweepOnce {
+			sweep(sweeperTask)
+			return
+		}
+
+		loc, err := time.LoadLocation("America/Mazatlan")
+		if err != nil {
+			panic(err)
+		}
+
+		log.Println("Init schedule for stale reservations")
+
+		s := gocron.NewScheduler(loc)
+
+		s.Every(sweepEveryMinutes).Minutes().SingletonMode().Do(func() {
+			sweep(sweeperTask)
+		})
+
+		s.StartBlocking()
+	},
+}
+
+func sweep(sweeperTask tasks.ReservationSweeperTask) {
+	report, err := sweeperTask.SweepStaleReservations()
+	if err != nil {
+		log.Println("Sweeper: error looking for stale reservations", err)
+	}
+
+	if report == nil {
+		return
+	}
+
+	for _, swept := range report.Canceled {
+		log.Printf(
+			"Sweeper: canceled payment %s (%s, %s) stuck in %s\n",
+			swept.PaymentID, swept.PaymentProvider, swept.Amount, swept.LastEvent,
+		)
+	}
+
+	for _, swept := range report.Captured {
+		log.Printf(
+			"Sweeper: captured payment %s (%s, %s) before its authorization expired\n",
+			swept.PaymentID, swept.PaymentProvider, swept.Amount,
+		)
+	}
+
+	for _, swept := range report.Failed {
+		log.Printf(
+			"Sweeper: failed to cancel payment %s (%s, %s) stuck in %s: %s\n",
+			swept.PaymentID, swept.PaymentProvider, swept.Amount, swept.LastEvent, swept.Error,
+		)
+	}
+
+	log.Printf(
+		"Sweeper: %d payments canceled, %d captured, %d failed, %d idempotency keys expired\n",
+		len(report.Canceled), len(report.Captured), len(report.Failed), report.ExpiredIdempotencyKeys,
+	)
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleSweepCmd)
+
+	scheduleSweepCmd.Flags().IntVar(&sweepEveryMinutes, "every", 5, "Minutes between sweeps")
+	scheduleSweepCmd.Flags().BoolVar(&sweepOnce, "once", false, "Sweep once and exit")
+}
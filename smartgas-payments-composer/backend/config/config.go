@@ -32,14 +32,26 @@ type Invoicing struct {
 	CertificateNumber string `env:"INVOICING_CERTIFICATE_NUMBER"`
 }
 
+// Sweeper holds the minutes a payment can stay in a state before its funds are given back,
+// zero uses the default of the sweeper task
+type Sweeper struct {
+	FundsReservedMinutes uint `env:"SWEEPER_FUNDS_RESERVED_MINUTES"`
+	PaidMinutes          uint `env:"SWEEPER_PAID_MINUTES"`
+	PumpReadyMinutes     uint `env:"SWEEPER_PUMP_READY_MINUTES"`
+	// Payments never confirmed by the customer, pending or requiring action
+	PendingMinutes uint `env:"SWEEPER_PENDING_MINUTES"`
+}
+
 type Config struct {
 	Host                string `env:"HOST"`
 	Port                int    `env:"PORT"`
 	Debug               bool   `env:"DEBUG"`
 	SecretKey           string `env:"SECRET_KEY"`
 	SecretKeyRefresh    string `env:"SECRET_KEY_REFRESH"`
+	SecretKeyQuote      string `env:"SECRET_KEY_QUOTE"`
 	JwtExpMinutes       uint   `env:"JWT_EXP_MINUTES"`
 	JwtRefreshExpDays   uint   `env:"JWT_REFRESH_EXP_DAYS"`
+	QuoteExpMinutes     uint   `env:"QUOTE_EXP_MINUTES"`
 	Tz                  string `env:"TZ"`
 	TrustedProxies      string `env:"TRUSTED_PROXIES"`
 	AllowedHosts        string `env:"ALLOWED_HOSTS"`
@@ -69,6 +81,8 @@ type Config struct {
 	SMTP SMTP
 
 	Invoicing Invoicing
+
+	Sweeper Sweeper
 }
 
 func NewConfig() (c Config, err error) {
@@ -103,6 +117,10 @@ func (cfg *Config) Setup() error {
 		cfg.JwtRefreshExpDays = 30
 	}
 
+	if cfg.QuoteExpMinutes == 0 {
+		cfg.QuoteExpMinutes = 5
+	}
+
 	// avoiding empty
 	if cfg.SecretKey == "" {
 		cfg.SecretKey = "secret_key"
@@ -112,6 +130,10 @@ func (cfg *Config) Setup() error {
 		cfg.SecretKeyRefresh = "secret_refresh_key"
 	}
 
+	if cfg.SecretKeyQuote == "" {
+		cfg.SecretKeyQuote = "secret_quote_key"
+	}
+
 	if cfg.TrustedProxies == "" {
 		cfg.TrustedProxies = "*"
 	}
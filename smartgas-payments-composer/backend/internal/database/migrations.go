@@ -3,12 +3,115 @@ package database
 import (
 	"fmt"
 	"smartgas-payment/internal/models"
+	"strings"
 
 	"gorm.io/gorm"
 )
 
+// moneyColumns were stored as float before amounts were handled in centavos
+var moneyColumns = map[string]string{
+	"amount":               "Amount",
+	"refunded_amount":      "RefundedAmount",
+	"real_amount_reported": "RealAmountReported",
+	"charge_fee":           "ChargeFee",
+}
+
+// migrateMoneyColumns converts the float money columns of payments to DECIMAL(12,2),
+// MySQL rounds the stored values to the nearest centavo
+func migrateMoneyColumns(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.Payment{}) {
+		return nil
+	}
+
+	columns, err := migrator.ColumnTypes(&models.Payment{})
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		field, ok := moneyColumns[column.Name()]
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(column.DatabaseTypeName()) {
+		case "float", "double":
+			fmt.Println("Converting payments." + column.Name() + " to decimal...")
+			if err := migrator.AlterColumn(&models.Payment{}, field); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// enumColumn is an enum column whose values grew, values are the ones added
+type enumColumn struct {
+	model  any
+	column string
+	field  string
+	values []string
+}
+
+var enumColumns = []enumColumn{
+	{&models.PaymentEvent{}, "type", "Type", []string{"authorization_expired"}},
+	{&models.Payment{}, "discount_type", "DiscountType", []string{"combined", "promo_code"}},
+	{&models.OutboxMessage{}, "type", "Type", []string{"campaign_usage"}},
+}
+
+// migrateEnumColumns adds the new values to the enum columns, AutoMigrate does not
+// compare the values of enum columns
+func migrateEnumColumns(db *gorm.DB) error {
+	migrator := db.Migrator()
+
+	for _, enum := range enumColumns {
+		if !migrator.HasTable(enum.model) {
+			continue
+		}
+
+		columns, err := migrator.ColumnTypes(enum.model)
+		if err != nil {
+			return err
+		}
+
+		for _, column := range columns {
+			if column.Name() != enum.column {
+				continue
+			}
+
+			columnType, _ := column.ColumnType()
+
+			missing := false
+			for _, value := range enum.values {
+				if !strings.Contains(columnType, "'"+value+"'") {
+					missing = true
+				}
+			}
+
+			if !missing {
+				break
+			}
+
+			fmt.Println("Adding " + strings.Join(enum.values, ", ") + " to " + enum.column + "...")
+			if err := migrator.AlterColumn(enum.model, enum.field); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func RunMigrations(db *gorm.DB) {
 	fmt.Println("Applying migrations...")
+	if err := migrateMoneyColumns(db); err != nil {
+		panic(err)
+	}
+	if err := migrateEnumColumns(db); err != nil {
+		panic(err)
+	}
 	if err := db.AutoMigrate(
 		models.User{},
 		models.GasStation{},
@@ -24,8 +127,23 @@ func RunMigrations(db *gorm.DB) {
 		models.Group{},
 		models.Setting{},
 		models.Campaign{},
+		models.CampaignUsage{},
+		models.PromoCode{},
+		models.PromoRedemption{},
 		models.Level{},
 		models.CustomerLevel{},
+		models.IdempotencyKey{},
+		models.OutboxMessage{},
+		models.Reconciliation{},
+		models.ReconciliationDiscrepancy{},
+		models.StripeEvent{},
+		models.Dispute{},
+		models.Refund{},
+		models.Settlement{},
+		models.SettlementLine{},
+		models.FraudRule{},
+		models.FraudBlock{},
+		models.FraudDecision{},
 	); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,26 @@
+// Package discounts prices the promotions of a load. Every enabled source (campaigns,
+// customer levels) proposes a discount per liter and the stacking mode decides which
+// ones apply:
+//
+//   - best_of: only the greatest discount applies
+//   - additive: the discounts are added up
+//   - capped: the discounts are added up, limited per liter and per load
+//
+// Campaigns only propose a discount for the loads they target (fuel types, weekdays
+// and hours, payment providers, customer levels, first loads and minimums), the one
+// with the greatest discount among them is taken.
+//
+// A promo code sent with the load is a source of its own: the campaign of the code
+// takes the place of the station campaigns and stacks with the other sources.
+//
+// The policy is read from the settings discount_sources, discount_stacking,
+// discount_cap_per_liter and discount_cap_per_load
+package discounts
+
+import "github.com/google/wire"
+
+var DiscountsSet = wire.NewSet(
+	ProvideEngine,
+
+	wire.Bind(new(Engine), new(*engine)),
+)
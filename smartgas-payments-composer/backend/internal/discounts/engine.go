@@ -0,0 +1,626 @@
+package discounts
+
+import (
+	"errors"
+	"math"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/reports"
+	"smartgas-payment/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Sources of discounts, also the discount type stored in the payment when it is the
+// only one applied
+const (
+	SourceCampaign    = "campaign"
+	SourceElegibility = "elegibility"
+	SourcePromoCode   = "promo_code"
+)
+
+// Discount types of a payment besides the sources
+const (
+	TypeNone     = "none"
+	TypeCombined = "combined"
+)
+
+// Stacking modes
+const (
+	StackingBestOf   = "best_of"
+	StackingAdditive = "additive"
+	StackingCapped   = "capped"
+)
+
+// Caps reached by a discount
+const (
+	CapPerLiter = "per_liter"
+	CapPerLoad  = "per_load"
+)
+
+// TODO: check if the levels validity should follow the timezone of the station
+const levelsTimezone = "America/Mazatlan"
+
+var (
+	ErrUnknownStacking        = errors.New("Unknown discount stacking mode")
+	ErrPromoCodeNotApplicable = errors.New("The promo code does not apply to the load")
+)
+
+// Policy is how discounts are chosen, caps are only applied by the capped mode and
+// zero means no cap
+type Policy struct {
+	Sources     []string
+	Stacking    string
+	CapPerLiter float64
+	CapPerLoad  money.Amount
+}
+
+func (p *Policy) Enabled(source string) bool {
+	for _, s := range p.Sources {
+		if s == source {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Input is the load to price, PumpPrice is the price per liter before discounts
+type Input struct {
+	Customer        *models.Customer
+	GasStation      *models.GasStation
+	FuelType        string
+	PaymentProvider string
+	PumpPrice       float64
+	ChargeType      string
+	Amount          money.Amount
+	TotalLiter      float64
+	At              time.Time
+	// PromoCode sent with the load, its campaign takes the place of the station ones
+	PromoCode string
+	// Preview prices a load not requested yet, the targeting on the load (fuel type,
+	// payment provider, minimum amount and liters) and the cap per load are skipped
+	Preview bool
+}
+
+// loadSize is the amount and liters of the load at the pump price
+func (i Input) loadSize() (money.Amount, float64) {
+	if i.ChargeType == "by_liter" {
+		return money.ForLiters(i.TotalLiter, i.PumpPrice), i.TotalLiter
+	}
+
+	return i.Amount, i.Amount.Liters(i.PumpPrice)
+}
+
+// Discount is one discount found for the load, Applied tells whether the stacking
+// mode kept it
+type Discount struct {
+	Source           string    `json:"source"`
+	ID               uuid.UUID `json:"id"`
+	Name             string    `json:"name"`
+	DiscountPerLiter float64   `json:"discount_per_liter"`
+	Applied          bool      `json:"applied"`
+}
+
+// Result is the discount per liter of the load and the explanation of how it was
+// reached
+type Result struct {
+	DiscountPerLiter float64
+	Type             string
+	Stacking         string
+	Discounts        []Discount
+	// Capped is the cap that lowered the discount, empty when none did
+	Capped     string
+	CapPerLoad money.Amount
+	Campaign   *models.Campaign
+	Level      *models.CustomerLevel
+	PromoCode  *models.PromoCode
+}
+
+// CampaignID is the campaign applied, also when it was through a promo code, nil when
+// none was
+func (r *Result) CampaignID() *uuid.UUID {
+	if r.Campaign == nil || !r.applied(SourceCampaign) && !r.applied(SourcePromoCode) {
+		return nil
+	}
+
+	return &r.Campaign.ID
+}
+
+// PromoCodeID is the promo code applied, nil when none was
+func (r *Result) PromoCodeID() *uuid.UUID {
+	if r.PromoCode == nil || !r.applied(SourcePromoCode) {
+		return nil
+	}
+
+	return &r.PromoCode.ID
+}
+
+// LevelID is the customer level applied, nil when none was
+func (r *Result) LevelID() *uuid.UUID {
+	if r.Level == nil || !r.applied(SourceElegibility) {
+		return nil
+	}
+
+	return r.Level.LevelID
+}
+
+// capForLoad limits the discount per liter so the discount of the whole load does not
+// exceed the cap per load
+func (r *Result) capForLoad(input Input) {
+	if r.CapPerLoad <= 0 || r.DiscountPerLiter <= 0 {
+		return
+	}
+
+	capPerLoad := r.CapPerLoad.Float64()
+
+	var maxPerLiter float64
+	if input.ChargeType == "by_liter" {
+		if input.TotalLiter <= 0 {
+			return
+		}
+		maxPerLiter = capPerLoad / input.TotalLiter
+	} else {
+		// Liters bought are amount / (pumpPrice - discount), so the discount of the
+		// load is under the cap while discount <= cap * pumpPrice / (amount + cap)
+		maxPerLiter = capPerLoad * input.PumpPrice / (input.Amount.Float64() + capPerLoad)
+	}
+
+	if r.DiscountPerLiter > maxPerLiter {
+		// Rounding down to centavos so the cap is never exceeded
+		r.DiscountPerLiter = math.Floor(maxPerLiter*100) / 100
+		r.Capped = CapPerLoad
+	}
+}
+
+func (r *Result) applied(source string) bool {
+	for _, d := range r.Discounts {
+		if d.Source == source && d.Applied {
+			return true
+		}
+	}
+
+	return false
+}
+
+//go:generate mockery --name Engine --filename=mock_engine.go --inpackage=true
+type Engine interface {
+	Policy() (*Policy, error)
+	Evaluate(Input) (*Result, error)
+}
+
+type engine struct {
+	settingRepository     repository.SettingRepository
+	campaignRepository    repository.CampaignRepository
+	elegibilityRepository repository.ElegibilityRepository
+	paymentRepository     repository.PaymentRepository
+	promoCodeRepository   repository.PromoCodeRepository
+}
+
+func ProvideEngine(
+	settingRepository repository.SettingRepository,
+	campaignRepository repository.CampaignRepository,
+	elegibilityRepository repository.ElegibilityRepository,
+	paymentRepository repository.PaymentRepository,
+	promoCodeRepository repository.PromoCodeRepository,
+) *engine {
+	return &engine{
+		settingRepository:     settingRepository,
+		campaignRepository:    campaignRepository,
+		elegibilityRepository: elegibilityRepository,
+		paymentRepository:     paymentRepository,
+		promoCodeRepository:   promoCodeRepository,
+	}
+}
+
+// Policy reads the discount settings. Without discount_sources the source is the one
+// chosen by the former setting applicable_promotion_type
+func (e *engine) Policy() (*Policy, error) {
+	settings, err := e.settingRepository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for _, setting := range settings {
+		values[setting.Name] = strings.TrimSpace(setting.Value)
+	}
+
+	policy := &Policy{Stacking: StackingBestOf}
+
+	if sources, ok := values["discount_sources"]; ok {
+		for _, source := range strings.Split(sources, ",") {
+			source = strings.TrimSpace(source)
+			if source == SourceCampaign || source == SourceElegibility {
+				policy.Sources = append(policy.Sources, source)
+			}
+		}
+	} else if promotionType := values["applicable_promotion_type"]; promotionType == SourceCampaign ||
+		promotionType == SourceElegibility {
+		policy.Sources = []string{promotionType}
+	}
+
+	if stacking := values["discount_stacking"]; stacking != "" {
+		policy.Stacking = stacking
+	}
+
+	switch policy.Stacking {
+	case StackingBestOf, StackingAdditive, StackingCapped:
+	default:
+		return nil, ErrUnknownStacking
+	}
+
+	if value := values["discount_cap_per_liter"]; value != "" {
+		policy.CapPerLiter, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if value := values["discount_cap_per_load"]; value != "" {
+		policy.CapPerLoad, err = money.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+// Evaluate finds the discounts of every enabled source and stacks them. A promo code
+// sent with the load is always evaluated, ErrPromoCodeNotApplicable or
+// repository.ErrPromoCodeUsed are returned when it can not be used
+func (e *engine) Evaluate(input Input) (*Result, error) {
+	policy, err := e.Policy()
+	if err != nil {
+		return nil, err
+	}
+
+	if input.At.IsZero() {
+		input.At = time.Now()
+	}
+
+	result := &Result{
+		Type:      TypeNone,
+		Stacking:  policy.Stacking,
+		Discounts: []Discount{},
+	}
+
+	ev := &evaluation{engine: e, input: input}
+
+	if input.PromoCode != "" {
+		code, err := ev.promoCode()
+		if err != nil {
+			return nil, err
+		}
+
+		result.Campaign = code.Campaign
+		result.PromoCode = code
+		result.Discounts = append(result.Discounts, Discount{
+			Source:           SourcePromoCode,
+			ID:               code.ID,
+			Name:             code.Code,
+			DiscountPerLiter: *code.Campaign.Discount,
+		})
+	} else if policy.Enabled(SourceCampaign) {
+		campaign, err := ev.campaign()
+		if err != nil {
+			return nil, err
+		}
+
+		if campaign != nil && campaign.Discount != nil {
+			result.Campaign = campaign
+			result.Discounts = append(result.Discounts, Discount{
+				Source:           SourceCampaign,
+				ID:               campaign.ID,
+				Name:             campaign.Name,
+				DiscountPerLiter: *campaign.Discount,
+			})
+		}
+	}
+
+	if policy.Enabled(SourceElegibility) {
+		cusLevel, err := ev.customerLevel()
+		if err != nil {
+			return nil, err
+		}
+
+		if cusLevel != nil && cusLevel.Level != nil && cusLevel.Level.Discount != nil {
+			result.Level = cusLevel
+			discount := Discount{
+				Source:           SourceElegibility,
+				DiscountPerLiter: *cusLevel.Level.Discount,
+			}
+			if cusLevel.LevelID != nil {
+				discount.ID = *cusLevel.LevelID
+			}
+			if cusLevel.Level.Name != nil {
+				discount.Name = *cusLevel.Level.Name
+			}
+			result.Discounts = append(result.Discounts, discount)
+		}
+	}
+
+	stack(result, policy)
+
+	if !input.Preview {
+		result.capForLoad(input)
+	}
+
+	return result, nil
+}
+
+// evaluation looks up what the sources share about the customer only once
+type evaluation struct {
+	engine *engine
+	input  Input
+
+	levelLoaded bool
+	level       *models.CustomerLevel
+}
+
+// customerLevel is the level of the customer in the current month, nil when it has
+// none
+func (ev *evaluation) customerLevel() (*models.CustomerLevel, error) {
+	if ev.levelLoaded || ev.input.Customer == nil {
+		return ev.level, nil
+	}
+
+	n := ev.input.At.In(reports.Location(levelsTimezone))
+	cusLevel, err := ev.engine.elegibilityRepository.GetCustomerLevelByCriterias(map[string]any{
+		"customer_id":    ev.input.Customer.ID,
+		"validity_month": n.Month(),
+		"validity_year":  n.Year(),
+	})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	ev.levelLoaded = true
+	ev.level = cusLevel
+
+	return ev.level, nil
+}
+
+// campaign is the campaign of the station with the greatest discount targeting the
+// load, nil when none does
+func (ev *evaluation) campaign() (*models.Campaign, error) {
+	campaigns, err := ev.engine.campaignRepository.ListApplicableCampaigns(
+		ev.input.At,
+		ev.input.GasStation.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, campaign := range campaigns {
+		targeted, err := ev.targets(campaign)
+		if err != nil {
+			return nil, err
+		}
+
+		if targeted {
+			return campaign, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// promoCode is the promo code of the load when it can be used by the customer and its
+// campaign targets the load
+func (ev *evaluation) promoCode() (*models.PromoCode, error) {
+	input := ev.input
+
+	code, err := ev.engine.promoCodeRepository.GetByCode(input.PromoCode)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPromoCodeNotApplicable
+		}
+		return nil, err
+	}
+
+	if code.Active == nil || !*code.Active ||
+		code.Campaign == nil || code.Campaign.Discount == nil ||
+		input.Customer == nil ||
+		code.CustomerID != nil && *code.CustomerID != input.Customer.ID {
+		return nil, ErrPromoCodeNotApplicable
+	}
+
+	if code.MaxUses != nil && code.Uses >= *code.MaxUses {
+		return nil, repository.ErrPromoCodeUsed
+	}
+
+	if code.MaxUsesPerCustomer != nil {
+		uses, err := ev.engine.promoCodeRepository.CountRedemptions(code.ID, input.Customer.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if uses >= int64(*code.MaxUsesPerCustomer) {
+			return nil, repository.ErrPromoCodeUsed
+		}
+	}
+
+	if !ev.available(code.Campaign) {
+		return nil, ErrPromoCodeNotApplicable
+	}
+
+	targeted, err := ev.targets(code.Campaign)
+	if err != nil {
+		return nil, err
+	}
+
+	if !targeted {
+		return nil, ErrPromoCodeNotApplicable
+	}
+
+	return code, nil
+}
+
+// available checks the campaign of a promo code as ListApplicableCampaigns does with
+// the station ones: active, valid, with budget left and for the station of the load
+func (ev *evaluation) available(campaign *models.Campaign) bool {
+	at := ev.input.At
+
+	if campaign.Active == nil || !*campaign.Active ||
+		campaign.ValidFrom != nil && at.Before(*campaign.ValidFrom) ||
+		campaign.ValidTo != nil && at.After(*campaign.ValidTo) ||
+		campaign.BudgetAmount != nil && campaign.SpentAmount >= *campaign.BudgetAmount ||
+		campaign.BudgetLiters != nil && campaign.SpentLiters >= *campaign.BudgetLiters ||
+		campaign.GasStations == nil {
+		return false
+	}
+
+	for _, gasStation := range *campaign.GasStations {
+		if gasStation.ID == ev.input.GasStation.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// targets checks the targeting of the campaign against the load
+func (ev *evaluation) targets(campaign *models.Campaign) (bool, error) {
+	input := ev.input
+	at := input.At.In(reports.Location(input.GasStation.Timezone))
+
+	if !campaign.Weekdays.Matches(strings.ToLower(at.Weekday().String())) {
+		return false, nil
+	}
+
+	if campaign.FromHour != nil && campaign.ToHour != nil {
+		from, to, hour := *campaign.FromHour, *campaign.ToHour, at.Hour()
+		if from < to && (hour < from || hour >= to) ||
+			from > to && hour < from && hour >= to {
+			return false, nil
+		}
+	}
+
+	if !input.Preview {
+		if !campaign.FuelTypes.Matches(input.FuelType) ||
+			!campaign.PaymentProviders.Matches(input.PaymentProvider) {
+			return false, nil
+		}
+
+		amount, liters := input.loadSize()
+		if campaign.MinAmount != nil && amount < *campaign.MinAmount ||
+			campaign.MinLiters != nil && liters < *campaign.MinLiters {
+			return false, nil
+		}
+	}
+
+	if campaign.Levels != nil && len(*campaign.Levels) > 0 {
+		cusLevel, err := ev.customerLevel()
+		if err != nil {
+			return false, err
+		}
+
+		if cusLevel == nil || cusLevel.LevelID == nil {
+			return false, nil
+		}
+
+		found := false
+		for _, level := range *campaign.Levels {
+			if level.ID == *cusLevel.LevelID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+
+	if campaign.FirstLoadOnly != nil && *campaign.FirstLoadOnly {
+		if input.Customer == nil {
+			return false, nil
+		}
+
+		served, err := ev.engine.paymentRepository.HasServedLoads(input.Customer.ID)
+		if err != nil {
+			return false, err
+		}
+
+		if served {
+			return false, nil
+		}
+	}
+
+	// Uses are counted once the loads are served, loads in progress are not limited
+	if campaign.MaxUsesPerDay != nil {
+		dayStart := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+		uses, err := ev.engine.campaignRepository.CountUsages(campaign.ID, nil, dayStart)
+		if err != nil {
+			return false, err
+		}
+
+		if uses >= int64(*campaign.MaxUsesPerDay) {
+			return false, nil
+		}
+	}
+
+	if campaign.MaxUsesPerCustomer != nil {
+		if input.Customer == nil {
+			return false, nil
+		}
+
+		uses, err := ev.engine.campaignRepository.CountUsages(campaign.ID, &input.Customer.ID, time.Time{})
+		if err != nil {
+			return false, err
+		}
+
+		if uses >= int64(*campaign.MaxUsesPerCustomer) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// stack marks the discounts applied by the stacking mode and sets the total
+func stack(result *Result, policy *Policy) {
+	if len(result.Discounts) == 0 {
+		return
+	}
+
+	if policy.Stacking == StackingBestOf {
+		best := 0
+		for i, d := range result.Discounts {
+			if d.DiscountPerLiter > result.Discounts[best].DiscountPerLiter {
+				best = i
+			}
+		}
+		result.Discounts[best].Applied = true
+		result.DiscountPerLiter = result.Discounts[best].DiscountPerLiter
+		result.Type = result.Discounts[best].Source
+
+		return
+	}
+
+	for i := range result.Discounts {
+		result.Discounts[i].Applied = true
+		result.DiscountPerLiter += result.Discounts[i].DiscountPerLiter
+	}
+
+	result.Type = result.Discounts[0].Source
+	if len(result.Discounts) > 1 {
+		result.Type = TypeCombined
+	}
+
+	if policy.Stacking != StackingCapped {
+		return
+	}
+
+	result.CapPerLoad = policy.CapPerLoad
+
+	if policy.CapPerLiter > 0 && result.DiscountPerLiter > policy.CapPerLiter {
+		result.DiscountPerLiter = policy.CapPerLiter
+		result.Capped = CapPerLiter
+	}
+}
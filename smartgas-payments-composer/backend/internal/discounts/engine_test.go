@@ -0,0 +1,545 @@
+package discounts
+
+import (
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/reports"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type engineTest struct {
+	suite.Suite
+	settingRepository     *repository.MockSettingRepository
+	campaignRepository    *repository.MockCampaignRepository
+	elegibilityRepository *repository.MockElegibilityRepository
+	paymentRepository     *repository.MockPaymentRepository
+	promoCodeRepository   *repository.MockPromoCodeRepository
+	engine                *engine
+	input                 Input
+	campaign              *models.Campaign
+	level                 *models.CustomerLevel
+}
+
+func (suite *engineTest) SetupTest() {
+	suite.settingRepository = &repository.MockSettingRepository{}
+	suite.campaignRepository = &repository.MockCampaignRepository{}
+	suite.elegibilityRepository = &repository.MockElegibilityRepository{}
+	suite.paymentRepository = &repository.MockPaymentRepository{}
+	suite.promoCodeRepository = &repository.MockPromoCodeRepository{}
+
+	suite.engine = ProvideEngine(
+		suite.settingRepository,
+		suite.campaignRepository,
+		suite.elegibilityRepository,
+		suite.paymentRepository,
+		suite.promoCodeRepository,
+	)
+
+	suite.input = Input{
+		Customer:        &models.Customer{ID: uuid.New()},
+		GasStation:      &models.GasStation{ID: uuid.New(), Timezone: "America/Mazatlan"},
+		FuelType:        "regular",
+		PaymentProvider: "stripe",
+		PumpPrice:       25,
+		ChargeType:      "by_total",
+		Amount:          money.FromFloat(500),
+		// A wednesday at noon in the station
+		At: time.Date(2024, 3, 13, 19, 0, 0, 0, time.UTC),
+	}
+
+	suite.campaign = &models.Campaign{
+		ID:       uuid.New(),
+		Name:     "Marzo",
+		Discount: float64Addr(0.5),
+	}
+	suite.campaignRepository.On("ListApplicableCampaigns", suite.input.At, suite.input.GasStation.ID).
+		Return([]*models.Campaign{suite.campaign}, nil).Maybe()
+
+	levelID := uuid.New()
+	suite.level = &models.CustomerLevel{
+		LevelID: &levelID,
+		Level: &models.Level{
+			ID:       levelID,
+			Name:     utils.StringAddr("Oro"),
+			Discount: float64Addr(0.3),
+		},
+	}
+	suite.elegibilityRepository.On("GetCustomerLevelByCriterias", mock.Anything).
+		Return(suite.level, nil).Maybe()
+}
+
+// settings makes the settings the only ones stored
+func (suite *engineTest) settings(values map[string]string) {
+	settings := make([]*models.Setting, 0, len(values))
+	for name, value := range values {
+		settings = append(settings, &models.Setting{Name: name, Value: value})
+	}
+
+	suite.settingRepository.On("GetAll").Return(settings, nil)
+}
+
+func (suite *engineTest) TestStacking() {
+	testcases := []struct {
+		Name     string
+		Settings map[string]string
+		Preview  bool
+		Discount float64
+		Type     string
+		Capped   string
+		Applied  []bool
+	}{
+		{
+			Name:     "TestEngine_NoSources",
+			Settings: map[string]string{},
+			Type:     TypeNone,
+			Applied:  []bool{},
+		},
+		{
+			Name:     "TestEngine_FormerPromotionType",
+			Settings: map[string]string{"applicable_promotion_type": SourceElegibility},
+			Discount: 0.3,
+			Type:     SourceElegibility,
+			Applied:  []bool{true},
+		},
+		{
+			Name:     "TestEngine_BestOf",
+			Settings: map[string]string{"discount_sources": "campaign, elegibility"},
+			Discount: 0.5,
+			Type:     SourceCampaign,
+			Applied:  []bool{true, false},
+		},
+		{
+			Name: "TestEngine_Additive",
+			Settings: map[string]string{
+				"discount_sources":  "campaign,elegibility",
+				"discount_stacking": StackingAdditive,
+			},
+			Discount: 0.8,
+			Type:     TypeCombined,
+			Applied:  []bool{true, true},
+		},
+		{
+			Name: "TestEngine_AdditiveSingleSource",
+			Settings: map[string]string{
+				"discount_sources":  "elegibility",
+				"discount_stacking": StackingAdditive,
+			},
+			Discount: 0.3,
+			Type:     SourceElegibility,
+			Applied:  []bool{true},
+		},
+		{
+			Name: "TestEngine_AdditiveIgnoresCaps",
+			Settings: map[string]string{
+				"discount_sources":       "campaign,elegibility",
+				"discount_stacking":      StackingAdditive,
+				"discount_cap_per_liter": "0.6",
+				"discount_cap_per_load":  "10",
+			},
+			Discount: 0.8,
+			Type:     TypeCombined,
+			Applied:  []bool{true, true},
+		},
+		{
+			Name: "TestEngine_CappedPerLiter",
+			Settings: map[string]string{
+				"discount_sources":       "campaign,elegibility",
+				"discount_stacking":      StackingCapped,
+				"discount_cap_per_liter": "0.6",
+			},
+			Discount: 0.6,
+			Type:     TypeCombined,
+			Capped:   CapPerLiter,
+			Applied:  []bool{true, true},
+		},
+		{
+			// 10 * 25 / (500 + 10) is 0.4902 per liter, rounded down
+			Name: "TestEngine_CappedPerLoad",
+			Settings: map[string]string{
+				"discount_sources":      "campaign,elegibility",
+				"discount_stacking":     StackingCapped,
+				"discount_cap_per_load": "10",
+			},
+			Discount: 0.49,
+			Type:     TypeCombined,
+			Capped:   CapPerLoad,
+			Applied:  []bool{true, true},
+		},
+		{
+			Name: "TestEngine_CappedUnderCaps",
+			Settings: map[string]string{
+				"discount_sources":       "campaign,elegibility",
+				"discount_stacking":      StackingCapped,
+				"discount_cap_per_liter": "1",
+				"discount_cap_per_load":  "50",
+			},
+			Discount: 0.8,
+			Type:     TypeCombined,
+			Applied:  []bool{true, true},
+		},
+		{
+			Name: "TestEngine_PreviewSkipsCapPerLoad",
+			Settings: map[string]string{
+				"discount_sources":      "campaign,elegibility",
+				"discount_stacking":     StackingCapped,
+				"discount_cap_per_load": "10",
+			},
+			Preview:  true,
+			Discount: 0.8,
+			Type:     TypeCombined,
+			Applied:  []bool{true, true},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+			suite.settings(tc.Settings)
+
+			input := suite.input
+			input.Preview = tc.Preview
+
+			result, err := suite.engine.Evaluate(input)
+
+			suite.Nil(err)
+			suite.InDelta(tc.Discount, result.DiscountPerLiter, 0.0001)
+			suite.Equal(tc.Type, result.Type)
+			suite.Equal(tc.Capped, result.Capped)
+
+			applied := make([]bool, 0, len(result.Discounts))
+			for _, discount := range result.Discounts {
+				applied = append(applied, discount.Applied)
+			}
+			suite.Equal(tc.Applied, applied)
+		})
+	}
+}
+
+func (suite *engineTest) TestCappedPerLoadByLiter() {
+	suite.settings(map[string]string{
+		"discount_sources":      "campaign,elegibility",
+		"discount_stacking":     StackingCapped,
+		"discount_cap_per_load": "12",
+	})
+
+	input := suite.input
+	input.ChargeType = "by_liter"
+	input.TotalLiter = 40
+
+	result, err := suite.engine.Evaluate(input)
+
+	suite.Nil(err)
+	suite.InDelta(0.3, result.DiscountPerLiter, 0.0001)
+	suite.Equal(CapPerLoad, result.Capped)
+	suite.Equal(money.FromFloat(12), result.CapPerLoad)
+}
+
+func (suite *engineTest) TestAppliedIDs() {
+	suite.settings(map[string]string{"discount_sources": "campaign,elegibility"})
+
+	result, err := suite.engine.Evaluate(suite.input)
+
+	suite.Nil(err)
+	suite.Equal(&suite.campaign.ID, result.CampaignID())
+	suite.Nil(result.LevelID())
+	suite.Nil(result.PromoCodeID())
+}
+
+func (suite *engineTest) TestPolicyErrors() {
+	testcases := []struct {
+		Name     string
+		Settings map[string]string
+		Err      error
+	}{
+		{
+			Name:     "TestEngine_UnknownStacking",
+			Settings: map[string]string{"discount_stacking": "greatest"},
+			Err:      ErrUnknownStacking,
+		},
+		{
+			Name:     "TestEngine_InvalidCapPerLoad",
+			Settings: map[string]string{"discount_cap_per_load": "ten"},
+			Err:      money.ErrInvalidAmount,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+			suite.settings(tc.Settings)
+
+			result, err := suite.engine.Evaluate(suite.input)
+
+			suite.Nil(result)
+			suite.ErrorIs(err, tc.Err)
+		})
+	}
+}
+
+func (suite *engineTest) TestTargeting() {
+	dayStart := time.Date(2024, 3, 13, 0, 0, 0, 0, reports.Location("America/Mazatlan"))
+	sinceDayStart := mock.MatchedBy(func(since time.Time) bool {
+		return since.Equal(dayStart)
+	})
+
+	testcases := []struct {
+		Name     string
+		Campaign func(campaign *models.Campaign)
+		Mock     func()
+		Preview  bool
+		Targeted bool
+	}{
+		{
+			Name:     "TestEngine_NoTargeting",
+			Campaign: func(campaign *models.Campaign) {},
+			Targeted: true,
+		},
+		{
+			Name: "TestEngine_Weekday",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.Weekdays = models.StringList{"monday", "wednesday"}
+			},
+			Targeted: true,
+		},
+		{
+			Name: "TestEngine_OtherWeekdays",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.Weekdays = models.StringList{"saturday", "sunday"}
+			},
+		},
+		{
+			Name: "TestEngine_HoursOfStation",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.FromHour, campaign.ToHour = utils.IntAddr(11), utils.IntAddr(13)
+			},
+			Targeted: true,
+		},
+		{
+			Name: "TestEngine_HoursEndAtTo",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.FromHour, campaign.ToHour = utils.IntAddr(8), utils.IntAddr(12)
+			},
+		},
+		{
+			Name: "TestEngine_HoursOfUTC",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.FromHour, campaign.ToHour = utils.IntAddr(18), utils.IntAddr(20)
+			},
+		},
+		{
+			Name: "TestEngine_HoursPastMidnight",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.FromHour, campaign.ToHour = utils.IntAddr(22), utils.IntAddr(13)
+			},
+			Targeted: true,
+		},
+		{
+			Name: "TestEngine_OutsideHoursPastMidnight",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.FromHour, campaign.ToHour = utils.IntAddr(22), utils.IntAddr(6)
+			},
+		},
+		{
+			Name: "TestEngine_FuelType",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.FuelTypes = models.StringList{"regular", "diesel"}
+			},
+			Targeted: true,
+		},
+		{
+			Name: "TestEngine_OtherFuelType",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.FuelTypes = models.StringList{"premium"}
+			},
+		},
+		{
+			Name: "TestEngine_PreviewAnyFuelType",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.FuelTypes = models.StringList{"premium"}
+			},
+			Preview:  true,
+			Targeted: true,
+		},
+		{
+			Name: "TestEngine_OtherPaymentProvider",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.PaymentProviders = models.StringList{"swit", "debit"}
+			},
+		},
+		{
+			Name: "TestEngine_MinAmount",
+			Campaign: func(campaign *models.Campaign) {
+				minAmount := money.FromFloat(500)
+				campaign.MinAmount = &minAmount
+			},
+			Targeted: true,
+		},
+		{
+			Name: "TestEngine_UnderMinAmount",
+			Campaign: func(campaign *models.Campaign) {
+				minAmount := money.FromFloat(600)
+				campaign.MinAmount = &minAmount
+			},
+		},
+		{
+			Name: "TestEngine_PreviewAnyAmount",
+			Campaign: func(campaign *models.Campaign) {
+				minAmount := money.FromFloat(600)
+				campaign.MinAmount = &minAmount
+			},
+			Preview:  true,
+			Targeted: true,
+		},
+		{
+			// 500 at 25 are 20 liters
+			Name: "TestEngine_UnderMinLiters",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.MinLiters = float64Addr(25)
+			},
+		},
+		{
+			Name: "TestEngine_Level",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.Levels = &[]*models.Level{{ID: uuid.New()}, suite.level.Level}
+			},
+			Targeted: true,
+		},
+		{
+			Name: "TestEngine_OtherLevel",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.Levels = &[]*models.Level{{ID: uuid.New()}}
+			},
+		},
+		{
+			Name: "TestEngine_FirstLoad",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.FirstLoadOnly = utils.BoolAddr(true)
+			},
+			Mock: func() {
+				suite.paymentRepository.On("HasServedLoads", suite.input.Customer.ID).Return(false, nil)
+			},
+			Targeted: true,
+		},
+		{
+			Name: "TestEngine_NotFirstLoad",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.FirstLoadOnly = utils.BoolAddr(true)
+			},
+			Mock: func() {
+				suite.paymentRepository.On("HasServedLoads", suite.input.Customer.ID).Return(true, nil)
+			},
+		},
+		{
+			Name: "TestEngine_UsesOfDayLeft",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.MaxUsesPerDay = utils.IntAddr(3)
+			},
+			Mock: func() {
+				suite.campaignRepository.On("CountUsages", suite.campaign.ID, (*uuid.UUID)(nil), sinceDayStart).
+					Return(int64(2), nil)
+			},
+			Targeted: true,
+		},
+		{
+			Name: "TestEngine_NoUsesOfDayLeft",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.MaxUsesPerDay = utils.IntAddr(3)
+			},
+			Mock: func() {
+				suite.campaignRepository.On("CountUsages", suite.campaign.ID, (*uuid.UUID)(nil), sinceDayStart).
+					Return(int64(3), nil)
+			},
+		},
+		{
+			Name: "TestEngine_UsesOfCustomerLeft",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.MaxUsesPerCustomer = utils.IntAddr(1)
+			},
+			Mock: func() {
+				suite.campaignRepository.On("CountUsages", suite.campaign.ID, &suite.input.Customer.ID, time.Time{}).
+					Return(int64(0), nil)
+			},
+			Targeted: true,
+		},
+		{
+			Name: "TestEngine_NoUsesOfCustomerLeft",
+			Campaign: func(campaign *models.Campaign) {
+				campaign.MaxUsesPerCustomer = utils.IntAddr(1)
+			},
+			Mock: func() {
+				suite.campaignRepository.On("CountUsages", suite.campaign.ID, &suite.input.Customer.ID, time.Time{}).
+					Return(int64(1), nil)
+			},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+			suite.settings(map[string]string{"discount_sources": SourceCampaign})
+			tc.Campaign(suite.campaign)
+			if tc.Mock != nil {
+				tc.Mock()
+			}
+
+			input := suite.input
+			input.Preview = tc.Preview
+
+			result, err := suite.engine.Evaluate(input)
+
+			suite.Nil(err)
+			if tc.Targeted {
+				suite.Equal(SourceCampaign, result.Type)
+				suite.Equal(&suite.campaign.ID, result.CampaignID())
+			} else {
+				suite.Equal(TypeNone, result.Type)
+				suite.Nil(result.CampaignID())
+			}
+			suite.paymentRepository.AssertExpectations(suite.T())
+			suite.campaignRepository.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func (suite *engineTest) TestTargetingNextCampaign() {
+	suite.settings(map[string]string{"discount_sources": SourceCampaign})
+
+	suite.campaign.FuelTypes = models.StringList{"premium"}
+	next := &models.Campaign{
+		ID:       uuid.New(),
+		Name:     "Regular",
+		Discount: float64Addr(0.2),
+	}
+
+	suite.campaignRepository = &repository.MockCampaignRepository{}
+	suite.campaignRepository.On("ListApplicableCampaigns", suite.input.At, suite.input.GasStation.ID).
+		Return([]*models.Campaign{suite.campaign, next}, nil)
+	suite.engine.campaignRepository = suite.campaignRepository
+
+	result, err := suite.engine.Evaluate(suite.input)
+
+	suite.Nil(err)
+	suite.Equal(&next.ID, result.CampaignID())
+	suite.InDelta(0.2, result.DiscountPerLiter, 0.0001)
+}
+
+func float64Addr(f float64) *float64 {
+	return &f
+}
+
+func TestEngine(t *testing.T) {
+	suite.Run(t, new(engineTest))
+}
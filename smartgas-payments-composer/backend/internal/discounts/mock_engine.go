@@ -0,0 +1,84 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package discounts
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockEngine is an autogenerated mock type for the Engine type
+type MockEngine struct {
+	mock.Mock
+}
+
+// Evaluate provides a mock function with given fields: _a0
+func (_m *MockEngine) Evaluate(_a0 Input) (*Result, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Evaluate")
+	}
+
+	var r0 *Result
+	var r1 error
+	if rf, ok := ret.Get(0).(func(Input) (*Result, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(Input) *Result); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Result)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(Input) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Policy provides a mock function with given fields:
+func (_m *MockEngine) Policy() (*Policy, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Policy")
+	}
+
+	var r0 *Policy
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (*Policy, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() *Policy); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Policy)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMockEngine creates a new instance of MockEngine. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockEngine(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockEngine {
+	mock := &MockEngine{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -1,5 +1,7 @@
 package dto
 
+import "smartgas-payment/internal/money"
+
 type CampaignCreateRequest struct {
 	Name         string   `json:"name"         validate:"required"                              binding:"required"`
 	Discount     *float64 `json:"discount"     validate:"required,gt=0"                         binding:"required,gt=0"`
@@ -9,6 +11,8 @@ type CampaignCreateRequest struct {
 	GasStations  *[]struct {
 		ID string `json:"id" binding:"required,uuid4"`
 	} `json:"gas_stations"                                                  binding:"omitempty,dive"`
+	CampaignTargetingRequest
+	CampaignBudgetRequest
 }
 
 type CampaignUpdatePathRequest struct {
@@ -28,4 +32,33 @@ type CampaignUpdateRequest struct {
 	GasStations        *[]struct {
 		ID string `json:"id" binding:"required,uuid4"`
 	} `json:"gas_stations"                                                   binding:"omitempty,dive"`
+	CampaignTargetingRequest
+	CampaignBudgetRequest
+}
+
+// CampaignTargetingRequest are the loads a campaign applies to, empty lists match
+// every load. Weekdays and hours are in the timezone of the station, to_hour is
+// excluded and the window wraps around midnight when from_hour is greater
+type CampaignTargetingRequest struct {
+	FuelTypes        *[]string `json:"fuel_types"        binding:"omitempty,dive,oneof=regular premium diesel"                                 example:"regular,premium"`
+	PaymentProviders *[]string `json:"payment_providers" binding:"omitempty,dive,oneof=stripe swit debit"                                      example:"stripe"`
+	Weekdays         *[]string `json:"weekdays"          binding:"omitempty,dive,oneof=sunday monday tuesday wednesday thursday friday saturday" example:"saturday,sunday"`
+	FromHour         *int      `json:"from_hour"         binding:"required_with=ToHour,omitempty,min=0,max=23"                                 example:"6"`
+	ToHour           *int      `json:"to_hour"           binding:"required_with=FromHour,omitempty,min=1,max=24"                               example:"10"`
+	Levels           *[]struct {
+		ID string `json:"id" binding:"required,uuid4"`
+	} `json:"levels"            binding:"omitempty,dive"`
+	FirstLoadOnly *bool         `json:"first_load_only"   description:"Only customers without served loads"`
+	MinAmount     *money.Amount `json:"min_amount"        binding:"omitempty,gte=0"                                                             swaggertype:"number" description:"Minimum amount of the load at the pump price"`
+	MinLiters     *float64      `json:"min_liters"        binding:"omitempty,gte=0"                                                             description:"Minimum liters of the load at the pump price"`
+	CodeOnly      *bool         `json:"code_only"         description:"Only loads sending one of its promo codes"`
+}
+
+// CampaignBudgetRequest are the optional caps of a campaign, it is deactivated once the
+// discount given or the liters discounted reach their budget
+type CampaignBudgetRequest struct {
+	BudgetAmount       *money.Amount `json:"budget_amount"         binding:"omitempty,gt=0" swaggertype:"number" description:"Total discount to give in pesos"`
+	BudgetLiters       *float64      `json:"budget_liters"         binding:"omitempty,gt=0"                      description:"Total liters to discount"`
+	MaxUsesPerCustomer *int          `json:"max_uses_per_customer" binding:"omitempty,gt=0"                      example:"1"`
+	MaxUsesPerDay      *int          `json:"max_uses_per_day"      binding:"omitempty,gt=0"                      description:"Uses per day in the timezone of the station"`
 }
@@ -2,6 +2,7 @@ package dto
 
 import (
 	"encoding/json"
+	"smartgas-payment/internal/money"
 	"time"
 )
 
@@ -38,6 +39,38 @@ type CampaignDetailResponse struct {
 		Name string `json:"name"`
 	} `json:"gas_stations"`
 	Active bool `json:"active"`
+	CampaignTargetingResponse
+	CampaignBudgetResponse
+}
+
+type CampaignTargetingResponse struct {
+	FuelTypes        []string `json:"fuel_types"`
+	PaymentProviders []string `json:"payment_providers"`
+	Weekdays         []string `json:"weekdays"`
+	FromHour         *int     `json:"from_hour"`
+	ToHour           *int     `json:"to_hour"`
+	Levels           []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"levels"`
+	FirstLoadOnly bool         `json:"first_load_only"`
+	MinAmount     money.Amount `json:"min_amount"      swaggertype:"number"`
+	MinLiters     float64      `json:"min_liters"`
+	CodeOnly      bool         `json:"code_only"`
+}
+
+// CampaignBudgetResponse are the caps of the campaign and its usage, budgets and
+// remainings are null when unlimited
+type CampaignBudgetResponse struct {
+	BudgetAmount       *money.Amount `json:"budget_amount"         swaggertype:"number"`
+	BudgetLiters       *float64      `json:"budget_liters"`
+	MaxUsesPerCustomer *int          `json:"max_uses_per_customer"`
+	MaxUsesPerDay      *int          `json:"max_uses_per_day"`
+	SpentAmount        money.Amount  `json:"spent_amount"          swaggertype:"number"`
+	SpentLiters        float64       `json:"spent_liters"`
+	Uses               int           `json:"uses"`
+	RemainingAmount    *money.Amount `json:"remaining_amount"      swaggertype:"number"`
+	RemainingLiters    *float64      `json:"remaining_liters"`
 }
 
 func (plr *CampaignDetailResponse) MarshalJSON() ([]byte, error) {
@@ -48,5 +81,18 @@ func (plr *CampaignDetailResponse) MarshalJSON() ([]byte, error) {
 		}, 0)
 	}
 
+	if plr.Levels == nil {
+		plr.Levels = make([]struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}, 0)
+	}
+
+	for _, list := range []*[]string{&plr.FuelTypes, &plr.PaymentProviders, &plr.Weekdays} {
+		if *list == nil {
+			*list = make([]string, 0)
+		}
+	}
+
 	return json.Marshal(*plr)
 }
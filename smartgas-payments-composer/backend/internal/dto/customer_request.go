@@ -3,3 +3,13 @@ package dto
 type CustomerDeleteCard struct {
 	CardID string `json:"card_id" validate:"required" binding:"required"`
 }
+
+type CustomerDefaultPaymentMethodRequest struct {
+	PaymentMethodID string `json:"payment_method_id" validate:"required" binding:"required" example:"pm_1NQ2pJ2eZvKYlo2CxV5zE8Pa"`
+}
+
+type CustomerPaymentListQueryRequest struct {
+	From         string `form:"from"           binding:"omitempty,datetime=2006-01-02" validate:"omitempty,datetime=2006-01-02" example:"2023-06-01"`
+	To           string `form:"to"             binding:"omitempty,datetime=2006-01-02" validate:"omitempty,datetime=2006-01-02" example:"2023-06-30"`
+	GasStationID string `form:"gas_station_id" binding:"omitempty,uuid4"               validate:"omitempty,uuid4"`
+}
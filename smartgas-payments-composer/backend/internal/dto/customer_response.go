@@ -1,9 +1,20 @@
 package dto
 
-import "smartgas-payment/internal/schemas"
+import (
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/schemas"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type ListCustomerPaymentMethodResponse []schemas.PaymentMethod
 
+type CustomerSetupIntentResponse struct {
+	ID           string `json:"id"`
+	ClientSecret string `json:"client_secret" description:"Client secret used to save the card"`
+}
+
 type ListAllCustomersResponse struct {
 	ID             string `json:"id"`
 	FirstName      string `json:"first_name"`
@@ -17,3 +28,33 @@ type CustomerLevelAssignedResponse struct {
 	Discount      float64 `json:"discount"`
 	LevelsEnabled bool    `json:"levels_enabled"`
 }
+
+type CustomerPaymentEventResponse struct {
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CustomerPaymentResponse struct {
+	ID                 uuid.UUID                     `json:"id"`
+	Amount             money.Amount                  `json:"amount"               swaggertype:"number"`
+	TotalLiter         float32                       `json:"total_liter"`
+	Price              float64                       `json:"price"`
+	FuelType           string                        `json:"fuel_type"`
+	PaymentProvider    string                        `json:"payment_provider"`
+	Status             string                        `json:"status"`
+	RealAmountReported money.Amount                  `json:"real_amount_reported" swaggertype:"number"`
+	RefundedAmount     money.Amount                  `json:"refunded_amount"      swaggertype:"number"`
+	ChargeFee          money.Amount                  `json:"charge_fee"           swaggertype:"number"`
+	GMPoints           float32                       `json:"gm_points"`
+	Invoiced           bool                          `json:"invoiced"`
+	Invoiceable        bool                          `json:"invoiceable"          description:"The load was served and it is not invoiced yet"`
+	CreatedAt          time.Time                     `json:"created_at"`
+	LastEvent          *CustomerPaymentEventResponse `json:"last_event"`
+	GasPump            struct {
+		Number     string `json:"number"`
+		GasStation struct {
+			ID   uuid.UUID `json:"id"`
+			Name string    `json:"name"`
+		} `json:"gas_station"`
+	} `json:"gas_pump"`
+}
@@ -0,0 +1,6 @@
+package dto
+
+type DisputeListQueryRequest struct {
+	Status    string `form:"status"     binding:"omitempty,oneof=warning_needs_response warning_under_review warning_closed needs_response under_review charge_refunded won lost" validate:"omitempty,oneof=warning_needs_response warning_under_review warning_closed needs_response under_review charge_refunded won lost"`
+	PaymentID string `form:"payment_id" binding:"omitempty,uuid4"                                                                                                                   validate:"omitempty,uuid4"`
+}
@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DisputeResponse struct {
+	ID              uuid.UUID    `json:"id"`
+	PaymentID       uuid.UUID    `json:"payment_id"`
+	StripeDisputeID string       `json:"stripe_dispute_id"`
+	Amount          money.Amount `json:"amount"            swaggertype:"number"`
+	Reason          string       `json:"reason"`
+	Status          string       `json:"status"`
+	EvidenceDueBy   *time.Time   `json:"evidence_due_by"`
+	ClosedAt        *time.Time   `json:"closed_at"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+	Payment         struct {
+		Amount          money.Amount `json:"amount"           swaggertype:"number"`
+		PaymentProvider string       `json:"payment_provider"`
+		Status          string       `json:"status"`
+		CreatedAt       time.Time    `json:"created_at"`
+		GasPump         struct {
+			ID         uuid.UUID `json:"id"`
+			Number     string    `json:"number"`
+			GasStation struct {
+				ID   uuid.UUID `json:"id"`
+				Name string    `json:"name"`
+			} `json:"gas_station"`
+		} `json:"gas_pump"`
+	} `json:"payment"`
+}
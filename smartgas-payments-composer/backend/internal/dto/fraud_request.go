@@ -0,0 +1,40 @@
+package dto
+
+import "smartgas-payment/internal/money"
+
+type FraudRuleRequest struct {
+	Type         string       `json:"type"           binding:"required,oneof=max_amount_per_load max_loads_per_day max_amount_per_day max_open_payments" validate:"required,oneof=max_amount_per_load max_loads_per_day max_amount_per_day max_open_payments"`
+	GasStationID string       `json:"gas_station_id" binding:"omitempty,uuid4"                                                                           validate:"omitempty,uuid4"                                                                description:"Only loads of the station are limited, every station when empty"`
+	MaxAmount    money.Amount `json:"max_amount"     binding:"required_if=Type max_amount_per_load,required_if=Type max_amount_per_day,gte=0"            validate:"required_if=Type max_amount_per_load,required_if=Type max_amount_per_day,gte=0" swaggertype:"number" description:"Pesos, for max_amount_per_load and max_amount_per_day"`
+	MaxCount     int          `json:"max_count"      binding:"required_if=Type max_loads_per_day,required_if=Type max_open_payments,gte=0"               validate:"required_if=Type max_loads_per_day,required_if=Type max_open_payments,gte=0"    description:"For max_loads_per_day and max_open_payments"`
+	Active       *bool        `json:"active"         binding:"required"                                                                                  validate:"required"`
+}
+
+type FraudPathRequest struct {
+	ID string `json:"id" uri:"id" binding:"required,uuid4" validate:"required,uuid4" example:"23ae8c18-4d7a-41a3-a148-8ae2d0a75690"`
+}
+
+type FraudRuleListQueryRequest struct {
+	Type         string `form:"type"           binding:"omitempty,oneof=max_amount_per_load max_loads_per_day max_amount_per_day max_open_payments" validate:"omitempty,oneof=max_amount_per_load max_loads_per_day max_amount_per_day max_open_payments"`
+	GasStationID string `form:"gas_station_id" binding:"omitempty,uuid4"                                                                            validate:"omitempty,uuid4"`
+}
+
+type FraudBlockRequest struct {
+	Type   string `json:"type"   binding:"required,oneof=customer card gift_card" validate:"required,oneof=customer card gift_card"`
+	Value  string `json:"value"  binding:"required,max=255"                       validate:"required,max=255" description:"Customer id, swit source id or stripe payment method id, or gift card key"`
+	Reason string `json:"reason" binding:"omitempty,max=255"                      validate:"omitempty,max=255"`
+}
+
+type FraudBlockListQueryRequest struct {
+	Type  string `form:"type"  binding:"omitempty,oneof=customer card gift_card" validate:"omitempty,oneof=customer card gift_card"`
+	Value string `form:"value" binding:"omitempty,max=255"                       validate:"omitempty,max=255"`
+}
+
+type FraudDecisionListQueryRequest struct {
+	Allowed      string `form:"allowed"        binding:"omitempty,oneof=true false"    validate:"omitempty,oneof=true false"`
+	Reason       string `form:"reason"         binding:"omitempty,max=50"              validate:"omitempty,max=50"              example:"max_loads_per_day"`
+	CustomerID   string `form:"customer_id"    binding:"omitempty,uuid4"               validate:"omitempty,uuid4"`
+	GasStationID string `form:"gas_station_id" binding:"omitempty,uuid4"               validate:"omitempty,uuid4"`
+	From         string `form:"from"           binding:"omitempty,datetime=2006-01-02" validate:"omitempty,datetime=2006-01-02" example:"2023-06-01"`
+	To           string `form:"to"             binding:"omitempty,datetime=2006-01-02" validate:"omitempty,datetime=2006-01-02" example:"2023-06-30"`
+}
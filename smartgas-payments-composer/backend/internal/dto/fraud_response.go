@@ -0,0 +1,54 @@
+package dto
+
+import (
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FraudGasStationResponse struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+type FraudCustomerResponse struct {
+	ID             uuid.UUID `json:"id"`
+	FirstName      string    `json:"first_name"`
+	FirstLastName  string    `json:"first_last_name"`
+	SecondLastName string    `json:"second_last_name"`
+}
+
+type FraudRuleResponse struct {
+	ID         uuid.UUID                `json:"id"`
+	Type       string                   `json:"type"`
+	GasStation *FraudGasStationResponse `json:"gas_station" description:"Null for the rules of every station"`
+	MaxAmount  money.Amount             `json:"max_amount"  swaggertype:"number"`
+	MaxCount   int                      `json:"max_count"`
+	Active     bool                     `json:"active"`
+	CreatedAt  time.Time                `json:"created_at"`
+	UpdatedAt  time.Time                `json:"updated_at"`
+}
+
+type FraudBlockResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	Value     string    `json:"value"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FraudDecisionResponse struct {
+	ID              uuid.UUID                `json:"id"`
+	Source          string                   `json:"source"`
+	Allowed         bool                     `json:"allowed"`
+	Reason          string                   `json:"reason"           description:"Rule or block type that rejected the load"`
+	FraudRuleID     *uuid.UUID               `json:"fraud_rule_id"`
+	PaymentProvider string                   `json:"payment_provider"`
+	Amount          money.Amount             `json:"amount"           swaggertype:"number"`
+	CardID          string                   `json:"card_id"`
+	GiftCardKey     string                   `json:"gift_card_key"`
+	CreatedAt       time.Time                `json:"created_at"`
+	GasStation      *FraudGasStationResponse `json:"gas_station"`
+	Customer        *FraudCustomerResponse   `json:"customer"`
+}
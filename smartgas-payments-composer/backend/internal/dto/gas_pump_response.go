@@ -43,8 +43,11 @@ type GasPumpGetDetailForCustomerResponse struct {
 		Neighborhood  string `json:"neighborhood"`
 		OutsideNumber string `json:"outside_number"`
 	} `json:"gas_station"`
-	DiscountType string `json:"discount_type"`
-	Campaign     *struct {
+	DiscountType     string             `json:"discount_type"      enums:"none,campaign,elegibility,combined"`
+	DiscountPerLiter float64            `json:"discount_per_liter" description:"Expected discount per liter, the targeting on the load and the cap per load are checked when quoting"`
+	Stacking         string             `json:"stacking"           enums:"best_of,additive,capped"`
+	Discounts        []DiscountResponse `json:"discounts"`
+	Campaign         *struct {
 		Name     string  `json:"name"`
 		Discount float64 `json:"discount"`
 	} `json:"campaign"`
@@ -9,6 +9,7 @@ type GasStationCreateRequest struct {
 	Name          string `json:"name" binding:"required,min=3,max=255" validate:"required,min=3,max=255" example:"Guerrero"`
 	Ip            string `json:"ip" binding:"required,ipv4" validate:"required,ipv4" example:"192.168.100.100"`
 	CrePermission string `json:"cre_permission" binding:"required" validate:"required" example:"PL/01/01..."`
+	Timezone      string `json:"timezone" binding:"omitempty,timezone" validate:"omitempty,timezone" example:"America/Mexico_City" description:"Used by reports, the timezone of the app when empty"`
 	Active        *bool  `json:"active" binding:"omitempty" validate:"omitempty" example:"true"`
 }
 
@@ -22,5 +23,6 @@ type GasStationUpdateRequest struct {
 	Name          string `json:"name" binding:"omitempty,min=3,max=255" validate:"omitempty,min=3,max=255" example:"Guerrero"`
 	Ip            string `json:"ip" binding:"omitempty,ipv4" validate:"omitempty,ipv4" example:"192.168.100.100"`
 	CrePermission string `json:"cre_permission" binding:"omitempty" validate:"omitempty" example:"PL/01/01..."`
+	Timezone      string `json:"timezone" binding:"omitempty,timezone" validate:"omitempty,timezone" example:"America/Mexico_City" description:"Used by reports, the timezone of the app when empty"`
 	Active        *bool  `json:"active" binding:"omitempty" validate:"omitempty" example:"true"`
 }
@@ -8,6 +8,7 @@ type GasStationListResponse struct {
 	Name          string    `json:"name" example:"Guerrero"`
 	Ip            string    `json:"ip" example:"192.168.100.100"`
 	CrePermission string    `json:"cre_permission"`
+	Timezone      string    `json:"timezone" example:"America/Mexico_City"`
 	Active        bool      `json:"active" example:"true"`
 }
 
@@ -17,6 +18,7 @@ type GasStationGetResponse struct {
 	Active        bool   `json:"active" example:"true"`
 	ExternalID    string `json:"external_id" example:"13"`
 	CrePermission string `json:"cre_permission"`
+	Timezone      string `json:"timezone" example:"America/Mexico_City"`
 }
 
 type GasStationCreateResponse struct {
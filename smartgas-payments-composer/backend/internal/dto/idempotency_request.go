@@ -0,0 +1,5 @@
+package dto
+
+type IdempotencyHeadersRequest struct {
+	Key string `json:"Idempotency-Key" header:"Idempotency-Key" binding:"omitempty,max=255" validate:"omitempty,max=255"`
+}
@@ -4,3 +4,8 @@ type PaginateRequest struct {
 	Page  int `json:"page" form:"page" binding:"omitempty,gte=1" validate:"gte=1" minimum:"1"`
 	Limit int `json:"limit" form:"limit" binding:"omitempty,gte=1,lte=100" validate:"gte=1,lte=100" minimum:"1" maximum:"100"`
 }
+
+type CursorPaginateRequest struct {
+	Cursor string `json:"cursor" form:"cursor" binding:"omitempty,max=255" validate:"omitempty,max=255"`
+	Limit  int    `json:"limit" form:"limit" binding:"omitempty,gte=1,lte=100" validate:"gte=1,lte=100" minimum:"1" maximum:"100"`
+}
@@ -7,3 +7,9 @@ type PaginationResponse struct {
 	TotalPages int   `json:"total_pages"`
 	Data       any   `json:"data" format:"array"`
 }
+
+type CursorPaginationResponse struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor" description:"Empty on the last page"`
+	Data       any    `json:"data" format:"array"`
+}
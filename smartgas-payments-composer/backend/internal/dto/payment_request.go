@@ -1,24 +1,41 @@
 package dto
 
+import "smartgas-payment/internal/money"
+
+// Money amounts are validated in centavos, gte=1000 is $10.00 MXN
+
 type CreatePaymentIntentRequest struct {
-	FuelType        string  `json:"fuel_type"        validate:"required,oneof=regular premium diesel"             binding:"required,oneof=regular premium diesel"`
-	Amount          float32 `json:"amount"           validate:"required_if=ChargeType by_total,omitempty,gte=10"  binding:"required_if=ChargeType by_total,omitempty,gte=10"`
-	TotalLiter      float32 `json:"total_liter"      validate:"required_if=ChargeType by_liter,omitempty,gte=0.5" binding:"required_if=ChargeType by_liter,omitempty,gte=0.5"`
-	ChargeType      string  `json:"charge_type"      validate:"required,oneof=by_liter by_total"                  binding:"required,oneof=by_liter by_total"`
-	GasPumpID       string  `json:"gas_pump_id"      validate:"required,uuid4"                                    binding:"required,uuid4"                                    example:"23ae8c18-4d7a-41a3-a148-8ae2d0a75690"`
-	PaymentProvider string  `json:"payment_provider" validate:"required,oneof=stripe swit debit"                  binding:"required,oneof=stripe swit debit"`
-	SourceID        string  `json:"source_id"                                                                     binding:"required_if=PaymentProvider swit"`
-	Last4           string  `json:"last_4"                                                                        binding:"required_if=PaymentProvider swit"`
-	Cvv             string  `json:"cvv"                                                                           binding:"required_if=PaymentProvider swit"`
+	FuelType                string       `json:"fuel_type"        validate:"required,oneof=regular premium diesel"             binding:"required,oneof=regular premium diesel"`
+	Amount                  money.Amount `json:"amount"           validate:"required_if=ChargeType by_total,omitempty,gte=1000" binding:"required_if=ChargeType by_total,omitempty,gte=1000" swaggertype:"number"`
+	TotalLiter              float32      `json:"total_liter"      validate:"required_if=ChargeType by_liter,omitempty,gte=0.5" binding:"required_if=ChargeType by_liter,omitempty,gte=0.5"`
+	ChargeType              string       `json:"charge_type"      validate:"required,oneof=by_liter by_total"                  binding:"required,oneof=by_liter by_total"`
+	GasPumpID               string       `json:"gas_pump_id"      validate:"required,uuid4"                                    binding:"required,uuid4"                                    example:"23ae8c18-4d7a-41a3-a148-8ae2d0a75690"`
+	PaymentProvider         string       `json:"payment_provider" validate:"required,oneof=stripe swit debit"                  binding:"required,oneof=stripe swit debit"`
+	SourceID                string       `json:"source_id"                                                                     binding:"required_if=PaymentProvider swit"`
+	Last4                   string       `json:"last_4"                                                                        binding:"required_if=PaymentProvider swit"`
+	Cvv                     string       `json:"cvv"                                                                           binding:"required_if=PaymentProvider swit"`
+	UseDefaultPaymentMethod bool         `json:"use_default_payment_method" binding:"excluded_unless=PaymentProvider stripe" description:"One tap load, the payment is confirmed with the default card of the customer"`
+	QuoteToken              string       `json:"quote_token"                description:"Token of a quote, the load is charged with the quoted terms"`
+	PromoCode               string       `json:"promo_code"                 binding:"omitempty,max=40" description:"Promo code reserved with the payment, it must be the quoted one when a quote token is sent"`
+}
+
+type PaymentQuoteRequest struct {
+	FuelType        string       `json:"fuel_type"        validate:"required,oneof=regular premium diesel"             binding:"required,oneof=regular premium diesel"`
+	Amount          money.Amount `json:"amount"           validate:"required_if=ChargeType by_total,omitempty,gte=1000" binding:"required_if=ChargeType by_total,omitempty,gte=1000" swaggertype:"number"`
+	TotalLiter      float32      `json:"total_liter"      validate:"required_if=ChargeType by_liter,omitempty,gte=0.5" binding:"required_if=ChargeType by_liter,omitempty,gte=0.5"`
+	ChargeType      string       `json:"charge_type"      validate:"required,oneof=by_liter by_total"                  binding:"required,oneof=by_liter by_total"`
+	GasPumpID       string       `json:"gas_pump_id"      validate:"required,uuid4"                                    binding:"required,uuid4"                                    example:"23ae8c18-4d7a-41a3-a148-8ae2d0a75690"`
+	PaymentProvider string       `json:"payment_provider" validate:"required,oneof=stripe swit debit"                  binding:"required,oneof=stripe swit debit"`
+	PromoCode       string       `json:"promo_code"       validate:"omitempty,max=40"                                  binding:"omitempty,max=40"`
 }
 
 type CreatePaymentIntentOperationRequest struct {
-	FuelType           string  `json:"fuel_type"            validate:"required,oneof=regular premium diesel" binding:"required,oneof=regular premium diesel"`
-	ChargeType         string  `json:"charge_type"          validate:"required,oneof=customer card_key"      binding:"required,oneof=customer card_key"`
-	Amount             float32 `json:"amount"               validate:"required,gte=10"                       binding:"required,gte=10"`
-	PumpNumber         string  `json:"pump_number"          validate:"required,len=2"                        binding:"required,len=2"`
-	ExternalCustomerID string  `json:"external_customer_id" validate:"required_if=ChargeType customer"       binding:"required_if=ChargeType customer"`
-	CardKey            string  `json:"card_key"             validate:"required_if=ChargeType card_key"       binding:"required_if=ChargeType card_key"`
+	FuelType           string       `json:"fuel_type"            validate:"required,oneof=regular premium diesel" binding:"required,oneof=regular premium diesel"`
+	ChargeType         string       `json:"charge_type"          validate:"required,oneof=customer card_key"      binding:"required,oneof=customer card_key"`
+	Amount             money.Amount `json:"amount"               validate:"required,gte=1000"                     binding:"required,gte=1000"                     swaggertype:"number"`
+	PumpNumber         string       `json:"pump_number"          validate:"required,len=2"                        binding:"required,len=2"`
+	ExternalCustomerID string       `json:"external_customer_id" validate:"required_if=ChargeType customer"       binding:"required_if=ChargeType customer"`
+	CardKey            string       `json:"card_key"             validate:"required_if=ChargeType card_key"       binding:"required_if=ChargeType card_key"`
 }
 
 type AddEventPathRequest struct {
@@ -26,14 +43,44 @@ type AddEventPathRequest struct {
 }
 
 type AddEventBodyRequest struct {
-	Type          string  `json:"type"           binding:"required,oneof=serving serving_paused served" validate:"required,oneof=serving serving_paused served"`
-	AmountCharged float32 `json:"amount_charged" binding:"required_if=Type served"                      validate:"required_if=Type served"`
+	Type          string       `json:"type"           binding:"required,oneof=serving serving_paused served" validate:"required,oneof=serving serving_paused served"`
+	AmountCharged money.Amount `json:"amount_charged" binding:"required_if=Type served"                      validate:"required_if=Type served"                      swaggertype:"number"`
 }
 
 type PaymentDetailCustomerPath struct {
 	ID string `json:"id" uri:"id" binding:"required,uuid4" validate:"required,uuid4" example:"23ae8c18-4d7a-41a3-a148-8ae2d0a75690"`
 }
 
+type PaymentListQueryRequest struct {
+	// Lookup in provider, id, customer name and station
+	Search          string `form:"search"           binding:"omitempty,max=255"                                                         validate:"omitempty,max=255"`
+	Status          string `form:"status"           binding:"omitempty,oneof=pending paid canceled failed"                              validate:"omitempty,oneof=pending paid canceled failed"`
+	LastEvent       string `form:"last_event"       binding:"omitempty,oneof=paid funds_reserved failed canceled pending serving serving_paused served partial_refund pump_ready internal_cancellation manual_action requires_action processing disputed dispute_won dispute_lost authorization_expired" validate:"omitempty,oneof=paid funds_reserved failed canceled pending serving serving_paused served partial_refund pump_ready internal_cancellation manual_action requires_action processing disputed dispute_won dispute_lost authorization_expired"`
+	PaymentProvider string `form:"payment_provider" binding:"omitempty,oneof=stripe swit debit"                                         validate:"omitempty,oneof=stripe swit debit"`
+	FuelType        string `form:"fuel_type"        binding:"omitempty,oneof=regular premium diesel"                                    validate:"omitempty,oneof=regular premium diesel"`
+	DiscountType    string `form:"discount_type"    binding:"omitempty,oneof=campaign elegibility promo_code combined none"             validate:"omitempty,oneof=campaign elegibility promo_code combined none"`
+	GasStationID    string `form:"gas_station_id"   binding:"omitempty,uuid4"                                                           validate:"omitempty,uuid4"`
+	GasPumpID       string `form:"gas_pump_id"      binding:"omitempty,uuid4"                                                           validate:"omitempty,uuid4"`
+	CustomerID      string `form:"customer_id"      binding:"omitempty,uuid4"                                                           validate:"omitempty,uuid4"`
+	EmployeeID      string `form:"employee_id"      binding:"omitempty,max=20"                                                          validate:"omitempty,max=20"`
+	FromOperations  *bool  `form:"from_operations"`
+	Invoiced        *bool  `form:"invoiced"`
+	From            string `form:"from"             binding:"omitempty,datetime=2006-01-02"                                             validate:"omitempty,datetime=2006-01-02"                                             example:"2023-06-01"`
+	To              string `form:"to"               binding:"omitempty,datetime=2006-01-02"                                             validate:"omitempty,datetime=2006-01-02"                                             example:"2023-06-30"`
+	MinAmount       string `form:"min_amount"       binding:"omitempty,numeric"                                                         validate:"omitempty,numeric"                                                         example:"100.00" swaggertype:"number"`
+	MaxAmount       string `form:"max_amount"       binding:"omitempty,numeric"                                                         validate:"omitempty,numeric"                                                         example:"500.00" swaggertype:"number"`
+	SortBy          string `form:"sort_by"          binding:"omitempty,oneof=created_at amount real_amount_reported total_liter status payment_provider fuel_type" validate:"omitempty,oneof=created_at amount real_amount_reported total_liter status payment_provider fuel_type"`
+	SortOrder       string `form:"sort_order"       binding:"omitempty,oneof=asc desc"                                                  validate:"omitempty,oneof=asc desc"`
+}
+
+type PaymentExportQueryRequest struct {
+	Format string `form:"format" binding:"omitempty,oneof=csv xlsx" validate:"omitempty,oneof=csv xlsx" example:"csv"`
+}
+
+type PaymentDetailPath struct {
+	ID string `json:"id" uri:"id" binding:"required,uuid4" validate:"required,uuid4" example:"23ae8c18-4d7a-41a3-a148-8ae2d0a75690"`
+}
+
 type PaymentDetailCustomerPathWebsocket struct {
 	ID string `json:"id" uri:"id" binding:"required,uuid4" validate:"required,uuid4" example:"23ae8c18-4d7a-41a3-a148-8ae2d0a75690"`
 }
@@ -53,6 +100,9 @@ type ResendInvoiceRequest struct {
 
 type DoPaymentActionRequest struct {
 	Action string `json:"action" binding:"required,oneof=refund preset" validate:"required,oneof=refund preset"`
+	// Amount to refund, everything left when omitted. Loads not served are always refunded entirely
+	Amount money.Amount `json:"amount" binding:"omitempty,gte=1"                   validate:"omitempty,gte=1"                   swaggertype:"number" example:"50.00"`
+	Reason string       `json:"reason" binding:"required_if=Action refund,max=255" validate:"required_if=Action refund,max=255" example:"Bomba despachó de menos"`
 }
 
 type DoPaymentActionRequestPath struct {
@@ -2,6 +2,7 @@ package dto
 
 import (
 	"encoding/json"
+	"smartgas-payment/internal/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,34 +13,60 @@ type PaymentWebsocketNotification struct {
 }
 
 type PaymentCrateIntentResponse struct {
-	ClientSecret string    `json:"client_secret,omitempty" description:"Client secret used to pay the rerquested charge of fuel"`
-	Amount       float64   `json:"amount"                  description:"the amount that is gonna be charged"`
-	TotalLiter   float64   `json:"total_liter"             description:"The total liter that are gonna be charged"`
-	ID           uuid.UUID `json:"id"`
+	ClientSecret   string       `json:"client_secret,omitempty" description:"Client secret used to pay the rerquested charge of fuel"`
+	Amount         money.Amount `json:"amount"                  description:"the amount that is gonna be charged" swaggertype:"number"`
+	TotalLiter     float64      `json:"total_liter"             description:"The total liter that are gonna be charged"`
+	ID             uuid.UUID    `json:"id"`
+	RequiresAction bool         `json:"requires_action"         description:"The client must authenticate the payment with the client secret"`
+}
+
+// DiscountResponse explains a discount found for a load
+type DiscountResponse struct {
+	Source           string    `json:"source"  enums:"campaign,elegibility,promo_code"`
+	ID               uuid.UUID `json:"id"`
+	Name             string    `json:"name"`
+	DiscountPerLiter float64   `json:"discount_per_liter"`
+	Applied          bool      `json:"applied" description:"Whether the stacking mode applied it"`
+}
+
+type PaymentQuoteResponse struct {
+	Price            float64            `json:"price"         description:"Price per liter with the discount applied"`
+	DiscountPerLiter float64            `json:"discount_per_liter"`
+	DiscountType     string             `json:"discount_type" enums:"none,campaign,elegibility,promo_code,combined"`
+	CampaignID       *uuid.UUID         `json:"campaign_id"`
+	LevelID          *uuid.UUID         `json:"level_id"`
+	PromoCodeID      *uuid.UUID         `json:"promo_code_id" description:"Null when the promo code sent was not kept by the stacking mode"`
+	Amount           money.Amount       `json:"amount"        description:"the amount that is gonna be charged" swaggertype:"number"`
+	TotalLiter       float64            `json:"total_liter"   description:"The total liter that are gonna be charged"`
+	Stacking         string             `json:"stacking"      enums:"best_of,additive,capped"`
+	Capped           string             `json:"capped"        description:"Cap that lowered the discount, per_liter or per_load"`
+	Discounts        []DiscountResponse `json:"discounts"`
+	QuoteToken       string             `json:"quote_token"   description:"Token to send to create intent in order to charge these terms"`
+	ExpiresAt        time.Time          `json:"expires_at"`
 }
 
 type PaymentCrateIntentOperationResponse struct {
-	Amount     float64   `json:"amount"      description:"the amount that is gonna be charged"`
-	TotalLiter float64   `json:"total_liter" description:"The total liter that are gonna be charged"`
-	ID         uuid.UUID `json:"id"`
+	Amount     money.Amount `json:"amount"      description:"the amount that is gonna be charged" swaggertype:"number"`
+	TotalLiter float64      `json:"total_liter" description:"The total liter that are gonna be charged"`
+	ID         uuid.UUID    `json:"id"`
 }
 
 type PaymentListResponse struct {
-	ID                    uuid.UUID `json:"id"`
-	ExternalTransactionID string    `json:"external_transaction_id"`
-	PaymentProvider       string    `json:"payment_provider"`
-	Amount                float32   `json:"amount"`
-	TotalLiter            float32   `json:"total_liter"`
-	Price                 float64   `json:"price"`
-	ChargeType            string    `json:"charge_type"`
-	FuelType              string    `json:"fuel_type"`
-	RefundedAmount        float32   `json:"refunded_amount"`
-	RealAmountReported    float32   `json:"real_amount_reported"`
-	DiscountPerLiter      float64   `json:"discount_per_liter"`
-	ChargeFee             float32   `json:"charge_fee"`
-	GMPoints              float32   `json:"gm_points"`
-	Status                string    `json:"status"`
-	CreatedAt             time.Time `json:"created_at"`
+	ID                    uuid.UUID    `json:"id"`
+	ExternalTransactionID string       `json:"external_transaction_id"`
+	PaymentProvider       string       `json:"payment_provider"`
+	Amount                money.Amount `json:"amount"               swaggertype:"number"`
+	TotalLiter            float32      `json:"total_liter"`
+	Price                 float64      `json:"price"`
+	ChargeType            string       `json:"charge_type"`
+	FuelType              string       `json:"fuel_type"`
+	RefundedAmount        money.Amount `json:"refunded_amount"      swaggertype:"number"`
+	RealAmountReported    money.Amount `json:"real_amount_reported" swaggertype:"number"`
+	DiscountPerLiter      float64      `json:"discount_per_liter"`
+	ChargeFee             money.Amount `json:"charge_fee"           swaggertype:"number"`
+	GMPoints              float32      `json:"gm_points"`
+	Status                string       `json:"status"`
+	CreatedAt             time.Time    `json:"created_at"`
 	Customer              struct {
 		ID             uuid.UUID `json:"id"`
 		FirstName      string    `json:"first_name"`
@@ -60,17 +87,87 @@ type PaymentListResponse struct {
 	} `json:"events"`
 }
 
+type PaymentEventResponse struct {
+	Type                  string    `json:"type"`
+	CreatedAt             time.Time `json:"created_at"`
+	AuthorizedApplication *struct {
+		ID              uuid.UUID `json:"id"`
+		ApplicationName string    `json:"application_name"`
+	} `json:"authorized_application"`
+	User *struct {
+		ID        uuid.UUID `json:"id"`
+		FirstName string    `json:"first_name"`
+		LastName  string    `json:"last_name"`
+		Email     string    `json:"email"`
+	} `json:"user"`
+}
+
+type PaymentDetailResponse struct {
+	ID                    uuid.UUID              `json:"id"`
+	ExternalTransactionID string                 `json:"external_transaction_id"`
+	PaymentProvider       string                 `json:"payment_provider"`
+	Amount                money.Amount           `json:"amount"                  swaggertype:"number"`
+	TotalLiter            float32                `json:"total_liter"`
+	Price                 float64                `json:"price"`
+	ChargeType            string                 `json:"charge_type"`
+	FuelType              string                 `json:"fuel_type"`
+	RefundedAmount        money.Amount           `json:"refunded_amount"         swaggertype:"number"`
+	RealAmountReported    money.Amount           `json:"real_amount_reported"    swaggertype:"number"`
+	DiscountPerLiter      float64                `json:"discount_per_liter"`
+	DiscountType          string                 `json:"discount_type"`
+	ChargeFee             money.Amount           `json:"charge_fee"              swaggertype:"number"`
+	Status                string                 `json:"status"`
+	FromOperations        *bool                  `json:"from_operations"`
+	SetByEmployeeID       *string                `json:"set_by_employee_id"`
+	GMPoints              float32                `json:"gm_points"`
+	GMID                  string                 `json:"gm_points_id"`
+	Invoiced              bool                   `json:"invoiced"`
+	InvoiceID             string                 `json:"invoice_id"`
+	CreatedAt             time.Time              `json:"created_at"`
+	UpdatedAt             time.Time              `json:"updated_at"`
+	Events                []PaymentEventResponse `json:"events"`
+	Refunds               []RefundResponse       `json:"refunds"`
+	Customer              *struct {
+		ID             uuid.UUID `json:"id"`
+		ExternalID     string    `json:"external_id"`
+		FirstName      string    `json:"first_name"`
+		FirstLastName  string    `json:"first_last_name"`
+		SecondLastName string    `json:"second_last_name"`
+		Email          string    `json:"email"`
+		PhoneNumber    string    `json:"phone_number"`
+	} `json:"customer"`
+	GasPump *struct {
+		ID         uuid.UUID `json:"id"`
+		Number     string    `json:"number"`
+		GasStation struct {
+			ID          uuid.UUID `json:"id"`
+			Name        string    `json:"name"`
+			LegalNameID string    `json:"legal_name_id"`
+		} `json:"gas_station"`
+	} `json:"gas_pump"`
+	Campaign *struct {
+		ID       uuid.UUID `json:"id"`
+		Name     string    `json:"name"`
+		Discount *float64  `json:"discount"`
+	} `json:"campaign"`
+	Level *struct {
+		ID       uuid.UUID `json:"id"`
+		Name     *string   `json:"name"`
+		Discount *float64  `json:"discount"`
+	} `json:"level"`
+}
+
 type PaymentDetailCustomer struct {
-	Amount              float32   `json:"amount"`
-	TotalLiter          float32   `json:"total_liter"`
-	Price               float64   `json:"price"`
-	FuelType            string    `json:"fuel_type"`
-	CreatedAt           time.Time `json:"created_at"`
-	RefundedAmount      float32   `json:"refunded_amount"`
-	RealAmountReported  float32   `json:"real_amount_reported"`
-	ChargeFee           float32   `json:"charge_fee"`
-	GMPoints            float32   `json:"gm_points"`
-	RealDiscountApplied float64   `json:"real_discount_applied"`
+	Amount              money.Amount `json:"amount"               swaggertype:"number"`
+	TotalLiter          float32      `json:"total_liter"`
+	Price               float64      `json:"price"`
+	FuelType            string       `json:"fuel_type"`
+	CreatedAt           time.Time    `json:"created_at"`
+	RefundedAmount      money.Amount `json:"refunded_amount"      swaggertype:"number"`
+	RealAmountReported  money.Amount `json:"real_amount_reported" swaggertype:"number"`
+	ChargeFee           money.Amount `json:"charge_fee"           swaggertype:"number"`
+	GMPoints            float32      `json:"gm_points"`
+	RealDiscountApplied float64      `json:"real_discount_applied"`
 	GasPump             struct {
 		Number     string `json:"number"`
 		GasStation struct {
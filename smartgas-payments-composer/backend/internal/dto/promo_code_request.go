@@ -0,0 +1,43 @@
+package dto
+
+type PromoCodeCreateRequest struct {
+	Code               string `json:"code"                  binding:"required,min=3,max=40,alphanum"      validate:"required,min=3,max=40,alphanum" example:"VERANO2024" description:"Not case sensitive"`
+	Type               string `json:"type"                  binding:"required,oneof=single_use multi_use" validate:"required,oneof=single_use multi_use"`
+	CampaignID         string `json:"campaign_id"           binding:"required,uuid4"                      validate:"required,uuid4"                 description:"Campaign giving the discount, its targeting and budget apply"`
+	CustomerID         string `json:"customer_id"           binding:"omitempty,uuid4"                     validate:"omitempty,uuid4"                description:"Only the customer can use it"`
+	MaxUses            *int   `json:"max_uses"              binding:"omitempty,gt=0"                      validate:"omitempty,gt=0"                 description:"Unlimited when empty, single use codes are used once"`
+	MaxUsesPerCustomer *int   `json:"max_uses_per_customer" binding:"omitempty,gt=0"                      validate:"omitempty,gt=0"                 description:"Unlimited when empty"`
+	Active             *bool  `json:"active"                binding:"required"                            validate:"required"`
+}
+
+// PromoCodeBatchRequest creates codes used once each, one for each customer when
+// customer_ids is sent, quantity codes for anyone otherwise
+type PromoCodeBatchRequest struct {
+	CampaignID  string   `json:"campaign_id"  binding:"required,uuid4"                                          validate:"required,uuid4"`
+	Prefix      string   `json:"prefix"       binding:"omitempty,max=20,alphanum"                               validate:"omitempty,max=20,alphanum" example:"VERANO"`
+	Quantity    int      `json:"quantity"     binding:"required_without=CustomerIDs,omitempty,gt=0,lte=1000"    validate:"required_without=CustomerIDs,omitempty,gt=0,lte=1000"`
+	CustomerIDs []string `json:"customer_ids" binding:"required_without=Quantity,omitempty,max=1000,dive,uuid4" validate:"required_without=Quantity,omitempty,max=1000,dive,uuid4"`
+	Active      *bool    `json:"active"       binding:"required"                                                validate:"required"`
+}
+
+type PromoCodeUpdateRequest struct {
+	MaxUses            *int  `json:"max_uses"              binding:"omitempty,gt=0" validate:"omitempty,gt=0" description:"Unlimited when empty, ignored by single use and batch codes"`
+	MaxUsesPerCustomer *int  `json:"max_uses_per_customer" binding:"omitempty,gt=0" validate:"omitempty,gt=0" description:"Unlimited when empty"`
+	Active             *bool `json:"active"                binding:"required"       validate:"required"`
+}
+
+type PromoCodePathRequest struct {
+	ID string `json:"id" uri:"id" binding:"required,uuid4" validate:"required,uuid4" example:"23ae8c18-4d7a-41a3-a148-8ae2d0a75690"`
+}
+
+type PromoCodeListQueryRequest struct {
+	Search     string `form:"search"      binding:"omitempty,max=40"                           validate:"omitempty,max=40"`
+	Type       string `form:"type"        binding:"omitempty,oneof=single_use multi_use batch" validate:"omitempty,oneof=single_use multi_use batch"`
+	CampaignID string `form:"campaign_id" binding:"omitempty,uuid4"                            validate:"omitempty,uuid4"`
+	BatchID    string `form:"batch_id"    binding:"omitempty,uuid4"                            validate:"omitempty,uuid4"`
+	CustomerID string `form:"customer_id" binding:"omitempty,uuid4"                            validate:"omitempty,uuid4"`
+}
+
+type PromoRedemptionListQueryRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=reserved redeemed released" validate:"omitempty,oneof=reserved redeemed released"`
+}
@@ -0,0 +1,64 @@
+package dto
+
+import (
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PromoCodeCampaignResponse struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Discount float64   `json:"discount"`
+}
+
+type PromoCodeCustomerResponse struct {
+	ID             uuid.UUID `json:"id"`
+	FirstName      string    `json:"first_name"`
+	FirstLastName  string    `json:"first_last_name"`
+	SecondLastName string    `json:"second_last_name"`
+	Email          string    `json:"email"`
+}
+
+type PromoCodeResponse struct {
+	ID                 uuid.UUID                  `json:"id"`
+	Code               string                     `json:"code"`
+	Type               string                     `json:"type"`
+	Campaign           *PromoCodeCampaignResponse `json:"campaign"`
+	BatchID            *uuid.UUID                 `json:"batch_id"`
+	Customer           *PromoCodeCustomerResponse `json:"customer"              description:"Null when anyone can use it"`
+	MaxUses            *int                       `json:"max_uses"              description:"Null when unlimited"`
+	MaxUsesPerCustomer *int                       `json:"max_uses_per_customer" description:"Null when unlimited"`
+	Uses               int                        `json:"uses"                  description:"Reserved and redeemed uses"`
+	Active             bool                       `json:"active"`
+	CreatedAt          time.Time                  `json:"created_at"`
+	UpdatedAt          time.Time                  `json:"updated_at"`
+}
+
+type PromoCodeBatchCodeResponse struct {
+	Code       string     `json:"code"`
+	CustomerID *uuid.UUID `json:"customer_id"`
+}
+
+type PromoCodeBatchResponse struct {
+	BatchID uuid.UUID                    `json:"batch_id"`
+	Codes   []PromoCodeBatchCodeResponse `json:"codes"`
+}
+
+type PromoRedemptionPaymentResponse struct {
+	ID                 uuid.UUID    `json:"id"`
+	Amount             money.Amount `json:"amount"               swaggertype:"number"`
+	RealAmountReported money.Amount `json:"real_amount_reported" swaggertype:"number"`
+	Status             string       `json:"status"`
+}
+
+type PromoRedemptionResponse struct {
+	ID               uuid.UUID                       `json:"id"`
+	Status           string                          `json:"status"             enums:"reserved,redeemed,released"`
+	DiscountPerLiter float64                         `json:"discount_per_liter" description:"Discount per liter of the payment"`
+	Customer         *PromoCodeCustomerResponse      `json:"customer"`
+	Payment          *PromoRedemptionPaymentResponse `json:"payment"`
+	CreatedAt        time.Time                       `json:"created_at"`
+	UpdatedAt        time.Time                       `json:"updated_at"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+type ReconciliationRunRequest struct {
+	From string `json:"from" binding:"required,datetime=2006-01-02" validate:"required,datetime=2006-01-02" example:"2023-06-01"`
+	To   string `json:"to"   binding:"required,datetime=2006-01-02" validate:"required,datetime=2006-01-02" example:"2023-06-30"`
+}
+
+type ReconciliationPathRequest struct {
+	ID string `json:"id" uri:"id" binding:"required,uuid4" validate:"required,uuid4" example:"23ae8c18-4d7a-41a3-a148-8ae2d0a75690"`
+}
+
+type ReconciliationDiscrepancyListQueryRequest struct {
+	Type            string `form:"type"             binding:"omitempty,oneof=missing_locally missing_remotely amount_mismatch refund_mismatch" validate:"omitempty,oneof=missing_locally missing_remotely amount_mismatch refund_mismatch"`
+	PaymentProvider string `form:"payment_provider" binding:"omitempty,oneof=stripe swit debit"                                                validate:"omitempty,oneof=stripe swit debit"`
+}
@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReconciliationResponse struct {
+	ID        uuid.UUID `json:"id"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Status    string    `json:"status"`
+	Matched   int       `json:"matched"`
+	Errors    string    `json:"errors"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ReconciliationDiscrepancyResponse struct {
+	ID                    uuid.UUID    `json:"id"`
+	Type                  string       `json:"type"`
+	PaymentProvider       string       `json:"payment_provider"`
+	ExternalTransactionID string       `json:"external_transaction_id"`
+	PaymentID             *uuid.UUID   `json:"payment_id"`
+	LocalAmount           money.Amount `json:"local_amount"            swaggertype:"number"`
+	RemoteAmount          money.Amount `json:"remote_amount"           swaggertype:"number"`
+	CreatedAt             time.Time    `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RefundResponse struct {
+	ID              uuid.UUID    `json:"id"`
+	Amount          money.Amount `json:"amount"           swaggertype:"number"`
+	Reason          string       `json:"reason"`
+	PaymentProvider string       `json:"payment_provider"`
+	Status          string       `json:"status"`
+	Error           string       `json:"error"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+	User            *struct {
+		ID        uuid.UUID `json:"id"`
+		FirstName string    `json:"first_name"`
+		LastName  string    `json:"last_name"`
+		Email     string    `json:"email"`
+	} `json:"user"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+type SalesReportQueryRequest struct {
+	From            string   `form:"from"             binding:"required,datetime=2006-01-02"                                 validate:"required,datetime=2006-01-02"                                 example:"2023-06-01"`
+	To              string   `form:"to"               binding:"required,datetime=2006-01-02"                                 validate:"required,datetime=2006-01-02"                                 example:"2023-06-30"`
+	Period          string   `form:"period"           binding:"omitempty,oneof=day week month"                               validate:"omitempty,oneof=day week month"                               example:"day"`
+	GroupBy         []string `form:"group_by"         binding:"omitempty,unique,dive,oneof=station pump fuel_type payment_provider" validate:"omitempty,unique,dive,oneof=station pump fuel_type payment_provider"`
+	GasStationID    string   `form:"gas_station_id"   binding:"omitempty,uuid4"                                              validate:"omitempty,uuid4"`
+	FuelType        string   `form:"fuel_type"        binding:"omitempty,oneof=regular premium diesel"                       validate:"omitempty,oneof=regular premium diesel"`
+	PaymentProvider string   `form:"payment_provider" binding:"omitempty,oneof=stripe swit debit"                            validate:"omitempty,oneof=stripe swit debit"`
+}
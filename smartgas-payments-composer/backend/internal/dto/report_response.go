@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"smartgas-payment/internal/money"
+)
+
+type SalesTotalsResponse struct {
+	Transactions int64        `json:"transactions"`
+	Amount       money.Amount `json:"amount"       swaggertype:"number"`
+	Liters       float64      `json:"liters"`
+	Refunds      money.Amount `json:"refunds"      swaggertype:"number"`
+	ChargeFees   money.Amount `json:"charge_fees"  swaggertype:"number"`
+	Discounts    money.Amount `json:"discounts"    swaggertype:"number"`
+}
+
+type SalesGroupResponse struct {
+	// First day of the period in the timezone of the stations
+	Period          string `json:"period,omitempty"           example:"2023-06-01"`
+	GasStationID    string `json:"gas_station_id,omitempty"`
+	GasStationName  string `json:"gas_station_name,omitempty"`
+	GasPumpID       string `json:"gas_pump_id,omitempty"`
+	GasPumpNumber   string `json:"gas_pump_number,omitempty"`
+	FuelType        string `json:"fuel_type,omitempty"`
+	PaymentProvider string `json:"payment_provider,omitempty"`
+	SalesTotalsResponse
+}
+
+type SalesReportResponse struct {
+	From    string               `json:"from"     example:"2023-06-01"`
+	To      string               `json:"to"       example:"2023-06-30"`
+	Period  string               `json:"period"   example:"day"`
+	GroupBy []string             `json:"group_by"`
+	Totals  SalesTotalsResponse  `json:"totals"`
+	Groups  []SalesGroupResponse `json:"groups"`
+}
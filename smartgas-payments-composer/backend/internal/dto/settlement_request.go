@@ -0,0 +1,21 @@
+package dto
+
+type SettlementGenerateRequest struct {
+	LegalNameID string `json:"legal_name_id" binding:"required,max=10"                validate:"required,max=10"                example:"1"`
+	From        string `json:"from"          binding:"required,datetime=2006-01-02" validate:"required,datetime=2006-01-02" example:"2023-06-01"`
+	To          string `json:"to"            binding:"required,datetime=2006-01-02" validate:"required,datetime=2006-01-02" example:"2023-06-15"`
+}
+
+type SettlementPathRequest struct {
+	ID string `json:"id" uri:"id" binding:"required,uuid4" validate:"required,uuid4" example:"23ae8c18-4d7a-41a3-a148-8ae2d0a75690"`
+}
+
+type SettlementListQueryRequest struct {
+	LegalNameID string `form:"legal_name_id" binding:"omitempty,max=10"           validate:"omitempty,max=10"           example:"1"`
+	Status      string `form:"status"        binding:"omitempty,oneof=open closed" validate:"omitempty,oneof=open closed"`
+}
+
+type SettlementLineListQueryRequest struct {
+	Type            string `form:"type"             binding:"omitempty,oneof=payment adjustment" validate:"omitempty,oneof=payment adjustment"`
+	PaymentProvider string `form:"payment_provider" binding:"omitempty,oneof=stripe swit debit"  validate:"omitempty,oneof=stripe swit debit"`
+}
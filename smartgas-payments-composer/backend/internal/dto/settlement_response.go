@@ -0,0 +1,69 @@
+package dto
+
+import (
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SettlementResponse struct {
+	ID           uuid.UUID    `json:"id"`
+	LegalNameID  string       `json:"legal_name_id"`
+	From         string       `json:"from"          example:"2023-06-01"`
+	To           string       `json:"to"            example:"2023-06-15" description:"Last day of the period, included"`
+	Status       string       `json:"status"`
+	Captured     money.Amount `json:"captured"      swaggertype:"number"`
+	Refunds      money.Amount `json:"refunds"       swaggertype:"number"`
+	ProviderFees money.Amount `json:"provider_fees" swaggertype:"number"`
+	Net          money.Amount `json:"net"           swaggertype:"number"`
+	Payments     int          `json:"payments"`
+	Adjustments  int          `json:"adjustments"`
+	ClosedAt     *time.Time   `json:"closed_at"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+	ClosedBy     *struct {
+		ID        uuid.UUID `json:"id"`
+		FirstName string    `json:"first_name"`
+		LastName  string    `json:"last_name"`
+		Email     string    `json:"email"`
+	} `json:"closed_by"`
+}
+
+type SettlementProviderTotalsResponse struct {
+	PaymentProvider string       `json:"payment_provider"`
+	Captured        money.Amount `json:"captured"         swaggertype:"number"`
+	Refunds         money.Amount `json:"refunds"          swaggertype:"number"`
+	ProviderFees    money.Amount `json:"provider_fees"    swaggertype:"number"`
+	Net             money.Amount `json:"net"              swaggertype:"number"`
+	Count           int          `json:"count"`
+}
+
+type SettlementDetailResponse struct {
+	SettlementResponse
+	Providers []SettlementProviderTotalsResponse `json:"providers"`
+}
+
+type SettlementLineResponse struct {
+	ID              uuid.UUID    `json:"id"`
+	PaymentID       uuid.UUID    `json:"payment_id"`
+	Type            string       `json:"type"`
+	PaymentProvider string       `json:"payment_provider"`
+	Captured        money.Amount `json:"captured"         swaggertype:"number"`
+	Refunds         money.Amount `json:"refunds"          swaggertype:"number"`
+	ProviderFee     money.Amount `json:"provider_fee"     swaggertype:"number"`
+	Net             money.Amount `json:"net"              swaggertype:"number"`
+	Payment         struct {
+		ExternalTransactionID string       `json:"external_transaction_id"`
+		RealAmountReported    money.Amount `json:"real_amount_reported"    swaggertype:"number"`
+		RefundedAmount        money.Amount `json:"refunded_amount"         swaggertype:"number"`
+		CreatedAt             time.Time    `json:"created_at"`
+		GasPump               struct {
+			Number     string `json:"number"`
+			GasStation struct {
+				ID   uuid.UUID `json:"id"`
+				Name string    `json:"name"`
+			} `json:"gas_station"`
+		} `json:"gas_pump"`
+	} `json:"payment"`
+}
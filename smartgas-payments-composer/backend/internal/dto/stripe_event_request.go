@@ -0,0 +1,10 @@
+package dto
+
+type StripeEventPathRequest struct {
+	ID string `json:"id" uri:"id" binding:"required,startswith=evt_,max=255" validate:"required,startswith=evt_,max=255" example:"evt_1NG8Du2eZvKYlo2CUI79vXWy"`
+}
+
+type StripeEventListQueryRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending processed failed ignored" validate:"omitempty,oneof=pending processed failed ignored"`
+	Type   string `form:"type"   binding:"omitempty,max=255"                               validate:"omitempty,max=255"                               example:"payment_intent.succeeded"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type StripeEventResponse struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error"`
+	Attempts    int        `json:"attempts"`
+	ProcessedAt *time.Time `json:"processed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type StripeEventDetailResponse struct {
+	StripeEventResponse
+	Payload json.RawMessage `json:"payload" swaggertype:"object"`
+}
@@ -10,6 +10,12 @@ const (
 	ViewSynchronizations = "view_synchronizations"
 	AddSynchronization   = "add_synchronization"
 
+	ViewReconciliations = "view_reconciliations"
+	AddReconciliation   = "add_reconciliation"
+
+	ViewStripeEvents  = "view_stripe_events"
+	ReplayStripeEvent = "replay_stripe_event"
+
 	ViewGasStations = "view_gas_stations"
 	EditGasStation  = "edit_gas_station"
 	AddGasStation   = "add_gas_station"
@@ -20,12 +26,25 @@ const (
 
 	ViewPayments = "view_payments"
 
+	ViewReports = "view_reports"
+
+	ViewSettlements = "view_settlements"
+	AddSettlement   = "add_settlement"
+	CloseSettlement = "close_settlement"
+
 	CanDoActionsPayments = "can_do_payment_actions"
 
+	ViewFraudRules     = "view_fraud_rules"
+	EditFraudRules     = "edit_fraud_rules"
+	ViewFraudDecisions = "view_fraud_decisions"
+
 	ViewCampaigns = "view_campaigns"
 	AddCampaign   = "add_campaign"
 	EditCampaign  = "edit_campaign"
 
+	ViewPromoCodes = "view_promo_codes"
+	EditPromoCodes = "edit_promo_codes"
+
 	ViewElegibilityLevels = "view_elegibility_levels"
 	AddElegibilityLevel   = "add_elegibility_level"
 	EditElegibilityLevel  = "edit_elegibility_level"
@@ -7,6 +7,7 @@ import (
 	"smartgas-payment/api/v1/routes"
 	"smartgas-payment/config"
 	"smartgas-payment/internal/database"
+	"smartgas-payment/internal/discounts"
 	"smartgas-payment/internal/middlewares"
 	"smartgas-payment/internal/repository"
 	"smartgas-payment/internal/server/app"
@@ -95,6 +96,74 @@ func ProvideDebitServiceMock() *services.MockDebitService {
 	return &services.MockDebitService{}
 }
 
+func ProvidePaymentProviderRegistryMock() *services.MockPaymentProviderRegistry {
+	return &services.MockPaymentProviderRegistry{}
+}
+
+func ProvideIdempotencyRepositoryMock() *repository.MockIdempotencyRepository {
+	return &repository.MockIdempotencyRepository{}
+}
+
+func ProvideOutboxTaskMock() *tasks.MockOutboxTask {
+	return &tasks.MockOutboxTask{}
+}
+
+func ProvideReconciliationRepositoryMock() *repository.MockReconciliationRepository {
+	return &repository.MockReconciliationRepository{}
+}
+
+func ProvideReconciliationTaskMock() *tasks.MockReconciliationTask {
+	return &tasks.MockReconciliationTask{}
+}
+
+func ProvideStripeEventRepositoryMock() *repository.MockStripeEventRepository {
+	return &repository.MockStripeEventRepository{}
+}
+
+func ProvideStripeWebhookTaskMock() *tasks.MockStripeWebhookTask {
+	return &tasks.MockStripeWebhookTask{}
+}
+
+func ProvideDisputeRepositoryMock() *repository.MockDisputeRepository {
+	return &repository.MockDisputeRepository{}
+}
+
+func ProvideRefundRepositoryMock() *repository.MockRefundRepository {
+	return &repository.MockRefundRepository{}
+}
+
+func ProvideReportRepositoryMock() *repository.MockReportRepository {
+	return &repository.MockReportRepository{}
+}
+
+func ProvideSettlementRepositoryMock() *repository.MockSettlementRepository {
+	return &repository.MockSettlementRepository{}
+}
+
+func ProvideSettlementTaskMock() *tasks.MockSettlementTask {
+	return &tasks.MockSettlementTask{}
+}
+
+func ProvideReceiptServiceMock() *services.MockReceiptService {
+	return &services.MockReceiptService{}
+}
+
+func ProvideFraudRepositoryMock() *repository.MockFraudRepository {
+	return &repository.MockFraudRepository{}
+}
+
+func ProvideFraudTaskMock() *tasks.MockFraudTask {
+	return &tasks.MockFraudTask{}
+}
+
+func ProvideDiscountEngineMock() *discounts.MockEngine {
+	return &discounts.MockEngine{}
+}
+
+func ProvidePromoCodeRepositoryMock() *repository.MockPromoCodeRepository {
+	return &repository.MockPromoCodeRepository{}
+}
+
 var MockSet = wire.NewSet(
 	ProvideUserRepositoryMock,
 	ProvideGasStationRepositoryMock,
@@ -115,6 +184,23 @@ var MockSet = wire.NewSet(
 	ProvideCampaignRepositoryMock,
 	ProvideElegibilityRepositoryMock,
 	ProvideDebitServiceMock,
+	ProvidePaymentProviderRegistryMock,
+	ProvideIdempotencyRepositoryMock,
+	ProvideOutboxTaskMock,
+	ProvideReconciliationRepositoryMock,
+	ProvideReconciliationTaskMock,
+	ProvideStripeEventRepositoryMock,
+	ProvideStripeWebhookTaskMock,
+	ProvideDisputeRepositoryMock,
+	ProvideRefundRepositoryMock,
+	ProvideReportRepositoryMock,
+	ProvideSettlementRepositoryMock,
+	ProvideSettlementTaskMock,
+	ProvideReceiptServiceMock,
+	ProvideFraudRepositoryMock,
+	ProvideFraudTaskMock,
+	ProvideDiscountEngineMock,
+	ProvidePromoCodeRepositoryMock,
 
 	wire.Bind(new(repository.UserRepository), new(*repository.MockUserRepository)),
 	wire.Bind(new(repository.GasStationRepository), new(*repository.MockGasStationRepository)),
@@ -138,6 +224,32 @@ var MockSet = wire.NewSet(
 	wire.Bind(new(repository.CampaignRepository), new(*repository.MockCampaignRepository)),
 	wire.Bind(new(repository.ElegibilityRepository), new(*repository.MockElegibilityRepository)),
 	wire.Bind(new(services.DebitService), new(*services.MockDebitService)),
+	wire.Bind(
+		new(services.PaymentProviderRegistry),
+		new(*services.MockPaymentProviderRegistry),
+	),
+	wire.Bind(
+		new(repository.IdempotencyRepository),
+		new(*repository.MockIdempotencyRepository),
+	),
+	wire.Bind(new(tasks.OutboxTask), new(*tasks.MockOutboxTask)),
+	wire.Bind(
+		new(repository.ReconciliationRepository),
+		new(*repository.MockReconciliationRepository),
+	),
+	wire.Bind(new(tasks.ReconciliationTask), new(*tasks.MockReconciliationTask)),
+	wire.Bind(new(repository.StripeEventRepository), new(*repository.MockStripeEventRepository)),
+	wire.Bind(new(tasks.StripeWebhookTask), new(*tasks.MockStripeWebhookTask)),
+	wire.Bind(new(repository.DisputeRepository), new(*repository.MockDisputeRepository)),
+	wire.Bind(new(repository.RefundRepository), new(*repository.MockRefundRepository)),
+	wire.Bind(new(repository.ReportRepository), new(*repository.MockReportRepository)),
+	wire.Bind(new(repository.SettlementRepository), new(*repository.MockSettlementRepository)),
+	wire.Bind(new(tasks.SettlementTask), new(*tasks.MockSettlementTask)),
+	wire.Bind(new(services.ReceiptService), new(*services.MockReceiptService)),
+	wire.Bind(new(repository.FraudRepository), new(*repository.MockFraudRepository)),
+	wire.Bind(new(tasks.FraudTask), new(*tasks.MockFraudTask)),
+	wire.Bind(new(discounts.Engine), new(*discounts.MockEngine)),
+	wire.Bind(new(repository.PromoCodeRepository), new(*repository.MockPromoCodeRepository)),
 )
 
 type App struct {
@@ -159,8 +271,8 @@ type AppWithMock struct {
 	GasPumpRepositoryMock         *repository.MockGasPumpRepository
 	PaymentRepositoryMock         *repository.MockPaymentRepository
 	CustomerRepositoryMock        *repository.MockCustomerRepository
-	extCustomerService            *services.MockCustomerService
-	stripeServiceMock             *services.MockStripeService
+	ExtCustomerService            *services.MockCustomerService
+	StripeServiceMock             *services.MockStripeService
 	socioSmartServiceMock         *services.MockSocioSmartService
 	synchronizationTaskMock       *tasks.MockSynchronizationTask
 	synchronizationRepositoryMock *repository.MockSynchronizationRepository
@@ -169,10 +281,27 @@ type AppWithMock struct {
 	switServiceMock               *services.MockSwitService
 	invoicingServiceMock          *services.MockInvoicingService
 	mailServiceMock               *services.MockMailService
-	settingRepositoryMock         *repository.MockSettingRepository
+	SettingRepositoryMock         *repository.MockSettingRepository
 	campaignRepositoryMock        *repository.MockCampaignRepository
 	elebilityRepositoryMock       *repository.MockElegibilityRepository
 	debitServiceMock              *services.MockDebitService
+	PaymentProviderRegistryMock   *services.MockPaymentProviderRegistry
+	idempotencyRepositoryMock     *repository.MockIdempotencyRepository
+	OutboxTaskMock                *tasks.MockOutboxTask
+	reconciliationRepositoryMock  *repository.MockReconciliationRepository
+	reconciliationTaskMock        *tasks.MockReconciliationTask
+	stripeEventRepositoryMock     *repository.MockStripeEventRepository
+	stripeWebhookTaskMock         *tasks.MockStripeWebhookTask
+	disputeRepositoryMock         *repository.MockDisputeRepository
+	RefundRepositoryMock          *repository.MockRefundRepository
+	reportRepositoryMock          *repository.MockReportRepository
+	settlementRepositoryMock      *repository.MockSettlementRepository
+	settlementTaskMock            *tasks.MockSettlementTask
+	ReceiptServiceMock            *services.MockReceiptService
+	fraudRepositoryMock           *repository.MockFraudRepository
+	FraudTaskMock                 *tasks.MockFraudTask
+	discountEngineMock            *discounts.MockEngine
+	promoCodeRepositoryMock       *repository.MockPromoCodeRepository
 }
 
 func ProvideAppWithMock(router *gin.Engine,
@@ -195,6 +324,23 @@ func ProvideAppWithMock(router *gin.Engine,
 	campaignRepositoryMock *repository.MockCampaignRepository,
 	elebilityRepositoryMock *repository.MockElegibilityRepository,
 	debitServiceMock *services.MockDebitService,
+	paymentProviderRegistryMock *services.MockPaymentProviderRegistry,
+	idempotencyRepositoryMock *repository.MockIdempotencyRepository,
+	outboxTaskMock *tasks.MockOutboxTask,
+	reconciliationRepositoryMock *repository.MockReconciliationRepository,
+	reconciliationTaskMock *tasks.MockReconciliationTask,
+	stripeEventRepositoryMock *repository.MockStripeEventRepository,
+	stripeWebhookTaskMock *tasks.MockStripeWebhookTask,
+	disputeRepositoryMock *repository.MockDisputeRepository,
+	refundRepositoryMock *repository.MockRefundRepository,
+	reportRepositoryMock *repository.MockReportRepository,
+	settlementRepositoryMock *repository.MockSettlementRepository,
+	settlementTaskMock *tasks.MockSettlementTask,
+	receiptServiceMock *services.MockReceiptService,
+	fraudRepositoryMock *repository.MockFraudRepository,
+	fraudTaskMock *tasks.MockFraudTask,
+	discountEngineMock *discounts.MockEngine,
+	promoCodeRepositoryMock *repository.MockPromoCodeRepository,
 ) *AppWithMock {
 	return &AppWithMock{
 		Router:                        router,
@@ -203,8 +349,8 @@ func ProvideAppWithMock(router *gin.Engine,
 		GasPumpRepositoryMock:         gasPumpRepository,
 		PaymentRepositoryMock:         paymentRepository,
 		CustomerRepositoryMock:        customerRepository,
-		extCustomerService:            extCustomerService,
-		stripeServiceMock:             stripeServiceMock,
+		ExtCustomerService:            extCustomerService,
+		StripeServiceMock:             stripeServiceMock,
 		socioSmartServiceMock:         socioSmartServiceMock,
 		synchronizationTaskMock:       synchronizationTaskMock,
 		synchronizationRepositoryMock: synchronizationRepositoryMock,
@@ -213,10 +359,27 @@ func ProvideAppWithMock(router *gin.Engine,
 		switServiceMock:               switServiceMock,
 		invoicingServiceMock:          invoicingServiceMock,
 		mailServiceMock:               mailServiceMock,
-		settingRepositoryMock:         settingRepositoryMock,
+		SettingRepositoryMock:         settingRepositoryMock,
 		campaignRepositoryMock:        campaignRepositoryMock,
 		elebilityRepositoryMock:       elebilityRepositoryMock,
 		debitServiceMock:              debitServiceMock,
+		PaymentProviderRegistryMock:   paymentProviderRegistryMock,
+		idempotencyRepositoryMock:     idempotencyRepositoryMock,
+		OutboxTaskMock:                outboxTaskMock,
+		reconciliationRepositoryMock:  reconciliationRepositoryMock,
+		reconciliationTaskMock:        reconciliationTaskMock,
+		stripeEventRepositoryMock:     stripeEventRepositoryMock,
+		stripeWebhookTaskMock:         stripeWebhookTaskMock,
+		disputeRepositoryMock:         disputeRepositoryMock,
+		RefundRepositoryMock:          refundRepositoryMock,
+		reportRepositoryMock:          reportRepositoryMock,
+		settlementRepositoryMock:      settlementRepositoryMock,
+		settlementTaskMock:            settlementTaskMock,
+		ReceiptServiceMock:            receiptServiceMock,
+		fraudRepositoryMock:           fraudRepositoryMock,
+		FraudTaskMock:                 fraudTaskMock,
+		discountEngineMock:            discountEngineMock,
+		promoCodeRepositoryMock:       promoCodeRepositoryMock,
 	}
 }
 
@@ -228,6 +391,7 @@ func InitializeServer() (*App, error) {
 		services.ServicesSet,
 		repository.RepositorySet,
 		tasks.TasksSet,
+		discounts.DiscountsSet,
 		controllers.ControllersSet,
 		routes.RoutesSet,
 		middlewares.MiddlewaresSet,
@@ -249,6 +413,42 @@ func InitializeSynchronizationTask() (tasks.SynchronizationTask, error) {
 	return nil, nil
 }
 
+func InitializeOutboxTask() (tasks.OutboxTask, error) {
+	wire.Build(
+		config.NewConfig,
+		database.ConnectDB,
+		services.ServicesSet,
+		repository.RepositorySet,
+		tasks.TasksSet,
+	)
+
+	return nil, nil
+}
+
+func InitializeReservationSweeperTask() (tasks.ReservationSweeperTask, error) {
+	wire.Build(
+		config.NewConfig,
+		database.ConnectDB,
+		services.ServicesSet,
+		repository.RepositorySet,
+		tasks.TasksSet,
+	)
+
+	return nil, nil
+}
+
+func InitializeReconciliationTask() (tasks.ReconciliationTask, error) {
+	wire.Build(
+		config.NewConfig,
+		database.ConnectDB,
+		services.ServicesSet,
+		repository.RepositorySet,
+		tasks.TasksSet,
+	)
+
+	return nil, nil
+}
+
 func InitializeDB() (*gorm.DB, error) {
 	wire.Build(
 		config.NewConfig,
@@ -291,3 +491,25 @@ func InitializeServerWithMocks() (*AppWithMock, error) {
 
 	return &AppWithMock{}, nil
 }
+
+func InitializeStripeWebhookTask() (tasks.StripeWebhookTask, error) {
+	wire.Build(
+		config.NewConfig,
+		database.ConnectDB,
+		services.ServicesSet,
+		repository.RepositorySet,
+		tasks.TasksSet,
+	)
+
+	return nil, nil
+}
+
+func InitializeStripeEventRepository() (repository.StripeEventRepository, error) {
+	wire.Build(
+		config.NewConfig,
+		database.ConnectDB,
+		repository.RepositorySet,
+	)
+
+	return nil, nil
+}
@@ -14,6 +14,7 @@ import (
 	"smartgas-payment/api/v1/routes"
 	"smartgas-payment/config"
 	"smartgas-payment/internal/database"
+	"smartgas-payment/internal/discounts"
 	"smartgas-payment/internal/middlewares"
 	"smartgas-payment/internal/repository"
 	"smartgas-payment/internal/server/app"
@@ -48,22 +49,39 @@ func InitializeServer() (*App, error) {
 	synchronizationTask := tasks.ProvideSynchronizationTask(gasStationRepository, gasPumpRepository, socioSmartService, synchronizationRepository, elegibilityRepository, customerRepository, paymentRepository)
 	gasStationController := controllers.ProvideGasStationProvider(gasStationRepository, synchronizationTask)
 	gasStationRoutes := routes.ProvideGasStationRoutes(gasStationController, authMiddleware)
-	campaignRepository := repository.ProvidePromotionRepository(db)
 	settingRepository := repository.ProvideSettingRepository(db)
-	gasPumpController := controllers.ProvideGasPumpProvider(gasPumpRepository, synchronizationTask, campaignRepository, settingRepository)
+	campaignRepository := repository.ProvidePromotionRepository(db)
+	promoCodeRepository := repository.ProvidePromoCodeRepository(db)
+	engine := discounts.ProvideEngine(settingRepository, campaignRepository, elegibilityRepository, paymentRepository, promoCodeRepository)
+	gasPumpController := controllers.ProvideGasPumpProvider(gasPumpRepository, synchronizationTask, engine)
 	customerService := services.ProvideCustomerService(configConfig)
 	stripeService := services.ProvideStripeService()
 	switService := services.ProvideSwitService(configConfig)
 	customerAuthMiddleware := middlewares.ProvideCustomerAUthMiddleware(customerRepository, customerService, stripeService, switService, elegibilityRepository)
 	gasPumpRoutes := routes.ProvideGasPumpRoutes(gasPumpController, authMiddleware, customerAuthMiddleware)
+	stripeProvider := services.ProvideStripeProvider(stripeService)
+	switProvider := services.ProvideSwitProvider(switService)
+	debitService := services.ProvideDebitService(configConfig)
+	debitProvider := services.ProvideDebitProvider(debitService)
+	paymentProviderRegistry := services.ProvidePaymentProviderRegistry(stripeProvider, switProvider, debitProvider)
 	invoicingService := services.ProvideInvoicingService(configConfig, settingRepository)
+	receiptService := services.ProvideReceiptService()
+	outboxRepository := repository.ProvideOutboxRepository(db)
 	mailService := services.ProvideMailService(configConfig)
-	debitService := services.ProvideDebitService(configConfig)
-	paymentController := controllers.ProvidePaymentController(paymentRepository, gasPumpRepository, stripeService, configConfig, socioSmartService, switService, invoicingService, mailService, settingRepository, campaignRepository, elegibilityRepository, debitService, customerRepository)
+	outboxTask := tasks.ProvideOutboxTask(outboxRepository, paymentRepository, campaignRepository, socioSmartService, mailService, receiptService, paymentProviderRegistry)
+	stripeEventRepository := repository.ProvideStripeEventRepository(db)
+	disputeRepository := repository.ProvideDisputeRepository(db)
+	stripeWebhookTask := tasks.ProvideStripeWebhookTask(stripeEventRepository, paymentRepository, settingRepository, disputeRepository, stripeService, outboxTask)
+	fraudRepository := repository.ProvideFraudRepository(db)
+	fraudTask := tasks.ProvideFraudTask(fraudRepository, stripeService)
+	refundRepository := repository.ProvideRefundRepository(db)
+	paymentController := controllers.ProvidePaymentController(paymentRepository, gasPumpRepository, paymentProviderRegistry, configConfig, socioSmartService, invoicingService, receiptService, outboxTask, stripeWebhookTask, fraudTask, refundRepository, settingRepository, engine, customerRepository)
 	securityRepository := repository.ProvideSecurityRepository(db)
 	securityMiddleware := middlewares.ProvideSecurityMiddleware(securityRepository, gasStationRepository, socioSmartService)
-	paymentRoutes := routes.ProvidePaymenRoutes(customerAuthMiddleware, paymentController, authMiddleware, securityMiddleware)
-	customerController := controllers.ProvideCustomerController(stripeService, switService, configConfig, settingRepository, customerRepository, elegibilityRepository)
+	idempotencyRepository := repository.ProvideIdempotencyRepository(db)
+	idempotencyMiddleware := middlewares.ProvideIdempotencyMiddleware(idempotencyRepository)
+	paymentRoutes := routes.ProvidePaymenRoutes(customerAuthMiddleware, paymentController, authMiddleware, securityMiddleware, idempotencyMiddleware)
+	customerController := controllers.ProvideCustomerController(stripeService, switService, configConfig, settingRepository, customerRepository, elegibilityRepository, paymentRepository, engine)
 	customerRoutes := routes.ProvideCustomerRoutes(customerAuthMiddleware, customerController, authMiddleware)
 	synchronizationController := controllers.ProvideSynchronizationController(synchronizationRepository, synchronizationTask)
 	synchronizationRoute := routes.ProvideSynchronizationRoutes(synchronizationController, authMiddleware)
@@ -76,9 +94,28 @@ func InitializeServer() (*App, error) {
 	campaignRoutes := routes.ProvideCampaingRoutes(campaignController, authMiddleware)
 	elegibilityController := controllers.ProvideElegibityController(elegibilityRepository)
 	elebilityRoutes := routes.ProvideElebilityRoutes(authMiddleware, elegibilityController)
-	routesRoutes := routes.ProvideV1Routes(userRoutes, authRoutes, gasStationRoutes, gasPumpRoutes, paymentRoutes, customerRoutes, synchronizationRoute, permissionRoutes, settingRoutes, campaignRoutes, elebilityRoutes)
-	engine := app.ProvideGinApp(configConfig, routesRoutes)
-	injectorsApp := ProvideApp(engine, db)
+	reconciliationRepository := repository.ProvideReconciliationRepository(db)
+	reconciliationTask := tasks.ProvideReconciliationTask(reconciliationRepository, paymentRepository, paymentProviderRegistry)
+	reconciliationController := controllers.ProvideReconciliationController(reconciliationRepository, reconciliationTask)
+	reconciliationRoutes := routes.ProvideReconciliationRoutes(reconciliationController, authMiddleware)
+	stripeEventController := controllers.ProvideStripeEventController(stripeEventRepository, stripeWebhookTask)
+	stripeEventRoutes := routes.ProvideStripeEventRoutes(stripeEventController, authMiddleware)
+	disputeController := controllers.ProvideDisputeController(disputeRepository)
+	disputeRoutes := routes.ProvideDisputeRoutes(disputeController, authMiddleware)
+	reportRepository := repository.ProvideReportRepository(db)
+	reportController := controllers.ProvideReportController(reportRepository)
+	reportRoutes := routes.ProvideReportRoutes(reportController, authMiddleware)
+	settlementRepository := repository.ProvideSettlementRepository(db)
+	settlementTask := tasks.ProvideSettlementTask(settlementRepository, settingRepository)
+	settlementController := controllers.ProvideSettlementController(settlementRepository, settlementTask)
+	settlementRoutes := routes.ProvideSettlementRoutes(settlementController, authMiddleware)
+	fraudController := controllers.ProvideFraudController(fraudRepository)
+	fraudRoutes := routes.ProvideFraudRoutes(fraudController, authMiddleware)
+	promoCodeController := controllers.ProvidePromoCodeController(promoCodeRepository)
+	promoCodeRoutes := routes.ProvidePromoCodeRoutes(promoCodeController, authMiddleware)
+	routesRoutes := routes.ProvideV1Routes(userRoutes, authRoutes, gasStationRoutes, gasPumpRoutes, paymentRoutes, customerRoutes, synchronizationRoute, permissionRoutes, settingRoutes, campaignRoutes, elebilityRoutes, reconciliationRoutes, stripeEventRoutes, disputeRoutes, reportRoutes, settlementRoutes, fraudRoutes, promoCodeRoutes)
+	ginEngine := app.ProvideGinApp(configConfig, routesRoutes)
+	injectorsApp := ProvideApp(ginEngine, db)
 	return injectorsApp, nil
 }
 
@@ -102,6 +139,82 @@ func InitializeSynchronizationTask() (tasks.SynchronizationTask, error) {
 	return synchronizationTask, nil
 }
 
+func InitializeOutboxTask() (tasks.OutboxTask, error) {
+	configConfig, err := config.NewConfig()
+	if err != nil {
+		return nil, err
+	}
+	db, err := database.ConnectDB(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	outboxRepository := repository.ProvideOutboxRepository(db)
+	paymentRepository := repository.ProvidePaymentRepository(db)
+	campaignRepository := repository.ProvidePromotionRepository(db)
+	socioSmartService := services.ProvideSocioSmartService(configConfig)
+	mailService := services.ProvideMailService(configConfig)
+	receiptService := services.ProvideReceiptService()
+	stripeService := services.ProvideStripeService()
+	stripeProvider := services.ProvideStripeProvider(stripeService)
+	switService := services.ProvideSwitService(configConfig)
+	switProvider := services.ProvideSwitProvider(switService)
+	debitService := services.ProvideDebitService(configConfig)
+	debitProvider := services.ProvideDebitProvider(debitService)
+	paymentProviderRegistry := services.ProvidePaymentProviderRegistry(stripeProvider, switProvider, debitProvider)
+	outboxTask := tasks.ProvideOutboxTask(outboxRepository, paymentRepository, campaignRepository, socioSmartService, mailService, receiptService, paymentProviderRegistry)
+	return outboxTask, nil
+}
+
+func InitializeReservationSweeperTask() (tasks.ReservationSweeperTask, error) {
+	configConfig, err := config.NewConfig()
+	if err != nil {
+		return nil, err
+	}
+	db, err := database.ConnectDB(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	paymentRepository := repository.ProvidePaymentRepository(db)
+	idempotencyRepository := repository.ProvideIdempotencyRepository(db)
+	outboxRepository := repository.ProvideOutboxRepository(db)
+	campaignRepository := repository.ProvidePromotionRepository(db)
+	socioSmartService := services.ProvideSocioSmartService(configConfig)
+	mailService := services.ProvideMailService(configConfig)
+	receiptService := services.ProvideReceiptService()
+	stripeService := services.ProvideStripeService()
+	stripeProvider := services.ProvideStripeProvider(stripeService)
+	switService := services.ProvideSwitService(configConfig)
+	switProvider := services.ProvideSwitProvider(switService)
+	debitService := services.ProvideDebitService(configConfig)
+	debitProvider := services.ProvideDebitProvider(debitService)
+	paymentProviderRegistry := services.ProvidePaymentProviderRegistry(stripeProvider, switProvider, debitProvider)
+	outboxTask := tasks.ProvideOutboxTask(outboxRepository, paymentRepository, campaignRepository, socioSmartService, mailService, receiptService, paymentProviderRegistry)
+	reservationSweeperTask := tasks.ProvideReservationSweeperTask(paymentRepository, idempotencyRepository, outboxTask, paymentProviderRegistry, configConfig)
+	return reservationSweeperTask, nil
+}
+
+func InitializeReconciliationTask() (tasks.ReconciliationTask, error) {
+	configConfig, err := config.NewConfig()
+	if err != nil {
+		return nil, err
+	}
+	db, err := database.ConnectDB(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	reconciliationRepository := repository.ProvideReconciliationRepository(db)
+	paymentRepository := repository.ProvidePaymentRepository(db)
+	stripeService := services.ProvideStripeService()
+	stripeProvider := services.ProvideStripeProvider(stripeService)
+	switService := services.ProvideSwitService(configConfig)
+	switProvider := services.ProvideSwitProvider(switService)
+	debitService := services.ProvideDebitService(configConfig)
+	debitProvider := services.ProvideDebitProvider(debitService)
+	paymentProviderRegistry := services.ProvidePaymentProviderRegistry(stripeProvider, switProvider, debitProvider)
+	reconciliationTask := tasks.ProvideReconciliationTask(reconciliationRepository, paymentRepository, paymentProviderRegistry)
+	return reconciliationTask, nil
+}
+
 func InitializeDB() (*gorm.DB, error) {
 	configConfig, err := config.NewConfig()
 	if err != nil {
@@ -156,9 +269,8 @@ func InitializeServerWithMocks() (*AppWithMock, error) {
 	gasStationController := controllers.ProvideGasStationProvider(mockGasStationRepository, mockSynchronizationTask)
 	gasStationRoutes := routes.ProvideGasStationRoutes(gasStationController, authMiddleware)
 	mockGasPumpRepository := ProvideGasPumpRepositoryMock()
-	mockCampaignRepository := ProvideCampaignRepositoryMock()
-	mockSettingRepository := ProvideSettingRepositoryMock()
-	gasPumpController := controllers.ProvideGasPumpProvider(mockGasPumpRepository, mockSynchronizationTask, mockCampaignRepository, mockSettingRepository)
+	mockEngine := ProvideDiscountEngineMock()
+	gasPumpController := controllers.ProvideGasPumpProvider(mockGasPumpRepository, mockSynchronizationTask, mockEngine)
 	mockCustomerRepository := ProvideCustomerRepositoryMock()
 	mockCustomerService := ProvideCustomerServiceMock()
 	mockStripeService := ProvideStripeServiceMock()
@@ -167,15 +279,22 @@ func InitializeServerWithMocks() (*AppWithMock, error) {
 	customerAuthMiddleware := middlewares.ProvideCustomerAUthMiddleware(mockCustomerRepository, mockCustomerService, mockStripeService, mockSwitService, mockElegibilityRepository)
 	gasPumpRoutes := routes.ProvideGasPumpRoutes(gasPumpController, authMiddleware, customerAuthMiddleware)
 	mockPaymentRepository := ProvidePaymentRepositoryMock()
+	mockPaymentProviderRegistry := ProvidePaymentProviderRegistryMock()
 	mockSocioSmartService := ProvideSocioSmartServiceMock()
 	mockInvoicingService := ProvideInvoicingServiceMock()
-	mockMailService := ProvideMailServiceMock()
-	mockDebitService := ProvideDebitServiceMock()
-	paymentController := controllers.ProvidePaymentController(mockPaymentRepository, mockGasPumpRepository, mockStripeService, configConfig, mockSocioSmartService, mockSwitService, mockInvoicingService, mockMailService, mockSettingRepository, mockCampaignRepository, mockElegibilityRepository, mockDebitService, mockCustomerRepository)
+	mockReceiptService := ProvideReceiptServiceMock()
+	mockOutboxTask := ProvideOutboxTaskMock()
+	mockStripeWebhookTask := ProvideStripeWebhookTaskMock()
+	mockFraudTask := ProvideFraudTaskMock()
+	mockRefundRepository := ProvideRefundRepositoryMock()
+	mockSettingRepository := ProvideSettingRepositoryMock()
+	paymentController := controllers.ProvidePaymentController(mockPaymentRepository, mockGasPumpRepository, mockPaymentProviderRegistry, configConfig, mockSocioSmartService, mockInvoicingService, mockReceiptService, mockOutboxTask, mockStripeWebhookTask, mockFraudTask, mockRefundRepository, mockSettingRepository, mockEngine, mockCustomerRepository)
 	mockSecurityRepository := ProvideSecurityRepositoryMock()
 	securityMiddleware := middlewares.ProvideSecurityMiddleware(mockSecurityRepository, mockGasStationRepository, mockSocioSmartService)
-	paymentRoutes := routes.ProvidePaymenRoutes(customerAuthMiddleware, paymentController, authMiddleware, securityMiddleware)
-	customerController := controllers.ProvideCustomerController(mockStripeService, mockSwitService, configConfig, mockSettingRepository, mockCustomerRepository, mockElegibilityRepository)
+	mockIdempotencyRepository := ProvideIdempotencyRepositoryMock()
+	idempotencyMiddleware := middlewares.ProvideIdempotencyMiddleware(mockIdempotencyRepository)
+	paymentRoutes := routes.ProvidePaymenRoutes(customerAuthMiddleware, paymentController, authMiddleware, securityMiddleware, idempotencyMiddleware)
+	customerController := controllers.ProvideCustomerController(mockStripeService, mockSwitService, configConfig, mockSettingRepository, mockCustomerRepository, mockElegibilityRepository, mockPaymentRepository, mockEngine)
 	customerRoutes := routes.ProvideCustomerRoutes(customerAuthMiddleware, customerController, authMiddleware)
 	mockSynchronizationRepository := ProvideSynchronizationRepository()
 	synchronizationController := controllers.ProvideSynchronizationController(mockSynchronizationRepository, mockSynchronizationTask)
@@ -185,16 +304,85 @@ func InitializeServerWithMocks() (*AppWithMock, error) {
 	permissionRoutes := routes.ProvidePermissionRoutes(authMiddleware, permissionController)
 	settingController := controllers.ProvideSettingController(mockSettingRepository)
 	settingRoutes := routes.ProvideSettingRoutes(authMiddleware, settingController)
+	mockCampaignRepository := ProvideCampaignRepositoryMock()
 	campaignController := controllers.ProvideCampaignController(mockCampaignRepository)
 	campaignRoutes := routes.ProvideCampaingRoutes(campaignController, authMiddleware)
 	elegibilityController := controllers.ProvideElegibityController(mockElegibilityRepository)
 	elebilityRoutes := routes.ProvideElebilityRoutes(authMiddleware, elegibilityController)
-	routesRoutes := routes.ProvideV1Routes(userRoutes, authRoutes, gasStationRoutes, gasPumpRoutes, paymentRoutes, customerRoutes, synchronizationRoute, permissionRoutes, settingRoutes, campaignRoutes, elebilityRoutes)
+	mockReconciliationRepository := ProvideReconciliationRepositoryMock()
+	mockReconciliationTask := ProvideReconciliationTaskMock()
+	reconciliationController := controllers.ProvideReconciliationController(mockReconciliationRepository, mockReconciliationTask)
+	reconciliationRoutes := routes.ProvideReconciliationRoutes(reconciliationController, authMiddleware)
+	mockStripeEventRepository := ProvideStripeEventRepositoryMock()
+	stripeEventController := controllers.ProvideStripeEventController(mockStripeEventRepository, mockStripeWebhookTask)
+	stripeEventRoutes := routes.ProvideStripeEventRoutes(stripeEventController, authMiddleware)
+	mockDisputeRepository := ProvideDisputeRepositoryMock()
+	disputeController := controllers.ProvideDisputeController(mockDisputeRepository)
+	disputeRoutes := routes.ProvideDisputeRoutes(disputeController, authMiddleware)
+	mockReportRepository := ProvideReportRepositoryMock()
+	reportController := controllers.ProvideReportController(mockReportRepository)
+	reportRoutes := routes.ProvideReportRoutes(reportController, authMiddleware)
+	mockSettlementRepository := ProvideSettlementRepositoryMock()
+	mockSettlementTask := ProvideSettlementTaskMock()
+	settlementController := controllers.ProvideSettlementController(mockSettlementRepository, mockSettlementTask)
+	settlementRoutes := routes.ProvideSettlementRoutes(settlementController, authMiddleware)
+	mockFraudRepository := ProvideFraudRepositoryMock()
+	fraudController := controllers.ProvideFraudController(mockFraudRepository)
+	fraudRoutes := routes.ProvideFraudRoutes(fraudController, authMiddleware)
+	mockPromoCodeRepository := ProvidePromoCodeRepositoryMock()
+	promoCodeController := controllers.ProvidePromoCodeController(mockPromoCodeRepository)
+	promoCodeRoutes := routes.ProvidePromoCodeRoutes(promoCodeController, authMiddleware)
+	routesRoutes := routes.ProvideV1Routes(userRoutes, authRoutes, gasStationRoutes, gasPumpRoutes, paymentRoutes, customerRoutes, synchronizationRoute, permissionRoutes, settingRoutes, campaignRoutes, elebilityRoutes, reconciliationRoutes, stripeEventRoutes, disputeRoutes, reportRoutes, settlementRoutes, fraudRoutes, promoCodeRoutes)
 	engine := app.ProvideGinApp(configConfig, routesRoutes)
-	appWithMock := ProvideAppWithMock(engine, mockUserRepository, mockGasStationRepository, mockGasPumpRepository, mockCustomerRepository, mockPaymentRepository, mockCustomerService, mockStripeService, mockSocioSmartService, mockSynchronizationTask, mockSynchronizationRepository, mockSecurityRepository, mockPermissionRepository, mockSwitService, mockInvoicingService, mockMailService, mockSettingRepository, mockCampaignRepository, mockElegibilityRepository, mockDebitService)
+	mockMailService := ProvideMailServiceMock()
+	mockDebitService := ProvideDebitServiceMock()
+	appWithMock := ProvideAppWithMock(engine, mockUserRepository, mockGasStationRepository, mockGasPumpRepository, mockCustomerRepository, mockPaymentRepository, mockCustomerService, mockStripeService, mockSocioSmartService, mockSynchronizationTask, mockSynchronizationRepository, mockSecurityRepository, mockPermissionRepository, mockSwitService, mockInvoicingService, mockMailService, mockSettingRepository, mockCampaignRepository, mockElegibilityRepository, mockDebitService, mockPaymentProviderRegistry, mockIdempotencyRepository, mockOutboxTask, mockReconciliationRepository, mockReconciliationTask, mockStripeEventRepository, mockStripeWebhookTask, mockDisputeRepository, mockRefundRepository, mockReportRepository, mockSettlementRepository, mockSettlementTask, mockReceiptService, mockFraudRepository, mockFraudTask, mockEngine, mockPromoCodeRepository)
 	return appWithMock, nil
 }
 
+func InitializeStripeWebhookTask() (tasks.StripeWebhookTask, error) {
+	configConfig, err := config.NewConfig()
+	if err != nil {
+		return nil, err
+	}
+	db, err := database.ConnectDB(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	stripeEventRepository := repository.ProvideStripeEventRepository(db)
+	paymentRepository := repository.ProvidePaymentRepository(db)
+	settingRepository := repository.ProvideSettingRepository(db)
+	disputeRepository := repository.ProvideDisputeRepository(db)
+	stripeService := services.ProvideStripeService()
+	outboxRepository := repository.ProvideOutboxRepository(db)
+	campaignRepository := repository.ProvidePromotionRepository(db)
+	socioSmartService := services.ProvideSocioSmartService(configConfig)
+	mailService := services.ProvideMailService(configConfig)
+	receiptService := services.ProvideReceiptService()
+	stripeProvider := services.ProvideStripeProvider(stripeService)
+	switService := services.ProvideSwitService(configConfig)
+	switProvider := services.ProvideSwitProvider(switService)
+	debitService := services.ProvideDebitService(configConfig)
+	debitProvider := services.ProvideDebitProvider(debitService)
+	paymentProviderRegistry := services.ProvidePaymentProviderRegistry(stripeProvider, switProvider, debitProvider)
+	outboxTask := tasks.ProvideOutboxTask(outboxRepository, paymentRepository, campaignRepository, socioSmartService, mailService, receiptService, paymentProviderRegistry)
+	stripeWebhookTask := tasks.ProvideStripeWebhookTask(stripeEventRepository, paymentRepository, settingRepository, disputeRepository, stripeService, outboxTask)
+	return stripeWebhookTask, nil
+}
+
+func InitializeStripeEventRepository() (repository.StripeEventRepository, error) {
+	configConfig, err := config.NewConfig()
+	if err != nil {
+		return nil, err
+	}
+	db, err := database.ConnectDB(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	stripeEventRepository := repository.ProvideStripeEventRepository(db)
+	return stripeEventRepository, nil
+}
+
 // wire.go:
 
 // Mocks provider
@@ -274,6 +462,74 @@ func ProvideDebitServiceMock() *services.MockDebitService {
 	return &services.MockDebitService{}
 }
 
+func ProvidePaymentProviderRegistryMock() *services.MockPaymentProviderRegistry {
+	return &services.MockPaymentProviderRegistry{}
+}
+
+func ProvideIdempotencyRepositoryMock() *repository.MockIdempotencyRepository {
+	return &repository.MockIdempotencyRepository{}
+}
+
+func ProvideOutboxTaskMock() *tasks.MockOutboxTask {
+	return &tasks.MockOutboxTask{}
+}
+
+func ProvideReconciliationRepositoryMock() *repository.MockReconciliationRepository {
+	return &repository.MockReconciliationRepository{}
+}
+
+func ProvideReconciliationTaskMock() *tasks.MockReconciliationTask {
+	return &tasks.MockReconciliationTask{}
+}
+
+func ProvideStripeEventRepositoryMock() *repository.MockStripeEventRepository {
+	return &repository.MockStripeEventRepository{}
+}
+
+func ProvideStripeWebhookTaskMock() *tasks.MockStripeWebhookTask {
+	return &tasks.MockStripeWebhookTask{}
+}
+
+func ProvideDisputeRepositoryMock() *repository.MockDisputeRepository {
+	return &repository.MockDisputeRepository{}
+}
+
+func ProvideRefundRepositoryMock() *repository.MockRefundRepository {
+	return &repository.MockRefundRepository{}
+}
+
+func ProvideReportRepositoryMock() *repository.MockReportRepository {
+	return &repository.MockReportRepository{}
+}
+
+func ProvideSettlementRepositoryMock() *repository.MockSettlementRepository {
+	return &repository.MockSettlementRepository{}
+}
+
+func ProvideSettlementTaskMock() *tasks.MockSettlementTask {
+	return &tasks.MockSettlementTask{}
+}
+
+func ProvideReceiptServiceMock() *services.MockReceiptService {
+	return &services.MockReceiptService{}
+}
+
+func ProvideFraudRepositoryMock() *repository.MockFraudRepository {
+	return &repository.MockFraudRepository{}
+}
+
+func ProvideFraudTaskMock() *tasks.MockFraudTask {
+	return &tasks.MockFraudTask{}
+}
+
+func ProvideDiscountEngineMock() *discounts.MockEngine {
+	return &discounts.MockEngine{}
+}
+
+func ProvidePromoCodeRepositoryMock() *repository.MockPromoCodeRepository {
+	return &repository.MockPromoCodeRepository{}
+}
+
 var MockSet = wire.NewSet(
 	ProvideUserRepositoryMock,
 	ProvideGasStationRepositoryMock,
@@ -293,10 +549,36 @@ var MockSet = wire.NewSet(
 	ProvideSettingRepositoryMock,
 	ProvideCampaignRepositoryMock,
 	ProvideElegibilityRepositoryMock,
-	ProvideDebitServiceMock, wire.Bind(new(repository.UserRepository), new(*repository.MockUserRepository)), wire.Bind(new(repository.GasStationRepository), new(*repository.MockGasStationRepository)), wire.Bind(new(repository.GasPumpRepository), new(*repository.MockGasPumpRepository)), wire.Bind(new(repository.CustomerRepository), new(*repository.MockCustomerRepository)), wire.Bind(new(repository.PaymentRepository), new(*repository.MockPaymentRepository)), wire.Bind(new(services.CustomerService), new(*services.MockCustomerService)), wire.Bind(new(services.StripeService), new(*services.MockStripeService)), wire.Bind(new(services.SocioSmartService), new(*services.MockSocioSmartService)), wire.Bind(new(tasks.SynchronizationTask), new(*tasks.MockSynchronizationTask)), wire.Bind(
+	ProvideDebitServiceMock,
+	ProvidePaymentProviderRegistryMock,
+	ProvideIdempotencyRepositoryMock,
+	ProvideOutboxTaskMock,
+	ProvideReconciliationRepositoryMock,
+	ProvideReconciliationTaskMock,
+	ProvideStripeEventRepositoryMock,
+	ProvideStripeWebhookTaskMock,
+	ProvideDisputeRepositoryMock,
+	ProvideRefundRepositoryMock,
+	ProvideReportRepositoryMock,
+	ProvideSettlementRepositoryMock,
+	ProvideSettlementTaskMock,
+	ProvideReceiptServiceMock,
+	ProvideFraudRepositoryMock,
+	ProvideFraudTaskMock,
+	ProvideDiscountEngineMock,
+	ProvidePromoCodeRepositoryMock, wire.Bind(new(repository.UserRepository), new(*repository.MockUserRepository)), wire.Bind(new(repository.GasStationRepository), new(*repository.MockGasStationRepository)), wire.Bind(new(repository.GasPumpRepository), new(*repository.MockGasPumpRepository)), wire.Bind(new(repository.CustomerRepository), new(*repository.MockCustomerRepository)), wire.Bind(new(repository.PaymentRepository), new(*repository.MockPaymentRepository)), wire.Bind(new(services.CustomerService), new(*services.MockCustomerService)), wire.Bind(new(services.StripeService), new(*services.MockStripeService)), wire.Bind(new(services.SocioSmartService), new(*services.MockSocioSmartService)), wire.Bind(new(tasks.SynchronizationTask), new(*tasks.MockSynchronizationTask)), wire.Bind(
 		new(repository.SynchronizationRepository),
 		new(*repository.MockSynchronizationRepository),
-	), wire.Bind(new(repository.SecurityRepository), new(*repository.MockSecurityRepository)), wire.Bind(new(repository.PermissionRepository), new(*repository.MockPermissionRepository)), wire.Bind(new(services.SwitService), new(*services.MockSwitService)), wire.Bind(new(services.InvoicingService), new(*services.MockInvoicingService)), wire.Bind(new(services.MailService), new(*services.MockMailService)), wire.Bind(new(repository.SettingRepository), new(*repository.MockSettingRepository)), wire.Bind(new(repository.CampaignRepository), new(*repository.MockCampaignRepository)), wire.Bind(new(repository.ElegibilityRepository), new(*repository.MockElegibilityRepository)), wire.Bind(new(services.DebitService), new(*services.MockDebitService)),
+	), wire.Bind(new(repository.SecurityRepository), new(*repository.MockSecurityRepository)), wire.Bind(new(repository.PermissionRepository), new(*repository.MockPermissionRepository)), wire.Bind(new(services.SwitService), new(*services.MockSwitService)), wire.Bind(new(services.InvoicingService), new(*services.MockInvoicingService)), wire.Bind(new(services.MailService), new(*services.MockMailService)), wire.Bind(new(repository.SettingRepository), new(*repository.MockSettingRepository)), wire.Bind(new(repository.CampaignRepository), new(*repository.MockCampaignRepository)), wire.Bind(new(repository.ElegibilityRepository), new(*repository.MockElegibilityRepository)), wire.Bind(new(services.DebitService), new(*services.MockDebitService)), wire.Bind(
+		new(services.PaymentProviderRegistry),
+		new(*services.MockPaymentProviderRegistry),
+	), wire.Bind(
+		new(repository.IdempotencyRepository),
+		new(*repository.MockIdempotencyRepository),
+	), wire.Bind(new(tasks.OutboxTask), new(*tasks.MockOutboxTask)), wire.Bind(
+		new(repository.ReconciliationRepository),
+		new(*repository.MockReconciliationRepository),
+	), wire.Bind(new(tasks.ReconciliationTask), new(*tasks.MockReconciliationTask)), wire.Bind(new(repository.StripeEventRepository), new(*repository.MockStripeEventRepository)), wire.Bind(new(tasks.StripeWebhookTask), new(*tasks.MockStripeWebhookTask)), wire.Bind(new(repository.DisputeRepository), new(*repository.MockDisputeRepository)), wire.Bind(new(repository.RefundRepository), new(*repository.MockRefundRepository)), wire.Bind(new(repository.ReportRepository), new(*repository.MockReportRepository)), wire.Bind(new(repository.SettlementRepository), new(*repository.MockSettlementRepository)), wire.Bind(new(tasks.SettlementTask), new(*tasks.MockSettlementTask)), wire.Bind(new(services.ReceiptService), new(*services.MockReceiptService)), wire.Bind(new(repository.FraudRepository), new(*repository.MockFraudRepository)), wire.Bind(new(tasks.FraudTask), new(*tasks.MockFraudTask)), wire.Bind(new(discounts.Engine), new(*discounts.MockEngine)), wire.Bind(new(repository.PromoCodeRepository), new(*repository.MockPromoCodeRepository)),
 )
 
 type App struct {
@@ -318,8 +600,8 @@ type AppWithMock struct {
 	GasPumpRepositoryMock         *repository.MockGasPumpRepository
 	PaymentRepositoryMock         *repository.MockPaymentRepository
 	CustomerRepositoryMock        *repository.MockCustomerRepository
-	extCustomerService            *services.MockCustomerService
-	stripeServiceMock             *services.MockStripeService
+	ExtCustomerService            *services.MockCustomerService
+	StripeServiceMock             *services.MockStripeService
 	socioSmartServiceMock         *services.MockSocioSmartService
 	synchronizationTaskMock       *tasks.MockSynchronizationTask
 	synchronizationRepositoryMock *repository.MockSynchronizationRepository
@@ -328,10 +610,27 @@ type AppWithMock struct {
 	switServiceMock               *services.MockSwitService
 	invoicingServiceMock          *services.MockInvoicingService
 	mailServiceMock               *services.MockMailService
-	settingRepositoryMock         *repository.MockSettingRepository
+	SettingRepositoryMock         *repository.MockSettingRepository
 	campaignRepositoryMock        *repository.MockCampaignRepository
 	elebilityRepositoryMock       *repository.MockElegibilityRepository
 	debitServiceMock              *services.MockDebitService
+	PaymentProviderRegistryMock   *services.MockPaymentProviderRegistry
+	idempotencyRepositoryMock     *repository.MockIdempotencyRepository
+	OutboxTaskMock                *tasks.MockOutboxTask
+	reconciliationRepositoryMock  *repository.MockReconciliationRepository
+	reconciliationTaskMock        *tasks.MockReconciliationTask
+	stripeEventRepositoryMock     *repository.MockStripeEventRepository
+	stripeWebhookTaskMock         *tasks.MockStripeWebhookTask
+	disputeRepositoryMock         *repository.MockDisputeRepository
+	RefundRepositoryMock          *repository.MockRefundRepository
+	reportRepositoryMock          *repository.MockReportRepository
+	settlementRepositoryMock      *repository.MockSettlementRepository
+	settlementTaskMock            *tasks.MockSettlementTask
+	ReceiptServiceMock            *services.MockReceiptService
+	fraudRepositoryMock           *repository.MockFraudRepository
+	FraudTaskMock                 *tasks.MockFraudTask
+	discountEngineMock            *discounts.MockEngine
+	promoCodeRepositoryMock       *repository.MockPromoCodeRepository
 }
 
 func ProvideAppWithMock(router *gin.Engine,
@@ -354,6 +653,23 @@ func ProvideAppWithMock(router *gin.Engine,
 	campaignRepositoryMock *repository.MockCampaignRepository,
 	elebilityRepositoryMock *repository.MockElegibilityRepository,
 	debitServiceMock *services.MockDebitService,
+	paymentProviderRegistryMock *services.MockPaymentProviderRegistry,
+	idempotencyRepositoryMock *repository.MockIdempotencyRepository,
+	outboxTaskMock *tasks.MockOutboxTask,
+	reconciliationRepositoryMock *repository.MockReconciliationRepository,
+	reconciliationTaskMock *tasks.MockReconciliationTask,
+	stripeEventRepositoryMock *repository.MockStripeEventRepository,
+	stripeWebhookTaskMock *tasks.MockStripeWebhookTask,
+	disputeRepositoryMock *repository.MockDisputeRepository,
+	refundRepositoryMock *repository.MockRefundRepository,
+	reportRepositoryMock *repository.MockReportRepository,
+	settlementRepositoryMock *repository.MockSettlementRepository,
+	settlementTaskMock *tasks.MockSettlementTask,
+	receiptServiceMock *services.MockReceiptService,
+	fraudRepositoryMock *repository.MockFraudRepository,
+	fraudTaskMock *tasks.MockFraudTask,
+	discountEngineMock *discounts.MockEngine,
+	promoCodeRepositoryMock *repository.MockPromoCodeRepository,
 ) *AppWithMock {
 	return &AppWithMock{
 		Router:                        router,
@@ -362,8 +678,8 @@ func ProvideAppWithMock(router *gin.Engine,
 		GasPumpRepositoryMock:         gasPumpRepository,
 		PaymentRepositoryMock:         paymentRepository,
 		CustomerRepositoryMock:        customerRepository,
-		extCustomerService:            extCustomerService,
-		stripeServiceMock:             stripeServiceMock,
+		ExtCustomerService:            extCustomerService,
+		StripeServiceMock:             stripeServiceMock,
 		socioSmartServiceMock:         socioSmartServiceMock,
 		synchronizationTaskMock:       synchronizationTaskMock,
 		synchronizationRepositoryMock: synchronizationRepositoryMock,
@@ -372,9 +688,26 @@ func ProvideAppWithMock(router *gin.Engine,
 		switServiceMock:               switServiceMock,
 		invoicingServiceMock:          invoicingServiceMock,
 		mailServiceMock:               mailServiceMock,
-		settingRepositoryMock:         settingRepositoryMock,
+		SettingRepositoryMock:         settingRepositoryMock,
 		campaignRepositoryMock:        campaignRepositoryMock,
 		elebilityRepositoryMock:       elebilityRepositoryMock,
 		debitServiceMock:              debitServiceMock,
+		PaymentProviderRegistryMock:   paymentProviderRegistryMock,
+		idempotencyRepositoryMock:     idempotencyRepositoryMock,
+		OutboxTaskMock:                outboxTaskMock,
+		reconciliationRepositoryMock:  reconciliationRepositoryMock,
+		reconciliationTaskMock:        reconciliationTaskMock,
+		stripeEventRepositoryMock:     stripeEventRepositoryMock,
+		stripeWebhookTaskMock:         stripeWebhookTaskMock,
+		disputeRepositoryMock:         disputeRepositoryMock,
+		RefundRepositoryMock:          refundRepositoryMock,
+		reportRepositoryMock:          reportRepositoryMock,
+		settlementRepositoryMock:      settlementRepositoryMock,
+		settlementTaskMock:            settlementTaskMock,
+		ReceiptServiceMock:            receiptServiceMock,
+		fraudRepositoryMock:           fraudRepositoryMock,
+		FraudTaskMock:                 fraudTaskMock,
+		discountEngineMock:            discountEngineMock,
+		promoCodeRepositoryMock:       promoCodeRepositoryMock,
 	}
 }
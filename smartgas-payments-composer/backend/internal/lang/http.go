@@ -23,4 +23,29 @@ const (
 	AmountChargedGratherThanPaid = "Amount charged grather than paid amount"
 	GasStationNotFound           = "Gas Station Not Found"
 	UnauthorizedEmployee         = "No permissions to perform this action"
+	PaymentTransitionNotAllowed  = "Payment can not move to the requested state"
+	IdempotencyKeyReused         = "Idempotency-Key already used with a different request"
+	IdempotencyKeyInProgress     = "A request with this Idempotency-Key is still in progress"
+	InvalidDateRange             = "The end date must not be before the start date"
+	InvalidAmountRange           = "Amounts must not be negative and the maximum must not be less than the minimum"
+	StripeEventNotReplayable     = "Only failed or pending events can be replayed"
+	StripeEventProcessing        = "The event is being processed"
+	ReportRangeTooLong           = "The date range of reports must not exceed one year"
+	SettlementClosed             = "The settlement is closed"
+	SettlementOverlaps           = "The period overlaps another settlement of the legal name"
+	RefundMustBeTotal            = "Loads not served can only be refunded entirely"
+	RefundExceedsCaptured        = "The amount is greater than what is left to refund"
+	PartialRefundNotSupported    = "The payment provider does not support partial refunds"
+	InvalidCursor                = "Invalid cursor"
+	ReceiptNotAvailable          = "Load not finished, the receipt is not available yet"
+	NoDefaultPaymentMethod       = "There is no default payment method"
+	PaymentMethodNotFound        = "The payment method does not belong to the customer"
+	FraudBlocked                 = "Loads are blocked for this customer or card"
+	FraudLimitExceeded           = "The load exceeds the allowed limit: "
+	QuoteExpired                 = "Quote expired, request a new one"
+	InvalidQuote                 = "The quote is invalid or does not match the requested load"
+	PromoCodeNotApplicable       = "The promo code does not exist or does not apply to this load"
+	PromoCodeUsed                = "The promo code has no uses left"
+	PromoCodeExists              = "The promo code already exists"
+	ExportTooLarge               = "The export exceeds the rows allowed for xlsx files, narrow the filters or use csv"
 )
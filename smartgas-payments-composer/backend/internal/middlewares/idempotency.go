@@ -0,0 +1,243 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"smartgas-payment/internal/dto"
+	"smartgas-payment/internal/lang"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// Requests renew the lease of their key while they are processed, a key not renewed
+	// within the lease belongs to a request that died and can be retried
+	idempotencyLease        = time.Minute
+	idempotencyLeaseRenewal = time.Second * 20
+)
+
+type IdempotencyMiddleware struct {
+	repository repository.IdempotencyRepository
+}
+
+func ProvideIdempotencyMiddleware(
+	repository repository.IdempotencyRepository,
+) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		repository: repository,
+	}
+}
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (rr *responseRecorder) Write(data []byte) (int, error) {
+	rr.body.Write(data)
+	return rr.ResponseWriter.Write(data)
+}
+
+func (rr *responseRecorder) WriteString(data string) (int, error) {
+	rr.body.WriteString(data)
+	return rr.ResponseWriter.WriteString(data)
+}
+
+// Middleware honors the Idempotency-Key header, replaying the stored response when the
+// same request is sent again. It must run after the authentication middleware
+func (im *IdempotencyMiddleware) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var headers dto.IdempotencyHeadersRequest
+
+		if err := c.ShouldBindHeader(&headers); err != nil {
+			c.JSON(http.StatusBadRequest, utils.MapValidatorError[dto.IdempotencyHeadersRequest](err))
+			c.Abort()
+			return
+		}
+
+		if headers.Key == "" {
+			c.Next()
+			return
+		}
+
+		payload, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.GeneralMessage{Detail: err.Error()})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(payload))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(payload)
+
+		key := &models.IdempotencyKey{
+			Key:         headers.Key,
+			Scope:       c.Request.Method + " " + c.FullPath(),
+			Owner:       idempotencyOwner(c),
+			RequestHash: hex.EncodeToString(hash.Sum(nil)),
+		}
+
+		stored, err := im.acquire(key)
+		if err != nil {
+			opts := &utils.TrackErrorOpts{
+				Tags: map[string]string{"scope": "idempotency_middleware"},
+			}
+			utils.TrackError(c, err, opts)
+			c.JSON(
+				http.StatusInternalServerError,
+				dto.GeneralMessage{Detail: lang.InternalServerError},
+			)
+			c.Abort()
+			return
+		}
+
+		if stored != nil {
+			if stored.RequestHash != key.RequestHash {
+				c.JSON(
+					http.StatusUnprocessableEntity,
+					dto.GeneralMessage{Detail: lang.IdempotencyKeyReused},
+				)
+				c.Abort()
+				return
+			}
+
+			if stored.Status != "completed" {
+				c.JSON(
+					http.StatusConflict,
+					dto.GeneralMessage{Detail: lang.IdempotencyKeyInProgress},
+				)
+				c.Abort()
+				return
+			}
+
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.StatusCode, "application/json; charset=utf-8", []byte(stored.ResponseBody))
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = recorder
+
+		done := make(chan struct{})
+		defer close(done)
+		go im.renew(key.ID, done)
+
+		// A panic is recovered by an outer middleware, the key is deleted so the client
+		// is able to retry instead of finding it processing
+		defer func() {
+			if r := recover(); r != nil {
+				if err := im.repository.Delete(key.ID); err != nil {
+					opts := &utils.TrackErrorOpts{
+						Tags: map[string]string{"scope": "idempotency_middleware"},
+					}
+					utils.TrackError(c, err, opts)
+				}
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		// Server errors are not stored so the client is able to retry
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			err = im.repository.Delete(key.ID)
+		} else {
+			err = im.repository.Complete(key.ID, c.Writer.Status(), recorder.body.String())
+		}
+
+		if err != nil {
+			opts := &utils.TrackErrorOpts{
+				Tags: map[string]string{"scope": "idempotency_middleware"},
+			}
+			utils.TrackError(c, err, opts)
+		}
+	}
+}
+
+// acquire stores the key for the current request, when the key already exists the
+// stored one is returned instead
+func (im *IdempotencyMiddleware) acquire(key *models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	err := im.repository.Create(key)
+	if err == nil {
+		return nil, nil
+	}
+
+	if !utils.CheckDuplicatedEntry(err) {
+		return nil, err
+	}
+
+	stored, err := im.repository.Get(key.Scope, key.Owner, key.Key)
+	if err != nil {
+		// Deleted by a failed request in the meantime
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, im.repository.Create(key)
+		}
+		return nil, err
+	}
+
+	if stored.Status != "completed" &&
+		stored.RequestHash == key.RequestHash &&
+		time.Since(stored.UpdatedAt) > idempotencyLease {
+		taken, err := im.repository.TakeOver(stored.ID, idempotencyLease)
+		if err != nil {
+			return nil, err
+		}
+
+		if taken {
+			key.ID = stored.ID
+			return nil, nil
+		}
+	}
+
+	return stored, nil
+}
+
+// renew keeps the lease of the key until done is closed, a failed renewal is retried on
+// the next tick
+func (im *IdempotencyMiddleware) renew(id uuid.UUID, done <-chan struct{}) {
+	ticker := time.NewTicker(idempotencyLeaseRenewal)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			im.repository.Renew(id)
+		}
+	}
+}
+
+// idempotencyOwner scopes keys to whoever is authenticated so two clients never share them
+func idempotencyOwner(c *gin.Context) string {
+	if customer, ok := c.Get("customer"); ok {
+		return "customer:" + customer.(*models.Customer).ID.String()
+	}
+
+	if application, ok := c.Get("application"); ok {
+		return "application:" + application.(*models.AuthorizedApplication).ID.String()
+	}
+
+	if station, ok := c.Get("gas_station"); ok {
+		return "employee:" + station.(*models.GasStation).ID.String() + ":" + c.GetString("employee_id")
+	}
+
+	if user, ok := c.Get("user"); ok {
+		return "user:" + user.(*models.User).ID.String()
+	}
+
+	return ""
+}
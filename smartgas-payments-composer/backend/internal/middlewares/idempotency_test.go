@@ -0,0 +1,139 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type idempotencyMiddlewareTest struct {
+	suite.Suite
+	repository *repository.MockIdempotencyRepository
+	middleware *IdempotencyMiddleware
+}
+
+func (suite *idempotencyMiddlewareTest) SetupTest() {
+	suite.repository = &repository.MockIdempotencyRepository{}
+	suite.middleware = ProvideIdempotencyMiddleware(suite.repository)
+}
+
+func (suite *idempotencyMiddlewareTest) TestAcquireStoredKey() {
+	testcases := []struct {
+		Name      string
+		Status    string
+		UpdatedAt time.Time
+		TakenOver *bool
+		Replaced  bool
+	}{
+		{
+			Name:      "TestIdempotency_CompletedIsReplayed",
+			Status:    "completed",
+			UpdatedAt: time.Now().Add(-time.Hour),
+		},
+		{
+			Name:      "TestIdempotency_RenewedIsInProgress",
+			Status:    "processing",
+			UpdatedAt: time.Now(),
+		},
+		{
+			Name:      "TestIdempotency_NotRenewedIsTakenOver",
+			Status:    "processing",
+			UpdatedAt: time.Now().Add(-idempotencyLease * 2),
+			TakenOver: utils.BoolAddr(true),
+			Replaced:  true,
+		},
+		{
+			Name:      "TestIdempotency_TakenOverByAnotherRetry",
+			Status:    "processing",
+			UpdatedAt: time.Now().Add(-idempotencyLease * 2),
+			TakenOver: utils.BoolAddr(false),
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			key := &models.IdempotencyKey{
+				Key:         "key",
+				Scope:       "POST /api/v1/payments/create-intent",
+				Owner:       "customer:" + uuid.NewString(),
+				RequestHash: "hash",
+			}
+			stored := &models.IdempotencyKey{
+				ID:          uuid.New(),
+				Key:         key.Key,
+				Scope:       key.Scope,
+				Owner:       key.Owner,
+				RequestHash: key.RequestHash,
+				Status:      tc.Status,
+				UpdatedAt:   tc.UpdatedAt,
+			}
+
+			suite.repository.On("Create", key).Return(&mysql.MySQLError{Number: 1062}).Once()
+			suite.repository.On("Get", key.Scope, key.Owner, key.Key).Return(stored, nil).Once()
+			if tc.TakenOver != nil {
+				suite.repository.On("TakeOver", stored.ID, idempotencyLease).Return(*tc.TakenOver, nil).Once()
+			}
+
+			got, err := suite.middleware.acquire(key)
+
+			suite.Nil(err)
+			if tc.Replaced {
+				suite.Nil(got)
+				suite.Equal(stored.ID, key.ID)
+			} else {
+				suite.Equal(stored, got)
+			}
+			suite.repository.AssertExpectations(suite.T())
+			suite.repository.AssertNotCalled(suite.T(), "Delete", stored.ID)
+		})
+	}
+}
+
+func (suite *idempotencyMiddlewareTest) TestPanicDeletesKey() {
+	router := gin.New()
+	router.Use(gin.CustomRecovery(func(c *gin.Context, err any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	router.POST("/payments", suite.middleware.Middleware(), func(c *gin.Context) {
+		panic("handler failed")
+	})
+
+	var id uuid.UUID
+	suite.repository.On("Create", mock.AnythingOfType("*models.IdempotencyKey")).
+		Run(func(args mock.Arguments) {
+			key := args.Get(0).(*models.IdempotencyKey)
+			key.ID = uuid.New()
+			id = key.ID
+		}).
+		Return(nil).Once()
+	suite.repository.On("Delete", mock.AnythingOfType("uuid.UUID")).Return(nil).Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(`{"amount":500}`))
+	req.Header.Set("Idempotency-Key", "key")
+	res := httptest.NewRecorder()
+
+	router.ServeHTTP(res, req)
+
+	suite.Equal(http.StatusInternalServerError, res.Code)
+	suite.repository.AssertCalled(suite.T(), "Delete", id)
+	suite.repository.AssertNotCalled(suite.T(), "Complete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	suite.Run(t, new(idempotencyMiddlewareTest))
+}
@@ -6,4 +6,5 @@ var MiddlewaresSet = wire.NewSet(
 	ProvideAuthMiddleware,
 	ProvideCustomerAUthMiddleware,
 	ProvideSecurityMiddleware,
+	ProvideIdempotencyMiddleware,
 )
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"database/sql/driver"
+	"fmt"
+	"smartgas-payment/internal/money"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,9 +24,100 @@ type Campaign struct {
 	UpdatedByID *uuid.UUID     `gorm:"column:updated_by_id;type:varchar(36);"`
 	UpdatedBy   *User          `gorm:"foreignKey:UpdatedByID;constraint:OnDelete:SET NULL;"`
 
+	// Targeting, empty lists and nil hours match every load. Weekdays and hours are
+	// in the timezone of the station, ToHour is excluded and the window wraps around
+	// midnight when FromHour is greater
+	FuelTypes        StringList    `gorm:"column:fuel_types;type:set('regular','premium','diesel');not null;default:'';"`
+	PaymentProviders StringList    `gorm:"column:payment_providers;type:set('stripe','swit','debit');not null;default:'';"`
+	Weekdays         StringList    `gorm:"column:weekdays;type:set('sunday','monday','tuesday','wednesday','thursday','friday','saturday');not null;default:'';"`
+	FromHour         *int          `gorm:"column:from_hour;type:TINYINT;check:from_hour BETWEEN 0 AND 23;"`
+	ToHour           *int          `gorm:"column:to_hour;type:TINYINT;check:to_hour BETWEEN 1 AND 24;"`
+	Levels           *[]*Level     `gorm:"many2many:campaigns_levels;"`
+	FirstLoadOnly    *bool         `gorm:"column:first_load_only;type:boolean;not null;default:false;"`
+	MinAmount        *money.Amount `gorm:"column:min_amount;type:decimal(12,2);not null;default:0;check:min_amount > -1;"`
+	MinLiters        *float64      `gorm:"column:min_liters;type:double;not null;default:0;check:min_liters > -1;"`
+	// CodeOnly campaigns only apply to the loads sending one of their promo codes
+	CodeOnly *bool `gorm:"column:code_only;type:boolean;not null;default:false;"`
+
+	// Budget, nil means unlimited. The campaign is deactivated once the discount given
+	// or the liters discounted reach their budget
+	BudgetAmount       *money.Amount `gorm:"column:budget_amount;type:decimal(12,2);check:budget_amount > 0;"`
+	BudgetLiters       *float64      `gorm:"column:budget_liters;type:double;check:budget_liters > 0;"`
+	MaxUsesPerCustomer *int          `gorm:"column:max_uses_per_customer;type:int;check:max_uses_per_customer > 0;"`
+	MaxUsesPerDay      *int          `gorm:"column:max_uses_per_day;type:int;check:max_uses_per_day > 0;"`
+	SpentAmount        money.Amount  `gorm:"column:spent_amount;type:decimal(12,2);not null;default:0;"`
+	SpentLiters        float64       `gorm:"column:spent_liters;type:double;not null;default:0;"`
+	Uses               int           `gorm:"column:uses;type:int;not null;default:0;"`
+
 	gorm.Model
 }
 
+// CampaignUsage is the discount a campaign gave to a served payment
+type CampaignUsage struct {
+	ID         uuid.UUID    `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	CampaignID uuid.UUID    `gorm:"column:campaign_id;type:varchar(36);not null;index:idx_campaign_usage,priority:1;"`
+	Campaign   *Campaign    `gorm:"constraint:OnDelete:CASCADE;"`
+	CustomerID *uuid.UUID   `gorm:"column:customer_id;type:varchar(36);index;"`
+	Customer   *Customer    `gorm:"constraint:OnDelete:SET NULL;"`
+	PaymentID  uuid.UUID    `gorm:"column:payment_id;type:varchar(36);not null;uniqueIndex;"`
+	Payment    *Payment     `gorm:"constraint:OnDelete:CASCADE;"`
+	Amount     money.Amount `gorm:"column:amount;type:decimal(12,2);not null;default:0;"`
+	Liters     float64      `gorm:"column:liters;type:double;not null;default:0;"`
+	CreatedAt  time.Time    `gorm:"index:idx_campaign_usage,priority:2;"`
+}
+
+func (cu *CampaignUsage) TableName() string {
+	return "campaign_usages"
+}
+
+func (cu *CampaignUsage) BeforeCreate(tx *gorm.DB) (err error) {
+	cu.ID = uuid.New()
+
+	return
+}
+
+// StringList is stored as the comma separated values of a SET column
+type StringList []string
+
+func (sl StringList) Value() (driver.Value, error) {
+	return strings.Join(sl, ","), nil
+}
+
+func (sl *StringList) Scan(src any) error {
+	var value string
+	switch v := src.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("unsupported type %T for a string list", src)
+	}
+
+	*sl = StringList{}
+	if value != "" {
+		*sl = strings.Split(value, ",")
+	}
+
+	return nil
+}
+
+// Matches reports whether value is in the list, an empty list matches every value
+func (sl StringList) Matches(value string) bool {
+	if len(sl) == 0 {
+		return true
+	}
+
+	for _, v := range sl {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (c *Campaign) TableName() string {
 	return "campaigns"
 }
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Dispute is a chargeback (or inquiry) opened by the cardholder on a stripe payment
+type Dispute struct {
+	ID              uuid.UUID    `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	PaymentID       uuid.UUID    `gorm:"column:payment_id;type:varchar(36);not null;index;"`
+	Payment         *Payment     `gorm:"constraint:OnDelete:CASCADE;"`
+	StripeDisputeID string       `gorm:"column:stripe_dispute_id;type:varchar(255);not null;uniqueIndex;"`
+	Amount          money.Amount `gorm:"column:amount;type:decimal(12,2);not null;default:0;"`
+	Reason          string       `gorm:"column:reason;type:varchar(255);not null;default:'';"`
+	Status          string       `gorm:"column:status;type:varchar(50);not null;index;"`
+	EvidenceDueBy   *time.Time   `gorm:"column:evidence_due_by;"`
+	ClosedAt        *time.Time   `gorm:"column:closed_at;"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (d *Dispute) TableName() string {
+	return "disputes"
+}
+
+func (d *Dispute) BeforeCreate(tx *gorm.DB) (err error) {
+	d.ID = uuid.New()
+
+	return
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Limits checked before reserving the funds of a load
+const (
+	FraudMaxAmountPerLoad = "max_amount_per_load"
+	FraudMaxLoadsPerDay   = "max_loads_per_day"
+	FraudMaxAmountPerDay  = "max_amount_per_day"
+	FraudMaxOpenPayments  = "max_open_payments"
+)
+
+// Blocked payers, customer blocks hold the customer id, card blocks the swit source
+// id or stripe payment method id and gift card blocks the card key
+const (
+	FraudBlockCustomer = "customer"
+	FraudBlockCard     = "card"
+	FraudBlockGiftCard = "gift_card"
+)
+
+// FraudRule is a limit for the loads of every station, or only of GasStationID when
+// set. Amount limits use MaxAmount, the others MaxCount
+type FraudRule struct {
+	ID           uuid.UUID    `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	Type         string       `gorm:"column:type;type:enum('max_amount_per_load', 'max_loads_per_day', 'max_amount_per_day', 'max_open_payments');not null;"`
+	GasStationID *uuid.UUID   `gorm:"column:gas_station_id;type:varchar(36);index;"`
+	GasStation   *GasStation  `gorm:"constraint:OnDelete:CASCADE;"`
+	MaxAmount    money.Amount `gorm:"column:max_amount;type:decimal(12,2);not null;default:0;check:max_amount > -1;"`
+	MaxCount     int          `gorm:"column:max_count;not null;default:0;check:max_count > -1;"`
+	Active       *bool        `gorm:"column:active;type:boolean;not null;default:true;"`
+	CreatedByID  *uuid.UUID   `gorm:"column:created_by_id;type:varchar(36);"`
+	CreatedBy    *User        `gorm:"foreignKey:CreatedByID;constraint:OnDelete:SET NULL;"`
+	UpdatedByID  *uuid.UUID   `gorm:"column:updated_by_id;type:varchar(36);"`
+	UpdatedBy    *User        `gorm:"foreignKey:UpdatedByID;constraint:OnDelete:SET NULL;"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (fr *FraudRule) TableName() string {
+	return "fraud_rules"
+}
+
+func (fr *FraudRule) BeforeCreate(tx *gorm.DB) (err error) {
+	fr.ID = uuid.New()
+
+	return
+}
+
+type FraudBlock struct {
+	ID          uuid.UUID  `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	Type        string     `gorm:"column:type;type:enum('customer', 'card', 'gift_card');not null;uniqueIndex:idx_fraud_block;"`
+	Value       string     `gorm:"column:value;type:varchar(255);not null;uniqueIndex:idx_fraud_block;"`
+	Reason      string     `gorm:"column:reason;type:varchar(255);not null;default:'';"`
+	CreatedByID *uuid.UUID `gorm:"column:created_by_id;type:varchar(36);"`
+	CreatedBy   *User      `gorm:"foreignKey:CreatedByID;constraint:OnDelete:SET NULL;"`
+	CreatedAt   time.Time
+}
+
+func (fb *FraudBlock) TableName() string {
+	return "fraud_blocks"
+}
+
+func (fb *FraudBlock) BeforeCreate(tx *gorm.DB) (err error) {
+	fb.ID = uuid.New()
+
+	return
+}
+
+// FraudDecision logs the outcome of checking a load before reserving its funds, Reason
+// is the rule type or block type that rejected it
+type FraudDecision struct {
+	ID              uuid.UUID    `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	Source          string       `gorm:"column:source;type:enum('customer', 'operation');not null;"`
+	Allowed         bool         `gorm:"column:allowed;type:boolean;not null;index;"`
+	Reason          string       `gorm:"column:reason;type:varchar(50);not null;default:'';"`
+	FraudRuleID     *uuid.UUID   `gorm:"column:fraud_rule_id;type:varchar(36);"`
+	FraudRule       *FraudRule   `gorm:"constraint:OnDelete:SET NULL;"`
+	CustomerID      *uuid.UUID   `gorm:"column:customer_id;type:varchar(36);index;"`
+	Customer        *Customer    `gorm:"constraint:OnDelete:SET NULL;"`
+	GasStationID    *uuid.UUID   `gorm:"column:gas_station_id;type:varchar(36);index;"`
+	GasStation      *GasStation  `gorm:"constraint:OnDelete:SET NULL;"`
+	PaymentProvider string       `gorm:"column:payment_provider;type:enum('stripe', 'swit', 'debit');not null;"`
+	Amount          money.Amount `gorm:"column:amount;type:decimal(12,2);not null;default:0;"`
+	CardID          string       `gorm:"column:card_id;type:varchar(255);not null;default:'';"`
+	GiftCardKey     string       `gorm:"column:gift_card_key;type:varchar(40);not null;default:'';"`
+	CreatedAt       time.Time    `gorm:"index;"`
+}
+
+func (fd *FraudDecision) TableName() string {
+	return "fraud_decisions"
+}
+
+func (fd *FraudDecision) BeforeCreate(tx *gorm.DB) (err error) {
+	fd.ID = uuid.New()
+
+	return
+}
@@ -21,6 +21,7 @@ type GasStation struct {
 	Longitude     string      `gorm:"column:longitude;type:varchar(50);not null;default:'';"`
 	Active        *bool       `gorm:"column:active;type:boolean;not null;default:true;"`
 	LegalNameID   string      `gorm:"column:legal_name_id;type:varchar(10);not null;default:'';"`
+	Timezone      string      `gorm:"column:timezone;type:varchar(50);not null;default:'';"`
 	CreatedByID   *uuid.UUID  `gorm:"column:created_by_id;type:varchar(36);"`
 	CreatedBy     *User       `gorm:"foreignKey:CreatedByID;constraint:OnDelete:SET NULL;"`
 	UpdatedByID   *uuid.UUID  `gorm:"column:updated_by_id;type:varchar(36);"`
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type IdempotencyKey struct {
+	ID           uuid.UUID `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	Key          string    `gorm:"column:idempotency_key;type:varchar(255);not null;uniqueIndex:idx_idempotency_keys_scope;"`
+	Scope        string    `gorm:"column:scope;type:varchar(100);not null;uniqueIndex:idx_idempotency_keys_scope;"`
+	Owner        string    `gorm:"column:owner;type:varchar(100);not null;uniqueIndex:idx_idempotency_keys_scope;"`
+	RequestHash  string    `gorm:"column:request_hash;type:varchar(64);not null;"`
+	Status       string    `gorm:"column:status;type:enum('processing', 'completed');not null;default:'processing';"`
+	StatusCode   int       `gorm:"column:status_code;type:int;not null;default:0;"`
+	ResponseBody string    `gorm:"column:response_body;type:text;"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (ik *IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+func (ik *IdempotencyKey) BeforeCreate(tx *gorm.DB) (err error) {
+	ik.ID = uuid.New()
+
+	return
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	OutboxSetGasPump         = "set_gas_pump"
+	OutboxAccumPoints        = "accum_points"
+	OutboxReportTransaction  = "report_transaction"
+	OutboxSendMail           = "send_mail"
+	OutboxCapturePayment     = "capture_payment"
+	OutboxCancelPayment      = "cancel_payment"
+	OutboxRefundPayment      = "refund_payment"
+	OutboxCampaignUsage      = "campaign_usage"
+	OutboxDefaultMaxAttempts = 10
+)
+
+type OutboxMessage struct {
+	ID          uuid.UUID  `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	Type        string     `gorm:"column:type;type:enum('set_gas_pump', 'accum_points', 'report_transaction', 'send_mail', 'capture_payment', 'cancel_payment', 'refund_payment', 'campaign_usage');not null;"`
+	PaymentID   *uuid.UUID `gorm:"column:payment_id;type:varchar(36);index;"`
+	Payment     *Payment   `gorm:"constraint:OnDelete:SET NULL;"`
+	Payload     string     `gorm:"column:payload;type:text;"`
+	Status      string     `gorm:"column:status;type:enum('pending', 'processing', 'done', 'dead');not null;default:'pending';index:idx_outbox_messages_available,priority:1;"`
+	Attempts    int        `gorm:"column:attempts;type:int;not null;default:0;"`
+	MaxAttempts int        `gorm:"column:max_attempts;type:int;not null;default:10;"`
+	AvailableAt time.Time  `gorm:"column:available_at;not null;index:idx_outbox_messages_available,priority:2;"`
+	LastError   string     `gorm:"column:last_error;type:varchar(1000);not null;default:'';"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (om *OutboxMessage) TableName() string {
+	return "outbox_messages"
+}
+
+func (om *OutboxMessage) BeforeCreate(tx *gorm.DB) (err error) {
+	om.ID = uuid.New()
+
+	if om.AvailableAt.IsZero() {
+		om.AvailableAt = time.Now()
+	}
+
+	if om.MaxAttempts == 0 {
+		om.MaxAttempts = OutboxDefaultMaxAttempts
+	}
+
+	return
+}
@@ -1,26 +1,34 @@
 package models
 
 import (
+	"smartgas-payment/internal/money"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Capture methods of stripe payments, manual ones only hold the funds until the load
+// is served and the real amount is captured
+const (
+	CaptureAutomatic = "automatic"
+	CaptureManual    = "manual"
+)
+
 type Payment struct {
-	ID                    uuid.UUID `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
-	ExternalTransactionID string    `gorm:"column:external_transaction_id;type:varchar(255);not null;unique;"`
-	Amount                float32   `gorm:"column:amount;type:float;not null;default:0;check:amount > -1;"`
-	TotalLiter            float32   `gorm:"column:total_liter;type:float;not null;default:0;check:total_liter > -1;"`
-	ChargeType            string    `gorm:"column:charge_type;type:enum('by_total', 'by_liter');not null;default:'by_total';"`
-	RefundedAmount        float32   `gorm:"column:refunded_amount;type:float;not null;default:0;check:refunded_amount > -1;"`
-	RealAmountReported    float32   `gorm:"column:real_amount_reported;type:float;"`
-	ChargeFee             float32   `gorm:"column:charge_fee;type:float;default:0;not null;check:charge_fee > -1;"`
+	ID                    uuid.UUID    `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	ExternalTransactionID string       `gorm:"column:external_transaction_id;type:varchar(255);not null;unique;"`
+	Amount                money.Amount `gorm:"column:amount;type:decimal(12,2);not null;default:0;check:amount > -1;"`
+	TotalLiter            float32      `gorm:"column:total_liter;type:float;not null;default:0;check:total_liter > -1;"`
+	ChargeType            string       `gorm:"column:charge_type;type:enum('by_total', 'by_liter');not null;default:'by_total';"`
+	RefundedAmount        money.Amount `gorm:"column:refunded_amount;type:decimal(12,2);not null;default:0;check:refunded_amount > -1;"`
+	RealAmountReported    money.Amount `gorm:"column:real_amount_reported;type:decimal(12,2);"`
+	ChargeFee             money.Amount `gorm:"column:charge_fee;type:decimal(12,2);default:0;not null;check:charge_fee > -1;"`
 
 	FuelType         string  `gorm:"column:fuel_type;type:enum('regular', 'premium', 'diesel');not null;default:'regular';"`
 	Price            float64 `gorm:"column:price;type:double;not null;default:0;check:price > -1;"`
 	DiscountPerLiter float64 `gorm:"column:discount_per_liter;type:double;not null;default:0;check:discount_per_liter > -1;"`
-	DiscountType     string  `gorm:"column:discount_type;type:enum('campaign','elegibility','none');not null;default:'none';"`
+	DiscountType     string  `gorm:"column:discount_type;type:enum('campaign','elegibility','promo_code','combined','none');not null;default:'none';"`
 
 	Status          string `gorm:"column:status;type:enum('pending', 'paid', 'canceled', 'failed');not null;default:'pending';"`
 	PaymentProvider string `gorm:"column:payment_provider;type:enum('stripe', 'swit', 'debit');not null;default:'stripe';"`
@@ -38,10 +46,15 @@ type Payment struct {
 	Campaign        *Campaign  `gorm:"constraint:OnDelete:SET NULL;"`
 	LevelID         *uuid.UUID `gorm:"column:elegibility_level_id;type:varchar(36);"`
 	Level           *Level     `gorm:"foreignKey:LevelID;constraint:OnDelete:SET NULL;"`
+	PromoCodeID     *uuid.UUID `gorm:"column:promo_code_id;type:varchar(36);index;"`
+	PromoCode       *PromoCode `gorm:"constraint:OnDelete:SET NULL;"`
 	FromOperations  *bool      `gorm:"column:from_operations;type:boolean;not null;default:false;"`
 	GiftCardKey     *string    `gorm:"column:gift_card_key;type:varchar(40);"`
 	SetByEmployeeID *string    `gorm:"column:set_by_employee_id;type:varchar(20);"`
 
+	CaptureMethod          string     `gorm:"column:capture_method;type:enum('automatic', 'manual');not null;default:'automatic';"`
+	AuthorizationExpiresAt *time.Time `gorm:"column:authorization_expires_at;"`
+
 	Events []PaymentEvent
 
 	gorm.Model
@@ -59,10 +72,12 @@ func (p *Payment) BeforeCreate(tx *gorm.DB) (err error) {
 
 type PaymentEvent struct {
 	ID                      uuid.UUID `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
-	Type                    string    `gorm:"column:type;type:enum('paid','funds_reserved', 'failed', 'canceled', 'pending', 'serving', 'serving_paused', 'served', 'partial_refund', 'pump_ready', 'internal_cancellation', 'manual_action');not null;default:'pending'"`
+	Type                    string    `gorm:"column:type;type:enum('paid','funds_reserved', 'failed', 'canceled', 'pending', 'serving', 'serving_paused', 'served', 'partial_refund', 'pump_ready', 'internal_cancellation', 'manual_action', 'requires_action', 'processing', 'disputed', 'dispute_won', 'dispute_lost', 'authorization_expired');not null;default:'pending'"`
 	PaymentID               uuid.UUID
 	AuthorizedApplicationID *uuid.UUID
 	AuthorizedApplication   *AuthorizedApplication
+	UserID                  *uuid.UUID `gorm:"column:user_id;type:varchar(36);"`
+	User                    *User      `gorm:"constraint:OnDelete:SET NULL;"`
 	CreatedAt               time.Time
 }
 
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Promo code types, batch codes are created together and can be used once each
+const (
+	PromoCodeSingleUse = "single_use"
+	PromoCodeMultiUse  = "multi_use"
+	PromoCodeBatch     = "batch"
+)
+
+// Promo redemption statuses, reserved along with the payment and redeemed once it is
+// served or released if it is canceled
+const (
+	PromoRedemptionReserved = "reserved"
+	PromoRedemptionRedeemed = "redeemed"
+	PromoRedemptionReleased = "released"
+)
+
+// PromoCode gives the discount of its campaign to the loads sending it. MaxUses and
+// MaxUsesPerCustomer are unlimited when nil, Uses counts the reserved and redeemed ones
+type PromoCode struct {
+	ID                 uuid.UUID  `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	Code               string     `gorm:"column:code;type:varchar(40);not null;uniqueIndex;"`
+	Type               string     `gorm:"column:type;type:enum('single_use', 'multi_use', 'batch');not null;"`
+	CampaignID         uuid.UUID  `gorm:"column:campaign_id;type:varchar(36);not null;index;"`
+	Campaign           *Campaign  `gorm:"constraint:OnDelete:CASCADE;"`
+	BatchID            *uuid.UUID `gorm:"column:batch_id;type:varchar(36);index;"`
+	CustomerID         *uuid.UUID `gorm:"column:customer_id;type:varchar(36);index;"`
+	Customer           *Customer  `gorm:"constraint:OnDelete:CASCADE;"`
+	MaxUses            *int       `gorm:"column:max_uses;type:int;check:max_uses > 0;"`
+	MaxUsesPerCustomer *int       `gorm:"column:max_uses_per_customer;type:int;check:max_uses_per_customer > 0;"`
+	Uses               int        `gorm:"column:uses;type:int;not null;default:0;check:uses > -1;"`
+	Active             *bool      `gorm:"column:active;type:boolean;not null;default:true;"`
+	CreatedByID        *uuid.UUID `gorm:"column:created_by_id;type:varchar(36);"`
+	CreatedBy          *User      `gorm:"foreignKey:CreatedByID;constraint:OnDelete:SET NULL;"`
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+func (pc *PromoCode) TableName() string {
+	return "promo_codes"
+}
+
+func (pc *PromoCode) BeforeCreate(tx *gorm.DB) (err error) {
+	pc.ID = uuid.New()
+	pc.Code = NormalizePromoCode(pc.Code)
+
+	return
+}
+
+// NormalizePromoCode is the code as stored, codes are not case sensitive
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// PromoRedemption is the use of a promo code by a payment
+type PromoRedemption struct {
+	ID               uuid.UUID  `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	PromoCodeID      uuid.UUID  `gorm:"column:promo_code_id;type:varchar(36);not null;index:idx_promo_redemption,priority:1;"`
+	PromoCode        *PromoCode `gorm:"constraint:OnDelete:CASCADE;"`
+	PaymentID        uuid.UUID  `gorm:"column:payment_id;type:varchar(36);not null;uniqueIndex;"`
+	Payment          *Payment   `gorm:"constraint:OnDelete:CASCADE;"`
+	CustomerID       *uuid.UUID `gorm:"column:customer_id;type:varchar(36);index:idx_promo_redemption,priority:2;"`
+	Customer         *Customer  `gorm:"constraint:OnDelete:SET NULL;"`
+	Status           string     `gorm:"column:status;type:enum('reserved', 'redeemed', 'released');not null;default:'reserved';"`
+	DiscountPerLiter float64    `gorm:"column:discount_per_liter;type:double;not null;default:0;"`
+	CreatedAt        time.Time  `gorm:"index;"`
+	UpdatedAt        time.Time
+}
+
+func (pr *PromoRedemption) TableName() string {
+	return "promo_redemptions"
+}
+
+func (pr *PromoRedemption) BeforeCreate(tx *gorm.DB) (err error) {
+	pr.ID = uuid.New()
+
+	return
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DiscrepancyMissingLocally  = "missing_locally"
+	DiscrepancyMissingRemotely = "missing_remotely"
+	DiscrepancyAmount          = "amount_mismatch"
+	DiscrepancyRefund          = "refund_mismatch"
+)
+
+type Reconciliation struct {
+	ID      uuid.UUID `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	From    time.Time `gorm:"column:from_date;not null;"`
+	To      time.Time `gorm:"column:to_date;not null;"`
+	Status  string    `gorm:"column:status;type:enum('running', 'done', 'failed');default:'running';not null;"`
+	Matched int       `gorm:"column:matched;not null;default:0;"`
+	Errors  string    `gorm:"column:errors;type:varchar(1000);not null;default:'';"`
+	// Running is true until the reconciliation ends and NULL after, its unique index
+	// keeps a single reconciliation running at a time
+	Running       *bool `gorm:"column:running;uniqueIndex;"`
+	Discrepancies []ReconciliationDiscrepancy
+	CreatedAt     time.Time
+}
+
+func (r *Reconciliation) TableName() string {
+	return "reconciliations"
+}
+
+func (r *Reconciliation) BeforeCreate(tx *gorm.DB) (err error) {
+	r.ID = uuid.New()
+
+	return
+}
+
+type ReconciliationDiscrepancy struct {
+	ID                    uuid.UUID    `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	ReconciliationID      uuid.UUID    `gorm:"column:reconciliation_id;type:varchar(36);not null;"`
+	Type                  string       `gorm:"column:type;type:enum('missing_locally', 'missing_remotely', 'amount_mismatch', 'refund_mismatch');not null;"`
+	PaymentProvider       string       `gorm:"column:payment_provider;type:enum('stripe', 'swit', 'debit');not null;"`
+	ExternalTransactionID string       `gorm:"column:external_transaction_id;type:varchar(255);not null;"`
+	PaymentID             *uuid.UUID   `gorm:"column:payment_id;type:varchar(36);"`
+	Payment               *Payment     `gorm:"constraint:OnDelete:SET NULL;"`
+	LocalAmount           money.Amount `gorm:"column:local_amount;type:decimal(12,2);not null;default:0;"`
+	RemoteAmount          money.Amount `gorm:"column:remote_amount;type:decimal(12,2);not null;default:0;"`
+	CreatedAt             time.Time
+}
+
+func (rd *ReconciliationDiscrepancy) TableName() string {
+	return "reconciliation_discrepancies"
+}
+
+func (rd *ReconciliationDiscrepancy) BeforeCreate(tx *gorm.DB) (err error) {
+	rd.ID = uuid.New()
+
+	return
+}
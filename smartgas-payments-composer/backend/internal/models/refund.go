@@ -0,0 +1,42 @@
+package models
+
+import (
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	RefundPending = "pending"
+	RefundDone    = "done"
+	RefundFailed  = "failed"
+)
+
+// Refund is a refund requested by an admin, its amount is reserved while pending so
+// refunds running at the same time can not give back more than was captured
+type Refund struct {
+	ID              uuid.UUID    `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	PaymentID       uuid.UUID    `gorm:"column:payment_id;type:varchar(36);not null;index;"`
+	Payment         *Payment     `gorm:"constraint:OnDelete:CASCADE;"`
+	UserID          *uuid.UUID   `gorm:"column:user_id;type:varchar(36);"`
+	User            *User        `gorm:"constraint:OnDelete:SET NULL;"`
+	Amount          money.Amount `gorm:"column:amount;type:decimal(12,2);not null;"`
+	Reason          string       `gorm:"column:reason;type:varchar(255);not null;"`
+	PaymentProvider string       `gorm:"column:payment_provider;type:varchar(50);not null;"`
+	Status          string       `gorm:"column:status;type:enum('pending', 'done', 'failed');not null;default:'pending';"`
+	Error           string       `gorm:"column:error;type:varchar(1000);not null;default:'';"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (r *Refund) TableName() string {
+	return "refunds"
+}
+
+func (r *Refund) BeforeCreate(tx *gorm.DB) (err error) {
+	r.ID = uuid.New()
+
+	return
+}
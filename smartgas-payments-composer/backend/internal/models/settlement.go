@@ -0,0 +1,73 @@
+package models
+
+import (
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	SettlementOpen   = "open"
+	SettlementClosed = "closed"
+
+	SettlementLinePayment    = "payment"
+	SettlementLineAdjustment = "adjustment"
+)
+
+type Settlement struct {
+	ID           uuid.UUID    `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	LegalNameID  string       `gorm:"column:legal_name_id;type:varchar(10);not null;uniqueIndex:idx_settlement_period;"`
+	From         time.Time    `gorm:"column:from_date;not null;uniqueIndex:idx_settlement_period;"`
+	To           time.Time    `gorm:"column:to_date;not null;uniqueIndex:idx_settlement_period;"`
+	Status       string       `gorm:"column:status;type:enum('open', 'closed');default:'open';not null;"`
+	Captured     money.Amount `gorm:"column:captured;type:decimal(12,2);not null;default:0;"`
+	Refunds      money.Amount `gorm:"column:refunds;type:decimal(12,2);not null;default:0;"`
+	ProviderFees money.Amount `gorm:"column:provider_fees;type:decimal(12,2);not null;default:0;"`
+	Net          money.Amount `gorm:"column:net;type:decimal(12,2);not null;default:0;"`
+	Payments     int          `gorm:"column:payments;not null;default:0;"`
+	Adjustments  int          `gorm:"column:adjustments;not null;default:0;"`
+	ClosedAt     *time.Time   `gorm:"column:closed_at;"`
+	ClosedByID   *uuid.UUID   `gorm:"column:closed_by_id;type:varchar(36);"`
+	ClosedBy     *User        `gorm:"foreignKey:ClosedByID;constraint:OnDelete:SET NULL;"`
+	Lines        []SettlementLine
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (s *Settlement) TableName() string {
+	return "settlements"
+}
+
+func (s *Settlement) BeforeCreate(tx *gorm.DB) (err error) {
+	s.ID = uuid.New()
+
+	return
+}
+
+// SettlementLine is a payment captured in the period of the settlement, or an
+// adjustment for the refunds of a payment settled in a closed period
+type SettlementLine struct {
+	ID              uuid.UUID    `gorm:"column:id;primaryKey;type:varchar(36);<-:create;"`
+	SettlementID    uuid.UUID    `gorm:"column:settlement_id;type:varchar(36);not null;"`
+	PaymentID       uuid.UUID    `gorm:"column:payment_id;type:varchar(36);not null;index;"`
+	Payment         *Payment     `gorm:"constraint:OnDelete:RESTRICT;"`
+	Type            string       `gorm:"column:type;type:enum('payment', 'adjustment');not null;"`
+	PaymentProvider string       `gorm:"column:payment_provider;type:enum('stripe', 'swit', 'debit');not null;"`
+	Captured        money.Amount `gorm:"column:captured;type:decimal(12,2);not null;default:0;"`
+	Refunds         money.Amount `gorm:"column:refunds;type:decimal(12,2);not null;default:0;"`
+	ProviderFee     money.Amount `gorm:"column:provider_fee;type:decimal(12,2);not null;default:0;"`
+	Net             money.Amount `gorm:"column:net;type:decimal(12,2);not null;default:0;"`
+	CreatedAt       time.Time
+}
+
+func (sl *SettlementLine) TableName() string {
+	return "settlement_lines"
+}
+
+func (sl *SettlementLine) BeforeCreate(tx *gorm.DB) (err error) {
+	sl.ID = uuid.New()
+
+	return
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	StripeEventPending   = "pending"
+	StripeEventProcessed = "processed"
+	StripeEventFailed    = "failed"
+	StripeEventIgnored   = "ignored"
+)
+
+// StripeEvent is a verified webhook event, kept so redeliveries are not processed
+// twice and failed ones can be replayed
+type StripeEvent struct {
+	ID          string     `gorm:"column:id;primaryKey;type:varchar(255);<-:create;"`
+	Type        string     `gorm:"column:type;type:varchar(255);not null;index;"`
+	Payload     string     `gorm:"column:payload;type:mediumtext;not null;"`
+	Status      string     `gorm:"column:status;type:enum('pending', 'processed', 'failed', 'ignored');not null;default:'pending';index;"`
+	Error       string     `gorm:"column:error;type:varchar(1000);not null;default:'';"`
+	Attempts    int        `gorm:"column:attempts;type:int;not null;default:0;"`
+	ProcessedAt *time.Time `gorm:"column:processed_at;"`
+	// LockedUntil is the end of the lease of the attempt processing the event
+	LockedUntil *time.Time `gorm:"column:locked_until;"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (se *StripeEvent) TableName() string {
+	return "stripe_events"
+}
@@ -0,0 +1,205 @@
+// Package money handles MXN amounts as integer centavos so payment, provider and
+// invoice totals reconcile to the cent.
+//
+// Rounding rules: every conversion or operation producing fractions of a centavo
+// rounds half away from zero (the rule used by the SAT for CFDI amounts), and
+// liters are kept with litersPrecision decimals.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	centsPerUnit    = 100
+	litersPrecision = 1000
+)
+
+var ErrInvalidAmount = errors.New("Invalid money amount")
+
+// Amount is an amount of MXN in centavos. It is written to json as a decimal number
+// and stored as DECIMAL(12,2)
+type Amount int64
+
+const Zero Amount = 0
+
+func FromCents(cents int64) Amount {
+	return Amount(cents)
+}
+
+// FromFloat converts pesos to an Amount, rounding to the nearest centavo
+func FromFloat(pesos float64) Amount {
+	return Amount(math.Round(pesos * centsPerUnit))
+}
+
+// Parse reads a decimal string ("123.45", "-3", "10.005") without going through floats,
+// extra decimals are rounded to the nearest centavo
+func Parse(value string) (Amount, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Zero, fmt.Errorf("%w: empty", ErrInvalidAmount)
+	}
+
+	negative := false
+	if value[0] == '-' || value[0] == '+' {
+		negative = value[0] == '-'
+		value = value[1:]
+	}
+
+	integer, decimals, _ := strings.Cut(value, ".")
+	if integer == "" {
+		integer = "0"
+	}
+
+	units, err := strconv.ParseInt(integer, 10, 64)
+	if err != nil || strings.ContainsAny(integer, "+-") {
+		return Zero, fmt.Errorf("%w: %s", ErrInvalidAmount, value)
+	}
+
+	for _, d := range decimals {
+		if d < '0' || d > '9' {
+			return Zero, fmt.Errorf("%w: %s", ErrInvalidAmount, value)
+		}
+	}
+
+	padded := decimals + "00"
+	cents := units*centsPerUnit + int64(padded[0]-'0')*10 + int64(padded[1]-'0')
+	if len(decimals) > 2 && decimals[2] >= '5' {
+		cents++
+	}
+
+	if negative {
+		cents = -cents
+	}
+
+	return Amount(cents), nil
+}
+
+func (a Amount) Cents() int64 {
+	return int64(a)
+}
+
+// Float64 is meant for services outside our control that only take floats
+func (a Amount) Float64() float64 {
+	return float64(a) / centsPerUnit
+}
+
+func (a Amount) String() string {
+	cents := int64(a)
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, cents/centsPerUnit, cents%centsPerUnit)
+}
+
+// Mul multiplies the amount by factor, rounding to the nearest centavo
+func (a Amount) Mul(factor float64) Amount {
+	return Amount(math.Round(float64(a) * factor))
+}
+
+// Div divides the amount by divisor, rounding to the nearest centavo
+func (a Amount) Div(divisor float64) Amount {
+	return Amount(math.Round(float64(a) / divisor))
+}
+
+// Liters is how many liters the amount buys at pricePerLiter
+func (a Amount) Liters(pricePerLiter float64) float64 {
+	if pricePerLiter <= 0 {
+		return 0
+	}
+
+	return RoundLiters(a.Float64() / pricePerLiter)
+}
+
+// ForLiters is the cost of liters at pricePerLiter
+func ForLiters(liters float64, pricePerLiter float64) Amount {
+	return FromFloat(liters * pricePerLiter)
+}
+
+// RoundLiters rounds liters to the precision reported by the pumps
+func RoundLiters(liters float64) float64 {
+	return math.Round(liters*litersPrecision) / litersPrecision
+}
+
+func Min(a Amount, b Amount) Amount {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts numbers and numeric strings
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "null" {
+		return nil
+	}
+
+	// Exponent notation is not exact anyway
+	if strings.ContainsAny(value, "eE") {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidAmount, value)
+		}
+		*a = FromFloat(number)
+		return nil
+	}
+
+	parsed, err := Parse(value)
+	if err != nil {
+		return err
+	}
+
+	*a = parsed
+
+	return nil
+}
+
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+func (a *Amount) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*a = Zero
+	case []byte:
+		return a.scanString(string(value))
+	case string:
+		return a.scanString(value)
+	case float64:
+		*a = FromFloat(value)
+	case float32:
+		*a = FromFloat(float64(value))
+	case int64:
+		*a = Amount(value * centsPerUnit)
+	default:
+		return fmt.Errorf("%w: unsupported type %T", ErrInvalidAmount, src)
+	}
+
+	return nil
+}
+
+func (a *Amount) scanString(value string) error {
+	parsed, err := Parse(value)
+	if err != nil {
+		return err
+	}
+
+	*a = parsed
+
+	return nil
+}
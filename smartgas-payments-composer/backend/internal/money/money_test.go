@@ -0,0 +1,143 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type moneyTest struct {
+	suite.Suite
+}
+
+func (suite *moneyTest) TestParse() {
+	testcases := []struct {
+		Name    string
+		Value   string
+		Amount  Amount
+		Invalid bool
+	}{
+		{Name: "TestMoney_Integer", Value: "150", Amount: 15000},
+		{Name: "TestMoney_Decimals", Value: "123.45", Amount: 12345},
+		{Name: "TestMoney_OneDecimal", Value: "0.5", Amount: 50},
+		{Name: "TestMoney_NoInteger", Value: ".07", Amount: 7},
+		{Name: "TestMoney_TrailingDot", Value: "5.", Amount: 500},
+		{Name: "TestMoney_Spaces", Value: " 12.30 ", Amount: 1230},
+		{Name: "TestMoney_Plus", Value: "+3", Amount: 300},
+		{Name: "TestMoney_Negative", Value: "-3.10", Amount: -310},
+		{Name: "TestMoney_RoundsHalfUp", Value: "10.005", Amount: 1001},
+		{Name: "TestMoney_RoundsDown", Value: "10.0049", Amount: 1000},
+		{Name: "TestMoney_RoundsToNextPeso", Value: "1.999", Amount: 200},
+		{Name: "TestMoney_NegativeRoundsAwayFromZero", Value: "-10.005", Amount: -1001},
+		{Name: "TestMoney_Empty", Value: "", Invalid: true},
+		{Name: "TestMoney_Letters", Value: "abc", Invalid: true},
+		{Name: "TestMoney_LettersInDecimals", Value: "1.2a", Invalid: true},
+		{Name: "TestMoney_DoubleSign", Value: "--1", Invalid: true},
+		{Name: "TestMoney_Exponent", Value: "1e3", Invalid: true},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			amount, err := Parse(tc.Value)
+
+			if tc.Invalid {
+				suite.ErrorIs(err, ErrInvalidAmount)
+				return
+			}
+
+			suite.Nil(err)
+			suite.Equal(tc.Amount, amount)
+		})
+	}
+}
+
+func (suite *moneyTest) TestRounding() {
+	testcases := []struct {
+		Name   string
+		Got    Amount
+		Amount Amount
+	}{
+		{Name: "TestMoney_FromFloat", Got: FromFloat(99.99), Amount: 9999},
+		{Name: "TestMoney_FromFloatHalf", Got: FromFloat(0.125), Amount: 13},
+		{Name: "TestMoney_FromFloatNegativeHalf", Got: FromFloat(-0.125), Amount: -13},
+		{Name: "TestMoney_MulHalf", Got: Amount(5).Mul(0.5), Amount: 3},
+		{Name: "TestMoney_MulNegativeHalf", Got: Amount(-5).Mul(0.5), Amount: -3},
+		{Name: "TestMoney_Mul", Got: FromFloat(1000).Mul(0.16), Amount: 16000},
+		{Name: "TestMoney_DivHalf", Got: Amount(10).Div(4), Amount: 3},
+		{Name: "TestMoney_Div", Got: Amount(10000).Div(3), Amount: 3333},
+		{Name: "TestMoney_ForLiters", Got: ForLiters(10.5, 23.49), Amount: 24665},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.Equal(tc.Amount, tc.Got)
+		})
+	}
+}
+
+func (suite *moneyTest) TestLiters() {
+	suite.Equal(21.277, FromFloat(500).Liters(23.5))
+	suite.Equal(0.0, FromFloat(500).Liters(0))
+	suite.Equal(1.235, RoundLiters(1.2345))
+}
+
+func (suite *moneyTest) TestString() {
+	suite.Equal("0.05", Amount(5).String())
+	suite.Equal("-0.05", Amount(-5).String())
+	suite.Equal("1234.50", Amount(123450).String())
+}
+
+func (suite *moneyTest) TestJSON() {
+	var amount Amount
+
+	suite.Nil(amount.UnmarshalJSON([]byte(`"12.30"`)))
+	suite.Equal(Amount(1230), amount)
+
+	suite.Nil(amount.UnmarshalJSON([]byte(`99.995`)))
+	suite.Equal(Amount(10000), amount)
+
+	suite.Nil(amount.UnmarshalJSON([]byte(`1e2`)))
+	suite.Equal(Amount(10000), amount)
+
+	suite.ErrorIs(amount.UnmarshalJSON([]byte(`"ten"`)), ErrInvalidAmount)
+
+	data, err := Amount(1230).MarshalJSON()
+	suite.Nil(err)
+	suite.Equal("12.30", string(data))
+}
+
+func (suite *moneyTest) TestScan() {
+	testcases := []struct {
+		Name   string
+		Src    any
+		Amount Amount
+	}{
+		{Name: "TestMoney_ScanDecimal", Src: []byte("12.34"), Amount: 1234},
+		{Name: "TestMoney_ScanString", Src: "0.10", Amount: 10},
+		{Name: "TestMoney_ScanInteger", Src: int64(5), Amount: 500},
+		{Name: "TestMoney_ScanFloat", Src: 0.1, Amount: 10},
+		{Name: "TestMoney_ScanNull", Src: nil, Amount: Zero},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			amount := Amount(1)
+
+			suite.Nil(amount.Scan(tc.Src))
+			suite.Equal(tc.Amount, amount)
+		})
+	}
+
+	var amount Amount
+	suite.ErrorIs(amount.Scan(true), ErrInvalidAmount)
+}
+
+func TestMoney(t *testing.T) {
+	suite.Run(t, new(moneyTest))
+}
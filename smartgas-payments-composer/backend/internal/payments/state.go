@@ -0,0 +1,149 @@
+// Package payments holds the payment lifecycle rules shared by controllers,
+// repositories and tasks
+package payments
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	StatusPending  = "pending"
+	StatusPaid     = "paid"
+	StatusCanceled = "canceled"
+	StatusFailed   = "failed"
+)
+
+var (
+	ErrInvalidTransition     = errors.New("Payment transition not allowed")
+	ErrRefundExceedsCaptured = errors.New("Refund greater than the amount left to refund")
+)
+
+// TransitionError is returned when an event can not follow the last one of a payment
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (te *TransitionError) Error() string {
+	from := te.From
+	if from == "" {
+		from = "none"
+	}
+
+	return fmt.Sprintf("%s: %s -> %s", ErrInvalidTransition.Error(), from, te.To)
+}
+
+func (te *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// transitions maps the last event of a payment to the events allowed after it,
+// the empty key holds the events a payment can be created with
+var transitions = map[string][]string{
+	"":                      {"pending", "funds_reserved"},
+	"pending":               {"paid", "failed", "canceled", "requires_action", "processing"},
+	"failed":                {"paid", "failed", "canceled", "requires_action", "processing"},
+	"requires_action":       {"paid", "failed", "canceled", "requires_action", "processing"},
+	"processing":            {"paid", "failed", "canceled", "requires_action", "processing"},
+	"paid":                  {"pump_ready", "serving", "served", "manual_action", "internal_cancellation", "authorization_expired", "disputed"},
+	"funds_reserved":        {"pump_ready", "serving", "served", "manual_action", "internal_cancellation"},
+	"pump_ready":            {"serving", "served", "internal_cancellation", "authorization_expired", "disputed"},
+	"serving":               {"serving", "serving_paused", "served", "disputed"},
+	"serving_paused":        {"serving", "serving_paused", "served", "disputed"},
+	"served":                {"partial_refund", "disputed", "authorization_expired"},
+	"partial_refund":        {"partial_refund", "disputed"},
+	"manual_action":         {"partial_refund", "disputed"},
+	"internal_cancellation": {"disputed"},
+	"canceled":              {},
+	"disputed":              {"disputed", "dispute_won", "dispute_lost", "partial_refund"},
+	"dispute_won":           {"disputed", "partial_refund"},
+	"dispute_lost":          {"partial_refund"},
+	"authorization_expired": {},
+}
+
+// statusByEvent is the status a payment gets after an event, events not listed
+// keep the status the payment already had
+var statusByEvent = map[string]string{
+	"pending":               StatusPending,
+	"funds_reserved":        StatusPaid,
+	"paid":                  StatusPaid,
+	"failed":                StatusFailed,
+	"requires_action":       StatusPending,
+	"processing":            StatusPending,
+	"canceled":              StatusCanceled,
+	"internal_cancellation": StatusCanceled,
+	"manual_action":         StatusCanceled,
+	"authorization_expired": StatusFailed,
+}
+
+// OpenEvents are the last events of the payments whose load is not finished, their
+// funds may be held and their pump preset
+var OpenEvents = []string{
+	"pending",
+	"requires_action",
+	"processing",
+	"paid",
+	"funds_reserved",
+	"pump_ready",
+	"serving",
+	"serving_paused",
+}
+
+// CanTransition reports whether event can be added after the last one (empty if none)
+func CanTransition(last string, event string) bool {
+	for _, allowed := range transitions[last] {
+		if allowed == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateTransition returns a *TransitionError when event can not follow last
+func ValidateTransition(last string, event string) error {
+	if !CanTransition(last, event) {
+		return &TransitionError{From: last, To: event}
+	}
+
+	return nil
+}
+
+// ValidateStream checks every transition of an event stream ordered from oldest to newest
+func ValidateStream(events []string) error {
+	last := ""
+
+	for _, event := range events {
+		if err := ValidateTransition(last, event); err != nil {
+			return err
+		}
+		last = event
+	}
+
+	return nil
+}
+
+// DeriveStatus computes the payment status from its events ordered from oldest to newest
+func DeriveStatus(events []string) string {
+	status := StatusPending
+
+	for _, event := range events {
+		if s, ok := statusByEvent[event]; ok {
+			status = s
+		}
+	}
+
+	return status
+}
+
+// IsFinished reports whether the load was served and the payment kept, that is,
+// it can be invoiced
+func IsFinished(status string, last string) bool {
+	return status == StatusPaid && (last == "served" || last == "partial_refund" || last == "dispute_won")
+}
+
+// IsInvoiceable reports whether a payment not invoiced yet can be invoiced
+func IsInvoiceable(invoiced bool, status string, last string) bool {
+	return !invoiced && IsFinished(status, last)
+}
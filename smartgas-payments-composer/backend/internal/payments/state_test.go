@@ -0,0 +1,111 @@
+package payments
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type stateTest struct {
+	suite.Suite
+}
+
+func (suite *stateTest) TestValidateTransition() {
+	testcases := []struct {
+		Name  string
+		Last  string
+		Event string
+		Valid bool
+	}{
+		{Name: "TestState_CreatedPending", Last: "", Event: "pending", Valid: true},
+		{Name: "TestState_CreatedReserved", Last: "", Event: "funds_reserved", Valid: true},
+		{Name: "TestState_CreatedServed", Last: "", Event: "served"},
+		{Name: "TestState_PendingPaid", Last: "pending", Event: "paid", Valid: true},
+		{Name: "TestState_FailedRetried", Last: "failed", Event: "paid", Valid: true},
+		{Name: "TestState_PaidServed", Last: "paid", Event: "served", Valid: true},
+		{Name: "TestState_PaidExpired", Last: "paid", Event: "authorization_expired", Valid: true},
+		{Name: "TestState_ServingPaused", Last: "serving", Event: "serving_paused", Valid: true},
+		{Name: "TestState_ServingCanceled", Last: "serving", Event: "internal_cancellation"},
+		{Name: "TestState_ServedRefunded", Last: "served", Event: "partial_refund", Valid: true},
+		{Name: "TestState_ServedServedAgain", Last: "served", Event: "served"},
+		{Name: "TestState_CanceledIsFinal", Last: "canceled", Event: "paid"},
+		{Name: "TestState_DisputeWonRefunded", Last: "dispute_won", Event: "partial_refund", Valid: true},
+		{Name: "TestState_PaidDisputed", Last: "paid", Event: "disputed", Valid: true},
+		{Name: "TestState_PumpReadyDisputed", Last: "pump_ready", Event: "disputed", Valid: true},
+		{Name: "TestState_ServingDisputed", Last: "serving", Event: "disputed", Valid: true},
+		{Name: "TestState_ServingPausedDisputed", Last: "serving_paused", Event: "disputed", Valid: true},
+		{Name: "TestState_ServedDisputed", Last: "served", Event: "disputed", Valid: true},
+		{Name: "TestState_CanceledDisputed", Last: "internal_cancellation", Event: "disputed", Valid: true},
+		{Name: "TestState_PendingDisputed", Last: "pending", Event: "disputed"},
+		{Name: "TestState_DisputeLostRefunded", Last: "dispute_lost", Event: "partial_refund", Valid: true},
+		{Name: "TestState_DisputeLostNotDisputedAgain", Last: "dispute_lost", Event: "disputed"},
+		{Name: "TestState_UnknownLast", Last: "unknown", Event: "paid"},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			err := ValidateTransition(tc.Last, tc.Event)
+
+			if tc.Valid {
+				suite.Nil(err)
+				return
+			}
+
+			var transitionErr *TransitionError
+			suite.True(errors.As(err, &transitionErr))
+			suite.ErrorIs(err, ErrInvalidTransition)
+			suite.Equal(tc.Last, transitionErr.From)
+			suite.Equal(tc.Event, transitionErr.To)
+		})
+	}
+}
+
+func (suite *stateTest) TestValidateStream() {
+	suite.Nil(ValidateStream([]string{"pending", "paid", "pump_ready", "serving", "served", "partial_refund"}))
+	suite.ErrorIs(ValidateStream([]string{"pending", "served"}), ErrInvalidTransition)
+	suite.ErrorIs(ValidateStream([]string{"paid"}), ErrInvalidTransition)
+}
+
+func (suite *stateTest) TestDeriveStatus() {
+	testcases := []struct {
+		Name   string
+		Events []string
+		Status string
+	}{
+		{Name: "TestState_NoEvents", Events: nil, Status: StatusPending},
+		{Name: "TestState_Pending", Events: []string{"pending", "requires_action"}, Status: StatusPending},
+		{Name: "TestState_Paid", Events: []string{"pending", "paid"}, Status: StatusPaid},
+		{Name: "TestState_Reserved", Events: []string{"funds_reserved"}, Status: StatusPaid},
+		{Name: "TestState_ServedKeepsPaid", Events: []string{"pending", "paid", "serving", "served"}, Status: StatusPaid},
+		{Name: "TestState_RefundKeepsPaid", Events: []string{"funds_reserved", "served", "partial_refund"}, Status: StatusPaid},
+		{Name: "TestState_FailedThenPaid", Events: []string{"pending", "failed", "paid"}, Status: StatusPaid},
+		{Name: "TestState_Failed", Events: []string{"pending", "failed"}, Status: StatusFailed},
+		{Name: "TestState_Canceled", Events: []string{"pending", "canceled"}, Status: StatusCanceled},
+		{Name: "TestState_InternalCancellation", Events: []string{"funds_reserved", "internal_cancellation"}, Status: StatusCanceled},
+		{Name: "TestState_ManualAction", Events: []string{"pending", "paid", "manual_action"}, Status: StatusCanceled},
+		{Name: "TestState_AuthorizationExpired", Events: []string{"pending", "paid", "authorization_expired"}, Status: StatusFailed},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.Equal(tc.Status, DeriveStatus(tc.Events))
+		})
+	}
+}
+
+func (suite *stateTest) TestIsInvoiceable() {
+	suite.True(IsInvoiceable(false, StatusPaid, "served"))
+	suite.True(IsInvoiceable(false, StatusPaid, "dispute_won"))
+	suite.False(IsInvoiceable(true, StatusPaid, "served"))
+	suite.False(IsInvoiceable(false, StatusPaid, "serving"))
+	suite.False(IsInvoiceable(false, StatusCanceled, "manual_action"))
+}
+
+func TestState(t *testing.T) {
+	suite.Run(t, new(stateTest))
+}
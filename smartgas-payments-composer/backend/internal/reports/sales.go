@@ -0,0 +1,210 @@
+// Package reports groups the aggregates of the payments in the periods and
+// dimensions requested by the reporting endpoints
+package reports
+
+import (
+	"smartgas-payment/internal/money"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// Dimensions payments can be grouped by
+const (
+	GroupByStation  = "station"
+	GroupByPump     = "pump"
+	GroupByFuelType = "fuel_type"
+	GroupByProvider = "payment_provider"
+)
+
+// HourLayout is the format of SalesRow.Hour
+const HourLayout = "2006-01-02 15:04:05"
+
+// DateLayout is the format of the start of the periods
+const DateLayout = "2006-01-02"
+
+type SalesTotals struct {
+	Transactions int64
+	Amount       money.Amount
+	Liters       float64
+	Refunds      money.Amount
+	ChargeFees   money.Amount
+	Discounts    money.Amount
+}
+
+func (st *SalesTotals) add(other *SalesTotals) {
+	st.Transactions += other.Transactions
+	st.Amount += other.Amount
+	st.Liters += other.Liters
+	st.Refunds += other.Refunds
+	st.ChargeFees += other.ChargeFees
+	st.Discounts += other.Discounts
+}
+
+// SalesRow holds the totals of an hour (in the timezone of the app) for the
+// dimensions requested, the ones not requested are empty
+type SalesRow struct {
+	Hour            string
+	Timezone        string
+	GasStationID    string
+	GasStationName  string
+	GasPumpID       string
+	GasPumpNumber   string
+	FuelType        string
+	PaymentProvider string
+	SalesTotals
+}
+
+// SalesGroup holds the totals of a period, which starts at midnight in the timezone
+// of each station. Period is nil when no period was requested
+type SalesGroup struct {
+	Period          *time.Time
+	GasStationID    string
+	GasStationName  string
+	GasPumpID       string
+	GasPumpNumber   string
+	FuelType        string
+	PaymentProvider string
+	SalesTotals
+}
+
+var locations sync.Map
+
+// Location returns the timezone named, the timezone of the app when it is empty
+// or unknown
+func Location(name string) *time.Location {
+	if name == "" {
+		return time.Local
+	}
+
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+
+	locations.Store(name, loc)
+
+	return loc
+}
+
+// PeriodStart returns the start of the period containing t, in the location of t.
+// Weeks start on monday
+func PeriodStart(t time.Time, period string) time.Time {
+	year, month, day := t.Date()
+
+	switch period {
+	case PeriodMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	case PeriodWeek:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// DaysIn returns the range with the same dates of [from, to) starting at midnight in loc
+func DaysIn(from time.Time, to time.Time, loc *time.Location) (time.Time, time.Time) {
+	return time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc),
+		time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, loc)
+}
+
+type groupKey struct {
+	period          string
+	gasStationID    string
+	gasPumpID       string
+	fuelType        string
+	paymentProvider string
+}
+
+// GroupSales merges the hourly rows in the periods of the timezone of each station,
+// rows are merged as they are when period is empty. Groups are sorted by period and
+// then by the dimensions
+func GroupSales(rows []*SalesRow, period string) []*SalesGroup {
+	groups := map[groupKey]*SalesGroup{}
+
+	for _, row := range rows {
+		key := groupKey{
+			gasStationID:    row.GasStationID,
+			gasPumpID:       row.GasPumpID,
+			fuelType:        row.FuelType,
+			paymentProvider: row.PaymentProvider,
+		}
+
+		var start *time.Time
+		if period != "" {
+			hour, err := time.ParseInLocation(HourLayout, row.Hour, time.Local)
+			if err != nil {
+				continue
+			}
+
+			periodStart := PeriodStart(hour.In(Location(row.Timezone)), period)
+			start = &periodStart
+			// Stations in other timezones start the same period at other instants
+			key.period = periodStart.Format(DateLayout)
+		}
+
+		group, ok := groups[key]
+		if !ok {
+			group = &SalesGroup{
+				Period:          start,
+				GasStationID:    row.GasStationID,
+				GasStationName:  row.GasStationName,
+				GasPumpID:       row.GasPumpID,
+				GasPumpNumber:   row.GasPumpNumber,
+				FuelType:        row.FuelType,
+				PaymentProvider: row.PaymentProvider,
+			}
+			groups[key] = group
+		}
+
+		group.add(&row.SalesTotals)
+	}
+
+	result := make([]*SalesGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, group)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Period != nil && b.Period != nil {
+			aPeriod, bPeriod := a.Period.Format(DateLayout), b.Period.Format(DateLayout)
+			if aPeriod != bPeriod {
+				return aPeriod < bPeriod
+			}
+		}
+		if a.GasStationName != b.GasStationName {
+			return a.GasStationName < b.GasStationName
+		}
+		if a.GasPumpNumber != b.GasPumpNumber {
+			return a.GasPumpNumber < b.GasPumpNumber
+		}
+		if a.FuelType != b.FuelType {
+			return a.FuelType < b.FuelType
+		}
+		return a.PaymentProvider < b.PaymentProvider
+	})
+
+	return result
+}
+
+// SumSales returns the totals of all the groups
+func SumSales(groups []*SalesGroup) SalesTotals {
+	var totals SalesTotals
+	for _, group := range groups {
+		totals.add(&group.SalesTotals)
+	}
+
+	return totals
+}
@@ -0,0 +1,163 @@
+package reports
+
+import (
+	"smartgas-payment/internal/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type salesTest struct {
+	suite.Suite
+	local *time.Location
+}
+
+// Hours of the rows are in the timezone of the app
+func (suite *salesTest) SetupSuite() {
+	suite.local = time.Local
+	time.Local = Location("America/Mazatlan")
+}
+
+func (suite *salesTest) TearDownSuite() {
+	time.Local = suite.local
+}
+
+func (suite *salesTest) TestPeriodStart() {
+	loc := Location("America/Mexico_City")
+
+	testcases := []struct {
+		Name   string
+		Time   time.Time
+		Period string
+		Start  string
+	}{
+		{Name: "TestSales_Day", Time: time.Date(2024, 3, 13, 15, 30, 0, 0, loc), Period: PeriodDay, Start: "2024-03-13"},
+		{Name: "TestSales_WeekFromWednesday", Time: time.Date(2024, 3, 13, 15, 30, 0, 0, loc), Period: PeriodWeek, Start: "2024-03-11"},
+		{Name: "TestSales_WeekFromSunday", Time: time.Date(2024, 3, 17, 23, 0, 0, 0, loc), Period: PeriodWeek, Start: "2024-03-11"},
+		{Name: "TestSales_WeekFromMonday", Time: time.Date(2024, 3, 11, 0, 0, 0, 0, loc), Period: PeriodWeek, Start: "2024-03-11"},
+		{Name: "TestSales_WeekAcrossMonths", Time: time.Date(2024, 3, 2, 12, 0, 0, 0, loc), Period: PeriodWeek, Start: "2024-02-26"},
+		{Name: "TestSales_Month", Time: time.Date(2024, 3, 31, 23, 59, 0, 0, loc), Period: PeriodMonth, Start: "2024-03-01"},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			start := PeriodStart(tc.Time, tc.Period)
+
+			suite.Equal(tc.Start, start.Format(DateLayout))
+			suite.Equal(loc, start.Location())
+			suite.Zero(start.Hour())
+		})
+	}
+}
+
+func (suite *salesTest) TestDaysIn() {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2024, 3, 2, 0, 0, 0, 0, time.Local)
+
+	low, high := DaysIn(from, to, Location("America/Mexico_City"))
+
+	// An hour before the midnight of the app
+	suite.True(from.Add(-time.Hour).Equal(low), low)
+	suite.True(to.Add(-time.Hour).Equal(high), high)
+
+	low, high = DaysIn(from, to, time.Local)
+	suite.Equal(from, low)
+	suite.Equal(to, high)
+}
+
+func (suite *salesTest) TestGroupSales() {
+	row := func(hour string, timezone string, station string, amount float64) *SalesRow {
+		return &SalesRow{
+			Hour:           hour,
+			Timezone:       timezone,
+			GasStationID:   station,
+			GasStationName: station,
+			SalesTotals: SalesTotals{
+				Transactions: 1,
+				Amount:       money.FromFloat(amount),
+				Liters:       amount / 20,
+			},
+		}
+	}
+
+	rows := []*SalesRow{
+		// Midnight of the next day in Mexico City
+		row("2024-03-13 23:00:00", "America/Mexico_City", "centro", 100),
+		row("2024-03-13 22:00:00", "America/Mexico_City", "centro", 200),
+		row("2024-03-13 23:00:00", "America/Mazatlan", "playa", 300),
+		row("2024-03-14 01:00:00", "America/Mazatlan", "playa", 400),
+		// Unknown timezones fall back to the one of the app
+		row("2024-03-13 23:00:00", "Not/AZone", "sierra", 500),
+	}
+
+	testcases := []struct {
+		Name    string
+		Period  string
+		Periods []string
+		Names   []string
+		Amounts []money.Amount
+	}{
+		{
+			Name:    "TestSales_ByDay",
+			Period:  PeriodDay,
+			Periods: []string{"2024-03-13", "2024-03-13", "2024-03-13", "2024-03-14", "2024-03-14"},
+			Names:   []string{"centro", "playa", "sierra", "centro", "playa"},
+			Amounts: []money.Amount{
+				money.FromFloat(200), money.FromFloat(300), money.FromFloat(500),
+				money.FromFloat(100), money.FromFloat(400),
+			},
+		},
+		{
+			Name:    "TestSales_ByWeek",
+			Period:  PeriodWeek,
+			Periods: []string{"2024-03-11", "2024-03-11", "2024-03-11"},
+			Names:   []string{"centro", "playa", "sierra"},
+			Amounts: []money.Amount{money.FromFloat(300), money.FromFloat(700), money.FromFloat(500)},
+		},
+		{
+			Name:    "TestSales_NoPeriod",
+			Names:   []string{"centro", "playa", "sierra"},
+			Amounts: []money.Amount{money.FromFloat(300), money.FromFloat(700), money.FromFloat(500)},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			groups := GroupSales(rows, tc.Period)
+
+			suite.Len(groups, len(tc.Names))
+			for j, group := range groups {
+				suite.Equal(tc.Names[j], group.GasStationName)
+				suite.Equal(tc.Amounts[j], group.Amount)
+
+				if tc.Periods == nil {
+					suite.Nil(group.Period)
+				} else {
+					suite.Equal(tc.Periods[j], group.Period.Format(DateLayout))
+				}
+			}
+
+			totals := SumSales(groups)
+			suite.Equal(int64(5), totals.Transactions)
+			suite.Equal(money.FromFloat(1500), totals.Amount)
+		})
+	}
+}
+
+func (suite *salesTest) TestGroupSalesSkipsInvalidHours() {
+	rows := []*SalesRow{
+		{Hour: "not an hour", SalesTotals: SalesTotals{Transactions: 1}},
+	}
+
+	suite.Empty(GroupSales(rows, PeriodDay))
+	suite.Len(GroupSales(rows, ""), 1)
+}
+
+func TestSales(t *testing.T) {
+	suite.Run(t, new(salesTest))
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 //go:generate mockery --name CampaignRepository --filename=mock_campaign.go --inpackage=true
@@ -16,7 +17,10 @@ type CampaignRepository interface {
 	Create(*models.Campaign) error
 	UpdateByID(uuid.UUID, *models.Campaign) error
 	GetCampaignByID(uuid.UUID, map[string]any) (*models.Campaign, error)
-	GetApplicableCampaign(time.Time, uuid.UUID) (*models.Campaign, error)
+	ListApplicableCampaigns(time.Time, uuid.UUID) ([]*models.Campaign, error)
+	// Uses of the campaign since the given time, only of the customer when not nil
+	CountUsages(uuid.UUID, *uuid.UUID, time.Time) (int64, error)
+	RecordUsage(*models.CampaignUsage) error
 }
 
 type campaignRepository struct {
@@ -61,13 +65,13 @@ func (pc *campaignRepository) List(
 }
 
 func (cr *campaignRepository) Create(campaign *models.Campaign) error {
-	return cr.db.Omit("GasStations.*").Create(campaign).Error
+	return cr.db.Omit("GasStations.*", "Levels.*").Create(campaign).Error
 }
 
 func (cr *campaignRepository) UpdateByID(id uuid.UUID, campaing *models.Campaign) error {
 	campaing.ID = id
 	result := cr.db.
-		Omit("GasStations").
+		Omit("GasStations", "Levels").
 		Updates(campaing)
 
 	if campaing.GasStations != nil {
@@ -76,6 +80,12 @@ func (cr *campaignRepository) UpdateByID(id uuid.UUID, campaing *models.Campaign
 		}
 	}
 
+	if campaing.Levels != nil {
+		if err := cr.db.Model(campaing).Omit("Levels.*").Association("Levels").Replace(campaing.Levels); err != nil {
+			return err
+		}
+	}
+
 	return result.Error
 }
 
@@ -87,27 +97,87 @@ func (cr *campaignRepository) GetCampaignByID(
 
 	var campaign models.Campaign
 
-	if err := cr.db.Preload("GasStations").Where(filters).First(&campaign).Error; err != nil {
+	if err := cr.db.Preload("GasStations").Preload("Levels").Where(filters).First(&campaign).Error; err != nil {
 		return nil, err
 	}
 
 	return &campaign, nil
 }
 
-func (cr *campaignRepository) GetApplicableCampaign(
+// ListApplicableCampaigns returns the active campaigns of the station valid at date
+// with budget left and not only for promo codes, greatest discount first. Their
+// targeting is left to the caller
+func (cr *campaignRepository) ListApplicableCampaigns(
 	date time.Time,
 	stationID uuid.UUID,
-) (*models.Campaign, error) {
-	var campaign models.Campaign
+) ([]*models.Campaign, error) {
+	var campaigns []*models.Campaign
 
 	if err := cr.
 		db.
 		Model(models.Campaign{}).
+		Preload("Levels").
 		Where("valid_from <= ? AND valid_to >= ? AND active = TRUE AND EXISTS (SELECT * FROM gas_stations_campaigns as gs WHERE gs.campaign_id = campaigns.id AND gs.gas_station_id = ?)", date, date, stationID).
+		Where("(budget_amount IS NULL OR spent_amount < budget_amount) AND (budget_liters IS NULL OR spent_liters < budget_liters)").
+		Where("code_only = FALSE").
 		Order("discount desc").
-		First(&campaign).Error; err != nil {
+		Find(&campaigns).Error; err != nil {
 		return nil, err
 	}
 
-	return &campaign, nil
+	return campaigns, nil
+}
+
+func (cr *campaignRepository) CountUsages(
+	campaignID uuid.UUID,
+	customerID *uuid.UUID,
+	since time.Time,
+) (int64, error) {
+	var count int64
+
+	query := cr.db.
+		Model(&models.CampaignUsage{}).
+		Where("campaign_id = ? AND created_at >= ?", campaignID, since)
+
+	if customerID != nil {
+		query = query.Where("customer_id = ?", customerID)
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// RecordUsage stores the usage and adds it to the counters of its campaign, deactivating
+// the campaign once its budget is exhausted. A payment is only counted once
+func (cr *campaignRepository) RecordUsage(usage *models.CampaignUsage) error {
+	return cr.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(usage)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.
+			Model(&models.Campaign{}).
+			Where("id = ?", usage.CampaignID).
+			UpdateColumns(map[string]any{
+				"spent_amount": gorm.Expr("spent_amount + ?", usage.Amount),
+				"spent_liters": gorm.Expr("spent_liters + ?", usage.Liters),
+				"uses":         gorm.Expr("uses + 1"),
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.
+			Model(&models.Campaign{}).
+			Where("id = ?", usage.CampaignID).
+			Where("(budget_amount IS NOT NULL AND spent_amount >= budget_amount) OR (budget_liters IS NOT NULL AND spent_liters >= budget_liters)").
+			UpdateColumn("active", false).Error
+	})
 }
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"errors"
+	"regexp"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+type campaignRepositoryTest struct {
+	suite.Suite
+	sql        sqlmock.Sqlmock
+	repository *campaignRepository
+}
+
+// openMockDB opens the database on a mock of the connection expecting the statements
+func openMockDB() (*gorm.DB, sqlmock.Sqlmock, error) {
+	conn, sql, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      conn,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return db, sql, nil
+}
+
+func (suite *campaignRepositoryTest) SetupTest() {
+	db, sql, err := openMockDB()
+	suite.Require().Nil(err)
+
+	suite.sql = sql
+	suite.repository = ProvidePromotionRepository(db)
+}
+
+func (suite *campaignRepositoryTest) TestRecordUsage() {
+	insertUsage := regexp.QuoteMeta("INSERT INTO `campaign_usages`")
+	addUsage := regexp.QuoteMeta(
+		"UPDATE `campaigns` SET `spent_amount`=spent_amount + ?,`spent_liters`=spent_liters + ?,`uses`=uses + 1 WHERE id = ?",
+	)
+	deactivate := regexp.QuoteMeta(
+		"UPDATE `campaigns` SET `active`=? WHERE id = ? AND " +
+			"((budget_amount IS NOT NULL AND spent_amount >= budget_amount) OR (budget_liters IS NOT NULL AND spent_liters >= budget_liters))",
+	)
+
+	testcases := []struct {
+		Name string
+		Mock func(usage *models.CampaignUsage)
+		Err  bool
+	}{
+		{
+			Name: "TestCampaignRepository_BudgetLeft",
+			Mock: func(usage *models.CampaignUsage) {
+				suite.sql.ExpectBegin()
+				suite.sql.ExpectExec(insertUsage).WillReturnResult(sqlmock.NewResult(0, 1))
+				suite.sql.ExpectExec(addUsage).
+					WithArgs(usage.Amount, usage.Liters, usage.CampaignID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				suite.sql.ExpectExec(deactivate).
+					WithArgs(false, usage.CampaignID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				suite.sql.ExpectCommit()
+			},
+		},
+		{
+			Name: "TestCampaignRepository_BudgetExhausted",
+			Mock: func(usage *models.CampaignUsage) {
+				suite.sql.ExpectBegin()
+				suite.sql.ExpectExec(insertUsage).WillReturnResult(sqlmock.NewResult(0, 1))
+				suite.sql.ExpectExec(addUsage).
+					WithArgs(usage.Amount, usage.Liters, usage.CampaignID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				suite.sql.ExpectExec(deactivate).
+					WithArgs(false, usage.CampaignID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				suite.sql.ExpectCommit()
+			},
+		},
+		{
+			// The payment was already counted, neither the counters nor active change
+			Name: "TestCampaignRepository_UsageRecorded",
+			Mock: func(usage *models.CampaignUsage) {
+				suite.sql.ExpectBegin()
+				suite.sql.ExpectExec(insertUsage).WillReturnResult(sqlmock.NewResult(0, 0))
+				suite.sql.ExpectCommit()
+			},
+		},
+		{
+			Name: "TestCampaignRepository_DeactivationFails",
+			Mock: func(usage *models.CampaignUsage) {
+				suite.sql.ExpectBegin()
+				suite.sql.ExpectExec(insertUsage).WillReturnResult(sqlmock.NewResult(0, 1))
+				suite.sql.ExpectExec(addUsage).
+					WithArgs(usage.Amount, usage.Liters, usage.CampaignID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				suite.sql.ExpectExec(deactivate).
+					WithArgs(false, usage.CampaignID).
+					WillReturnError(errors.New("lock wait timeout"))
+				suite.sql.ExpectRollback()
+			},
+			Err: true,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			usage := &models.CampaignUsage{
+				CampaignID: uuid.New(),
+				PaymentID:  uuid.New(),
+				Amount:     money.FromFloat(10.5),
+				Liters:     21,
+			}
+			tc.Mock(usage)
+
+			err := suite.repository.RecordUsage(usage)
+
+			if tc.Err {
+				suite.NotNil(err)
+			} else {
+				suite.Nil(err)
+			}
+			suite.Nil(suite.sql.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCampaignRepository(t *testing.T) {
+	suite.Run(t, new(campaignRepositoryTest))
+}
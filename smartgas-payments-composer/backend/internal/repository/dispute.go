@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/utils"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:generate mockery --name DisputeRepository --filename=mock_dispute.go --inpackage=true
+type DisputeRepository interface {
+	Save(*models.Dispute) error
+	List(*schemas.Pagination, any) ([]*models.Dispute, error)
+}
+
+type disputeRepository struct {
+	db *gorm.DB
+}
+
+func ProvideDisputeRepository(db *gorm.DB) *disputeRepository {
+	return &disputeRepository{
+		db: db,
+	}
+}
+
+// Save creates the dispute or updates the one with the same stripe id
+func (dr *disputeRepository) Save(dispute *models.Dispute) error {
+	result := dr.db.
+		Omit("Payment").
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "stripe_dispute_id"}},
+			DoUpdates: clause.AssignmentColumns(
+				[]string{"amount", "reason", "status", "evidence_due_by", "closed_at", "updated_at"},
+			),
+		}).
+		Create(dispute)
+
+	return result.Error
+}
+
+// List filters by status and payment_id when present, and by the gas stations of
+// the user when "stations" is set
+func (dr *disputeRepository) List(
+	pagination *schemas.Pagination,
+	filters any,
+) ([]*models.Dispute, error) {
+	var disputes []*models.Dispute
+
+	relatedTables := []string{
+		"INNER JOIN payments ON payments.id = disputes.payment_id",
+		"INNER JOIN gas_pumps ON gas_pumps.id = payments.gas_pump_id",
+	}
+
+	filtersMap := filters.(map[string]any)
+
+	conditions := []string{"disputes.status LIKE @status"}
+	if _, ok := filtersMap["status"]; !ok {
+		filtersMap["status"] = "%"
+	}
+
+	if _, ok := filtersMap["payment_id"]; ok {
+		conditions = append(conditions, "disputes.payment_id = @payment_id")
+	}
+
+	if utils.CheckIfStationsExist(filters) {
+		conditions = append(conditions, "gas_pumps.gas_station_id IN @stations")
+	}
+
+	filterQuery := strings.Join(conditions, " AND ")
+
+	result := dr.db.
+		Joins(relatedTables[0]).
+		Joins(relatedTables[1]).
+		Preload("Payment.GasPump.GasStation").
+		Scopes(utils.Paginate(pagination, disputes, dr.db, filterQuery, filters, relatedTables...)).
+		Where(filterQuery, filters).
+		Order("disputes.created_at desc").
+		Find(&disputes)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return disputes, nil
+}
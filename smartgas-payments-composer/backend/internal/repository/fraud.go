@@ -0,0 +1,353 @@
+package repository
+
+import (
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/payments"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FraudActivity is what a customer did that counts against the fraud limits
+type FraudActivity struct {
+	// Loads and Amount of the payments created since the given time, failed and
+	// canceled ones are not counted
+	Loads  int
+	Amount money.Amount
+	// Open payments, whatever their age
+	Open int
+}
+
+//go:generate mockery --name FraudRepository --filename=mock_fraud.go --inpackage=true
+type FraudRepository interface {
+	ListRules(*schemas.Pagination, any) ([]*models.FraudRule, error)
+	ListActiveRules(uuid.UUID) ([]*models.FraudRule, error)
+	GetRuleByID(uuid.UUID) (*models.FraudRule, error)
+	CreateRule(*models.FraudRule) error
+	UpdateRule(*models.FraudRule) error
+	DeleteRule(uuid.UUID) (bool, error)
+	ListBlocks(*schemas.Pagination, any) ([]*models.FraudBlock, error)
+	CreateBlock(*models.FraudBlock) error
+	DeleteBlock(uuid.UUID) (bool, error)
+	HasBlocks(string) (bool, error)
+	FindBlock(map[string]string) (*models.FraudBlock, error)
+	CustomerActivity(uuid.UUID, *uuid.UUID, time.Time) (*FraudActivity, error)
+	LockCustomer(uuid.UUID) error
+	CreateDecision(*models.FraudDecision) error
+	ListDecisions(*schemas.Pagination, any) ([]*models.FraudDecision, error)
+}
+
+type fraudRepository struct {
+	db *gorm.DB
+}
+
+func ProvideFraudRepository(db *gorm.DB) *fraudRepository {
+	return &fraudRepository{
+		db: db,
+	}
+}
+
+func (fr *fraudRepository) ListRules(
+	pagination *schemas.Pagination,
+	filters any,
+) ([]*models.FraudRule, error) {
+	var rules []*models.FraudRule
+
+	filtersMap := filters.(map[string]any)
+
+	conditions := []string{}
+
+	if _, ok := filtersMap["type"]; ok {
+		conditions = append(conditions, "type = @type")
+	}
+
+	if _, ok := filtersMap["gas_station_id"]; ok {
+		conditions = append(conditions, "gas_station_id = @gas_station_id")
+	}
+
+	filterQuery := strings.Join(conditions, " AND ")
+
+	query := fr.db.
+		Scopes(utils.Paginate(pagination, rules, fr.db, filterQuery, filters)).
+		Preload("GasStation").
+		Order("created_at desc")
+
+	if filterQuery != "" {
+		query = query.Where(filterQuery, filters)
+	}
+
+	if result := query.Find(&rules); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return rules, nil
+}
+
+// ListActiveRules returns the active rules applying to the loads of a station, the
+// global ones and the ones of the station
+func (fr *fraudRepository) ListActiveRules(gasStationID uuid.UUID) ([]*models.FraudRule, error) {
+	var rules []*models.FraudRule
+
+	result := fr.db.
+		Where("active = ? AND (gas_station_id IS NULL OR gas_station_id = ?)", true, gasStationID).
+		Find(&rules)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return rules, nil
+}
+
+func (fr *fraudRepository) GetRuleByID(id uuid.UUID) (*models.FraudRule, error) {
+	var rule models.FraudRule
+
+	if result := fr.db.Preload("GasStation").First(&rule, "id = ?", id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &rule, nil
+}
+
+func (fr *fraudRepository) CreateRule(rule *models.FraudRule) error {
+	return fr.db.Omit("GasStation", "CreatedBy", "UpdatedBy").Create(rule).Error
+}
+
+// UpdateRule stores every field of the rule, so limits can be set back to zero
+func (fr *fraudRepository) UpdateRule(rule *models.FraudRule) error {
+	return fr.db.
+		Model(rule).
+		Select("type", "gas_station_id", "max_amount", "max_count", "active", "updated_by_id").
+		Updates(rule).Error
+}
+
+func (fr *fraudRepository) DeleteRule(id uuid.UUID) (bool, error) {
+	result := fr.db.Delete(&models.FraudRule{}, "id = ?", id)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (fr *fraudRepository) ListBlocks(
+	pagination *schemas.Pagination,
+	filters any,
+) ([]*models.FraudBlock, error) {
+	var blocks []*models.FraudBlock
+
+	filtersMap := filters.(map[string]any)
+
+	conditions := []string{}
+
+	if _, ok := filtersMap["type"]; ok {
+		conditions = append(conditions, "type = @type")
+	}
+
+	if _, ok := filtersMap["value"]; ok {
+		conditions = append(conditions, "value = @value")
+	}
+
+	filterQuery := strings.Join(conditions, " AND ")
+
+	query := fr.db.
+		Scopes(utils.Paginate(pagination, blocks, fr.db, filterQuery, filters)).
+		Preload("CreatedBy").
+		Order("created_at desc")
+
+	if filterQuery != "" {
+		query = query.Where(filterQuery, filters)
+	}
+
+	if result := query.Find(&blocks); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return blocks, nil
+}
+
+func (fr *fraudRepository) CreateBlock(block *models.FraudBlock) error {
+	return fr.db.Omit("CreatedBy").Create(block).Error
+}
+
+func (fr *fraudRepository) DeleteBlock(id uuid.UUID) (bool, error) {
+	result := fr.db.Delete(&models.FraudBlock{}, "id = ?", id)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// HasBlocks reports whether there is any block of the type
+func (fr *fraudRepository) HasBlocks(blockType string) (bool, error) {
+	var count int64
+
+	result := fr.db.Model(&models.FraudBlock{}).Where("type = ?", blockType).Limit(1).Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return count > 0, nil
+}
+
+// FindBlock returns a block matching any of the values by block type, empty values
+// are skipped. gorm.ErrRecordNotFound is returned when none is blocked
+func (fr *fraudRepository) FindBlock(values map[string]string) (*models.FraudBlock, error) {
+	var block models.FraudBlock
+
+	query := fr.db.Where("1 = 0")
+
+	for blockType, value := range values {
+		if value == "" {
+			continue
+		}
+		query = query.Or("type = ? AND value = ?", blockType, value)
+	}
+
+	if result := query.First(&block); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &block, nil
+}
+
+// CustomerActivity sums the payments of a customer, only the ones of a station
+// when gasStationID is given
+func (fr *fraudRepository) CustomerActivity(
+	customerID uuid.UUID,
+	gasStationID *uuid.UUID,
+	since time.Time,
+) (*FraudActivity, error) {
+	var activity FraudActivity
+
+	scope := func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("payments.customer_id = ?", customerID)
+
+		if gasStationID != nil {
+			tx = tx.
+				Joins("INNER JOIN gas_pumps AS GasPump ON GasPump.id = payments.gas_pump_id").
+				Where("GasPump.gas_station_id = ?", *gasStationID)
+		}
+
+		return tx
+	}
+
+	result := fr.db.
+		Model(&models.Payment{}).
+		Scopes(scope).
+		Select("COUNT(*) AS loads, COALESCE(SUM(payments.amount), 0) AS amount").
+		Where(
+			"payments.created_at >= ? AND payments.status NOT IN ?",
+			since, []string{payments.StatusCanceled, payments.StatusFailed},
+		).
+		Scan(&activity)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	lastEvents := fr.db.
+		Model(&models.PaymentEvent{}).
+		Select("payment_id, MAX(created_at) AS created_at").
+		Group("payment_id")
+
+	var open int64
+
+	result = fr.db.
+		Model(&models.Payment{}).
+		Scopes(scope).
+		Joins("INNER JOIN (?) AS last_events ON last_events.payment_id = payments.id", lastEvents).
+		Joins(`INNER JOIN payment_events AS LastEvent ON LastEvent.payment_id = last_events.payment_id
+  AND LastEvent.created_at = last_events.created_at`).
+		Where("LastEvent.type IN ?", payments.OpenEvents).
+		Distinct("payments.id").
+		Count(&open)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	activity.Open = int(open)
+
+	return &activity, nil
+}
+
+// LockCustomer locks the row of the customer until the transaction of the repository
+// ends, so the loads of a customer are checked against the limits one at a time
+func (fr *fraudRepository) LockCustomer(customerID uuid.UUID) error {
+	var customer models.Customer
+
+	return fr.db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		First(&customer, "id = ?", customerID).
+		Error
+}
+
+func (fr *fraudRepository) CreateDecision(decision *models.FraudDecision) error {
+	return fr.db.Omit("FraudRule", "Customer", "GasStation").Create(decision).Error
+}
+
+func (fr *fraudRepository) ListDecisions(
+	pagination *schemas.Pagination,
+	filters any,
+) ([]*models.FraudDecision, error) {
+	var decisions []*models.FraudDecision
+
+	filtersMap := filters.(map[string]any)
+
+	conditions := []string{}
+
+	if _, ok := filtersMap["allowed"]; ok {
+		conditions = append(conditions, "allowed = @allowed")
+	}
+
+	if _, ok := filtersMap["reason"]; ok {
+		conditions = append(conditions, "reason = @reason")
+	}
+
+	if _, ok := filtersMap["customer_id"]; ok {
+		conditions = append(conditions, "customer_id = @customer_id")
+	}
+
+	if _, ok := filtersMap["gas_station_id"]; ok {
+		conditions = append(conditions, "gas_station_id = @gas_station_id")
+	}
+
+	if _, ok := filtersMap["stations"]; ok {
+		conditions = append(conditions, "gas_station_id IN @stations")
+	}
+
+	if _, ok := filtersMap["from"]; ok {
+		conditions = append(conditions, "created_at >= @from")
+	}
+
+	if _, ok := filtersMap["to"]; ok {
+		conditions = append(conditions, "created_at < @to")
+	}
+
+	filterQuery := strings.Join(conditions, " AND ")
+
+	query := fr.db.
+		Scopes(utils.Paginate(pagination, decisions, fr.db, filterQuery, filters)).
+		Preload("Customer").
+		Preload("GasStation").
+		Order("created_at desc")
+
+	if filterQuery != "" {
+		query = query.Where(filterQuery, filters)
+	}
+
+	if result := query.Find(&decisions); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return decisions, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"smartgas-payment/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+//go:generate mockery --name IdempotencyRepository --filename=mock_idempotency.go --inpackage=true
+type IdempotencyRepository interface {
+	Create(*models.IdempotencyKey) error
+	Get(string, string, string) (*models.IdempotencyKey, error)
+	Complete(uuid.UUID, int, string) error
+	Delete(uuid.UUID) error
+	Renew(uuid.UUID) error
+	TakeOver(uuid.UUID, time.Duration) (bool, error)
+	DeleteCreatedBefore(time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func ProvideIdempotencyRepository(db *gorm.DB) *idempotencyRepository {
+	return &idempotencyRepository{
+		db: db,
+	}
+}
+
+func (ir *idempotencyRepository) Create(key *models.IdempotencyKey) error {
+	return ir.db.Create(key).Error
+}
+
+func (ir *idempotencyRepository) Get(
+	scope string,
+	owner string,
+	key string,
+) (*models.IdempotencyKey, error) {
+	var idempotencyKey models.IdempotencyKey
+
+	result := ir.db.
+		Where("scope = ? AND owner = ? AND idempotency_key = ?", scope, owner, key).
+		First(&idempotencyKey)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &idempotencyKey, nil
+}
+
+func (ir *idempotencyRepository) Complete(id uuid.UUID, statusCode int, body string) error {
+	return ir.db.Model(&models.IdempotencyKey{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":        "completed",
+			"status_code":   statusCode,
+			"response_body": body,
+		}).Error
+}
+
+func (ir *idempotencyRepository) Delete(id uuid.UUID) error {
+	return ir.db.Delete(&models.IdempotencyKey{}, "id = ?", id).Error
+}
+
+// Renew extends the lease of a key still processing
+func (ir *idempotencyRepository) Renew(id uuid.UUID) error {
+	return ir.db.Model(&models.IdempotencyKey{}).
+		Where("id = ? AND status = ?", id, "processing").
+		Update("updated_at", time.Now()).Error
+}
+
+// TakeOver gives a key still processing to a new request when it was not renewed within
+// the lease, it reports false when the key was renewed, completed or taken meanwhile
+func (ir *idempotencyRepository) TakeOver(id uuid.UUID, lease time.Duration) (bool, error) {
+	now := time.Now()
+
+	result := ir.db.Model(&models.IdempotencyKey{}).
+		Where("id = ? AND status = ? AND updated_at < ?", id, "processing", now.Add(-lease)).
+		Update("updated_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteCreatedBefore deletes the keys created before date, returning how many were deleted
+func (ir *idempotencyRepository) DeleteCreatedBefore(date time.Time) (int64, error) {
+	result := ir.db.Delete(&models.IdempotencyKey{}, "created_at < ?", date)
+
+	return result.RowsAffected, result.Error
+}
@@ -19,6 +19,34 @@ type MockCampaignRepository struct {
 	mock.Mock
 }
 
+// CountUsages provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockCampaignRepository) CountUsages(_a0 uuid.UUID, _a1 *uuid.UUID, _a2 time.Time) (int64, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountUsages")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, *uuid.UUID, time.Time) (int64, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID, *uuid.UUID, time.Time) int64); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID, *uuid.UUID, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: _a0
 func (_m *MockCampaignRepository) Create(_a0 *models.Campaign) error {
 	ret := _m.Called(_a0)
@@ -37,20 +65,20 @@ func (_m *MockCampaignRepository) Create(_a0 *models.Campaign) error {
 	return r0
 }
 
-// GetApplicableCampaign provides a mock function with given fields: _a0, _a1
-func (_m *MockCampaignRepository) GetApplicableCampaign(_a0 time.Time, _a1 uuid.UUID) (*models.Campaign, error) {
+// GetCampaignByID provides a mock function with given fields: _a0, _a1
+func (_m *MockCampaignRepository) GetCampaignByID(_a0 uuid.UUID, _a1 map[string]any) (*models.Campaign, error) {
 	ret := _m.Called(_a0, _a1)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetApplicableCampaign")
+		panic("no return value specified for GetCampaignByID")
 	}
 
 	var r0 *models.Campaign
 	var r1 error
-	if rf, ok := ret.Get(0).(func(time.Time, uuid.UUID) (*models.Campaign, error)); ok {
+	if rf, ok := ret.Get(0).(func(uuid.UUID, map[string]any) (*models.Campaign, error)); ok {
 		return rf(_a0, _a1)
 	}
-	if rf, ok := ret.Get(0).(func(time.Time, uuid.UUID) *models.Campaign); ok {
+	if rf, ok := ret.Get(0).(func(uuid.UUID, map[string]any) *models.Campaign); ok {
 		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
@@ -58,7 +86,7 @@ func (_m *MockCampaignRepository) GetApplicableCampaign(_a0 time.Time, _a1 uuid.
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(time.Time, uuid.UUID) error); ok {
+	if rf, ok := ret.Get(1).(func(uuid.UUID, map[string]any) error); ok {
 		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
@@ -67,28 +95,28 @@ func (_m *MockCampaignRepository) GetApplicableCampaign(_a0 time.Time, _a1 uuid.
 	return r0, r1
 }
 
-// GetCampaignByID provides a mock function with given fields: _a0, _a1
-func (_m *MockCampaignRepository) GetCampaignByID(_a0 uuid.UUID, _a1 map[string]any) (*models.Campaign, error) {
+// List provides a mock function with given fields: _a0, _a1
+func (_m *MockCampaignRepository) List(_a0 *schemas.Pagination, _a1 any) ([]*models.Campaign, error) {
 	ret := _m.Called(_a0, _a1)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetCampaignByID")
+		panic("no return value specified for List")
 	}
 
-	var r0 *models.Campaign
+	var r0 []*models.Campaign
 	var r1 error
-	if rf, ok := ret.Get(0).(func(uuid.UUID, map[string]any) (*models.Campaign, error)); ok {
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) ([]*models.Campaign, error)); ok {
 		return rf(_a0, _a1)
 	}
-	if rf, ok := ret.Get(0).(func(uuid.UUID, map[string]any) *models.Campaign); ok {
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) []*models.Campaign); ok {
 		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.Campaign)
+			r0 = ret.Get(0).([]*models.Campaign)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(uuid.UUID, map[string]any) error); ok {
+	if rf, ok := ret.Get(1).(func(*schemas.Pagination, any) error); ok {
 		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
@@ -97,20 +125,20 @@ func (_m *MockCampaignRepository) GetCampaignByID(_a0 uuid.UUID, _a1 map[string]
 	return r0, r1
 }
 
-// List provides a mock function with given fields: _a0, _a1
-func (_m *MockCampaignRepository) List(_a0 *schemas.Pagination, _a1 any) ([]*models.Campaign, error) {
+// ListApplicableCampaigns provides a mock function with given fields: _a0, _a1
+func (_m *MockCampaignRepository) ListApplicableCampaigns(_a0 time.Time, _a1 uuid.UUID) ([]*models.Campaign, error) {
 	ret := _m.Called(_a0, _a1)
 
 	if len(ret) == 0 {
-		panic("no return value specified for List")
+		panic("no return value specified for ListApplicableCampaigns")
 	}
 
 	var r0 []*models.Campaign
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) ([]*models.Campaign, error)); ok {
+	if rf, ok := ret.Get(0).(func(time.Time, uuid.UUID) ([]*models.Campaign, error)); ok {
 		return rf(_a0, _a1)
 	}
-	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) []*models.Campaign); ok {
+	if rf, ok := ret.Get(0).(func(time.Time, uuid.UUID) []*models.Campaign); ok {
 		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
@@ -118,7 +146,7 @@ func (_m *MockCampaignRepository) List(_a0 *schemas.Pagination, _a1 any) ([]*mod
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(*schemas.Pagination, any) error); ok {
+	if rf, ok := ret.Get(1).(func(time.Time, uuid.UUID) error); ok {
 		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
@@ -127,6 +155,24 @@ func (_m *MockCampaignRepository) List(_a0 *schemas.Pagination, _a1 any) ([]*mod
 	return r0, r1
 }
 
+// RecordUsage provides a mock function with given fields: _a0
+func (_m *MockCampaignRepository) RecordUsage(_a0 *models.CampaignUsage) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordUsage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.CampaignUsage) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateByID provides a mock function with given fields: _a0, _a1
 func (_m *MockCampaignRepository) UpdateByID(_a0 uuid.UUID, _a1 *models.Campaign) error {
 	ret := _m.Called(_a0, _a1)
@@ -0,0 +1,78 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package repository
+
+import (
+	models "smartgas-payment/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	schemas "smartgas-payment/internal/schemas"
+)
+
+// MockDisputeRepository is an autogenerated mock type for the DisputeRepository type
+type MockDisputeRepository struct {
+	mock.Mock
+}
+
+// List provides a mock function with given fields: _a0, _a1
+func (_m *MockDisputeRepository) List(_a0 *schemas.Pagination, _a1 any) ([]*models.Dispute, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []*models.Dispute
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) ([]*models.Dispute, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) []*models.Dispute); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Dispute)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*schemas.Pagination, any) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Save provides a mock function with given fields: _a0
+func (_m *MockDisputeRepository) Save(_a0 *models.Dispute) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Save")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.Dispute) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockDisputeRepository creates a new instance of MockDisputeRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockDisputeRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockDisputeRepository {
+	mock := &MockDisputeRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,418 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package repository
+
+import (
+	models "smartgas-payment/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	schemas "smartgas-payment/internal/schemas"
+
+	time "time"
+
+	uuid "github.com/google/uuid"
+)
+
+// MockFraudRepository is an autogenerated mock type for the FraudRepository type
+type MockFraudRepository struct {
+	mock.Mock
+}
+
+// CreateBlock provides a mock function with given fields: _a0
+func (_m *MockFraudRepository) CreateBlock(_a0 *models.FraudBlock) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateBlock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.FraudBlock) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateDecision provides a mock function with given fields: _a0
+func (_m *MockFraudRepository) CreateDecision(_a0 *models.FraudDecision) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateDecision")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.FraudDecision) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateRule provides a mock function with given fields: _a0
+func (_m *MockFraudRepository) CreateRule(_a0 *models.FraudRule) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateRule")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.FraudRule) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CustomerActivity provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockFraudRepository) CustomerActivity(_a0 uuid.UUID, _a1 *uuid.UUID, _a2 time.Time) (*FraudActivity, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CustomerActivity")
+	}
+
+	var r0 *FraudActivity
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, *uuid.UUID, time.Time) (*FraudActivity, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID, *uuid.UUID, time.Time) *FraudActivity); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*FraudActivity)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID, *uuid.UUID, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteBlock provides a mock function with given fields: _a0
+func (_m *MockFraudRepository) DeleteBlock(_a0 uuid.UUID) (bool, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteBlock")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) (bool, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteRule provides a mock function with given fields: _a0
+func (_m *MockFraudRepository) DeleteRule(_a0 uuid.UUID) (bool, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteRule")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) (bool, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindBlock provides a mock function with given fields: _a0
+func (_m *MockFraudRepository) FindBlock(_a0 map[string]string) (*models.FraudBlock, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindBlock")
+	}
+
+	var r0 *models.FraudBlock
+	var r1 error
+	if rf, ok := ret.Get(0).(func(map[string]string) (*models.FraudBlock, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(map[string]string) *models.FraudBlock); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.FraudBlock)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(map[string]string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRuleByID provides a mock function with given fields: _a0
+func (_m *MockFraudRepository) GetRuleByID(_a0 uuid.UUID) (*models.FraudRule, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRuleByID")
+	}
+
+	var r0 *models.FraudRule
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) (*models.FraudRule, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID) *models.FraudRule); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.FraudRule)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// HasBlocks provides a mock function with given fields: _a0
+func (_m *MockFraudRepository) HasBlocks(_a0 string) (bool, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HasBlocks")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (bool, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListActiveRules provides a mock function with given fields: _a0
+func (_m *MockFraudRepository) ListActiveRules(_a0 uuid.UUID) ([]*models.FraudRule, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListActiveRules")
+	}
+
+	var r0 []*models.FraudRule
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) ([]*models.FraudRule, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID) []*models.FraudRule); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.FraudRule)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListBlocks provides a mock function with given fields: _a0, _a1
+func (_m *MockFraudRepository) ListBlocks(_a0 *schemas.Pagination, _a1 any) ([]*models.FraudBlock, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListBlocks")
+	}
+
+	var r0 []*models.FraudBlock
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) ([]*models.FraudBlock, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) []*models.FraudBlock); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.FraudBlock)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*schemas.Pagination, any) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDecisions provides a mock function with given fields: _a0, _a1
+func (_m *MockFraudRepository) ListDecisions(_a0 *schemas.Pagination, _a1 any) ([]*models.FraudDecision, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDecisions")
+	}
+
+	var r0 []*models.FraudDecision
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) ([]*models.FraudDecision, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) []*models.FraudDecision); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.FraudDecision)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*schemas.Pagination, any) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRules provides a mock function with given fields: _a0, _a1
+func (_m *MockFraudRepository) ListRules(_a0 *schemas.Pagination, _a1 any) ([]*models.FraudRule, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRules")
+	}
+
+	var r0 []*models.FraudRule
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) ([]*models.FraudRule, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) []*models.FraudRule); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.FraudRule)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*schemas.Pagination, any) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LockCustomer provides a mock function with given fields: _a0
+func (_m *MockFraudRepository) LockCustomer(_a0 uuid.UUID) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LockCustomer")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateRule provides a mock function with given fields: _a0
+func (_m *MockFraudRepository) UpdateRule(_a0 *models.FraudRule) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateRule")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.FraudRule) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockFraudRepository creates a new instance of MockFraudRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockFraudRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockFraudRepository {
+	mock := &MockFraudRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
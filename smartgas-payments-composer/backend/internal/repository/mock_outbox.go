@@ -0,0 +1,150 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package repository
+
+import (
+	models "smartgas-payment/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+
+	uuid "github.com/google/uuid"
+)
+
+// MockOutboxRepository is an autogenerated mock type for the OutboxRepository type
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+// Claim provides a mock function with given fields: _a0, _a1
+func (_m *MockOutboxRepository) Claim(_a0 *models.OutboxMessage, _a1 time.Duration) (bool, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Claim")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*models.OutboxMessage, time.Duration) (bool, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(*models.OutboxMessage, time.Duration) bool); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(*models.OutboxMessage, time.Duration) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Enqueue provides a mock function with given fields: _a0
+func (_m *MockOutboxRepository) Enqueue(_a0 ...*models.OutboxMessage) error {
+	_va := make([]interface{}, len(_a0))
+	for _i := range _a0 {
+		_va[_i] = _a0[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Enqueue")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(...*models.OutboxMessage) error); ok {
+		r0 = rf(_a0...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListAvailable provides a mock function with given fields: _a0
+func (_m *MockOutboxRepository) ListAvailable(_a0 int) ([]*models.OutboxMessage, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAvailable")
+	}
+
+	var r0 []*models.OutboxMessage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int) ([]*models.OutboxMessage, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(int) []*models.OutboxMessage); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.OutboxMessage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkDone provides a mock function with given fields: _a0
+func (_m *MockOutboxRepository) MarkDone(_a0 uuid.UUID) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkDone")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkFailed provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockOutboxRepository) MarkFailed(_a0 uuid.UUID, _a1 string, _a2 *time.Time) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkFailed")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, string, *time.Time) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockOutboxRepository creates a new instance of MockOutboxRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockOutboxRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockOutboxRepository {
+	mock := &MockOutboxRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
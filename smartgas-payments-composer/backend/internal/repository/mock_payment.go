@@ -9,6 +9,8 @@ import (
 
 	schemas "smartgas-payment/internal/schemas"
 
+	time "time"
+
 	uuid "github.com/google/uuid"
 )
 
@@ -17,16 +19,16 @@ type MockPaymentRepository struct {
 	mock.Mock
 }
 
-// CreateEvent provides a mock function with given fields: _a0
-func (_m *MockPaymentRepository) CreateEvent(_a0 *models.PaymentEvent) error {
+// ClearAuthorizationExpiry provides a mock function with given fields: _a0
+func (_m *MockPaymentRepository) ClearAuthorizationExpiry(_a0 uuid.UUID) error {
 	ret := _m.Called(_a0)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CreateEvent")
+		panic("no return value specified for ClearAuthorizationExpiry")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*models.PaymentEvent) error); ok {
+	if rf, ok := ret.Get(0).(func(uuid.UUID) error); ok {
 		r0 = rf(_a0)
 	} else {
 		r0 = ret.Error(0)
@@ -35,17 +37,92 @@ func (_m *MockPaymentRepository) CreateEvent(_a0 *models.PaymentEvent) error {
 	return r0
 }
 
-// CreatePaymentIntent provides a mock function with given fields: _a0
-func (_m *MockPaymentRepository) CreatePaymentIntent(_a0 *models.Payment) error {
-	ret := _m.Called(_a0)
+// CreateEvent provides a mock function with given fields: _a0, _a1
+func (_m *MockPaymentRepository) CreateEvent(_a0 *models.PaymentEvent, _a1 ...*models.OutboxMessage) error {
+	_va := make([]interface{}, len(_a1))
+	for _i := range _a1 {
+		_va[_i] = _a1[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _a0)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateEvent")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.PaymentEvent, ...*models.OutboxMessage) error); ok {
+		r0 = rf(_a0, _a1...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateEventWithUpdates provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockPaymentRepository) CreateEventWithUpdates(_a0 *models.PaymentEvent, _a1 map[string]any, _a2 ...*models.OutboxMessage) error {
+	_va := make([]interface{}, len(_a2))
+	for _i := range _a2 {
+		_va[_i] = _a2[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _a0, _a1)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateEventWithUpdates")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.PaymentEvent, map[string]any, ...*models.OutboxMessage) error); ok {
+		r0 = rf(_a0, _a1, _a2...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreatePaymentIntent provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockPaymentRepository) CreatePaymentIntent(_a0 *models.Payment, _a1 []*models.OutboxMessage, _a2 ...PaymentGuard) error {
+	_va := make([]interface{}, len(_a2))
+	for _i := range _a2 {
+		_va[_i] = _a2[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _a0, _a1)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CreatePaymentIntent")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*models.Payment) error); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(*models.Payment, []*models.OutboxMessage, ...PaymentGuard) error); ok {
+		r0 = rf(_a0, _a1, _a2...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Export provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockPaymentRepository) Export(_a0 any, _a1 string, _a2 func(*PaymentExportRow) error) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Export")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(any, string, func(*PaymentExportRow) error) error); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -83,6 +160,36 @@ func (_m *MockPaymentRepository) GetByID(_a0 uuid.UUID) (*models.Payment, error)
 	return r0, r1
 }
 
+// GetByIDDetailed provides a mock function with given fields: _a0, _a1
+func (_m *MockPaymentRepository) GetByIDDetailed(_a0 uuid.UUID, _a1 any) (*models.Payment, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByIDDetailed")
+	}
+
+	var r0 *models.Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, any) (*models.Payment, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID, any) *models.Payment); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID, any) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetByIDForCustomer provides a mock function with given fields: _a0, _a1
 func (_m *MockPaymentRepository) GetByIDForCustomer(_a0 uuid.UUID, _a1 uuid.UUID) (*models.Payment, error) {
 	ret := _m.Called(_a0, _a1)
@@ -233,6 +340,34 @@ func (_m *MockPaymentRepository) GetStatsForCustomer(_a0 uuid.UUID, _a1 StatsFor
 	return r0, r1
 }
 
+// HasServedLoads provides a mock function with given fields: _a0
+func (_m *MockPaymentRepository) HasServedLoads(_a0 uuid.UUID) (bool, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HasServedLoads")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) (bool, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // List provides a mock function with given fields: _a0, _a1
 func (_m *MockPaymentRepository) List(_a0 *schemas.Pagination, _a1 any) ([]*models.Payment, error) {
 	ret := _m.Called(_a0, _a1)
@@ -263,6 +398,126 @@ func (_m *MockPaymentRepository) List(_a0 *schemas.Pagination, _a1 any) ([]*mode
 	return r0, r1
 }
 
+// ListByProviderAndDate provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockPaymentRepository) ListByProviderAndDate(_a0 string, _a1 time.Time, _a2 time.Time) ([]*models.Payment, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByProviderAndDate")
+	}
+
+	var r0 []*models.Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, time.Time, time.Time) ([]*models.Payment, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(string, time.Time, time.Time) []*models.Payment); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, time.Time, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListExpiringAuthorizations provides a mock function with given fields: _a0
+func (_m *MockPaymentRepository) ListExpiringAuthorizations(_a0 time.Time) ([]*models.Payment, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListExpiringAuthorizations")
+	}
+
+	var r0 []*models.Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(time.Time) ([]*models.Payment, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time) []*models.Payment); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListForCustomer provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockPaymentRepository) ListForCustomer(_a0 uuid.UUID, _a1 *schemas.CursorPagination, _a2 any) ([]*models.Payment, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListForCustomer")
+	}
+
+	var r0 []*models.Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, *schemas.CursorPagination, any) ([]*models.Payment, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID, *schemas.CursorPagination, any) []*models.Payment); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID, *schemas.CursorPagination, any) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListStaleByLastEvent provides a mock function with given fields: _a0, _a1
+func (_m *MockPaymentRepository) ListStaleByLastEvent(_a0 string, _a1 time.Time) ([]*models.Payment, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListStaleByLastEvent")
+	}
+
+	var r0 []*models.Payment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, time.Time) ([]*models.Payment, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(string, time.Time) []*models.Payment); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Payment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, time.Time) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateByID provides a mock function with given fields: _a0, _a1
 func (_m *MockPaymentRepository) UpdateByID(_a0 uuid.UUID, _a1 *models.Payment) (bool, error) {
 	ret := _m.Called(_a0, _a1)
@@ -0,0 +1,222 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package repository
+
+import (
+	models "smartgas-payment/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	schemas "smartgas-payment/internal/schemas"
+
+	uuid "github.com/google/uuid"
+)
+
+// MockPromoCodeRepository is an autogenerated mock type for the PromoCodeRepository type
+type MockPromoCodeRepository struct {
+	mock.Mock
+}
+
+// CountRedemptions provides a mock function with given fields: _a0, _a1
+func (_m *MockPromoCodeRepository) CountRedemptions(_a0 uuid.UUID, _a1 uuid.UUID) (int64, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountRedemptions")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID) (int64, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID, uuid.UUID) int64); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID, uuid.UUID) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Create provides a mock function with given fields: _a0
+func (_m *MockPromoCodeRepository) Create(_a0 ...*models.PromoCode) error {
+	_va := make([]interface{}, len(_a0))
+	for _i := range _a0 {
+		_va[_i] = _a0[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(...*models.PromoCode) error); ok {
+		r0 = rf(_a0...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetByCode provides a mock function with given fields: _a0
+func (_m *MockPromoCodeRepository) GetByCode(_a0 string) (*models.PromoCode, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByCode")
+	}
+
+	var r0 *models.PromoCode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*models.PromoCode, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) *models.PromoCode); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PromoCode)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByID provides a mock function with given fields: _a0
+func (_m *MockPromoCodeRepository) GetByID(_a0 uuid.UUID) (*models.PromoCode, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByID")
+	}
+
+	var r0 *models.PromoCode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) (*models.PromoCode, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID) *models.PromoCode); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PromoCode)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: _a0, _a1
+func (_m *MockPromoCodeRepository) List(_a0 *schemas.Pagination, _a1 any) ([]*models.PromoCode, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []*models.PromoCode
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) ([]*models.PromoCode, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) []*models.PromoCode); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.PromoCode)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*schemas.Pagination, any) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRedemptions provides a mock function with given fields: _a0, _a1
+func (_m *MockPromoCodeRepository) ListRedemptions(_a0 *schemas.Pagination, _a1 any) ([]*models.PromoRedemption, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRedemptions")
+	}
+
+	var r0 []*models.PromoRedemption
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) ([]*models.PromoRedemption, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) []*models.PromoRedemption); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.PromoRedemption)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*schemas.Pagination, any) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: _a0
+func (_m *MockPromoCodeRepository) Update(_a0 *models.PromoCode) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.PromoCode) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockPromoCodeRepository creates a new instance of MockPromoCodeRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockPromoCodeRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockPromoCodeRepository {
+	mock := &MockPromoCodeRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,208 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package repository
+
+import (
+	models "smartgas-payment/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	schemas "smartgas-payment/internal/schemas"
+
+	time "time"
+
+	uuid "github.com/google/uuid"
+)
+
+// MockReconciliationRepository is an autogenerated mock type for the ReconciliationRepository type
+type MockReconciliationRepository struct {
+	mock.Mock
+}
+
+// CreateBatchDiscrepancies provides a mock function with given fields: _a0
+func (_m *MockReconciliationRepository) CreateBatchDiscrepancies(_a0 []*models.ReconciliationDiscrepancy) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateBatchDiscrepancies")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]*models.ReconciliationDiscrepancy) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Finish provides a mock function with given fields: _a0
+func (_m *MockReconciliationRepository) Finish(_a0 *models.Reconciliation) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Finish")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.Reconciliation) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetByID provides a mock function with given fields: _a0
+func (_m *MockReconciliationRepository) GetByID(_a0 uuid.UUID) (*models.Reconciliation, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByID")
+	}
+
+	var r0 *models.Reconciliation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) (*models.Reconciliation, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID) *models.Reconciliation); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Reconciliation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLast provides a mock function with given fields:
+func (_m *MockReconciliationRepository) GetLast() (*models.Reconciliation, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLast")
+	}
+
+	var r0 *models.Reconciliation
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (*models.Reconciliation, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() *models.Reconciliation); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Reconciliation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: _a0, _a1
+func (_m *MockReconciliationRepository) List(_a0 *schemas.Pagination, _a1 any) ([]*models.Reconciliation, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []*models.Reconciliation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) ([]*models.Reconciliation, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) []*models.Reconciliation); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Reconciliation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*schemas.Pagination, any) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDiscrepancies provides a mock function with given fields: _a0, _a1
+func (_m *MockReconciliationRepository) ListDiscrepancies(_a0 *schemas.Pagination, _a1 any) ([]*models.ReconciliationDiscrepancy, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDiscrepancies")
+	}
+
+	var r0 []*models.ReconciliationDiscrepancy
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) ([]*models.ReconciliationDiscrepancy, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) []*models.ReconciliationDiscrepancy); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.ReconciliationDiscrepancy)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*schemas.Pagination, any) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Start provides a mock function with given fields: _a0, _a1
+func (_m *MockReconciliationRepository) Start(_a0 *models.Reconciliation, _a1 time.Time) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Start")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.Reconciliation, time.Time) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockReconciliationRepository creates a new instance of MockReconciliationRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockReconciliationRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockReconciliationRepository {
+	mock := &MockReconciliationRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
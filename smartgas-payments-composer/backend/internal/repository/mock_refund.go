@@ -0,0 +1,98 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package repository
+
+import (
+	models "smartgas-payment/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	money "smartgas-payment/internal/money"
+
+	uuid "github.com/google/uuid"
+)
+
+// MockRefundRepository is an autogenerated mock type for the RefundRepository type
+type MockRefundRepository struct {
+	mock.Mock
+}
+
+// Finish provides a mock function with given fields: _a0
+func (_m *MockRefundRepository) Finish(_a0 *models.Refund) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Finish")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.Refund) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListByPaymentID provides a mock function with given fields: _a0
+func (_m *MockRefundRepository) ListByPaymentID(_a0 uuid.UUID) ([]*models.Refund, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByPaymentID")
+	}
+
+	var r0 []*models.Refund
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) ([]*models.Refund, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID) []*models.Refund); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Refund)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Reserve provides a mock function with given fields: _a0, _a1
+func (_m *MockRefundRepository) Reserve(_a0 *models.Refund, _a1 money.Amount) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reserve")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.Refund, money.Amount) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockRefundRepository creates a new instance of MockRefundRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRefundRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRefundRepository {
+	mock := &MockRefundRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,58 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package repository
+
+import (
+	reports "smartgas-payment/internal/reports"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockReportRepository is an autogenerated mock type for the ReportRepository type
+type MockReportRepository struct {
+	mock.Mock
+}
+
+// Sales provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockReportRepository) Sales(_a0 any, _a1 []string, _a2 bool) ([]*reports.SalesRow, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Sales")
+	}
+
+	var r0 []*reports.SalesRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(any, []string, bool) ([]*reports.SalesRow, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(any, []string, bool) []*reports.SalesRow); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*reports.SalesRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(any, []string, bool) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMockReportRepository creates a new instance of MockReportRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockReportRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockReportRepository {
+	mock := &MockReportRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,326 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package repository
+
+import (
+	models "smartgas-payment/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	schemas "smartgas-payment/internal/schemas"
+
+	time "time"
+
+	uuid "github.com/google/uuid"
+)
+
+// MockSettlementRepository is an autogenerated mock type for the SettlementRepository type
+type MockSettlementRepository struct {
+	mock.Mock
+}
+
+// Close provides a mock function with given fields: _a0
+func (_m *MockSettlementRepository) Close(_a0 *models.Settlement) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Close")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.Settlement) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Create provides a mock function with given fields: _a0
+func (_m *MockSettlementRepository) Create(_a0 *models.Settlement) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.Settlement) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetByID provides a mock function with given fields: _a0
+func (_m *MockSettlementRepository) GetByID(_a0 uuid.UUID) (*models.Settlement, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByID")
+	}
+
+	var r0 *models.Settlement
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) (*models.Settlement, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID) *models.Settlement); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Settlement)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByPeriod provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockSettlementRepository) GetByPeriod(_a0 string, _a1 time.Time, _a2 time.Time) (*models.Settlement, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByPeriod")
+	}
+
+	var r0 *models.Settlement
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, time.Time, time.Time) (*models.Settlement, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(string, time.Time, time.Time) *models.Settlement); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Settlement)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, time.Time, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// HasOverlap provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *MockSettlementRepository) HasOverlap(_a0 string, _a1 time.Time, _a2 time.Time, _a3 uuid.UUID) (bool, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HasOverlap")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, time.Time, time.Time, uuid.UUID) (bool, error)); ok {
+		return rf(_a0, _a1, _a2, _a3)
+	}
+	if rf, ok := ret.Get(0).(func(string, time.Time, time.Time, uuid.UUID) bool); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, time.Time, time.Time, uuid.UUID) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: _a0, _a1
+func (_m *MockSettlementRepository) List(_a0 *schemas.Pagination, _a1 any) ([]*models.Settlement, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []*models.Settlement
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) ([]*models.Settlement, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) []*models.Settlement); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Settlement)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*schemas.Pagination, any) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListLines provides a mock function with given fields: _a0, _a1
+func (_m *MockSettlementRepository) ListLines(_a0 *schemas.Pagination, _a1 any) ([]*models.SettlementLine, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListLines")
+	}
+
+	var r0 []*models.SettlementLine
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) ([]*models.SettlementLine, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(*schemas.Pagination, any) []*models.SettlementLine); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.SettlementLine)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*schemas.Pagination, any) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPaymentsToSettle provides a mock function with given fields: _a0
+func (_m *MockSettlementRepository) ListPaymentsToSettle(_a0 *models.Settlement) ([]*SettlementPaymentRow, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPaymentsToSettle")
+	}
+
+	var r0 []*SettlementPaymentRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*models.Settlement) ([]*SettlementPaymentRow, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(*models.Settlement) []*SettlementPaymentRow); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*SettlementPaymentRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*models.Settlement) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRefundAdjustments provides a mock function with given fields: _a0
+func (_m *MockSettlementRepository) ListRefundAdjustments(_a0 *models.Settlement) ([]*SettlementPaymentRow, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRefundAdjustments")
+	}
+
+	var r0 []*SettlementPaymentRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*models.Settlement) ([]*SettlementPaymentRow, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(*models.Settlement) []*SettlementPaymentRow); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*SettlementPaymentRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*models.Settlement) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReplaceLines provides a mock function with given fields: _a0, _a1
+func (_m *MockSettlementRepository) ReplaceLines(_a0 *models.Settlement, _a1 []*models.SettlementLine) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplaceLines")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.Settlement, []*models.SettlementLine) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TotalsByProvider provides a mock function with given fields: _a0
+func (_m *MockSettlementRepository) TotalsByProvider(_a0 uuid.UUID) ([]*SettlementProviderTotals, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TotalsByProvider")
+	}
+
+	var r0 []*SettlementProviderTotals
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) ([]*SettlementProviderTotals, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID) []*SettlementProviderTotals); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*SettlementProviderTotals)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMockSettlementRepository creates a new instance of MockSettlementRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockSettlementRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockSettlementRepository {
+	mock := &MockSettlementRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"smartgas-payment/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+//go:generate mockery --name OutboxRepository --filename=mock_outbox.go --inpackage=true
+type OutboxRepository interface {
+	Enqueue(...*models.OutboxMessage) error
+	ListAvailable(int) ([]*models.OutboxMessage, error)
+	Claim(*models.OutboxMessage, time.Duration) (bool, error)
+	MarkDone(uuid.UUID) error
+	MarkFailed(uuid.UUID, string, *time.Time) error
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func ProvideOutboxRepository(db *gorm.DB) *outboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+func (or *outboxRepository) Enqueue(messages ...*models.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return or.db.Omit("Payment").Create(messages).Error
+}
+
+// ListAvailable returns pending messages and processing ones whose lease expired
+func (or *outboxRepository) ListAvailable(limit int) ([]*models.OutboxMessage, error) {
+	var messages []*models.OutboxMessage
+
+	result := or.db.
+		Where("status IN ? AND available_at <= ?", []string{"pending", "processing"}, time.Now()).
+		Order("available_at asc").
+		Limit(limit).
+		Find(&messages)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return messages, nil
+}
+
+// Claim takes the message for the given lease, it reports false when someone else got it
+func (or *outboxRepository) Claim(message *models.OutboxMessage, lease time.Duration) (bool, error) {
+	now := time.Now()
+	availableAt := now.Add(lease)
+
+	result := or.db.Model(&models.OutboxMessage{}).
+		Where("id = ? AND status IN ? AND available_at <= ?", message.ID, []string{"pending", "processing"}, now).
+		Updates(map[string]any{
+			"status":       "processing",
+			"attempts":     gorm.Expr("attempts + 1"),
+			"available_at": availableAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		return false, nil
+	}
+
+	message.Status = "processing"
+	message.Attempts++
+	message.AvailableAt = availableAt
+
+	return true, nil
+}
+
+func (or *outboxRepository) MarkDone(id uuid.UUID) error {
+	return or.db.Model(&models.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"status": "done", "last_error": ""}).Error
+}
+
+// MarkFailed schedules the message again at retryAt, a nil retryAt moves it to dead letter
+func (or *outboxRepository) MarkFailed(id uuid.UUID, lastError string, retryAt *time.Time) error {
+	if len(lastError) > 1000 {
+		lastError = lastError[:1000]
+	}
+
+	values := map[string]any{"status": "dead", "last_error": lastError}
+	if retryAt != nil {
+		values["status"] = "pending"
+		values["available_at"] = *retryAt
+	}
+
+	return or.db.Model(&models.OutboxMessage{}).Where("id = ?", id).Updates(values).Error
+}
@@ -2,12 +2,17 @@ package repository
 
 import (
 	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/payments"
 	"smartgas-payment/internal/schemas"
 	"smartgas-payment/internal/utils"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type StatsForCustomerOpts struct {
@@ -20,18 +25,58 @@ type CustomerStats struct {
 	TotalTransactions int
 }
 
+// PaymentExportRow is a payment flattened for reports
+type PaymentExportRow struct {
+	ID                    string
+	CreatedAt             time.Time
+	Status                string
+	LastEvent             string
+	PaymentProvider       string
+	ExternalTransactionID string
+	GasStationName        string
+	GasPumpNumber         string
+	CustomerName          string
+	FuelType              string
+	ChargeType            string
+	Price                 float64
+	TotalLiter            float32
+	Amount                money.Amount
+	RealAmountReported    money.Amount
+	RefundedAmount        money.Amount
+	ChargeFee             money.Amount
+	DiscountPerLiter      float64
+	DiscountType          string
+	GMPoints              float32
+	Invoiced              bool
+	InvoiceID             string
+}
+
+// PaymentGuard runs in the transaction creating a payment before it is stored, an
+// error rolls the payment back and is returned as is
+type PaymentGuard func(tx *gorm.DB) error
+
 //go:generate mockery --name PaymentRepository --filename=mock_payment.go --inpackage=true
 type PaymentRepository interface {
-	CreatePaymentIntent(*models.Payment) error
+	CreatePaymentIntent(*models.Payment, []*models.OutboxMessage, ...PaymentGuard) error
 	GetPaymentByStripePaymentIntentID(string) (*models.Payment, error)
 	UpdateByID(uuid.UUID, *models.Payment) (bool, error)
 	List(*schemas.Pagination, any) ([]*models.Payment, error)
+	Export(any, string, func(*PaymentExportRow) error) error
 	GetByID(uuid.UUID) (*models.Payment, error)
-	CreateEvent(*models.PaymentEvent) error
+	CreateEvent(*models.PaymentEvent, ...*models.OutboxMessage) error
+	// Columns of the payment are updated in the transaction storing the event
+	CreateEventWithUpdates(*models.PaymentEvent, map[string]any, ...*models.OutboxMessage) error
 	GetLastEventByPaymentID(uuid.UUID) (*models.PaymentEvent, error)
 	GetByIDForCustomer(uuid.UUID, uuid.UUID) (*models.Payment, error)
+	ListForCustomer(uuid.UUID, *schemas.CursorPagination, any) ([]*models.Payment, error)
 	GetByIDPreloaded(uuid.UUID) (*models.Payment, error)
+	GetByIDDetailed(uuid.UUID, any) (*models.Payment, error)
 	GetStatsForCustomer(uuid.UUID, StatsForCustomerOpts) (*CustomerStats, error)
+	HasServedLoads(uuid.UUID) (bool, error)
+	ListStaleByLastEvent(string, time.Time) ([]*models.Payment, error)
+	ListByProviderAndDate(string, time.Time, time.Time) ([]*models.Payment, error)
+	ListExpiringAuthorizations(time.Time) ([]*models.Payment, error)
+	ClearAuthorizationExpiry(uuid.UUID) error
 }
 
 type paymentRepository struct {
@@ -56,18 +101,8 @@ func (pr *paymentRepository) List(
 		"GasPump.GasStation",
 		"INNER JOIN gas_stations as GasStation ON GasStation.id = GasPump.gas_station_id",
 	}
-	filterQuery := `payment_provider LIKE @search OR
-  payments.id LIKE @search OR
-  CONCAT(Customer.first_name, ' ', Customer.first_last_name, ' ', Customer.second_last_name) LIKE @search OR
-  CONCAT(GasStation.name, ' ', GasPump.number) LIKE @search 
-  `
-	if utils.CheckIfStationsExist(filters) {
-		filterQuery = `GasStation.id IN @stations AND  (payment_provider LIKE @search OR
-  payments.id LIKE @search OR
-  CONCAT(Customer.first_name, ' ', Customer.first_last_name, ' ', Customer.second_last_name) LIKE @search OR
-  CONCAT(GasStation.name, ' ', GasPump.number) LIKE @search)
-  `
-	}
+
+	filterQuery := strings.Join(paymentListConditions(filters.(map[string]any)), " AND ")
 
 	result := pr.db.
 		Joins(relatedTables[0]).
@@ -79,7 +114,7 @@ func (pr *paymentRepository) List(
 		}).
 		Joins(relatedTables[3]).
 		Scopes(utils.Paginate(pagination, payments, pr.db, filterQuery, filters, relatedTables...)).
-		Order("created_at desc").
+		Order(pagination.GetSort()).
 		Where(filterQuery, filters).
 		Find(&payments)
 
@@ -90,12 +125,153 @@ func (pr *paymentRepository) List(
 	return payments, nil
 }
 
-func (pr *paymentRepository) CreatePaymentIntent(payment *models.Payment) error {
-	if result := pr.db.Create(&payment); result.Error != nil {
-		return result.Error
+// Export calls fn with every payment matching the list filters in the given order,
+// rows are read one by one so the result set is never loaded entirely
+func (pr *paymentRepository) Export(
+	filters any,
+	order string,
+	fn func(*PaymentExportRow) error,
+) error {
+	filterQuery := strings.Join(paymentListConditions(filters.(map[string]any)), " AND ")
+
+	rows, err := pr.db.
+		Model(&models.Payment{}).
+		Select(`payments.id, payments.created_at, payments.status, payments.payment_provider,
+  payments.external_transaction_id, payments.fuel_type, payments.charge_type, payments.price,
+  payments.total_liter, payments.amount, payments.real_amount_reported, payments.refunded_amount,
+  payments.charge_fee, payments.discount_per_liter, payments.discount_type, payments.gm_points,
+  payments.invoiced, payments.external_invoice_id AS invoice_id,
+  GasStation.name AS gas_station_name, GasPump.number AS gas_pump_number,
+  CONCAT_WS(' ', Customer.first_name, Customer.first_last_name, Customer.second_last_name) AS customer_name,
+  COALESCE((SELECT LastEvent.type FROM payment_events AS LastEvent
+    WHERE LastEvent.payment_id = payments.id
+    ORDER BY LastEvent.created_at DESC LIMIT 1), '') AS last_event`).
+		Joins("LEFT JOIN customers as Customer ON Customer.id = payments.customer_id").
+		Joins("INNER JOIN gas_pumps as GasPump ON GasPump.id = payments.gas_pump_id").
+		Joins("INNER JOIN gas_stations as GasStation ON GasStation.id = GasPump.gas_station_id").
+		Where(filterQuery, filters).
+		Order(order).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row PaymentExportRow
+		if err := pr.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// paymentListFilters maps the list filters to their condition, the rest of them
+// are added by paymentListConditions
+var paymentListFilters = map[string]string{
+	"status":           "payments.status = @status",
+	"payment_provider": "payments.payment_provider = @payment_provider",
+	"fuel_type":        "payments.fuel_type = @fuel_type",
+	"discount_type":    "payments.discount_type = @discount_type",
+	"gas_station_id":   "GasStation.id = @gas_station_id",
+	"gas_pump_id":      "payments.gas_pump_id = @gas_pump_id",
+	"customer_id":      "payments.customer_id = @customer_id",
+	"employee_id":      "payments.set_by_employee_id = @employee_id",
+	"from_operations":  "payments.from_operations = @from_operations",
+	"invoiced":         "payments.invoiced = @invoiced",
+	"from":             "payments.created_at >= @from",
+	"to":               "payments.created_at < @to",
+	"min_amount":       "payments.amount >= @min_amount",
+	"max_amount":       "payments.amount <= @max_amount",
+	"last_event": `(SELECT LastEvent.type FROM payment_events AS LastEvent
+  WHERE LastEvent.payment_id = payments.id
+  ORDER BY LastEvent.created_at DESC LIMIT 1) = @last_event`,
+}
+
+func paymentListConditions(filters map[string]any) []string {
+	conditions := []string{}
+
+	if _, ok := filters["search"]; ok {
+		conditions = append(conditions, `(payment_provider LIKE @search OR
+  payments.id LIKE @search OR
+  CONCAT(Customer.first_name, ' ', Customer.first_last_name, ' ', Customer.second_last_name) LIKE @search OR
+  CONCAT(GasStation.name, ' ', GasPump.number) LIKE @search)`)
+	}
+
+	if utils.CheckIfStationsExist(filters) {
+		conditions = append(conditions, "GasStation.id IN @stations")
+	}
+
+	// Sorting keys to get the same query for the same filters
+	keys := make([]string, 0, len(paymentListFilters))
+	for key := range paymentListFilters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, ok := filters[key]; ok {
+			conditions = append(conditions, paymentListFilters[key])
+		}
+	}
+
+	if len(conditions) == 0 {
+		conditions = append(conditions, "1 = 1")
+	}
+
+	return conditions
+}
+
+// CreatePaymentIntent stores the payment with its events, enqueuing the outbox messages
+// in the same transaction
+func (pr *paymentRepository) CreatePaymentIntent(
+	payment *models.Payment,
+	messages []*models.OutboxMessage,
+	guards ...PaymentGuard,
+) error {
+	events := make([]string, 0, len(payment.Events))
+	for _, e := range payment.Events {
+		events = append(events, e.Type)
 	}
 
-	return nil
+	if err := payments.ValidateStream(events); err != nil {
+		return err
+	}
+
+	payment.Status = payments.DeriveStatus(events)
+
+	return pr.db.Transaction(func(tx *gorm.DB) error {
+		for _, guard := range guards {
+			if err := guard(tx); err != nil {
+				return err
+			}
+		}
+
+		if result := tx.Create(&payment); result.Error != nil {
+			return result.Error
+		}
+
+		if len(messages) > 0 {
+			for _, message := range messages {
+				message.PaymentID = &payment.ID
+			}
+
+			if result := tx.Omit("Payment").Create(messages); result.Error != nil {
+				return result.Error
+			}
+		}
+
+		if payment.PromoCodeID == nil {
+			return nil
+		}
+
+		return reservePromoCode(tx, payment)
+	})
 }
 
 func (pr *paymentRepository) GetPaymentByStripePaymentIntentID(
@@ -113,7 +289,8 @@ func (pr *paymentRepository) GetPaymentByStripePaymentIntentID(
 }
 
 func (pr *paymentRepository) UpdateByID(id uuid.UUID, payment *models.Payment) (bool, error) {
-	result := pr.db.Model(payment).Omit("Campaign").Where("id = ?", id).Updates(payment)
+	// Status is only changed by CreateEvent
+	result := pr.db.Model(payment).Omit("Campaign", "Status").Where("id = ?", id).Updates(payment)
 
 	if result.Error != nil {
 		return false, result.Error
@@ -147,6 +324,73 @@ func (pr *paymentRepository) GetByIDForCustomer(
 	return &payment, nil
 }
 
+// ListForCustomer returns a page of the payments of a customer, newest first, with
+// their events newest first. Filters are from, to and gas_station_id
+func (pr *paymentRepository) ListForCustomer(
+	customerID uuid.UUID,
+	pagination *schemas.CursorPagination,
+	filters any,
+) ([]*models.Payment, error) {
+	var payments []*models.Payment
+
+	createdAt, id, ok, err := pagination.Position()
+	if err != nil {
+		return nil, err
+	}
+
+	filtersMap := filters.(map[string]any)
+	filtersMap["customer_id"] = customerID
+
+	conditions := []string{"payments.customer_id = @customer_id"}
+
+	if _, ok := filtersMap["from"]; ok {
+		conditions = append(conditions, "payments.created_at >= @from")
+	}
+
+	if _, ok := filtersMap["to"]; ok {
+		conditions = append(conditions, "payments.created_at < @to")
+	}
+
+	if _, ok := filtersMap["gas_station_id"]; ok {
+		conditions = append(conditions, "GasPump.gas_station_id = @gas_station_id")
+	}
+
+	if ok {
+		filtersMap["cursor_created_at"] = createdAt
+		filtersMap["cursor_id"] = id
+		conditions = append(conditions, `(payments.created_at < @cursor_created_at
+  OR (payments.created_at = @cursor_created_at AND payments.id < @cursor_id))`)
+	}
+
+	limit := pagination.GetLimit()
+
+	result := pr.db.
+		Joins("GasPump").
+		Preload("GasPump.GasStation").
+		Preload("Events", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("payment_events.created_at desc")
+		}).
+		Where(strings.Join(conditions, " AND "), filtersMap).
+		Order("payments.created_at desc, payments.id desc").
+		// One more row tells whether there is a next page
+		Limit(limit + 1).
+		Find(&payments)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	pagination.NextCursor = ""
+
+	if len(payments) > limit {
+		payments = payments[:limit]
+		last := payments[limit-1]
+		pagination.SetNext(last.CreatedAt, last.ID)
+	}
+
+	return payments, nil
+}
+
 func (pr *paymentRepository) GetByID(id uuid.UUID) (*models.Payment, error) {
 	var payment *models.Payment
 
@@ -172,12 +416,224 @@ func (pr *paymentRepository) GetByIDPreloaded(id uuid.UUID) (*models.Payment, er
 	return payment, nil
 }
 
-func (pr *paymentRepository) CreateEvent(event *models.PaymentEvent) error {
-	if result := pr.db.Create(&event); result.Error != nil {
-		return result.Error
+// GetByIDDetailed returns the payment with its relations and every event with the
+// application or user that produced it, scoped to the stations filter when present
+func (pr *paymentRepository) GetByIDDetailed(id uuid.UUID, filters any) (*models.Payment, error) {
+	var payment *models.Payment
+
+	query := pr.db.
+		Preload("GasPump.GasStation").
+		Preload("Customer").
+		Preload("Campaign").
+		Preload("Level").
+		Preload("Events", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("payment_events.created_at asc")
+		}).
+		Preload("Events.AuthorizedApplication").
+		Preload("Events.User")
+
+	if utils.CheckIfStationsExist(filters) {
+		query = query.
+			Joins("INNER JOIN gas_pumps AS StationPump ON StationPump.id = payments.gas_pump_id").
+			Where("StationPump.gas_station_id IN @stations", filters)
+	}
+
+	if result := query.First(&payment, "payments.id = ?", id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return payment, nil
+}
+
+// ListStaleByLastEvent returns the payments whose last event is eventType and was
+// created before the given time
+func (pr *paymentRepository) ListStaleByLastEvent(
+	eventType string,
+	before time.Time,
+) ([]*models.Payment, error) {
+	var payments []*models.Payment
+
+	lastEvents := pr.db.
+		Model(&models.PaymentEvent{}).
+		Select("payment_id, MAX(created_at) AS created_at").
+		Group("payment_id")
+
+	result := pr.db.
+		Joins("INNER JOIN (?) AS last_events ON last_events.payment_id = payments.id", lastEvents).
+		Joins(`INNER JOIN payment_events AS LastEvent ON LastEvent.payment_id = last_events.payment_id
+  AND LastEvent.created_at = last_events.created_at`).
+		Preload("GasPump.GasStation").
+		Preload("Customer").
+		Where("LastEvent.type = ? AND LastEvent.created_at < ?", eventType, before).
+		Order("payments.created_at asc").
+		Find(&payments)
+
+	if result.Error != nil {
+		return nil, result.Error
 	}
 
-	return nil
+	return payments, nil
+}
+
+// ListExpiringAuthorizations returns the paid payments whose authorization, not captured
+// yet, expires before the given time
+func (pr *paymentRepository) ListExpiringAuthorizations(before time.Time) ([]*models.Payment, error) {
+	var payments []*models.Payment
+
+	result := pr.db.
+		Preload("GasPump.GasStation").
+		Preload("Customer").
+		Where(
+			"capture_method = ? AND status = ? AND authorization_expires_at < ?",
+			models.CaptureManual, "paid", before,
+		).
+		Order("authorization_expires_at asc").
+		Find(&payments)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return payments, nil
+}
+
+// ClearAuthorizationExpiry is called once the authorization is captured, the funds are
+// charged so nothing expires anymore
+func (pr *paymentRepository) ClearAuthorizationExpiry(id uuid.UUID) error {
+	return pr.db.
+		Model(&models.Payment{}).
+		Where("id = ?", id).
+		Update("authorization_expires_at", nil).Error
+}
+
+// ListByProviderAndDate returns the payments of a provider created in [from, to)
+// with their events
+func (pr *paymentRepository) ListByProviderAndDate(
+	provider string,
+	from time.Time,
+	to time.Time,
+) ([]*models.Payment, error) {
+	var payments []*models.Payment
+
+	result := pr.db.
+		Preload("Events", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("payment_events.created_at asc")
+		}).
+		Where("payment_provider = ? AND created_at >= ? AND created_at < ?", provider, from, to).
+		Find(&payments)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return payments, nil
+}
+
+// CreateEvent validates the event against the payment's last one and stores it,
+// updating the payment status derived from its events. Outbox messages are stored
+// in the same transaction
+func (pr *paymentRepository) CreateEvent(
+	event *models.PaymentEvent,
+	messages ...*models.OutboxMessage,
+) error {
+	return pr.CreateEventWithUpdates(event, nil, messages...)
+}
+
+// CreateEventWithUpdates stores the event as CreateEvent does, updating the given columns
+// of the payment once the transition is validated. Updates may be expressions such as
+// gorm.Expr("refunded_amount + ?", amount), so they never work on a stale payment
+func (pr *paymentRepository) CreateEventWithUpdates(
+	event *models.PaymentEvent,
+	updates map[string]any,
+	messages ...*models.OutboxMessage,
+) error {
+	return pr.db.Transaction(func(tx *gorm.DB) error {
+		var payment models.Payment
+
+		// Locking payment row to serialize events of the same payment
+		result := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "status").
+			First(&payment, "id = ?", event.PaymentID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var stored []models.PaymentEvent
+		result = tx.
+			Select("type", "created_at").
+			Where("payment_id = ?", event.PaymentID).
+			Order("created_at asc").
+			Find(&stored)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		events := make([]string, 0, len(stored)+1)
+		for _, e := range stored {
+			events = append(events, e.Type)
+		}
+
+		last := ""
+		if len(stored) > 0 {
+			last = stored[len(stored)-1].Type
+
+			// Events are ordered by created_at, so the new one must never tie the last one
+			lastCreatedAt := stored[len(stored)-1].CreatedAt
+			if event.CreatedAt.IsZero() {
+				event.CreatedAt = time.Now()
+			}
+			if !event.CreatedAt.After(lastCreatedAt) {
+				event.CreatedAt = lastCreatedAt.Add(time.Millisecond)
+			}
+		}
+
+		if err := payments.ValidateTransition(last, event.Type); err != nil {
+			return err
+		}
+
+		if result := tx.Create(&event); result.Error != nil {
+			return result.Error
+		}
+
+		if len(messages) > 0 {
+			for _, message := range messages {
+				message.PaymentID = &event.PaymentID
+			}
+
+			if result := tx.Omit("Payment").Create(messages); result.Error != nil {
+				return result.Error
+			}
+		}
+
+		if len(updates) > 0 {
+			if err := tx.Model(&payment).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		if event.Type == "served" {
+			if err := redeemPromoCode(tx, event.PaymentID); err != nil {
+				return err
+			}
+		}
+
+		status := payments.DeriveStatus(append(events, event.Type))
+		if status == payment.Status {
+			return nil
+		}
+
+		// The promo code use is given back along with the payment, or when the funds of a
+		// load not served were released by the provider
+		if status == payments.StatusCanceled ||
+			event.Type == "authorization_expired" && last != "served" {
+			if err := releasePromoCode(tx, event.PaymentID); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&payment).Update("status", status).Error
+	})
 }
 
 func (pr *paymentRepository) GetLastEventByPaymentID(
@@ -192,6 +648,17 @@ func (pr *paymentRepository) GetLastEventByPaymentID(
 	return &event, nil
 }
 
+// HasServedLoads reports whether fuel was already served to the customer
+func (pr *paymentRepository) HasServedLoads(cusId uuid.UUID) (bool, error) {
+	var count int64
+	err := pr.db.Model(models.Payment{}).
+		Where("customer_id = ? AND real_amount_reported > 0", cusId).
+		Limit(1).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
 func (pr *paymentRepository) GetStatsForCustomer(
 	cusId uuid.UUID,
 	opts StatsForCustomerOpts,
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"smartgas-payment/internal/schemas"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type paymentRepositoryTest struct {
+	suite.Suite
+	sql        sqlmock.Sqlmock
+	repository *paymentRepository
+}
+
+func (suite *paymentRepositoryTest) SetupTest() {
+	db, sql, err := openMockDB()
+	suite.Require().Nil(err)
+
+	suite.sql = sql
+	suite.repository = ProvidePaymentRepository(db)
+}
+
+func (suite *paymentRepositoryTest) TestGetByIDDetailedScoping() {
+	stationA, stationB := uuid.NewString(), uuid.NewString()
+
+	testcases := []struct {
+		Name    string
+		Filters map[string]any
+		Query   string
+		Args    func(id uuid.UUID) []driver.Value
+	}{
+		{
+			Name:    "TestPaymentRepository_DetailAdmin",
+			Filters: map[string]any{},
+			Query:   regexp.QuoteMeta("SELECT * FROM `payments` WHERE payments.id = ? AND `payments`.`deleted_at` IS NULL"),
+			Args: func(id uuid.UUID) []driver.Value {
+				return []driver.Value{id}
+			},
+		},
+		{
+			Name:    "TestPaymentRepository_DetailStations",
+			Filters: map[string]any{"stations": []string{stationA, stationB}},
+			Query: regexp.QuoteMeta(
+				"INNER JOIN gas_pumps AS StationPump ON StationPump.id = payments.gas_pump_id " +
+					"WHERE StationPump.gas_station_id IN (?,?) AND payments.id = ?",
+			),
+			Args: func(id uuid.UUID) []driver.Value {
+				return []driver.Value{stationA, stationB, id}
+			},
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			id := uuid.New()
+
+			// Missing, or out of the stations of the user
+			suite.sql.ExpectQuery(tc.Query).WithArgs(tc.Args(id)...).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+			payment, err := suite.repository.GetByIDDetailed(id, tc.Filters)
+
+			suite.Nil(payment)
+			suite.ErrorIs(err, gorm.ErrRecordNotFound)
+			suite.Nil(suite.sql.ExpectationsWereMet())
+		})
+	}
+}
+
+func (suite *paymentRepositoryTest) TestListForCustomerCursor() {
+	customerID := uuid.New()
+	newest := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	rows := []struct {
+		ID        uuid.UUID
+		CreatedAt time.Time
+	}{
+		{ID: uuid.New(), CreatedAt: newest},
+		{ID: uuid.New(), CreatedAt: newest.Add(-time.Hour)},
+		{ID: uuid.New(), CreatedAt: newest.Add(-2 * time.Hour)},
+	}
+
+	// Cursors pointing to the first and second rows
+	first, second := schemas.CursorPagination{}, schemas.CursorPagination{}
+	first.SetNext(rows[0].CreatedAt, rows[0].ID)
+	second.SetNext(rows[1].CreatedAt, rows[1].ID)
+
+	testcases := []struct {
+		Name       string
+		Cursor     string
+		Query      string
+		Args       []driver.Value
+		From       int
+		Payments   int
+		NextCursor string
+		Err        error
+	}{
+		{
+			// The extra row only tells there is a next page
+			Name: "TestPaymentRepository_CustomerFirstPage",
+			Query: regexp.QuoteMeta(
+				"WHERE payments.customer_id = ? AND `payments`.`deleted_at` IS NULL " +
+					"ORDER BY payments.created_at desc, payments.id desc LIMIT 3",
+			),
+			Args:       []driver.Value{customerID},
+			Payments:   2,
+			NextCursor: second.NextCursor,
+		},
+		{
+			Name:   "TestPaymentRepository_CustomerLastPage",
+			Cursor: first.NextCursor,
+			Query: regexp.QuoteMeta(
+				"WHERE (payments.customer_id = ? AND (payments.created_at < ? " +
+					"OR (payments.created_at = ? AND payments.id < ?))) AND `payments`.`deleted_at` IS NULL",
+			),
+			Args:     []driver.Value{customerID, rows[0].CreatedAt, rows[0].CreatedAt, rows[0].ID},
+			From:     1,
+			Payments: 2,
+		},
+		{
+			Name:   "TestPaymentRepository_CustomerInvalidCursor",
+			Cursor: "not-a-cursor",
+			Err:    schemas.ErrInvalidCursor,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			if tc.Query != "" {
+				result := sqlmock.NewRows([]string{"id", "customer_id", "created_at"})
+				for _, row := range rows[tc.From:] {
+					result.AddRow(row.ID, customerID, row.CreatedAt)
+				}
+				suite.sql.ExpectQuery(tc.Query).WithArgs(tc.Args...).WillReturnRows(result)
+				suite.sql.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `payment_events`")).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			}
+
+			pagination := &schemas.CursorPagination{Cursor: tc.Cursor, Limit: 2}
+
+			payments, err := suite.repository.ListForCustomer(customerID, pagination, map[string]any{})
+
+			if tc.Err != nil {
+				suite.ErrorIs(err, tc.Err)
+				suite.Nil(payments)
+				return
+			}
+
+			suite.Nil(err)
+			suite.Len(payments, tc.Payments)
+			suite.Equal(tc.NextCursor, pagination.NextCursor)
+			suite.Nil(suite.sql.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPaymentRepository(t *testing.T) {
+	suite.Run(t, new(paymentRepositoryTest))
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"errors"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/utils"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrPromoCodeUsed = errors.New("The promo code has no uses left")
+
+//go:generate mockery --name PromoCodeRepository --filename=mock_promo_code.go --inpackage=true
+type PromoCodeRepository interface {
+	List(*schemas.Pagination, any) ([]*models.PromoCode, error)
+	GetByID(uuid.UUID) (*models.PromoCode, error)
+	GetByCode(string) (*models.PromoCode, error)
+	Create(...*models.PromoCode) error
+	Update(*models.PromoCode) error
+	// Reserved and redeemed uses of the code by the customer
+	CountRedemptions(uuid.UUID, uuid.UUID) (int64, error)
+	ListRedemptions(*schemas.Pagination, any) ([]*models.PromoRedemption, error)
+}
+
+type promoCodeRepository struct {
+	db *gorm.DB
+}
+
+func ProvidePromoCodeRepository(db *gorm.DB) *promoCodeRepository {
+	return &promoCodeRepository{
+		db: db,
+	}
+}
+
+func (pcr *promoCodeRepository) List(
+	pagination *schemas.Pagination,
+	filters any,
+) ([]*models.PromoCode, error) {
+	var codes []*models.PromoCode
+
+	filtersMap := filters.(map[string]any)
+
+	conditions := []string{}
+
+	if _, ok := filtersMap["search"]; ok {
+		conditions = append(conditions, "code LIKE @search")
+	}
+
+	for _, column := range []string{"type", "campaign_id", "batch_id", "customer_id"} {
+		if _, ok := filtersMap[column]; ok {
+			conditions = append(conditions, column+" = @"+column)
+		}
+	}
+
+	filterQuery := strings.Join(conditions, " AND ")
+
+	query := pcr.db.
+		Scopes(utils.Paginate(pagination, codes, pcr.db, filterQuery, filters)).
+		Preload("Campaign").
+		Preload("Customer").
+		Order("created_at desc")
+
+	if filterQuery != "" {
+		query = query.Where(filterQuery, filters)
+	}
+
+	if result := query.Find(&codes); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return codes, nil
+}
+
+func (pcr *promoCodeRepository) GetByID(id uuid.UUID) (*models.PromoCode, error) {
+	var code models.PromoCode
+
+	if result := pcr.db.
+		Preload("Campaign").
+		Preload("Customer").
+		First(&code, "id = ?", id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &code, nil
+}
+
+// GetByCode returns the code with the campaign, its stations and levels, to check
+// whether it applies to a load
+func (pcr *promoCodeRepository) GetByCode(code string) (*models.PromoCode, error) {
+	var promoCode models.PromoCode
+
+	if result := pcr.db.
+		Preload("Campaign.GasStations").
+		Preload("Campaign.Levels").
+		First(&promoCode, "code = ?", models.NormalizePromoCode(code)); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &promoCode, nil
+}
+
+// Create stores the codes at once, none is stored when one of them already exists
+func (pcr *promoCodeRepository) Create(codes ...*models.PromoCode) error {
+	return pcr.db.Omit("Campaign", "Customer", "CreatedBy").Create(codes).Error
+}
+
+// Update stores the limits and status of the code, so limits can be set back to unlimited
+func (pcr *promoCodeRepository) Update(code *models.PromoCode) error {
+	return pcr.db.
+		Model(code).
+		Select("max_uses", "max_uses_per_customer", "active").
+		Updates(code).Error
+}
+
+func (pcr *promoCodeRepository) CountRedemptions(codeID uuid.UUID, customerID uuid.UUID) (int64, error) {
+	return countRedemptions(pcr.db, codeID, customerID)
+}
+
+func (pcr *promoCodeRepository) ListRedemptions(
+	pagination *schemas.Pagination,
+	filters any,
+) ([]*models.PromoRedemption, error) {
+	var redemptions []*models.PromoRedemption
+
+	filtersMap := filters.(map[string]any)
+
+	conditions := []string{"promo_code_id = @promo_code_id"}
+
+	if _, ok := filtersMap["status"]; ok {
+		conditions = append(conditions, "status = @status")
+	}
+
+	filterQuery := strings.Join(conditions, " AND ")
+
+	result := pcr.db.
+		Scopes(utils.Paginate(pagination, redemptions, pcr.db, filterQuery, filters)).
+		Preload("Customer").
+		Preload("Payment").
+		Where(filterQuery, filters).
+		Order("created_at desc").
+		Find(&redemptions)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return redemptions, nil
+}
+
+func countRedemptions(db *gorm.DB, codeID uuid.UUID, customerID uuid.UUID) (int64, error) {
+	var count int64
+
+	result := db.
+		Model(&models.PromoRedemption{}).
+		Where(
+			"promo_code_id = ? AND customer_id = ? AND status <> ?",
+			codeID, customerID, models.PromoRedemptionReleased,
+		).
+		Count(&count)
+
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return count, nil
+}
+
+// reservePromoCode takes a use of the promo code of the payment within its transaction,
+// ErrPromoCodeUsed is returned when the code has no uses left for the customer
+func reservePromoCode(tx *gorm.DB, payment *models.Payment) error {
+	var code models.PromoCode
+
+	// Locking code row to serialize the reservations of the same code
+	result := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&code, "id = ?", payment.PromoCodeID)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if code.Active == nil || !*code.Active ||
+		code.MaxUses != nil && code.Uses >= *code.MaxUses {
+		return ErrPromoCodeUsed
+	}
+
+	if code.MaxUsesPerCustomer != nil && payment.CustomerID != nil {
+		uses, err := countRedemptions(tx, code.ID, *payment.CustomerID)
+		if err != nil {
+			return err
+		}
+
+		if uses >= int64(*code.MaxUsesPerCustomer) {
+			return ErrPromoCodeUsed
+		}
+	}
+
+	if err := tx.Model(&code).UpdateColumn("uses", gorm.Expr("uses + 1")).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&models.PromoRedemption{
+		PromoCodeID:      code.ID,
+		PaymentID:        payment.ID,
+		CustomerID:       payment.CustomerID,
+		DiscountPerLiter: payment.DiscountPerLiter,
+	}).Error
+}
+
+// releasePromoCode gives back the use reserved by the payment, if any
+func releasePromoCode(tx *gorm.DB, paymentID uuid.UUID) error {
+	var redemption models.PromoRedemption
+
+	result := tx.
+		Where("payment_id = ? AND status = ?", paymentID, models.PromoRedemptionReserved).
+		Limit(1).
+		Find(&redemption)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	if err := tx.
+		Model(&redemption).
+		Update("status", models.PromoRedemptionReleased).Error; err != nil {
+		return err
+	}
+
+	return tx.
+		Model(&models.PromoCode{}).
+		Where("id = ? AND uses > 0", redemption.PromoCodeID).
+		UpdateColumn("uses", gorm.Expr("uses - 1")).Error
+}
+
+// redeemPromoCode marks the use reserved by the payment as redeemed, if any
+func redeemPromoCode(tx *gorm.DB, paymentID uuid.UUID) error {
+	return tx.
+		Model(&models.PromoRedemption{}).
+		Where("payment_id = ? AND status = ?", paymentID, models.PromoRedemptionReserved).
+		Update("status", models.PromoRedemptionRedeemed).Error
+}
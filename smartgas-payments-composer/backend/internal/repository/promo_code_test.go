@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"regexp"
+	"smartgas-payment/internal/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type promoCodeRepositoryTest struct {
+	suite.Suite
+	db  *gorm.DB
+	sql sqlmock.Sqlmock
+}
+
+func (suite *promoCodeRepositoryTest) SetupTest() {
+	db, sql, err := openMockDB()
+	suite.Require().Nil(err)
+
+	suite.db = db
+	suite.sql = sql
+}
+
+func (suite *promoCodeRepositoryTest) TestReservePromoCode() {
+	lockCode := regexp.QuoteMeta("SELECT * FROM `promo_codes` WHERE id = ?") + ".*" + regexp.QuoteMeta("FOR UPDATE")
+	countRedemptions := regexp.QuoteMeta("SELECT count(*) FROM `promo_redemptions` WHERE promo_code_id = ? AND customer_id = ? AND status <> ?")
+	takeUse := regexp.QuoteMeta("UPDATE `promo_codes` SET `uses`=uses + 1 WHERE `id` = ?")
+	createRedemption := regexp.QuoteMeta("INSERT INTO `promo_redemptions`")
+
+	codeRow := func(id uuid.UUID, active bool, maxUses any, maxUsesPerCustomer any, uses int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "code", "active", "max_uses", "max_uses_per_customer", "uses"}).
+			AddRow(id.String(), "MARZO", active, maxUses, maxUsesPerCustomer, uses)
+	}
+
+	testcases := []struct {
+		Name string
+		Mock func(payment *models.Payment, codeID uuid.UUID)
+		Err  error
+	}{
+		{
+			Name: "TestPromoCodeRepository_UsesLeft",
+			Mock: func(payment *models.Payment, codeID uuid.UUID) {
+				suite.sql.ExpectQuery(lockCode).WithArgs(codeID).WillReturnRows(codeRow(codeID, true, 10, nil, 9))
+				suite.sql.ExpectExec(takeUse).WithArgs(codeID).WillReturnResult(sqlmock.NewResult(0, 1))
+				suite.sql.ExpectExec(createRedemption).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			Name: "TestPromoCodeRepository_Unlimited",
+			Mock: func(payment *models.Payment, codeID uuid.UUID) {
+				suite.sql.ExpectQuery(lockCode).WithArgs(codeID).WillReturnRows(codeRow(codeID, true, nil, nil, 250))
+				suite.sql.ExpectExec(takeUse).WithArgs(codeID).WillReturnResult(sqlmock.NewResult(0, 1))
+				suite.sql.ExpectExec(createRedemption).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			Name: "TestPromoCodeRepository_NoUsesLeft",
+			Mock: func(payment *models.Payment, codeID uuid.UUID) {
+				suite.sql.ExpectQuery(lockCode).WithArgs(codeID).WillReturnRows(codeRow(codeID, true, 10, nil, 10))
+			},
+			Err: ErrPromoCodeUsed,
+		},
+		{
+			Name: "TestPromoCodeRepository_Inactive",
+			Mock: func(payment *models.Payment, codeID uuid.UUID) {
+				suite.sql.ExpectQuery(lockCode).WithArgs(codeID).WillReturnRows(codeRow(codeID, false, 10, nil, 0))
+			},
+			Err: ErrPromoCodeUsed,
+		},
+		{
+			Name: "TestPromoCodeRepository_CustomerUsesLeft",
+			Mock: func(payment *models.Payment, codeID uuid.UUID) {
+				suite.sql.ExpectQuery(lockCode).WithArgs(codeID).WillReturnRows(codeRow(codeID, true, nil, 2, 5))
+				suite.sql.ExpectQuery(countRedemptions).
+					WithArgs(codeID, *payment.CustomerID, models.PromoRedemptionReleased).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				suite.sql.ExpectExec(takeUse).WithArgs(codeID).WillReturnResult(sqlmock.NewResult(0, 1))
+				suite.sql.ExpectExec(createRedemption).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			Name: "TestPromoCodeRepository_NoCustomerUsesLeft",
+			Mock: func(payment *models.Payment, codeID uuid.UUID) {
+				suite.sql.ExpectQuery(lockCode).WithArgs(codeID).WillReturnRows(codeRow(codeID, true, nil, 2, 5))
+				suite.sql.ExpectQuery(countRedemptions).
+					WithArgs(codeID, *payment.CustomerID, models.PromoRedemptionReleased).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+			},
+			Err: ErrPromoCodeUsed,
+		},
+		{
+			Name: "TestPromoCodeRepository_NotFound",
+			Mock: func(payment *models.Payment, codeID uuid.UUID) {
+				suite.sql.ExpectQuery(lockCode).WithArgs(codeID).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+			Err: gorm.ErrRecordNotFound,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			codeID, customerID := uuid.New(), uuid.New()
+			payment := &models.Payment{
+				ID:               uuid.New(),
+				CustomerID:       &customerID,
+				PromoCodeID:      &codeID,
+				DiscountPerLiter: 0.5,
+			}
+
+			suite.sql.ExpectBegin()
+			tc.Mock(payment, codeID)
+			if tc.Err == nil {
+				suite.sql.ExpectCommit()
+			} else {
+				suite.sql.ExpectRollback()
+			}
+
+			err := suite.db.Transaction(func(tx *gorm.DB) error {
+				return reservePromoCode(tx, payment)
+			})
+
+			if tc.Err == nil {
+				suite.Nil(err)
+			} else {
+				suite.ErrorIs(err, tc.Err)
+			}
+			suite.Nil(suite.sql.ExpectationsWereMet())
+		})
+	}
+}
+
+func (suite *promoCodeRepositoryTest) TestReleasePromoCode() {
+	findReserved := regexp.QuoteMeta("SELECT * FROM `promo_redemptions` WHERE payment_id = ? AND status = ? LIMIT 1")
+	release := regexp.QuoteMeta("UPDATE `promo_redemptions` SET `status`=?,`updated_at`=? WHERE `id` = ?")
+	giveBackUse := regexp.QuoteMeta("UPDATE `promo_codes` SET `uses`=uses - 1 WHERE id = ? AND uses > 0")
+
+	testcases := []struct {
+		Name     string
+		Reserved bool
+	}{
+		{Name: "TestPromoCodeRepository_ReleaseReserved", Reserved: true},
+		// Redeemed, released or no code at all
+		{Name: "TestPromoCodeRepository_NothingReserved"},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			paymentID, redemptionID, codeID := uuid.New(), uuid.New(), uuid.New()
+
+			rows := sqlmock.NewRows([]string{"id", "promo_code_id", "payment_id", "status"})
+			if tc.Reserved {
+				rows.AddRow(redemptionID.String(), codeID.String(), paymentID.String(), models.PromoRedemptionReserved)
+			}
+
+			suite.sql.ExpectBegin()
+			suite.sql.ExpectQuery(findReserved).
+				WithArgs(paymentID, models.PromoRedemptionReserved).
+				WillReturnRows(rows)
+			if tc.Reserved {
+				suite.sql.ExpectExec(release).
+					WithArgs(models.PromoRedemptionReleased, sqlmock.AnyArg(), redemptionID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				suite.sql.ExpectExec(giveBackUse).WithArgs(codeID).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			suite.sql.ExpectCommit()
+
+			err := suite.db.Transaction(func(tx *gorm.DB) error {
+				return releasePromoCode(tx, paymentID)
+			})
+
+			suite.Nil(err)
+			suite.Nil(suite.sql.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPromoCodeRepository(t *testing.T) {
+	suite.Run(t, new(promoCodeRepositoryTest))
+}
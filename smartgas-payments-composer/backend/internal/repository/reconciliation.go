@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"errors"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrReconciliationRunning = errors.New("There is a reconciliation already running")
+
+//go:generate mockery --name ReconciliationRepository --filename=mock_reconciliation.go --inpackage=true
+type ReconciliationRepository interface {
+	Start(*models.Reconciliation, time.Time) error
+	Finish(*models.Reconciliation) error
+	CreateBatchDiscrepancies([]*models.ReconciliationDiscrepancy) error
+	GetLast() (*models.Reconciliation, error)
+	GetByID(uuid.UUID) (*models.Reconciliation, error)
+	List(*schemas.Pagination, any) ([]*models.Reconciliation, error)
+	ListDiscrepancies(*schemas.Pagination, any) ([]*models.ReconciliationDiscrepancy, error)
+}
+
+type reconciliationRepository struct {
+	db *gorm.DB
+}
+
+func ProvideReconciliationRepository(db *gorm.DB) *reconciliationRepository {
+	return &reconciliationRepository{
+		db: db,
+	}
+}
+
+// Start stores a running reconciliation, the ones still running since before
+// staleBefore are marked as failed first. ErrReconciliationRunning is returned when
+// another reconciliation is running
+func (rr *reconciliationRepository) Start(reconciliation *models.Reconciliation, staleBefore time.Time) error {
+	return rr.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&models.Reconciliation{}).
+			Where("running = ? AND created_at < ?", true, staleBefore).
+			Updates(map[string]any{
+				"status":  "failed",
+				"running": nil,
+				"errors":  "Timed out",
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		running := true
+		reconciliation.Status = "running"
+		reconciliation.Running = &running
+
+		if result := tx.Create(reconciliation); result.Error != nil {
+			if utils.CheckDuplicatedEntry(result.Error) {
+				return ErrReconciliationRunning
+			}
+
+			return result.Error
+		}
+
+		return nil
+	})
+}
+
+// Finish stores the status, matched count and errors of a reconciliation, letting
+// the next one start
+func (rr *reconciliationRepository) Finish(reconciliation *models.Reconciliation) error {
+	reconciliation.Running = nil
+
+	result := rr.db.
+		Model(&models.Reconciliation{}).
+		Where("id = ?", reconciliation.ID).
+		Updates(map[string]any{
+			"status":  reconciliation.Status,
+			"running": nil,
+			"matched": reconciliation.Matched,
+			"errors":  reconciliation.Errors,
+		})
+
+	return result.Error
+}
+
+func (rr *reconciliationRepository) CreateBatchDiscrepancies(
+	discrepancies []*models.ReconciliationDiscrepancy,
+) error {
+	if len(discrepancies) == 0 {
+		return nil
+	}
+
+	if result := rr.db.CreateInBatches(discrepancies, 100); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (rr *reconciliationRepository) GetLast() (*models.Reconciliation, error) {
+	var reconciliation models.Reconciliation
+
+	if result := rr.db.Order("created_at desc").First(&reconciliation); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &reconciliation, nil
+}
+
+func (rr *reconciliationRepository) GetByID(id uuid.UUID) (*models.Reconciliation, error) {
+	var reconciliation models.Reconciliation
+
+	if result := rr.db.First(&reconciliation, "id = ?", id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &reconciliation, nil
+}
+
+func (rr *reconciliationRepository) List(
+	pagination *schemas.Pagination,
+	filters any,
+) ([]*models.Reconciliation, error) {
+	var reconciliations []*models.Reconciliation
+
+	result := rr.db.
+		Scopes(utils.Paginate(pagination, reconciliations, rr.db, "", filters, "")).
+		Where(filters).
+		Order("created_at desc").
+		Find(&reconciliations)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return reconciliations, nil
+}
+
+func (rr *reconciliationRepository) ListDiscrepancies(
+	pagination *schemas.Pagination,
+	filters any,
+) ([]*models.ReconciliationDiscrepancy, error) {
+	var discrepancies []*models.ReconciliationDiscrepancy
+
+	result := rr.db.
+		Scopes(utils.Paginate(pagination, discrepancies, rr.db, "", filters, "")).
+		Where(filters).
+		Order("created_at desc").
+		Find(&discrepancies)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return discrepancies, nil
+}
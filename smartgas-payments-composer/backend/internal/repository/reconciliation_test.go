@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"errors"
+	"regexp"
+	"smartgas-payment/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/suite"
+)
+
+type reconciliationRepositoryTest struct {
+	suite.Suite
+	sql        sqlmock.Sqlmock
+	repository *reconciliationRepository
+}
+
+func (suite *reconciliationRepositoryTest) SetupTest() {
+	db, sql, err := openMockDB()
+	suite.Require().Nil(err)
+
+	suite.sql = sql
+	suite.repository = ProvideReconciliationRepository(db)
+}
+
+func (suite *reconciliationRepositoryTest) TestStart() {
+	failStale := regexp.QuoteMeta(
+		"UPDATE `reconciliations` SET `errors`=?,`running`=?,`status`=? WHERE running = ? AND created_at < ?",
+	)
+	insert := regexp.QuoteMeta("INSERT INTO `reconciliations`")
+
+	testcases := []struct {
+		Name   string
+		Insert func(*sqlmock.ExpectedExec)
+		Err    error
+	}{
+		{
+			Name: "TestReconciliationRepository_Started",
+			Insert: func(exec *sqlmock.ExpectedExec) {
+				exec.WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			Name: "TestReconciliationRepository_AlreadyRunning",
+			Insert: func(exec *sqlmock.ExpectedExec) {
+				exec.WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'idx_reconciliations_running'"})
+			},
+			Err: ErrReconciliationRunning,
+		},
+		{
+			Name: "TestReconciliationRepository_InsertFailed",
+			Insert: func(exec *sqlmock.ExpectedExec) {
+				exec.WillReturnError(errors.New("lock wait timeout"))
+			},
+			Err: errors.New("lock wait timeout"),
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			staleBefore := time.Now().Add(-time.Hour)
+			reconciliation := &models.Reconciliation{From: staleBefore, To: time.Now()}
+
+			suite.sql.ExpectBegin()
+			suite.sql.ExpectExec(failStale).
+				WithArgs("Timed out", nil, "failed", true, staleBefore).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			tc.Insert(suite.sql.ExpectExec(insert))
+			if tc.Err == nil {
+				suite.sql.ExpectCommit()
+			} else {
+				suite.sql.ExpectRollback()
+			}
+
+			err := suite.repository.Start(reconciliation, staleBefore)
+
+			suite.Equal(tc.Err, err)
+			suite.Equal("running", reconciliation.Status)
+			suite.True(*reconciliation.Running)
+			suite.Nil(suite.sql.ExpectationsWereMet())
+		})
+	}
+}
+
+func (suite *reconciliationRepositoryTest) TestFinish() {
+	running := true
+	reconciliation := &models.Reconciliation{Status: "done", Matched: 3, Running: &running}
+
+	suite.sql.ExpectBegin()
+	suite.sql.ExpectExec(regexp.QuoteMeta(
+		"UPDATE `reconciliations` SET `errors`=?,`matched`=?,`running`=?,`status`=? WHERE id = ?",
+	)).
+		WithArgs("", 3, nil, "done", reconciliation.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	suite.sql.ExpectCommit()
+
+	suite.Nil(suite.repository.Finish(reconciliation))
+	suite.Nil(reconciliation.Running)
+	suite.Nil(suite.sql.ExpectationsWereMet())
+}
+
+func TestReconciliationRepository(t *testing.T) {
+	suite.Run(t, new(reconciliationRepositoryTest))
+}
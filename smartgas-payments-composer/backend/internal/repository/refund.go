@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/payments"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:generate mockery --name RefundRepository --filename=mock_refund.go --inpackage=true
+type RefundRepository interface {
+	Reserve(*models.Refund, money.Amount) error
+	Finish(*models.Refund) error
+	ListByPaymentID(uuid.UUID) ([]*models.Refund, error)
+}
+
+type refundRepository struct {
+	db *gorm.DB
+}
+
+func ProvideRefundRepository(db *gorm.DB) *refundRepository {
+	return &refundRepository{
+		db: db,
+	}
+}
+
+// Reserve stores a pending refund when it fits in what is left of captured after the
+// refunds not failed, a zero amount takes everything left
+func (rr *refundRepository) Reserve(refund *models.Refund, captured money.Amount) error {
+	return rr.db.Transaction(func(tx *gorm.DB) error {
+		var payment models.Payment
+
+		// Locking payment row to serialize refunds of the same payment
+		result := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&payment, "id = ?", refund.PaymentID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var refunded money.Amount
+
+		err := tx.
+			Model(&models.Refund{}).
+			Select("COALESCE(SUM(amount), 0)").
+			Where("payment_id = ? AND status <> ?", refund.PaymentID, models.RefundFailed).
+			Row().
+			Scan(&refunded)
+		if err != nil {
+			return err
+		}
+
+		left := captured - refunded
+		if refund.Amount <= 0 {
+			refund.Amount = left
+		}
+
+		if refund.Amount <= 0 || refund.Amount > left {
+			return payments.ErrRefundExceedsCaptured
+		}
+
+		refund.Status = models.RefundPending
+
+		return tx.Omit("Payment", "User").Create(refund).Error
+	})
+}
+
+func (rr *refundRepository) Finish(refund *models.Refund) error {
+	result := rr.db.
+		Model(&models.Refund{}).
+		Where("id = ?", refund.ID).
+		Updates(map[string]any{
+			"status": refund.Status,
+			"error":  refund.Error,
+		})
+
+	return result.Error
+}
+
+func (rr *refundRepository) ListByPaymentID(paymentID uuid.UUID) ([]*models.Refund, error) {
+	var refunds []*models.Refund
+
+	result := rr.db.
+		Preload("User").
+		Where("payment_id = ?", paymentID).
+		Order("created_at asc").
+		Find(&refunds)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return refunds, nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/reports"
+	"smartgas-payment/internal/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:generate mockery --name ReportRepository --filename=mock_report.go --inpackage=true
+type ReportRepository interface {
+	Sales(any, []string, bool) ([]*reports.SalesRow, error)
+}
+
+type reportRepository struct {
+	db *gorm.DB
+}
+
+func ProvideReportRepository(db *gorm.DB) *reportRepository {
+	return &reportRepository{
+		db: db,
+	}
+}
+
+// salesDimensions maps the dimensions of reports to their columns
+var salesDimensions = map[string][]string{
+	reports.GroupByStation: {
+		"GasStation.id AS gas_station_id",
+		"GasStation.name AS gas_station_name",
+	},
+	reports.GroupByPump: {
+		"GasPump.id AS gas_pump_id",
+		"GasPump.number AS gas_pump_number",
+	},
+	reports.GroupByFuelType: {"payments.fuel_type AS fuel_type"},
+	reports.GroupByProvider: {"payments.payment_provider AS payment_provider"},
+}
+
+// salesFilters maps the optional filters of the sales report to their condition
+var salesFilters = map[string]string{
+	"gas_station_id":   "GasStation.id = @gas_station_id",
+	"fuel_type":        "payments.fuel_type = @fuel_type",
+	"payment_provider": "payments.payment_provider = @payment_provider",
+}
+
+// Sales returns the totals of the payments served in the days [from, to) of the timezone
+// of each station, grouped by the dimensions given and that timezone, also by hour when
+// byHour is set. Liters and discounts are the ones served, the minimum charge fee is left
+// out of them
+func (rr *reportRepository) Sales(
+	filters any,
+	groupBy []string,
+	byHour bool,
+) ([]*reports.SalesRow, error) {
+	var rows []*reports.SalesRow
+
+	filtersMap := filters.(map[string]any)
+
+	columns := []string{"GasStation.timezone AS timezone"}
+	groups := []string{"timezone"}
+
+	if byHour {
+		columns = append(columns, "DATE_FORMAT(payments.created_at, '%Y-%m-%d %H:00:00') AS hour")
+		groups = append(groups, "hour")
+	}
+
+	for _, dimension := range groupBy {
+		for _, column := range salesDimensions[dimension] {
+			columns = append(columns, column)
+			groups = append(groups, column[strings.LastIndex(column, " ")+1:])
+		}
+	}
+
+	columns = append(columns, `COUNT(*) AS transactions,
+  SUM(payments.real_amount_reported) AS amount,
+  SUM(CASE WHEN payments.price > 0
+    THEN (payments.real_amount_reported - payments.charge_fee) / payments.price ELSE 0 END) AS liters,
+  SUM(payments.refunded_amount) AS refunds,
+  SUM(payments.charge_fee) AS charge_fees,
+  SUM(CASE WHEN payments.price > 0
+    THEN payments.discount_per_liter * (payments.real_amount_reported - payments.charge_fee) / payments.price
+    ELSE 0 END) AS discounts`)
+
+	dates, err := rr.stationDates(filtersMap)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{
+		"payments.real_amount_reported > 0",
+		dates,
+	}
+
+	if utils.CheckIfStationsExist(filters) {
+		conditions = append(conditions, "GasStation.id IN @stations")
+	}
+
+	for key, condition := range salesFilters {
+		if _, ok := filtersMap[key]; ok {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	result := rr.db.
+		Model(&models.Payment{}).
+		Select(strings.Join(columns, ", ")).
+		Joins("INNER JOIN gas_pumps as GasPump ON GasPump.id = payments.gas_pump_id").
+		Joins("INNER JOIN gas_stations as GasStation ON GasStation.id = GasPump.gas_station_id").
+		Where(strings.Join(conditions, " AND "), filters).
+		Group(strings.Join(groups, ", ")).
+		Scan(&rows)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return rows, nil
+}
+
+// stationDates returns the condition limiting the payments of each station to the days
+// [from, to) of its timezone, the ones of the app are used for stations without it. The
+// bounds of every timezone are added to filters
+func (rr *reportRepository) stationDates(filters map[string]any) (string, error) {
+	var timezones []string
+	if err := rr.db.Model(&models.GasStation{}).Distinct().Pluck("timezone", &timezones).Error; err != nil {
+		return "", err
+	}
+
+	if len(timezones) == 0 {
+		return "payments.created_at >= @from AND payments.created_at < @to", nil
+	}
+
+	from, to := filters["from"].(time.Time), filters["to"].(time.Time)
+
+	ranges := make([]string, 0, len(timezones))
+	for i, timezone := range timezones {
+		suffix := strconv.Itoa(i)
+		filters["timezone_"+suffix] = timezone
+		filters["from_"+suffix], filters["to_"+suffix] = reports.DaysIn(from, to, reports.Location(timezone))
+
+		ranges = append(ranges, "(GasStation.timezone = @timezone_"+suffix+
+			" AND payments.created_at >= @from_"+suffix+
+			" AND payments.created_at < @to_"+suffix+")")
+	}
+
+	return "(" + strings.Join(ranges, " OR ") + ")", nil
+}
@@ -16,6 +16,16 @@ var RepositorySet = wire.NewSet(
 	ProvideSettingRepository,
 	ProvidePromotionRepository,
 	ProvideElegibilityRepository,
+	ProvideIdempotencyRepository,
+	ProvideOutboxRepository,
+	ProvideReconciliationRepository,
+	ProvideStripeEventRepository,
+	ProvideDisputeRepository,
+	ProvideRefundRepository,
+	ProvideReportRepository,
+	ProvideSettlementRepository,
+	ProvideFraudRepository,
+	ProvidePromoCodeRepository,
 
 	wire.Bind(new(UserRepository), new(*userRepository)),
 	wire.Bind(new(GasStationRepository), new(*gasStationRepository)),
@@ -28,4 +38,14 @@ var RepositorySet = wire.NewSet(
 	wire.Bind(new(SettingRepository), new(*settingRepository)),
 	wire.Bind(new(CampaignRepository), new(*campaignRepository)),
 	wire.Bind(new(ElegibilityRepository), new(*elegibilityRepository)),
+	wire.Bind(new(IdempotencyRepository), new(*idempotencyRepository)),
+	wire.Bind(new(OutboxRepository), new(*outboxRepository)),
+	wire.Bind(new(ReconciliationRepository), new(*reconciliationRepository)),
+	wire.Bind(new(StripeEventRepository), new(*stripeEventRepository)),
+	wire.Bind(new(DisputeRepository), new(*disputeRepository)),
+	wire.Bind(new(RefundRepository), new(*refundRepository)),
+	wire.Bind(new(ReportRepository), new(*reportRepository)),
+	wire.Bind(new(SettlementRepository), new(*settlementRepository)),
+	wire.Bind(new(FraudRepository), new(*fraudRepository)),
+	wire.Bind(new(PromoCodeRepository), new(*promoCodeRepository)),
 )
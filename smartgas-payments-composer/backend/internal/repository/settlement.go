@@ -0,0 +1,339 @@
+package repository
+
+import (
+	"errors"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrSettlementClosed = errors.New("The settlement is closed")
+
+// SettlementPaymentRow is a payment to settle with the refunds already settled
+// in other settlements
+type SettlementPaymentRow struct {
+	PaymentID       uuid.UUID
+	PaymentProvider string
+	Captured        money.Amount
+	RefundedAmount  money.Amount
+	SettledRefunds  money.Amount
+}
+
+type SettlementProviderTotals struct {
+	PaymentProvider string
+	Captured        money.Amount
+	Refunds         money.Amount
+	ProviderFees    money.Amount
+	Net             money.Amount
+	Count           int
+}
+
+//go:generate mockery --name SettlementRepository --filename=mock_settlement.go --inpackage=true
+type SettlementRepository interface {
+	Create(*models.Settlement) error
+	GetByID(uuid.UUID) (*models.Settlement, error)
+	GetByPeriod(string, time.Time, time.Time) (*models.Settlement, error)
+	HasOverlap(string, time.Time, time.Time, uuid.UUID) (bool, error)
+	ListPaymentsToSettle(*models.Settlement) ([]*SettlementPaymentRow, error)
+	ListRefundAdjustments(*models.Settlement) ([]*SettlementPaymentRow, error)
+	ReplaceLines(*models.Settlement, []*models.SettlementLine) error
+	Close(*models.Settlement) error
+	List(*schemas.Pagination, any) ([]*models.Settlement, error)
+	ListLines(*schemas.Pagination, any) ([]*models.SettlementLine, error)
+	TotalsByProvider(uuid.UUID) ([]*SettlementProviderTotals, error)
+}
+
+type settlementRepository struct {
+	db *gorm.DB
+}
+
+func ProvideSettlementRepository(db *gorm.DB) *settlementRepository {
+	return &settlementRepository{
+		db: db,
+	}
+}
+
+func (sr *settlementRepository) Create(settlement *models.Settlement) error {
+	if result := sr.db.Omit("Lines", "ClosedBy").Create(settlement); result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (sr *settlementRepository) GetByID(id uuid.UUID) (*models.Settlement, error) {
+	var settlement models.Settlement
+
+	if result := sr.db.Preload("ClosedBy").First(&settlement, "id = ?", id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &settlement, nil
+}
+
+func (sr *settlementRepository) GetByPeriod(
+	legalNameID string,
+	from time.Time,
+	to time.Time,
+) (*models.Settlement, error) {
+	var settlement models.Settlement
+
+	result := sr.db.
+		Where("legal_name_id = ? AND from_date = ? AND to_date = ?", legalNameID, from, to).
+		First(&settlement)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &settlement, nil
+}
+
+// HasOverlap reports whether another settlement of the legal name overlaps [from, to)
+func (sr *settlementRepository) HasOverlap(
+	legalNameID string,
+	from time.Time,
+	to time.Time,
+	exceptID uuid.UUID,
+) (bool, error) {
+	var count int64
+
+	result := sr.db.
+		Model(&models.Settlement{}).
+		Where("legal_name_id = ? AND from_date < ? AND to_date > ? AND id <> ?", legalNameID, to, from, exceptID).
+		Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return count > 0, nil
+}
+
+// settledRefunds sums the refunds of each payment in the lines of the settlements
+// other than the given one
+func (sr *settlementRepository) settledRefunds(settlement *models.Settlement) *gorm.DB {
+	return sr.db.
+		Model(&models.SettlementLine{}).
+		Select("settlement_lines.payment_id, SUM(settlement_lines.refunds) AS refunds").
+		Joins("INNER JOIN settlements ON settlements.id = settlement_lines.settlement_id").
+		Where("settlements.legal_name_id = ? AND settlements.id <> ?", settlement.LegalNameID, settlement.ID).
+		Group("settlement_lines.payment_id")
+}
+
+// ListPaymentsToSettle returns the payments captured in the period of the settlement
+// by the stations of its legal name
+func (sr *settlementRepository) ListPaymentsToSettle(
+	settlement *models.Settlement,
+) ([]*SettlementPaymentRow, error) {
+	var rows []*SettlementPaymentRow
+
+	result := sr.db.
+		Model(&models.Payment{}).
+		Select(`payments.id AS payment_id, payments.payment_provider,
+  payments.real_amount_reported AS captured, payments.refunded_amount,
+  COALESCE(Settled.refunds, 0) AS settled_refunds`).
+		Joins("INNER JOIN gas_pumps AS GasPump ON GasPump.id = payments.gas_pump_id").
+		Joins("INNER JOIN gas_stations AS GasStation ON GasStation.id = GasPump.gas_station_id").
+		Joins("LEFT JOIN (?) AS Settled ON Settled.payment_id = payments.id", sr.settledRefunds(settlement)).
+		Where(`GasStation.legal_name_id = ? AND payments.real_amount_reported > 0
+  AND payments.created_at >= ? AND payments.created_at < ?`,
+			settlement.LegalNameID, settlement.From, settlement.To).
+		Order("payments.created_at asc").
+		Scan(&rows)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return rows, nil
+}
+
+// ListRefundAdjustments returns the payments settled by closed settlements before
+// the given one whose refunds grew after they were settled
+func (sr *settlementRepository) ListRefundAdjustments(
+	settlement *models.Settlement,
+) ([]*SettlementPaymentRow, error) {
+	var rows []*SettlementPaymentRow
+
+	closedLines := sr.db.
+		Model(&models.SettlementLine{}).
+		Select("DISTINCT settlement_lines.payment_id").
+		Joins("INNER JOIN settlements ON settlements.id = settlement_lines.settlement_id").
+		Where(`settlements.legal_name_id = ? AND settlements.status = ? AND settlements.to_date <= ?
+  AND settlement_lines.type = ?`,
+			settlement.LegalNameID, models.SettlementClosed, settlement.From, models.SettlementLinePayment)
+
+	result := sr.db.
+		Model(&models.Payment{}).
+		Select(`payments.id AS payment_id, payments.payment_provider, payments.refunded_amount,
+  COALESCE(Settled.refunds, 0) AS settled_refunds`).
+		Joins("INNER JOIN (?) AS ClosedLines ON ClosedLines.payment_id = payments.id", closedLines).
+		Joins("LEFT JOIN (?) AS Settled ON Settled.payment_id = payments.id", sr.settledRefunds(settlement)).
+		Where("payments.refunded_amount > COALESCE(Settled.refunds, 0)").
+		Order("payments.created_at asc").
+		Scan(&rows)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return rows, nil
+}
+
+// ReplaceLines stores the lines and totals of an open settlement, replacing the
+// previous ones. ErrSettlementClosed is returned when it was closed meanwhile
+func (sr *settlementRepository) ReplaceLines(
+	settlement *models.Settlement,
+	lines []*models.SettlementLine,
+) error {
+	return sr.db.Transaction(func(tx *gorm.DB) error {
+		var stored models.Settlement
+
+		// Locking settlement row so it is not closed while its lines are replaced
+		result := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "status").
+			First(&stored, "id = ?", settlement.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if stored.Status != models.SettlementOpen {
+			return ErrSettlementClosed
+		}
+
+		result = tx.Where("settlement_id = ?", settlement.ID).Delete(&models.SettlementLine{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if len(lines) > 0 {
+			if result := tx.Omit("Payment").CreateInBatches(lines, 500); result.Error != nil {
+				return result.Error
+			}
+		}
+
+		return tx.
+			Model(&models.Settlement{}).
+			Where("id = ?", settlement.ID).
+			Updates(map[string]any{
+				"captured":      settlement.Captured,
+				"refunds":       settlement.Refunds,
+				"provider_fees": settlement.ProviderFees,
+				"net":           settlement.Net,
+				"payments":      settlement.Payments,
+				"adjustments":   settlement.Adjustments,
+			}).Error
+	})
+}
+
+// Close locks an open settlement, ErrSettlementClosed is returned when it was
+// already closed
+func (sr *settlementRepository) Close(settlement *models.Settlement) error {
+	result := sr.db.
+		Model(&models.Settlement{}).
+		Where("id = ? AND status = ?", settlement.ID, models.SettlementOpen).
+		Updates(map[string]any{
+			"status":       models.SettlementClosed,
+			"closed_at":    settlement.ClosedAt,
+			"closed_by_id": settlement.ClosedByID,
+		})
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		return ErrSettlementClosed
+	}
+
+	return nil
+}
+
+func (sr *settlementRepository) List(
+	pagination *schemas.Pagination,
+	filters any,
+) ([]*models.Settlement, error) {
+	var settlements []*models.Settlement
+
+	filtersMap := filters.(map[string]any)
+
+	conditions := []string{}
+
+	if _, ok := filtersMap["legal_name_id"]; ok {
+		conditions = append(conditions, "legal_name_id = @legal_name_id")
+	}
+
+	if _, ok := filtersMap["status"]; ok {
+		conditions = append(conditions, "status = @status")
+	}
+
+	if _, ok := filtersMap["legal_names"]; ok {
+		conditions = append(conditions, "legal_name_id IN @legal_names")
+	}
+
+	filterQuery := strings.Join(conditions, " AND ")
+
+	query := sr.db.
+		Scopes(utils.Paginate(pagination, settlements, sr.db, filterQuery, filters)).
+		Preload("ClosedBy").
+		Order("from_date desc, legal_name_id asc")
+
+	if filterQuery != "" {
+		query = query.Where(filterQuery, filters)
+	}
+
+	if result := query.Find(&settlements); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return settlements, nil
+}
+
+func (sr *settlementRepository) ListLines(
+	pagination *schemas.Pagination,
+	filters any,
+) ([]*models.SettlementLine, error) {
+	var lines []*models.SettlementLine
+
+	result := sr.db.
+		Scopes(utils.Paginate(pagination, lines, sr.db, "", filters, "")).
+		Preload("Payment.GasPump.GasStation").
+		Where(filters).
+		// Payments first, enum columns are sorted by the position of their values
+		Order("type asc, created_at asc").
+		Find(&lines)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return lines, nil
+}
+
+func (sr *settlementRepository) TotalsByProvider(
+	settlementID uuid.UUID,
+) ([]*SettlementProviderTotals, error) {
+	var totals []*SettlementProviderTotals
+
+	result := sr.db.
+		Model(&models.SettlementLine{}).
+		Select(`payment_provider, SUM(captured) AS captured, SUM(refunds) AS refunds,
+  SUM(provider_fee) AS provider_fees, SUM(net) AS net, COUNT(*) AS count`).
+		Where("settlement_id = ?", settlementID).
+		Group("payment_provider").
+		Order("payment_provider asc").
+		Scan(&totals)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return totals, nil
+}
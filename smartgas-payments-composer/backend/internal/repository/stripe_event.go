@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:generate mockery --name StripeEventRepository --filename=mock_stripe_event.go --inpackage=true
+type StripeEventRepository interface {
+	Register(*models.StripeEvent) (bool, error)
+	Claim(*models.StripeEvent, time.Duration) (bool, error)
+	Finish(*models.StripeEvent) error
+	GetByID(string) (*models.StripeEvent, error)
+	List(*schemas.Pagination, any) ([]*models.StripeEvent, error)
+	ListByStatus(string, int) ([]*models.StripeEvent, error)
+}
+
+type stripeEventRepository struct {
+	db *gorm.DB
+}
+
+func ProvideStripeEventRepository(db *gorm.DB) *stripeEventRepository {
+	return &stripeEventRepository{
+		db: db,
+	}
+}
+
+// Register stores the event unless it was already received, reporting whether it was new
+func (ser *stripeEventRepository) Register(event *models.StripeEvent) (bool, error) {
+	result := ser.db.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// Claim takes a pending or failed event for the given lease, it reports false when
+// it was processed meanwhile or another attempt holds it
+func (ser *stripeEventRepository) Claim(event *models.StripeEvent, lease time.Duration) (bool, error) {
+	now := time.Now()
+	lockedUntil := now.Add(lease)
+
+	result := ser.db.Model(&models.StripeEvent{}).
+		Where(
+			"id = ? AND status IN ? AND (locked_until IS NULL OR locked_until <= ?)",
+			event.ID,
+			[]string{models.StripeEventPending, models.StripeEventFailed},
+			now,
+		).
+		Update("locked_until", lockedUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected < 1 {
+		return false, nil
+	}
+
+	event.LockedUntil = &lockedUntil
+
+	return true, nil
+}
+
+// Finish stores the outcome of an attempt to process the event, releasing its lease
+func (ser *stripeEventRepository) Finish(event *models.StripeEvent) error {
+	event.LockedUntil = nil
+
+	result := ser.db.
+		Model(&models.StripeEvent{}).
+		Where("id = ?", event.ID).
+		Updates(map[string]any{
+			"status":       event.Status,
+			"error":        event.Error,
+			"attempts":     event.Attempts,
+			"processed_at": event.ProcessedAt,
+			"locked_until": nil,
+		})
+
+	return result.Error
+}
+
+func (ser *stripeEventRepository) GetByID(id string) (*models.StripeEvent, error) {
+	var event models.StripeEvent
+
+	if result := ser.db.First(&event, "id = ?", id); result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &event, nil
+}
+
+func (ser *stripeEventRepository) List(
+	pagination *schemas.Pagination,
+	filters any,
+) ([]*models.StripeEvent, error) {
+	var events []*models.StripeEvent
+
+	result := ser.db.
+		Omit("Payload").
+		Scopes(utils.Paginate(pagination, events, ser.db, "", filters, "")).
+		Where(filters).
+		Order("created_at desc").
+		Find(&events)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return events, nil
+}
+
+// ListByStatus returns up to limit events with status, oldest first
+func (ser *stripeEventRepository) ListByStatus(status string, limit int) ([]*models.StripeEvent, error) {
+	var events []*models.StripeEvent
+
+	result := ser.db.
+		Where("status = ?", status).
+		Order("created_at asc").
+		Limit(limit).
+		Find(&events)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return events, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"regexp"
+	"smartgas-payment/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type stripeEventRepositoryTest struct {
+	suite.Suite
+	sql        sqlmock.Sqlmock
+	repository *stripeEventRepository
+}
+
+func (suite *stripeEventRepositoryTest) SetupTest() {
+	db, sql, err := openMockDB()
+	suite.Require().Nil(err)
+
+	suite.sql = sql
+	suite.repository = ProvideStripeEventRepository(db)
+}
+
+func (suite *stripeEventRepositoryTest) TestClaim() {
+	claim := regexp.QuoteMeta(
+		"UPDATE `stripe_events` SET `locked_until`=?,`updated_at`=? " +
+			"WHERE id = ? AND status IN (?,?) AND (locked_until IS NULL OR locked_until <= ?)",
+	)
+
+	testcases := []struct {
+		Name     string
+		Affected int64
+		Claimed  bool
+	}{
+		{Name: "TestStripeEventRepository_Claimed", Affected: 1, Claimed: true},
+		// Processed meanwhile or held by another attempt
+		{Name: "TestStripeEventRepository_NotClaimed", Affected: 0},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			event := &models.StripeEvent{ID: "evt_1", Status: models.StripeEventFailed}
+
+			suite.sql.ExpectBegin()
+			suite.sql.ExpectExec(claim).
+				WithArgs(
+					sqlmock.AnyArg(),
+					sqlmock.AnyArg(),
+					"evt_1",
+					models.StripeEventPending,
+					models.StripeEventFailed,
+					sqlmock.AnyArg(),
+				).
+				WillReturnResult(sqlmock.NewResult(0, tc.Affected))
+			suite.sql.ExpectCommit()
+
+			claimed, err := suite.repository.Claim(event, time.Minute)
+
+			suite.Nil(err)
+			suite.Equal(tc.Claimed, claimed)
+			if tc.Claimed {
+				suite.WithinDuration(time.Now().Add(time.Minute), *event.LockedUntil, time.Second)
+			} else {
+				suite.Nil(event.LockedUntil)
+			}
+			suite.Nil(suite.sql.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStripeEventRepository(t *testing.T) {
+	suite.Run(t, new(stripeEventRepositoryTest))
+}
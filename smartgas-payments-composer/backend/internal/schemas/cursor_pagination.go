@@ -0,0 +1,68 @@
+package schemas
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
+// CursorPagination pages rows sorted by creation, newest first. The cursor points
+// to the last row of the previous page so rows created meanwhile do not shift pages
+type CursorPagination struct {
+	Cursor     string
+	Limit      int
+	NextCursor string
+}
+
+func (p *CursorPagination) GetLimit() int {
+	if p.Limit == 0 {
+		p.Limit = 10
+	} else if p.Limit > 100 {
+		p.Limit = 100
+	}
+	return p.Limit
+}
+
+// Position decodes the cursor, ok is false when there is no cursor (first page)
+func (p *CursorPagination) Position() (createdAt time.Time, id uuid.UUID, ok bool, err error) {
+	if p.Cursor == "" {
+		return
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err != nil {
+		err = ErrInvalidCursor
+		return
+	}
+
+	createdAtStr, idStr, found := strings.Cut(string(raw), "|")
+	if !found {
+		err = ErrInvalidCursor
+		return
+	}
+
+	if createdAt, err = time.Parse(time.RFC3339Nano, createdAtStr); err != nil {
+		err = ErrInvalidCursor
+		return
+	}
+
+	if id, err = uuid.Parse(idStr); err != nil {
+		err = ErrInvalidCursor
+		return
+	}
+
+	ok = true
+
+	return
+}
+
+// SetNext points the cursor of the next page to the given row
+func (p *CursorPagination) SetNext(createdAt time.Time, id uuid.UUID) {
+	raw := createdAt.Format(time.RFC3339Nano) + "|" + id.String()
+	p.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
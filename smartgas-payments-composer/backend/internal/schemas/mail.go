@@ -3,34 +3,49 @@ package schemas
 import (
 	"fmt"
 	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
 	"strings"
 )
 
 type FuelRequest struct {
-	CustomerName   string
-	TransactionID  string
-	CustomerID     string
-	Date           string
-	FuelType       string
-	GasPump        string
-	GasStation     string
-	Amount         float32
-	RefundedAmount float32
-	TotalLiter     float32
-	RealAmount     float32
-	Error          bool
+	CustomerName     string
+	TransactionID    string
+	CustomerID       string
+	Date             string
+	Time             string
+	FuelType         string
+	GasPump          string
+	GasStation       string
+	Amount           money.Amount
+	RefundedAmount   money.Amount
+	TotalLiter       float32
+	Price            float64
+	DiscountPerLiter float64
+	Discount         money.Amount
+	RealAmount       money.Amount
+	GMPoints         float32
+	Error            bool
 }
 
 func (fr *FuelRequest) FillData(payment *models.Payment) {
 
-	fr.CustomerName = fmt.Sprintf("%v %v %v", payment.Customer.FirstName, payment.Customer.FirstLastName, payment.Customer.SecondLastName)
+	// Loads charged from operations may have no customer
+	if payment.Customer != nil {
+		fr.CustomerName = fmt.Sprintf("%v %v %v", payment.Customer.FirstName, payment.Customer.FirstLastName, payment.Customer.SecondLastName)
+		fr.CustomerID = payment.Customer.PhoneNumber
+	}
 	fr.TransactionID = payment.ID.String()
-	fr.CustomerID = payment.Customer.PhoneNumber
 	fr.Date = payment.CreatedAt.Format("01-02-2006")
+	fr.Time = payment.CreatedAt.Format("15:04")
 	fr.FuelType = strings.Title(payment.FuelType)
 	fr.GasPump = payment.GasPump.Number
 	fr.GasStation = payment.GasPump.GasStation.Name
 	fr.Amount = payment.Amount
 	fr.RefundedAmount = payment.RefundedAmount
 	fr.RealAmount = payment.RealAmountReported
+	fr.TotalLiter = payment.TotalLiter
+	fr.Price = payment.Price
+	fr.DiscountPerLiter = payment.DiscountPerLiter
+	fr.Discount = money.FromFloat(payment.DiscountPerLiter * float64(payment.TotalLiter))
+	fr.GMPoints = payment.GMPoints
 }
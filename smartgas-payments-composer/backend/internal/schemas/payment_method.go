@@ -7,4 +7,5 @@ type PaymentMethod struct {
 		Brand string `json:"brand"`
 	} `json:"card"`
 	IsLastUsed bool `json:"is_last_used"`
+	IsDefault  bool `json:"is_default"`
 }
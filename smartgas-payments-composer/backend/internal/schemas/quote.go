@@ -0,0 +1,51 @@
+package schemas
+
+import (
+	"smartgas-payment/config"
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+// Quote holds the terms of a load, Price is the price per liter with the discount
+// already applied
+type Quote struct {
+	GasPumpID        uuid.UUID    `json:"gas_pump_id"`
+	CustomerID       uuid.UUID    `json:"customer_id"`
+	FuelType         string       `json:"fuel_type"`
+	PaymentProvider  string       `json:"payment_provider"`
+	ChargeType       string       `json:"charge_type"`
+	Price            float64      `json:"price"`
+	DiscountPerLiter float64      `json:"discount_per_liter"`
+	DiscountType     string       `json:"discount_type"`
+	CampaignID       *uuid.UUID   `json:"campaign_id,omitempty"`
+	LevelID          *uuid.UUID   `json:"level_id,omitempty"`
+	PromoCode        string       `json:"promo_code,omitempty"`
+	PromoCodeID      *uuid.UUID   `json:"promo_code_id,omitempty"`
+	Amount           money.Amount `json:"amount"`
+	TotalLiter       float64      `json:"total_liter"`
+}
+
+// QuoteClaims is a quote signed for the customer, create intent charges its terms
+// until it expires
+type QuoteClaims struct {
+	Quote
+	jwt.RegisteredClaims
+}
+
+func (q QuoteClaims) ClaimToken() (string, error) {
+	cfg := config.ConfigSettings
+
+	if q.ExpiresAt == nil {
+		q.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute * time.Duration(cfg.QuoteExpMinutes)))
+	}
+
+	q.IssuedAt = jwt.NewNumericDate(time.Now())
+	q.Issuer = "Smart Gas"
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, q)
+
+	return token.SignedString([]byte(cfg.SecretKeyQuote))
+}
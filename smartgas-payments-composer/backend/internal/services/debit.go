@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"smartgas-payment/config"
+	"smartgas-payment/internal/money"
 	"time"
 )
 
@@ -23,20 +24,27 @@ var (
 	DebitPaymentAlreadyConfirmedOrCanceledError = errors.New(
 		"Given payment is already confirmed or canceled",
 	)
+	DebitRefundGreaterError = errors.New(
+		"Given amount is greater than what is left to refund",
+	)
+	DebitPaymentNotConfirmedError = errors.New(
+		"Given payment is not confirmed",
+	)
 )
 
 type DebitReserveFundsOpts struct {
-	Amount              float32 `json:"amount"`
-	ExternalCustomerID  string  `json:"external_customer_id,omitempty"`
-	ExternalLegalNameID string  `json:"external_legal_name_id"`
-	CardKey             string  `json:"card_key,omitempty"`
+	Amount              money.Amount `json:"amount"`
+	ExternalCustomerID  string       `json:"external_customer_id,omitempty"`
+	ExternalLegalNameID string       `json:"external_legal_name_id"`
+	CardKey             string       `json:"card_key,omitempty"`
 }
 
 //go:generate mockery --name DebitService --filename=mock_debit.go --inpackage=true
 type DebitService interface {
 	ReserveFunds(DebitReserveFundsOpts) (string, error)
 	CancelReservation(string) error
-	PaymentConfirmation(string, float32) error
+	PaymentConfirmation(string, money.Amount) error
+	PaymentRefund(string, money.Amount) error
 }
 
 type debitService struct {
@@ -49,13 +57,13 @@ func (db *debitService) addHeaders(r *http.Request) {
 	r.Header.Add("Content-Type", "application/json")
 }
 
-func (db *debitService) PaymentConfirmation(id string, amount float32) error {
+func (db *debitService) PaymentConfirmation(id string, amount money.Amount) error {
 	client := http.Client{
 		Timeout: time.Second * 10,
 	}
 	url := fmt.Sprintf("%s/api/v1/payments/confirmation/%s", db.config.DebitBaseUrl, id)
 	body, _ := json.Marshal(struct {
-		Amount float32 `json:"amount"`
+		Amount money.Amount `json:"amount"`
 	}{Amount: amount})
 
 	req, _ := http.NewRequest("POST", url, bytes.NewReader(body))
@@ -92,6 +100,51 @@ func (db *debitService) PaymentConfirmation(id string, amount float32) error {
 	return nil
 }
 
+// PaymentRefund gives back amount of a confirmed payment
+func (db *debitService) PaymentRefund(id string, amount money.Amount) error {
+	client := http.Client{
+		Timeout: time.Second * 10,
+	}
+	url := fmt.Sprintf("%s/api/v1/payments/refund/%s", db.config.DebitBaseUrl, id)
+	body, _ := json.Marshal(struct {
+		Amount money.Amount `json:"amount"`
+	}{Amount: amount})
+
+	req, _ := http.NewRequest("POST", url, bytes.NewReader(body))
+
+	db.addHeaders(req)
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == 404 {
+		return DebitErrNotFound
+	}
+
+	if res.StatusCode == 401 {
+		return DebitErrUnauthorized
+	}
+
+	if res.StatusCode == 406 {
+		return DebitRefundGreaterError
+	}
+
+	if res.StatusCode == 412 {
+		return DebitPaymentNotConfirmedError
+	}
+
+	if res.StatusCode == 500 {
+		return DebitInternalServerError
+	}
+
+	if res.StatusCode == 422 {
+		return DebitValidationError
+	}
+
+	return nil
+}
+
 func (db *debitService) CancelReservation(id string) error {
 	client := http.Client{
 		Timeout: time.Second * 10,
@@ -10,6 +10,7 @@ import (
 	"smartgas-payment/config"
 	"smartgas-payment/internal/dto"
 	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
 	"smartgas-payment/internal/repository"
 	"strconv"
 	"time"
@@ -90,18 +91,20 @@ func (is *invoicingService) SignInvoice(opts SignInvoiceOpts) (string, error) {
 		return "", err
 	}
 
-	total := float64(opts.Payment.RealAmountReported)
-	totalLiter := total / opts.Payment.Price
-	iepsTotal := totalLiter * iepsPrice
+	// Taxes are rounded to the centavo and IVA takes the remainder, so
+	// subtotal + IVA always equals the amount charged to the customer
+	total := opts.Payment.RealAmountReported
+	totalLiter := total.Liters(opts.Payment.Price)
+	iepsTotal := money.FromFloat(totalLiter * iepsPrice)
 
 	totalNoIeps := total - iepsTotal
 
-	base := totalNoIeps / iva
+	base := totalNoIeps.Div(iva)
 	subtotal := base + iepsTotal
 
-	realPricePerLiter := subtotal / totalLiter
+	realPricePerLiter := subtotal.Float64() / totalLiter
 
-	ivaImp := base * .16
+	ivaImp := totalNoIeps - base
 
 	// fmt.Println("total", total, "subtotal", subtotal, "base", base, "ieps", iepsTotal, "real reported", total, "price x liter", opts.Payment.Price, "total litersd", totalLiter)
 
@@ -116,11 +119,11 @@ func (is *invoicingService) SignInvoice(opts SignInvoiceOpts) (string, error) {
 		"NoCertificado":       is.config.Invoicing.CertificateNumber,
 		"Certificado":         "",
 		"CondicionesDePago":   "Una exhibicion",
-		"SubTotal":            fmt.Sprintf("%.2f", subtotal),
+		"SubTotal":            subtotal.String(),
 		"Moneda":              "MXN",
 		"TipoCambio":          1,
 		"TipoCambioSpecified": true,
-		"Total":               fmt.Sprintf("%.2f", total),
+		"Total":               total.String(),
 		"TipoDeComprobante":   "I",
 		"Exportacion":         "01",
 		"MetodoPago":          "PUE",
@@ -142,20 +145,20 @@ func (is *invoicingService) SignInvoice(opts SignInvoiceOpts) (string, error) {
 		"Impuestos": map[string]any{
 			"Traslados": []map[string]any{
 				{
-					"Base":       fmt.Sprintf("%.2f", base),
-					"Importe":    fmt.Sprintf("%.2f", ivaImp),
+					"Base":       base.String(),
+					"Importe":    ivaImp.String(),
 					"Impuesto":   "002",
 					"TasaOCuota": "0.160000",
 					"TipoFactor": "Tasa",
 				},
 			},
-			"TotalImpuestosTrasladados": fmt.Sprintf("%.2f", ivaImp),
+			"TotalImpuestosTrasladados": ivaImp.String(),
 		},
 		"Conceptos": []map[string]any{
 			{
 				"ClaveProdServ":    "15101514",
 				"NoIdentificacion": opts.Payment.GasPump.GasStation.CrePermission,
-				"Cantidad":         fmt.Sprintf("%.2f", totalLiter),
+				"Cantidad":         fmt.Sprintf("%.3f", totalLiter),
 				"ClaveUnidad":      "LTR",
 				"Unidad":           "Litro",
 				"Descripcion": fmt.Sprintf(
@@ -163,17 +166,17 @@ func (is *invoicingService) SignInvoice(opts SignInvoiceOpts) (string, error) {
 					opts.Payment.GasPump.GasStation.CrePermission,
 					opts.Payment.FuelType,
 				),
-				"ValorUnitario": fmt.Sprintf("%.2f", realPricePerLiter),
-				"Importe":       fmt.Sprintf("%.2f", subtotal),
+				"ValorUnitario": fmt.Sprintf("%.6f", realPricePerLiter),
+				"Importe":       subtotal.String(),
 				"ObjetoImp":     "02",
 				"Impuestos": map[string]any{
 					"Traslados": []map[string]any{
 						{
-							"Base":       fmt.Sprintf("%.2f", base),
+							"Base":       base.String(),
 							"Impuesto":   "002",
 							"TipoFactor": "Tasa",
 							"TasaOCuota": "0.160000",
-							"Importe":    fmt.Sprintf("%.2f", ivaImp),
+							"Importe":    ivaImp.String(),
 						},
 					},
 				},
@@ -2,17 +2,27 @@ package services
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
+	"mime/multipart"
 	"net/smtp"
+	"net/textproto"
 	"smartgas-payment/config"
 	"text/template"
 )
 
+type MailAttachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
 type SendMailOpts struct {
 	TemplatePath string
 	To           string
 	Data         any
 	Description  string
+	Attachments  []MailAttachment
 }
 
 //go:generate mockery --name MailService --filename=mock_mail.go --inpackage=true
@@ -48,6 +58,26 @@ func (ms *mailService) SendMail(opts SendMailOpts) error {
 
 	to := []string{opts.To}
 
+	if len(opts.Attachments) > 0 {
+		body, err := ms.multipartBody(tmplBytes.String(), opts.Attachments)
+		if err != nil {
+			return err
+		}
+
+		message := fmt.Sprintf("To: %s\r\n"+
+			"From: %s\r\n"+
+			"Subject: %s\r\n"+
+			"MIME-version: 1.0;\r\n"+
+			"%s",
+			opts.To,
+			ms.config.FromEmail,
+			opts.Description,
+			body,
+		)
+
+		return smtp.SendMail(addr, auth, ms.config.FromEmail, to, []byte(message))
+	}
+
 	message := fmt.Sprintf("To: %s\r\n"+
 		"From: %s\r\n"+
 		"Subject: %s\r\n"+
@@ -60,8 +90,48 @@ func (ms *mailService) SendMail(opts SendMailOpts) error {
 		tmplBytes.String(),
 	)
 
-	smtp.SendMail(addr, auth, ms.config.FromEmail, to, []byte(message))
+	return smtp.SendMail(addr, auth, ms.config.FromEmail, to, []byte(message))
+}
+
+// multipartBody returns the Content-Type header and the multipart/mixed body with the
+// html and the attachments encoded in base64
+func (ms *mailService) multipartBody(html string, attachments []MailAttachment) (string, error) {
+	var body bytes.Buffer
+
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/html; charset=\"UTF-8\""},
+	})
+	if err != nil {
+		return "", err
+	}
+	part.Write([]byte(html))
+
+	for _, attachment := range attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=\"%s\"", attachment.Filename)},
+		})
+		if err != nil {
+			return "", err
+		}
+
+		encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+		// Lines of encoded content must not exceed 76 characters
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
 
-	return nil
+	header := fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
 
+	return header + body.String(), nil
 }
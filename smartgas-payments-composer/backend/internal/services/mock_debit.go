@@ -2,7 +2,11 @@
 
 package services
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	money "smartgas-payment/internal/money"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // MockDebitService is an autogenerated mock type for the DebitService type
 type MockDebitService struct {
@@ -28,7 +32,7 @@ func (_m *MockDebitService) CancelReservation(_a0 string) error {
 }
 
 // PaymentConfirmation provides a mock function with given fields: _a0, _a1
-func (_m *MockDebitService) PaymentConfirmation(_a0 string, _a1 float32) error {
+func (_m *MockDebitService) PaymentConfirmation(_a0 string, _a1 money.Amount) error {
 	ret := _m.Called(_a0, _a1)
 
 	if len(ret) == 0 {
@@ -36,7 +40,25 @@ func (_m *MockDebitService) PaymentConfirmation(_a0 string, _a1 float32) error {
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, float32) error); ok {
+	if rf, ok := ret.Get(0).(func(string, money.Amount) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PaymentRefund provides a mock function with given fields: _a0, _a1
+func (_m *MockDebitService) PaymentRefund(_a0 string, _a1 money.Amount) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PaymentRefund")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, money.Amount) error); ok {
 		r0 = rf(_a0, _a1)
 	} else {
 		r0 = ret.Error(0)
@@ -0,0 +1,206 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package services
+
+import (
+	time "time"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockPaymentProvider is an autogenerated mock type for the PaymentProvider type
+type MockPaymentProvider struct {
+	mock.Mock
+}
+
+// Cancel provides a mock function with given fields: _a0
+func (_m *MockPaymentProvider) Cancel(_a0 string) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Cancel")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Capture provides a mock function with given fields: _a0
+func (_m *MockPaymentProvider) Capture(_a0 CaptureOpts) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Capture")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(CaptureOpts) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListTransactions provides a mock function with given fields: from, to
+func (_m *MockPaymentProvider) ListTransactions(from time.Time, to time.Time) ([]ProviderTransaction, error) {
+	ret := _m.Called(from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListTransactions")
+	}
+
+	var r0 []ProviderTransaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(time.Time, time.Time) ([]ProviderTransaction, error)); ok {
+		return rf(from, to)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time, time.Time) []ProviderTransaction); ok {
+		r0 = rf(from, to)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ProviderTransaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time, time.Time) error); ok {
+		r1 = rf(from, to)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Name provides a mock function with given fields:
+func (_m *MockPaymentProvider) Name() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Name")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// Refund provides a mock function with given fields: _a0
+func (_m *MockPaymentProvider) Refund(_a0 RefundOpts) error {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Refund")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(RefundOpts) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RefundsConfirmedAsync provides a mock function with given fields:
+func (_m *MockPaymentProvider) RefundsConfirmedAsync() bool {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for RefundsConfirmedAsync")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// Reserve provides a mock function with given fields: _a0
+func (_m *MockPaymentProvider) Reserve(_a0 ReserveOpts) (*ReserveResult, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reserve")
+	}
+
+	var r0 *ReserveResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(ReserveOpts) (*ReserveResult, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(ReserveOpts) *ReserveResult); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ReserveResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(ReserveOpts) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Status provides a mock function with given fields: _a0
+func (_m *MockPaymentProvider) Status(_a0 string) (string, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Status")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMockPaymentProvider creates a new instance of MockPaymentProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockPaymentProvider(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockPaymentProvider {
+	mock := &MockPaymentProvider{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
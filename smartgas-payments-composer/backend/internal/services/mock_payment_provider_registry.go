@@ -0,0 +1,74 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package services
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockPaymentProviderRegistry is an autogenerated mock type for the PaymentProviderRegistry type
+type MockPaymentProviderRegistry struct {
+	mock.Mock
+}
+
+// Get provides a mock function with given fields: _a0
+func (_m *MockPaymentProviderRegistry) Get(_a0 string) (PaymentProvider, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 PaymentProvider
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (PaymentProvider, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) PaymentProvider); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(PaymentProvider)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Names provides a mock function with given fields:
+func (_m *MockPaymentProviderRegistry) Names() []string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Names")
+	}
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
+// NewMockPaymentProviderRegistry creates a new instance of MockPaymentProviderRegistry. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockPaymentProviderRegistry(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockPaymentProviderRegistry {
+	mock := &MockPaymentProviderRegistry{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,47 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package services
+
+import (
+	io "io"
+	schemas "smartgas-payment/internal/schemas"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockReceiptService is an autogenerated mock type for the ReceiptService type
+type MockReceiptService struct {
+	mock.Mock
+}
+
+// Render provides a mock function with given fields: _a0, _a1
+func (_m *MockReceiptService) Render(_a0 *schemas.FuelRequest, _a1 io.Writer) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Render")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*schemas.FuelRequest, io.Writer) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockReceiptService creates a new instance of MockReceiptService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockReceiptService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockReceiptService {
+	mock := &MockReceiptService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -3,11 +3,15 @@
 package services
 
 import (
-	schemas "smartgas-payment/internal/schemas"
+	money "smartgas-payment/internal/money"
 
 	mock "github.com/stretchr/testify/mock"
 
+	schemas "smartgas-payment/internal/schemas"
+
 	stripe "github.com/stripe/stripe-go/v72"
+
+	time "time"
 )
 
 // MockStripeService is an autogenerated mock type for the StripeService type
@@ -33,6 +37,36 @@ func (_m *MockStripeService) CancelPaymentIntent(_a0 string) error {
 	return r0
 }
 
+// CapturePaymentIntent provides a mock function with given fields: _a0, _a1
+func (_m *MockStripeService) CapturePaymentIntent(_a0 string, _a1 money.Amount) (*stripe.PaymentIntent, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CapturePaymentIntent")
+	}
+
+	var r0 *stripe.PaymentIntent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, money.Amount) (*stripe.PaymentIntent, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(string, money.Amount) *stripe.PaymentIntent); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*stripe.PaymentIntent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, money.Amount) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateCustomer provides a mock function with given fields: _a0
 func (_m *MockStripeService) CreateCustomer(_a0 *schemas.Customer) (string, error) {
 	ret := _m.Called(_a0)
@@ -61,9 +95,9 @@ func (_m *MockStripeService) CreateCustomer(_a0 *schemas.Customer) (string, erro
 	return r0, r1
 }
 
-// CreatePaymentIntent provides a mock function with given fields: _a0, _a1
-func (_m *MockStripeService) CreatePaymentIntent(_a0 float64, _a1 string) (*stripe.PaymentIntent, error) {
-	ret := _m.Called(_a0, _a1)
+// CreatePaymentIntent provides a mock function with given fields: _a0
+func (_m *MockStripeService) CreatePaymentIntent(_a0 CreatePaymentIntentOpts) (*stripe.PaymentIntent, error) {
+	ret := _m.Called(_a0)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CreatePaymentIntent")
@@ -71,19 +105,49 @@ func (_m *MockStripeService) CreatePaymentIntent(_a0 float64, _a1 string) (*stri
 
 	var r0 *stripe.PaymentIntent
 	var r1 error
-	if rf, ok := ret.Get(0).(func(float64, string) (*stripe.PaymentIntent, error)); ok {
-		return rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(CreatePaymentIntentOpts) (*stripe.PaymentIntent, error)); ok {
+		return rf(_a0)
 	}
-	if rf, ok := ret.Get(0).(func(float64, string) *stripe.PaymentIntent); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(CreatePaymentIntentOpts) *stripe.PaymentIntent); ok {
+		r0 = rf(_a0)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*stripe.PaymentIntent)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(float64, string) error); ok {
-		r1 = rf(_a0, _a1)
+	if rf, ok := ret.Get(1).(func(CreatePaymentIntentOpts) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateSetupIntent provides a mock function with given fields: _a0
+func (_m *MockStripeService) CreateSetupIntent(_a0 string) (*stripe.SetupIntent, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateSetupIntent")
+	}
+
+	var r0 *stripe.SetupIntent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*stripe.SetupIntent, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) *stripe.SetupIntent); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*stripe.SetupIntent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -109,6 +173,126 @@ func (_m *MockStripeService) DeletePaymentMethod(_a0 string) error {
 	return r0
 }
 
+// GetCharge provides a mock function with given fields: _a0
+func (_m *MockStripeService) GetCharge(_a0 string) (*stripe.Charge, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCharge")
+	}
+
+	var r0 *stripe.Charge
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*stripe.Charge, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) *stripe.Charge); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*stripe.Charge)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDefaultPaymentMethod provides a mock function with given fields: _a0
+func (_m *MockStripeService) GetDefaultPaymentMethod(_a0 string) (*stripe.PaymentMethod, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDefaultPaymentMethod")
+	}
+
+	var r0 *stripe.PaymentMethod
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*stripe.PaymentMethod, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) *stripe.PaymentMethod); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*stripe.PaymentMethod)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPaymentIntent provides a mock function with given fields: _a0
+func (_m *MockStripeService) GetPaymentIntent(_a0 string) (*stripe.PaymentIntent, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPaymentIntent")
+	}
+
+	var r0 *stripe.PaymentIntent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*stripe.PaymentIntent, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) *stripe.PaymentIntent); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*stripe.PaymentIntent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPaymentIntents provides a mock function with given fields: _a0, _a1
+func (_m *MockStripeService) ListPaymentIntents(_a0 time.Time, _a1 time.Time) ([]*stripe.PaymentIntent, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPaymentIntents")
+	}
+
+	var r0 []*stripe.PaymentIntent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(time.Time, time.Time) ([]*stripe.PaymentIntent, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time, time.Time) []*stripe.PaymentIntent); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*stripe.PaymentIntent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time, time.Time) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ListPaymenthMethodsByCustomer provides a mock function with given fields: _a0
 func (_m *MockStripeService) ListPaymenthMethodsByCustomer(_a0 string) []*stripe.PaymentMethod {
 	ret := _m.Called(_a0)
@@ -129,9 +313,9 @@ func (_m *MockStripeService) ListPaymenthMethodsByCustomer(_a0 string) []*stripe
 	return r0
 }
 
-// MakeARefund provides a mock function with given fields: _a0, _a1
-func (_m *MockStripeService) MakeARefund(_a0 string, _a1 float64) (*stripe.Refund, error) {
-	ret := _m.Called(_a0, _a1)
+// MakeARefund provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *MockStripeService) MakeARefund(_a0 string, _a1 money.Amount, _a2 string, _a3 string) (*stripe.Refund, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
 
 	if len(ret) == 0 {
 		panic("no return value specified for MakeARefund")
@@ -139,19 +323,19 @@ func (_m *MockStripeService) MakeARefund(_a0 string, _a1 float64) (*stripe.Refun
 
 	var r0 *stripe.Refund
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, float64) (*stripe.Refund, error)); ok {
-		return rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(string, money.Amount, string, string) (*stripe.Refund, error)); ok {
+		return rf(_a0, _a1, _a2, _a3)
 	}
-	if rf, ok := ret.Get(0).(func(string, float64) *stripe.Refund); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(string, money.Amount, string, string) *stripe.Refund); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*stripe.Refund)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, float64) error); ok {
-		r1 = rf(_a0, _a1)
+	if rf, ok := ret.Get(1).(func(string, money.Amount, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -159,6 +343,24 @@ func (_m *MockStripeService) MakeARefund(_a0 string, _a1 float64) (*stripe.Refun
 	return r0, r1
 }
 
+// SetDefaultPaymentMethod provides a mock function with given fields: _a0, _a1
+func (_m *MockStripeService) SetDefaultPaymentMethod(_a0 string, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDefaultPaymentMethod")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewMockStripeService creates a new instance of MockStripeService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockStripeService(t interface {
@@ -3,9 +3,11 @@
 package services
 
 import (
-	schemas "smartgas-payment/internal/schemas"
+	money "smartgas-payment/internal/money"
 
 	mock "github.com/stretchr/testify/mock"
+
+	schemas "smartgas-payment/internal/schemas"
 )
 
 // MockSwitService is an autogenerated mock type for the SwitService type
@@ -32,7 +34,7 @@ func (_m *MockSwitService) CancelFundReservation(_a0 string) error {
 }
 
 // ConfirmFundReservation provides a mock function with given fields: _a0, _a1
-func (_m *MockSwitService) ConfirmFundReservation(_a0 string, _a1 float32) error {
+func (_m *MockSwitService) ConfirmFundReservation(_a0 string, _a1 money.Amount) error {
 	ret := _m.Called(_a0, _a1)
 
 	if len(ret) == 0 {
@@ -40,7 +42,7 @@ func (_m *MockSwitService) ConfirmFundReservation(_a0 string, _a1 float32) error
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, float32) error); ok {
+	if rf, ok := ret.Get(0).(func(string, money.Amount) error); ok {
 		r0 = rf(_a0, _a1)
 	} else {
 		r0 = ret.Error(0)
@@ -125,6 +127,24 @@ func (_m *MockSwitService) ListCardsByCustomer(_a0 string) ([]schemas.SwitSource
 	return r0, r1
 }
 
+// RefundPayment provides a mock function with given fields: _a0, _a1
+func (_m *MockSwitService) RefundPayment(_a0 string, _a1 money.Amount) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RefundPayment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, money.Amount) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ReserveFunds provides a mock function with given fields: _a0
 func (_m *MockSwitService) ReserveFunds(_a0 ReserveFundsOpts) (string, error) {
 	ret := _m.Called(_a0)
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"errors"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"sort"
+	"time"
+)
+
+var (
+	ErrPaymentProviderNotFound = errors.New("Payment provider not registered")
+	ErrInsufficientFunds       = errors.New("Unsufficient funds or invalid card data")
+	ErrOperationNotSupported   = errors.New("Operation not supported by payment provider")
+	ErrNoDefaultPaymentMethod  = errors.New("The customer has no default payment method")
+)
+
+type ReserveOpts struct {
+	Amount              money.Amount
+	Customer            *models.Customer
+	ExternalLegalNameID string
+	// Swit card data
+	SourceID string
+	Cvv      string
+	Last4    string
+	// Debit gift card, when the charge is not made to a customer
+	CardKey string
+	// ManualCapture asks providers charging up front to only authorize the amount
+	ManualCapture bool
+	// UseDefaultPaymentMethod charges the default card of the customer without
+	// the client confirming the payment
+	UseDefaultPaymentMethod bool
+}
+
+type ReserveResult struct {
+	TransactionID string
+	// ClientSecret is returned by providers that need the client to confirm the payment
+	ClientSecret string
+	// Reserved is true when funds are already held once Reserve returns,
+	// otherwise the provider confirms the payment asynchronously
+	Reserved bool
+	// ManualCapture is true when the amount was only authorized and Capture charges it
+	ManualCapture bool
+	// RequiresAction is true when the client must authenticate the payment (3D Secure)
+	// with the ClientSecret
+	RequiresAction bool
+}
+
+type CaptureOpts struct {
+	TransactionID  string
+	ReservedAmount money.Amount
+	Amount         money.Amount
+	// ManualCapture is the one returned by Reserve
+	ManualCapture bool
+}
+
+type RefundOpts struct {
+	TransactionID string
+	// Amount to refund, zero or less refunds the whole transaction
+	Amount money.Amount
+	// Event recorded once the provider confirms the refund, for providers confirming
+	// refunds asynchronously
+	Event string
+	// Reason is stored on the provider side when it is supported
+	Reason string
+}
+
+// ProviderTransaction is a transaction as seen by the provider, used to reconcile payments
+type ProviderTransaction struct {
+	ID        string
+	Captured  money.Amount
+	Refunded  money.Amount
+	Status    string
+	CreatedAt time.Time
+}
+
+//go:generate mockery --name PaymentProvider --filename=mock_payment_provider.go --inpackage=true
+type PaymentProvider interface {
+	Name() string
+	Reserve(ReserveOpts) (*ReserveResult, error)
+	Capture(CaptureOpts) error
+	Cancel(string) error
+	Refund(RefundOpts) error
+	Status(string) (string, error)
+	// ListTransactions returns the transactions created in [from, to)
+	ListTransactions(from time.Time, to time.Time) ([]ProviderTransaction, error)
+	// RefundsConfirmedAsync reports whether refunds are confirmed later by the
+	// provider (i.e. webhooks), so the caller must not record them by itself
+	RefundsConfirmedAsync() bool
+}
+
+//go:generate mockery --name PaymentProviderRegistry --filename=mock_payment_provider_registry.go --inpackage=true
+type PaymentProviderRegistry interface {
+	Get(string) (PaymentProvider, error)
+	Names() []string
+}
+
+type paymentProviderRegistry struct {
+	providers map[string]PaymentProvider
+}
+
+func ProvidePaymentProviderRegistry(
+	stripeProvider *stripeProvider,
+	switProvider *switProvider,
+	debitProvider *debitProvider,
+) *paymentProviderRegistry {
+	registry := &paymentProviderRegistry{
+		providers: map[string]PaymentProvider{},
+	}
+
+	registry.register(stripeProvider)
+	registry.register(switProvider)
+	registry.register(debitProvider)
+
+	return registry
+}
+
+func (pr *paymentProviderRegistry) register(provider PaymentProvider) {
+	pr.providers[provider.Name()] = provider
+}
+
+func (pr *paymentProviderRegistry) Get(name string) (PaymentProvider, error) {
+	provider, ok := pr.providers[name]
+	if !ok {
+		return nil, ErrPaymentProviderNotFound
+	}
+
+	return provider, nil
+}
+
+func (pr *paymentProviderRegistry) Names() []string {
+	names := make([]string, 0, len(pr.providers))
+
+	for name := range pr.providers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
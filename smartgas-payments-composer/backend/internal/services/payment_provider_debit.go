@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"time"
+)
+
+type debitProvider struct {
+	debitService DebitService
+}
+
+func ProvideDebitProvider(debitService DebitService) *debitProvider {
+	return &debitProvider{
+		debitService: debitService,
+	}
+}
+
+func (dp *debitProvider) Name() string {
+	return "debit"
+}
+
+func (dp *debitProvider) RefundsConfirmedAsync() bool {
+	return false
+}
+
+func (dp *debitProvider) Reserve(opts ReserveOpts) (*ReserveResult, error) {
+	reserveOpts := DebitReserveFundsOpts{
+		Amount:              opts.Amount,
+		ExternalLegalNameID: opts.ExternalLegalNameID,
+		CardKey:             opts.CardKey,
+	}
+
+	if opts.Customer != nil {
+		reserveOpts.ExternalCustomerID = opts.Customer.ExternalID
+	}
+
+	transID, err := dp.debitService.ReserveFunds(reserveOpts)
+	if err != nil {
+		if errors.Is(err, DebitUnsufficientFunds) {
+			return nil, ErrInsufficientFunds
+		}
+		return nil, err
+	}
+
+	return &ReserveResult{TransactionID: transID, Reserved: true}, nil
+}
+
+func (dp *debitProvider) Capture(opts CaptureOpts) error {
+	return dp.debitService.PaymentConfirmation(opts.TransactionID, opts.Amount)
+}
+
+func (dp *debitProvider) Cancel(transactionID string) error {
+	return dp.debitService.CancelReservation(transactionID)
+}
+
+// Refund gives back amount of a confirmed payment, without amount the whole reservation
+// of a load not served is released
+func (dp *debitProvider) Refund(opts RefundOpts) error {
+	if opts.Amount > 0 {
+		return dp.debitService.PaymentRefund(opts.TransactionID, opts.Amount)
+	}
+
+	return dp.debitService.CancelReservation(opts.TransactionID)
+}
+
+func (dp *debitProvider) Status(transactionID string) (string, error) {
+	return "", ErrOperationNotSupported
+}
+
+// The Debit service has no endpoint to list transactions, Debit payments are not reconciled
+func (dp *debitProvider) ListTransactions(from time.Time, to time.Time) ([]ProviderTransaction, error) {
+	return nil, ErrOperationNotSupported
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/stripe/stripe-go/v72"
+)
+
+type stripeProvider struct {
+	stripeService StripeService
+}
+
+func ProvideStripeProvider(stripeService StripeService) *stripeProvider {
+	return &stripeProvider{
+		stripeService: stripeService,
+	}
+}
+
+func (sp *stripeProvider) Name() string {
+	return "stripe"
+}
+
+func (sp *stripeProvider) RefundsConfirmedAsync() bool {
+	// charge.refunded webhook records the refund
+	return true
+}
+
+func (sp *stripeProvider) Reserve(opts ReserveOpts) (*ReserveResult, error) {
+	intentOpts := CreatePaymentIntentOpts{
+		Amount:        opts.Amount,
+		CustomerID:    opts.Customer.StripeCustomerID,
+		ManualCapture: opts.ManualCapture,
+	}
+
+	if opts.UseDefaultPaymentMethod {
+		paymentMethod, err := sp.stripeService.GetDefaultPaymentMethod(opts.Customer.StripeCustomerID)
+		if err != nil {
+			return nil, err
+		}
+
+		if paymentMethod == nil {
+			return nil, ErrNoDefaultPaymentMethod
+		}
+
+		intentOpts.PaymentMethodID = paymentMethod.ID
+	}
+
+	pi, err := sp.stripeService.CreatePaymentIntent(intentOpts)
+	if err != nil {
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) && stripeErr.Type == stripe.ErrorTypeCard {
+			// The declined payment intent is left open otherwise
+			if stripeErr.PaymentIntent != nil {
+				sp.stripeService.CancelPaymentIntent(stripeErr.PaymentIntent.ID)
+			}
+			return nil, ErrInsufficientFunds
+		}
+		return nil, err
+	}
+
+	return &ReserveResult{
+		TransactionID:  pi.ID,
+		ClientSecret:   pi.ClientSecret,
+		Reserved:       false,
+		ManualCapture:  opts.ManualCapture,
+		RequiresAction: pi.Status == stripe.PaymentIntentStatusRequiresAction,
+	}, nil
+}
+
+// Authorized payment intents are captured by the served amount. Otherwise stripe
+// charged the whole amount up front, so capturing means giving back what was not served
+func (sp *stripeProvider) Capture(opts CaptureOpts) error {
+	if opts.ManualCapture {
+		// Nothing was served, the authorization is released
+		if opts.Amount <= 0 {
+			return sp.stripeService.CancelPaymentIntent(opts.TransactionID)
+		}
+
+		_, err := sp.stripeService.CapturePaymentIntent(opts.TransactionID, opts.Amount)
+
+		return err
+	}
+
+	difference := opts.ReservedAmount - opts.Amount
+	if difference <= 0 {
+		return nil
+	}
+
+	_, err := sp.stripeService.MakeARefund(opts.TransactionID, difference, "", "")
+
+	return err
+}
+
+func (sp *stripeProvider) Cancel(transactionID string) error {
+	return sp.stripeService.CancelPaymentIntent(transactionID)
+}
+
+func (sp *stripeProvider) Refund(opts RefundOpts) error {
+	_, err := sp.stripeService.MakeARefund(opts.TransactionID, opts.Amount, opts.Event, opts.Reason)
+
+	return err
+}
+
+func (sp *stripeProvider) Status(transactionID string) (string, error) {
+	pi, err := sp.stripeService.GetPaymentIntent(transactionID)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pi.Status), nil
+}
+
+func (sp *stripeProvider) ListTransactions(from time.Time, to time.Time) ([]ProviderTransaction, error) {
+	intents, err := sp.stripeService.ListPaymentIntents(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]ProviderTransaction, 0, len(intents))
+	for _, pi := range intents {
+		transaction := ProviderTransaction{
+			ID:        pi.ID,
+			Captured:  money.FromCents(pi.AmountReceived),
+			Status:    string(pi.Status),
+			CreatedAt: time.Unix(pi.Created, 0),
+		}
+
+		if pi.Charges != nil {
+			for _, ch := range pi.Charges.Data {
+				transaction.Refunded += money.FromCents(ch.AmountRefunded)
+			}
+		}
+
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, nil
+}
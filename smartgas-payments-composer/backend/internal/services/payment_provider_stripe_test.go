@@ -0,0 +1,131 @@
+package services
+
+import (
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/stripe/stripe-go/v72"
+)
+
+type stripeProviderTest struct {
+	suite.Suite
+	stripeService *MockStripeService
+	provider      *stripeProvider
+}
+
+func (suite *stripeProviderTest) SetupTest() {
+	suite.stripeService = &MockStripeService{}
+	suite.provider = ProvideStripeProvider(suite.stripeService)
+}
+
+func (suite *stripeProviderTest) TestReserveDefaultPaymentMethod() {
+	amount := money.FromFloat(500)
+	declined := &stripe.Error{
+		Type:          stripe.ErrorTypeCard,
+		Code:          stripe.ErrorCodeCardDeclined,
+		PaymentIntent: &stripe.PaymentIntent{ID: "pi_declined"},
+	}
+
+	testcases := []struct {
+		Name            string
+		UseDefault      bool
+		Default         *stripe.PaymentMethod
+		PaymentMethodID string
+		Status          stripe.PaymentIntentStatus
+		CreateErr       error
+		RequiresAction  bool
+		Canceled        bool
+		Err             error
+	}{
+		{
+			// The client confirms the payment intent with the card it picks
+			Name:   "TestStripeProvider_ReserveWithoutDefault",
+			Status: stripe.PaymentIntentStatusRequiresPaymentMethod,
+		},
+		{
+			Name:            "TestStripeProvider_ReserveDefaultConfirmed",
+			UseDefault:      true,
+			Default:         &stripe.PaymentMethod{ID: "pm_default"},
+			PaymentMethodID: "pm_default",
+			Status:          stripe.PaymentIntentStatusSucceeded,
+		},
+		{
+			Name:            "TestStripeProvider_ReserveDefaultRequiresAction",
+			UseDefault:      true,
+			Default:         &stripe.PaymentMethod{ID: "pm_default"},
+			PaymentMethodID: "pm_default",
+			Status:          stripe.PaymentIntentStatusRequiresAction,
+			RequiresAction:  true,
+		},
+		{
+			Name:       "TestStripeProvider_ReserveNoDefault",
+			UseDefault: true,
+			Err:        ErrNoDefaultPaymentMethod,
+		},
+		{
+			Name:            "TestStripeProvider_ReserveDefaultDeclined",
+			UseDefault:      true,
+			Default:         &stripe.PaymentMethod{ID: "pm_default"},
+			PaymentMethodID: "pm_default",
+			CreateErr:       declined,
+			Canceled:        true,
+			Err:             ErrInsufficientFunds,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			customer := &models.Customer{StripeCustomerID: "cus_test"}
+
+			suite.stripeService.On("GetDefaultPaymentMethod", "cus_test").Return(tc.Default, nil).Maybe()
+			suite.stripeService.On("CreatePaymentIntent", CreatePaymentIntentOpts{
+				Amount:          amount,
+				CustomerID:      "cus_test",
+				PaymentMethodID: tc.PaymentMethodID,
+			}).Return(&stripe.PaymentIntent{ID: "pi_test", ClientSecret: "pi_test_secret", Status: tc.Status}, tc.CreateErr).Maybe()
+			suite.stripeService.On("CancelPaymentIntent", "pi_declined").Return(nil).Maybe()
+
+			result, err := suite.provider.Reserve(ReserveOpts{
+				Amount:                  amount,
+				Customer:                customer,
+				UseDefaultPaymentMethod: tc.UseDefault,
+			})
+
+			if tc.Canceled {
+				suite.stripeService.AssertCalled(suite.T(), "CancelPaymentIntent", "pi_declined")
+			} else {
+				suite.stripeService.AssertNotCalled(suite.T(), "CancelPaymentIntent", mock.Anything)
+			}
+
+			if !tc.UseDefault {
+				suite.stripeService.AssertNotCalled(suite.T(), "GetDefaultPaymentMethod", mock.Anything)
+			}
+
+			if tc.Err != nil {
+				suite.ErrorIs(err, tc.Err)
+				suite.Nil(result)
+				if tc.Default == nil {
+					suite.stripeService.AssertNotCalled(suite.T(), "CreatePaymentIntent", mock.Anything)
+				}
+				return
+			}
+
+			suite.Nil(err)
+			suite.Equal("pi_test", result.TransactionID)
+			suite.Equal("pi_test_secret", result.ClientSecret)
+			suite.False(result.Reserved)
+			suite.Equal(tc.RequiresAction, result.RequiresAction)
+		})
+	}
+}
+
+func TestStripeProvider(t *testing.T) {
+	suite.Run(t, new(stripeProviderTest))
+}
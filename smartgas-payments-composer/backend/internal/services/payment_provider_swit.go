@@ -0,0 +1,66 @@
+package services
+
+import "time"
+
+type switProvider struct {
+	switService SwitService
+}
+
+func ProvideSwitProvider(switService SwitService) *switProvider {
+	return &switProvider{
+		switService: switService,
+	}
+}
+
+func (sp *switProvider) Name() string {
+	return "swit"
+}
+
+func (sp *switProvider) RefundsConfirmedAsync() bool {
+	return false
+}
+
+func (sp *switProvider) Reserve(opts ReserveOpts) (*ReserveResult, error) {
+	transID, err := sp.switService.ReserveFunds(ReserveFundsOpts{
+		CustomerID: opts.Customer.SwitCustomerID,
+		SourceID:   opts.SourceID,
+		Cvv:        opts.Cvv,
+		Last4:      opts.Last4,
+		Amount:     opts.Amount,
+	})
+	if err != nil {
+		if err == ErrProccesingPayment {
+			return nil, ErrInsufficientFunds
+		}
+		return nil, err
+	}
+
+	return &ReserveResult{TransactionID: transID, Reserved: true}, nil
+}
+
+func (sp *switProvider) Capture(opts CaptureOpts) error {
+	return sp.switService.ConfirmFundReservation(opts.TransactionID, opts.Amount)
+}
+
+func (sp *switProvider) Cancel(transactionID string) error {
+	return sp.switService.CancelFundReservation(transactionID)
+}
+
+// Refund gives back amount of a confirmed payment, without amount the whole reservation
+// of a load not served is released
+func (sp *switProvider) Refund(opts RefundOpts) error {
+	if opts.Amount > 0 {
+		return sp.switService.RefundPayment(opts.TransactionID, opts.Amount)
+	}
+
+	return sp.switService.CancelFundReservation(opts.TransactionID)
+}
+
+func (sp *switProvider) Status(transactionID string) (string, error) {
+	return "", ErrOperationNotSupported
+}
+
+// The Swit service has no endpoint to list transactions, Swit payments are not reconciled
+func (sp *switProvider) ListTransactions(from time.Time, to time.Time) ([]ProviderTransaction, error) {
+	return nil, ErrOperationNotSupported
+}
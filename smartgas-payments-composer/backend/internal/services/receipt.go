@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/schemas"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	// Receipts are printed on 80mm ticket paper
+	receiptWidth  = 80.0
+	receiptHeight = 200.0
+	receiptMargin = 5.0
+	receiptLine   = 5.0
+)
+
+//go:generate mockery --name ReceiptService --filename=mock_receipt.go --inpackage=true
+type ReceiptService interface {
+	Render(*schemas.FuelRequest, io.Writer) error
+}
+
+type receiptService struct{}
+
+func ProvideReceiptService() *receiptService {
+	return &receiptService{}
+}
+
+// Render writes the non fiscal receipt (ticket) of a load as PDF
+func (rs *receiptService) Render(data *schemas.FuelRequest, w io.Writer) error {
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "mm",
+		Size:    fpdf.SizeType{Wd: receiptWidth, Ht: receiptHeight},
+	})
+	pdf.SetMargins(receiptMargin, receiptMargin, receiptMargin)
+	pdf.SetAutoPageBreak(true, receiptMargin)
+	pdf.AddPage()
+
+	// Core fonts are encoded in cp1252, texts have accents
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	width := receiptWidth - 2*receiptMargin
+
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.MultiCell(width, 6, tr(data.GasStation), "", "C", false)
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.CellFormat(width, 4, tr("Comprobante de carga, no es un comprobante fiscal"), "", 1, "C", false, 0, "")
+	pdf.Ln(2)
+
+	row := func(label string, value string) {
+		pdf.SetFont("Helvetica", "B", 8)
+		pdf.CellFormat(width/2, receiptLine, tr(label), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(width/2, receiptLine, tr(value), "", 1, "R", false, 0, "")
+	}
+
+	separator := func() {
+		y := pdf.GetY() + 1
+		pdf.Line(receiptMargin, y, receiptWidth-receiptMargin, y)
+		pdf.Ln(2)
+	}
+
+	pesos := func(amount money.Amount) string {
+		return "$" + amount.String()
+	}
+
+	row("Fecha", data.Date+" "+data.Time)
+	row("Dispensario", data.GasPump)
+	if data.CustomerName != "" {
+		row("Cliente", data.CustomerName)
+	}
+	separator()
+
+	row("Combustible", data.FuelType)
+	row("Litros", fmt.Sprintf("%.2f", data.TotalLiter))
+	row("Precio por litro", fmt.Sprintf("$%.2f", data.Price))
+	if data.DiscountPerLiter > 0 {
+		row("Descuento por litro", fmt.Sprintf("$%.2f", data.DiscountPerLiter))
+		row("Descuento", pesos(data.Discount))
+	}
+	separator()
+
+	row("Cantidad solicitada", pesos(data.Amount))
+	row("Cantidad cargada", pesos(data.RealAmount))
+	if data.RefundedAmount > 0 {
+		row("Reembolso", pesos(data.RefundedAmount))
+	}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(width/2, 6, "Total", "", 0, "L", false, 0, "")
+	pdf.CellFormat(width/2, 6, pesos(data.RealAmount-data.RefundedAmount), "", 1, "R", false, 0, "")
+	separator()
+
+	if data.GMPoints > 0 {
+		row("Puntos acumulados", fmt.Sprintf("%.2f", data.GMPoints))
+	}
+
+	pdf.SetFont("Helvetica", "", 7)
+	pdf.MultiCell(width, 4, tr("Folio: "+data.TransactionID), "", "C", false)
+
+	return pdf.Output(w)
+}
@@ -9,7 +9,12 @@ var ServicesSet = wire.NewSet(
 	ProvideSwitService,
 	ProvideInvoicingService,
 	ProvideMailService,
+	ProvideReceiptService,
 	ProvideDebitService,
+	ProvideStripeProvider,
+	ProvideSwitProvider,
+	ProvideDebitProvider,
+	ProvidePaymentProviderRegistry,
 
 	wire.Bind(new(CustomerService), new(*customerService)),
 	wire.Bind(new(StripeService), new(*stripeService)),
@@ -17,5 +22,7 @@ var ServicesSet = wire.NewSet(
 	wire.Bind(new(SwitService), new(*switService)),
 	wire.Bind(new(InvoicingService), new(*invoicingService)),
 	wire.Bind(new(MailService), new(*mailService)),
+	wire.Bind(new(ReceiptService), new(*receiptService)),
 	wire.Bind(new(DebitService), new(*debitService)),
+	wire.Bind(new(PaymentProviderRegistry), new(*paymentProviderRegistry)),
 )
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"smartgas-payment/config"
 	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
 	"smartgas-payment/internal/schemas"
 	"smartgas-payment/internal/utils"
 	"strconv"
@@ -26,7 +27,7 @@ type ReportTransactionOpts struct {
 type SetGasPumpOptions struct {
 	Number    string
 	Ip        string
-	Amount    float32
+	Amount    money.Amount
 	FuelType  string
 	PaymentID uuid.UUID
 	Discount  float64
@@ -247,8 +248,8 @@ func (ss *socioSmartService) AccumPoints(payment *models.Payment) (*ResponseAccu
 		TransID:          "GA_" + payment.ID.String(),
 		CrePermission:    payment.GasPump.GasStation.CrePermission,
 		GasPumpNumber:    payment.GasPump.Number,
-		Amount:           fmt.Sprintf("%v", payment.RealAmountReported),
-		TotalLiter:       fmt.Sprintf("%v", payment.RealAmountReported/float32(payment.Price)),
+		Amount:           payment.RealAmountReported.String(),
+		TotalLiter:       fmt.Sprintf("%v", payment.RealAmountReported.Liters(payment.Price)),
 		PhoneNumber:      payment.Customer.PhoneNumber,
 		Start:            "0",
 		Status:           "1",
@@ -1,26 +1,43 @@
 package services
 
 import (
-	"fmt"
+	"smartgas-payment/internal/money"
 	"smartgas-payment/internal/schemas"
-	"strconv"
-	"strings"
+	"time"
 
 	"github.com/stripe/stripe-go/v72"
+	"github.com/stripe/stripe-go/v72/charge"
 	"github.com/stripe/stripe-go/v72/customer"
 	"github.com/stripe/stripe-go/v72/paymentintent"
 	"github.com/stripe/stripe-go/v72/paymentmethod"
 	"github.com/stripe/stripe-go/v72/refund"
+	"github.com/stripe/stripe-go/v72/setupintent"
 )
 
+type CreatePaymentIntentOpts struct {
+	Amount     money.Amount
+	CustomerID string
+	// ManualCapture only authorizes the amount, it must be captured later
+	ManualCapture bool
+	// PaymentMethodID confirms the payment intent right away with a saved card
+	PaymentMethodID string
+}
+
 //go:generate mockery --name StripeService --filename=mock_stripe.go --inpackage=true
 type StripeService interface {
 	CreateCustomer(*schemas.Customer) (string, error)
-	CreatePaymentIntent(float64, string) (*stripe.PaymentIntent, error)
+	CreatePaymentIntent(CreatePaymentIntentOpts) (*stripe.PaymentIntent, error)
+	CapturePaymentIntent(string, money.Amount) (*stripe.PaymentIntent, error)
 	CancelPaymentIntent(string) error
 	ListPaymenthMethodsByCustomer(string) []*stripe.PaymentMethod
-	MakeARefund(string, float64) (*stripe.Refund, error)
+	MakeARefund(string, money.Amount, string, string) (*stripe.Refund, error)
 	DeletePaymentMethod(string) error
+	GetPaymentIntent(string) (*stripe.PaymentIntent, error)
+	ListPaymentIntents(time.Time, time.Time) ([]*stripe.PaymentIntent, error)
+	GetCharge(string) (*stripe.Charge, error)
+	CreateSetupIntent(string) (*stripe.SetupIntent, error)
+	GetDefaultPaymentMethod(string) (*stripe.PaymentMethod, error)
+	SetDefaultPaymentMethod(string, string) error
 }
 
 type stripeService struct{}
@@ -53,21 +70,28 @@ func (ss *stripeService) CreateCustomer(cus *schemas.Customer) (string, error) {
 }
 
 func (ss *stripeService) CreatePaymentIntent(
-	amount float64,
-	customerID string,
+	opts CreatePaymentIntentOpts,
 ) (*stripe.PaymentIntent, error) {
-	amount2Decimals := fmt.Sprintf("%0.2f", amount)
-
-	// replacing decimal
-	amountToInt, _ := strconv.Atoi(strings.Replace(amount2Decimals, ".", "", -1))
-
+	// Stripe takes MXN amounts in centavos
 	params := &stripe.PaymentIntentParams{
-		Amount:   stripe.Int64(int64(amountToInt)),
-		Customer: stripe.String(customerID),
+		Amount:   stripe.Int64(opts.Amount.Cents()),
+		Customer: stripe.String(opts.CustomerID),
 		Currency: stripe.String(string(stripe.CurrencyMXN)),
-		AutomaticPaymentMethods: &stripe.PaymentIntentAutomaticPaymentMethodsParams{
+	}
+
+	if opts.PaymentMethodID != "" {
+		// Saved cards are confirmed here, the client only handles 3D Secure if required
+		params.PaymentMethod = stripe.String(opts.PaymentMethodID)
+		params.PaymentMethodTypes = stripe.StringSlice([]string{"card"})
+		params.Confirm = stripe.Bool(true)
+	} else {
+		params.AutomaticPaymentMethods = &stripe.PaymentIntentAutomaticPaymentMethodsParams{
 			Enabled: stripe.Bool(true),
-		},
+		}
+	}
+
+	if opts.ManualCapture {
+		params.CaptureMethod = stripe.String(string(stripe.PaymentIntentCaptureMethodManual))
 	}
 
 	pi, err := paymentintent.New(params)
@@ -79,6 +103,50 @@ func (ss *stripeService) CreatePaymentIntent(
 	return pi, nil
 }
 
+// GetCharge gets a charge with its refunds expanded
+func (ss *stripeService) GetCharge(chargeID string) (*stripe.Charge, error) {
+	params := &stripe.ChargeParams{}
+	params.AddExpand("refunds")
+
+	return charge.Get(chargeID, params)
+}
+
+func (ss *stripeService) GetPaymentIntent(paymentIntentID string) (*stripe.PaymentIntent, error) {
+	return paymentintent.Get(paymentIntentID, nil)
+}
+
+// ListPaymentIntents returns the payment intents created in [from, to)
+func (ss *stripeService) ListPaymentIntents(from time.Time, to time.Time) ([]*stripe.PaymentIntent, error) {
+	params := &stripe.PaymentIntentListParams{
+		CreatedRange: &stripe.RangeQueryParams{
+			GreaterThanOrEqual: from.Unix(),
+			LesserThan:         to.Unix(),
+		},
+	}
+
+	i := paymentintent.List(params)
+
+	intents := make([]*stripe.PaymentIntent, 0)
+	for i.Next() {
+		intents = append(intents, i.PaymentIntent())
+	}
+
+	return intents, i.Err()
+}
+
+// CapturePaymentIntent captures amount of an authorized payment intent, stripe releases
+// the rest of the authorization
+func (ss *stripeService) CapturePaymentIntent(
+	paymentIntentID string,
+	amount money.Amount,
+) (*stripe.PaymentIntent, error) {
+	params := &stripe.PaymentIntentCaptureParams{
+		AmountToCapture: stripe.Int64(amount.Cents()),
+	}
+
+	return paymentintent.Capture(paymentIntentID, params)
+}
+
 func (ss *stripeService) CancelPaymentIntent(paymentIntentID string) error {
 	_, err := paymentintent.Cancel(paymentIntentID, nil)
 	return err
@@ -101,27 +169,71 @@ func (ss *stripeService) ListPaymenthMethodsByCustomer(customerID string) []*str
 	return paymentMethods
 }
 
-func (ss *stripeService) MakeARefund(transactionID string, amount float64) (*stripe.Refund, error) {
-	amount2Decimals := fmt.Sprintf("%0.2f", amount)
-
-	// replacing decimal
-	amountToInt, _ := strconv.Atoi(strings.Replace(amount2Decimals, ".", "", -1))
-
+// MakeARefund refunds the given amount, zero or less refunds the whole payment intent.
+// status is saved in the refund metadata and read back by the charge.refunded webhook,
+// reason is kept there for whoever looks at the refund in Stripe
+func (ss *stripeService) MakeARefund(
+	transactionID string,
+	amount money.Amount,
+	status string,
+	reason string,
+) (*stripe.Refund, error) {
 	params := &stripe.RefundParams{
 		PaymentIntent: stripe.String(transactionID),
 	}
 
 	if amount > 0 {
-		params.Amount = stripe.Int64(int64(amountToInt))
+		params.Amount = stripe.Int64(amount.Cents())
 	}
 
-	if amount == -3 {
-		params.AddMetadata("status", "manual_action")
+	if status != "" {
+		params.AddMetadata("status", status)
 	}
 
-	if amount == -2 {
-		params.AddMetadata("status", "internal_cancellation")
+	if reason != "" {
+		params.AddMetadata("reason", reason)
 	}
 
 	return refund.New(params)
 }
+
+// CreateSetupIntent starts saving a card of the customer without charging it, the
+// client confirms it with the returned client secret
+func (ss *stripeService) CreateSetupIntent(customerID string) (*stripe.SetupIntent, error) {
+	params := &stripe.SetupIntentParams{
+		Customer:           stripe.String(customerID),
+		PaymentMethodTypes: stripe.StringSlice([]string{"card"}),
+	}
+
+	return setupintent.New(params)
+}
+
+// GetDefaultPaymentMethod returns the default payment method of the customer, nil
+// when it has none
+func (ss *stripeService) GetDefaultPaymentMethod(customerID string) (*stripe.PaymentMethod, error) {
+	params := &stripe.CustomerParams{}
+	params.AddExpand("invoice_settings.default_payment_method")
+
+	c, err := customer.Get(customerID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.InvoiceSettings == nil {
+		return nil, nil
+	}
+
+	return c.InvoiceSettings.DefaultPaymentMethod, nil
+}
+
+func (ss *stripeService) SetDefaultPaymentMethod(customerID string, paymentMethodID string) error {
+	params := &stripe.CustomerParams{
+		InvoiceSettings: &stripe.CustomerInvoiceSettingsParams{
+			DefaultPaymentMethod: stripe.String(paymentMethodID),
+		},
+	}
+
+	_, err := customer.Update(customerID, params)
+
+	return err
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"smartgas-payment/config"
 	"smartgas-payment/internal/dto"
+	"smartgas-payment/internal/money"
 	"smartgas-payment/internal/schemas"
 	"smartgas-payment/internal/utils"
 	"time"
@@ -21,13 +22,13 @@ const (
 var ErrProccesingPayment = errors.New("CVV error or not funds")
 
 type ReserveFundsOpts struct {
-	CustomerID  string  `json:"customerId"`
-	SourceID    string  `json:"sourceId"`
-	Cvv         string  `json:"cvv"`
-	Last4       string  `json:"cardLastDigits"`
-	Amount      float32 `json:"amount"`
-	Description string  `json:"description"`
-	Capture     bool    `json:"capture"`
+	CustomerID  string       `json:"customerId"`
+	SourceID    string       `json:"sourceId"`
+	Cvv         string       `json:"cvv"`
+	Last4       string       `json:"cardLastDigits"`
+	Amount      money.Amount `json:"amount"`
+	Description string       `json:"description"`
+	Capture     bool         `json:"capture"`
 }
 
 //go:generate mockery --name SwitService --filename=mock_swit.go --inpackage=true
@@ -36,7 +37,8 @@ type SwitService interface {
 	ListCardsByCustomer(string) ([]schemas.SwitSource, error)
 	ReserveFunds(ReserveFundsOpts) (string, error)
 	CancelFundReservation(string) error
-	ConfirmFundReservation(string, float32) error
+	ConfirmFundReservation(string, money.Amount) error
+	RefundPayment(string, money.Amount) error
 	DeleteCard(string, string) error
 }
 
@@ -89,13 +91,13 @@ func (ss *switService) DeleteCard(cusID string, cardID string) error {
 	return nil
 }
 
-func (ss *switService) ConfirmFundReservation(transID string, amount float32) error {
+func (ss *switService) ConfirmFundReservation(transID string, amount money.Amount) error {
 	client := &http.Client{
 		Timeout: time.Second * timeout,
 	}
 
 	payload := struct {
-		Amount float32 `json:"amount"`
+		Amount money.Amount `json:"amount"`
 	}{
 		Amount: amount,
 	}
@@ -154,6 +156,44 @@ func (ss *switService) CancelFundReservation(transID string) error {
 	return nil
 }
 
+// RefundPayment gives back amount of a confirmed payment
+func (ss *switService) RefundPayment(transID string, amount money.Amount) error {
+	client := &http.Client{
+		Timeout: time.Second * timeout,
+	}
+
+	payload := struct {
+		Amount money.Amount `json:"amount"`
+	}{
+		Amount: amount,
+	}
+	payloadData, _ := json.Marshal(payload)
+
+	payloadRequest := bytes.NewReader(payloadData)
+
+	req, err := http.NewRequest("POST", ss.cfg.SwitBaseUrl+"/payments/"+transID+"/refunds", payloadRequest)
+	if err != nil {
+		return err
+	}
+
+	ss.addHeaders(req)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	var data dto.SwitGeneralResponse
+
+	json.NewDecoder(res.Body).Decode(&data)
+
+	if data.Status != "Success" {
+		return errors.New("Error refunding: " + res.Status)
+	}
+
+	return nil
+}
+
 func (ss *switService) ReserveFunds(opts ReserveFundsOpts) (string, error) {
 	client := &http.Client{
 		Timeout: time.Second * timeout,
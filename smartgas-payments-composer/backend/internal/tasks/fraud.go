@@ -0,0 +1,241 @@
+package tasks
+
+import (
+	"errors"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/reports"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/services"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Where the load checked by the fraud rules was requested
+const (
+	FraudSourceCustomer  = "customer"
+	FraudSourceOperation = "operation"
+)
+
+// FraudCheck is a load about to reserve funds
+type FraudCheck struct {
+	Source          string
+	Customer        *models.Customer
+	GasStation      *models.GasStation
+	PaymentProvider string
+	Amount          money.Amount
+	CardID          string
+	GiftCardKey     string
+	// UseDefaultPaymentMethod makes the default stripe card of the customer the one
+	// checked against the card blocks
+	UseDefaultPaymentMethod bool
+}
+
+// FraudRejectedError is returned by the guard of a payment rejected by the limits
+type FraudRejectedError struct {
+	Decision *models.FraudDecision
+}
+
+func (e *FraudRejectedError) Error() string {
+	return "Load rejected by the fraud rules: " + e.Decision.Reason
+}
+
+//go:generate mockery --name FraudTask --filename=mock_fraud.go --inpackage=true
+type FraudTask interface {
+	Evaluate(FraudCheck) (*models.FraudDecision, error)
+	Guard(FraudCheck) repository.PaymentGuard
+}
+
+type fraudTask struct {
+	fraudRepository repository.FraudRepository
+	stripeService   services.StripeService
+}
+
+func ProvideFraudTask(
+	fraudRepository repository.FraudRepository,
+	stripeService services.StripeService,
+) *fraudTask {
+	return &fraudTask{
+		fraudRepository: fraudRepository,
+		stripeService:   stripeService,
+	}
+}
+
+// Evaluate checks the blocks and the limits of the station and logs the decision,
+// the first block or limit reached rejects the load
+func (ft *fraudTask) Evaluate(check FraudCheck) (*models.FraudDecision, error) {
+	decision := newDecision(check)
+
+	if err := ft.resolveCard(check, decision); err != nil {
+		return nil, err
+	}
+
+	blocked := map[string]string{
+		models.FraudBlockCard:     decision.CardID,
+		models.FraudBlockGiftCard: decision.GiftCardKey,
+	}
+	if decision.CustomerID != nil {
+		blocked[models.FraudBlockCustomer] = decision.CustomerID.String()
+	}
+
+	block, err := ft.fraudRepository.FindBlock(blocked)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if block != nil {
+		decision.Allowed = false
+		decision.Reason = block.Type
+	} else if err := ft.checkRules(check, decision); err != nil {
+		return nil, err
+	}
+
+	if err := ft.fraudRepository.CreateDecision(decision); err != nil {
+		return nil, err
+	}
+
+	return decision, nil
+}
+
+// Guard checks the limits of the customer again in the transaction creating the
+// payment, with the customer row locked. Evaluate runs before the funds are reserved,
+// so concurrent loads of a customer could otherwise pass it at the same time
+func (ft *fraudTask) Guard(check FraudCheck) repository.PaymentGuard {
+	return func(tx *gorm.DB) error {
+		if check.Customer == nil {
+			return nil
+		}
+
+		fraudRepository := repository.ProvideFraudRepository(tx)
+		if err := fraudRepository.LockCustomer(check.Customer.ID); err != nil {
+			return err
+		}
+
+		decision := newDecision(check)
+		locked := &fraudTask{fraudRepository: fraudRepository}
+		if err := locked.checkRules(check, decision); err != nil {
+			return err
+		}
+
+		if decision.Allowed {
+			return nil
+		}
+
+		// Logged outside of the transaction, which is rolled back
+		if err := ft.fraudRepository.CreateDecision(decision); err != nil {
+			return err
+		}
+
+		return &FraudRejectedError{Decision: decision}
+	}
+}
+
+func newDecision(check FraudCheck) *models.FraudDecision {
+	decision := &models.FraudDecision{
+		Source:          check.Source,
+		Allowed:         true,
+		GasStationID:    &check.GasStation.ID,
+		PaymentProvider: check.PaymentProvider,
+		Amount:          check.Amount,
+		CardID:          check.CardID,
+		GiftCardKey:     check.GiftCardKey,
+	}
+
+	if check.Customer != nil {
+		decision.CustomerID = &check.Customer.ID
+	}
+
+	return decision
+}
+
+// resolveCard looks up the default card of one tap loads, only when cards are blocked
+func (ft *fraudTask) resolveCard(check FraudCheck, decision *models.FraudDecision) error {
+	if !check.UseDefaultPaymentMethod || check.Customer == nil || decision.CardID != "" {
+		return nil
+	}
+
+	hasBlocks, err := ft.fraudRepository.HasBlocks(models.FraudBlockCard)
+	if err != nil || !hasBlocks {
+		return err
+	}
+
+	paymentMethod, err := ft.stripeService.GetDefaultPaymentMethod(check.Customer.StripeCustomerID)
+	if err != nil {
+		return err
+	}
+
+	if paymentMethod != nil {
+		decision.CardID = paymentMethod.ID
+	}
+
+	return nil
+}
+
+func (ft *fraudTask) checkRules(check FraudCheck, decision *models.FraudDecision) error {
+	rules, err := ft.fraudRepository.ListActiveRules(check.GasStation.ID)
+	if err != nil {
+		return err
+	}
+
+	// Days start in the timezone of the station
+	now := time.Now().In(reports.Location(check.GasStation.Timezone))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	// Activity of the customer in every station, or in the station for its own rules
+	activities := map[uuid.UUID]*repository.FraudActivity{}
+
+	for _, rule := range rules {
+		if rule.Type == models.FraudMaxAmountPerLoad {
+			if check.Amount > rule.MaxAmount {
+				reject(decision, rule)
+				return nil
+			}
+			continue
+		}
+
+		// Gift card loads have no customer to limit
+		if decision.CustomerID == nil {
+			continue
+		}
+
+		scope := uuid.Nil
+		if rule.GasStationID != nil {
+			scope = *rule.GasStationID
+		}
+
+		activity, ok := activities[scope]
+		if !ok {
+			activity, err = ft.fraudRepository.CustomerActivity(*decision.CustomerID, rule.GasStationID, today)
+			if err != nil {
+				return err
+			}
+			activities[scope] = activity
+		}
+
+		exceeded := false
+
+		switch rule.Type {
+		case models.FraudMaxLoadsPerDay:
+			exceeded = activity.Loads+1 > rule.MaxCount
+		case models.FraudMaxAmountPerDay:
+			exceeded = activity.Amount+check.Amount > rule.MaxAmount
+		case models.FraudMaxOpenPayments:
+			exceeded = activity.Open+1 > rule.MaxCount
+		}
+
+		if exceeded {
+			reject(decision, rule)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func reject(decision *models.FraudDecision, rule *models.FraudRule) {
+	decision.Allowed = false
+	decision.Reason = rule.Type
+	decision.FraudRuleID = &rule.ID
+}
@@ -0,0 +1,80 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package tasks
+
+import (
+	models "smartgas-payment/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	repository "smartgas-payment/internal/repository"
+)
+
+// MockFraudTask is an autogenerated mock type for the FraudTask type
+type MockFraudTask struct {
+	mock.Mock
+}
+
+// Evaluate provides a mock function with given fields: _a0
+func (_m *MockFraudTask) Evaluate(_a0 FraudCheck) (*models.FraudDecision, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Evaluate")
+	}
+
+	var r0 *models.FraudDecision
+	var r1 error
+	if rf, ok := ret.Get(0).(func(FraudCheck) (*models.FraudDecision, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(FraudCheck) *models.FraudDecision); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.FraudDecision)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(FraudCheck) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Guard provides a mock function with given fields: _a0
+func (_m *MockFraudTask) Guard(_a0 FraudCheck) repository.PaymentGuard {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Guard")
+	}
+
+	var r0 repository.PaymentGuard
+	if rf, ok := ret.Get(0).(func(FraudCheck) repository.PaymentGuard); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(repository.PaymentGuard)
+		}
+	}
+
+	return r0
+}
+
+// NewMockFraudTask creates a new instance of MockFraudTask. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockFraudTask(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockFraudTask {
+	mock := &MockFraudTask{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
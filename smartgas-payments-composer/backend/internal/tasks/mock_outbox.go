@@ -0,0 +1,109 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package tasks
+
+import (
+	models "smartgas-payment/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockOutboxTask is an autogenerated mock type for the OutboxTask type
+type MockOutboxTask struct {
+	mock.Mock
+}
+
+// Dispatch provides a mock function with given fields: _a0
+func (_m *MockOutboxTask) Dispatch(_a0 ...*models.OutboxMessage) {
+	_va := make([]interface{}, len(_a0))
+	for _i := range _a0 {
+		_va[_i] = _a0[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	_m.Called(_ca...)
+}
+
+// Enqueue provides a mock function with given fields: _a0
+func (_m *MockOutboxTask) Enqueue(_a0 ...*models.OutboxMessage) error {
+	_va := make([]interface{}, len(_a0))
+	for _i := range _a0 {
+		_va[_i] = _a0[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Enqueue")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(...*models.OutboxMessage) error); ok {
+		r0 = rf(_a0...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GivePaymentBack provides a mock function with given fields: _a0, _a1
+func (_m *MockOutboxTask) GivePaymentBack(_a0 *models.Payment, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GivePaymentBack")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.Payment, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ProcessPending provides a mock function with given fields: _a0
+func (_m *MockOutboxTask) ProcessPending(_a0 int) (int, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ProcessPending")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int) (int, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(int) int); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMockOutboxTask creates a new instance of MockOutboxTask. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockOutboxTask(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockOutboxTask {
+	mock := &MockOutboxTask{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
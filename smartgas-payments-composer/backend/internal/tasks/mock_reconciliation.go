@@ -0,0 +1,60 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package tasks
+
+import (
+	models "smartgas-payment/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// MockReconciliationTask is an autogenerated mock type for the ReconciliationTask type
+type MockReconciliationTask struct {
+	mock.Mock
+}
+
+// Reconcile provides a mock function with given fields: _a0, _a1
+func (_m *MockReconciliationTask) Reconcile(_a0 time.Time, _a1 time.Time) (*models.Reconciliation, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reconcile")
+	}
+
+	var r0 *models.Reconciliation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(time.Time, time.Time) (*models.Reconciliation, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time, time.Time) *models.Reconciliation); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Reconciliation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time, time.Time) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMockReconciliationTask creates a new instance of MockReconciliationTask. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockReconciliationTask(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockReconciliationTask {
+	mock := &MockReconciliationTask{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
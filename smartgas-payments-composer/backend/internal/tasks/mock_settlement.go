@@ -0,0 +1,92 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package tasks
+
+import (
+	models "smartgas-payment/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+
+	uuid "github.com/google/uuid"
+)
+
+// MockSettlementTask is an autogenerated mock type for the SettlementTask type
+type MockSettlementTask struct {
+	mock.Mock
+}
+
+// Close provides a mock function with given fields: _a0, _a1
+func (_m *MockSettlementTask) Close(_a0 uuid.UUID, _a1 *models.User) (*models.Settlement, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Close")
+	}
+
+	var r0 *models.Settlement
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, *models.User) (*models.Settlement, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(uuid.UUID, *models.User) *models.Settlement); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Settlement)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uuid.UUID, *models.User) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Generate provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockSettlementTask) Generate(_a0 string, _a1 time.Time, _a2 time.Time) (*models.Settlement, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Generate")
+	}
+
+	var r0 *models.Settlement
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, time.Time, time.Time) (*models.Settlement, error)); ok {
+		return rf(_a0, _a1, _a2)
+	}
+	if rf, ok := ret.Get(0).(func(string, time.Time, time.Time) *models.Settlement); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Settlement)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, time.Time, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMockSettlementTask creates a new instance of MockSettlementTask. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockSettlementTask(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockSettlementTask {
+	mock := &MockSettlementTask{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,120 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package tasks
+
+import (
+	models "smartgas-payment/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+
+	stripe "github.com/stripe/stripe-go/v72"
+)
+
+// MockStripeWebhookTask is an autogenerated mock type for the StripeWebhookTask type
+type MockStripeWebhookTask struct {
+	mock.Mock
+}
+
+// Receive provides a mock function with given fields: _a0, _a1
+func (_m *MockStripeWebhookTask) Receive(_a0 stripe.Event, _a1 []byte) (*models.StripeEvent, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Receive")
+	}
+
+	var r0 *models.StripeEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(stripe.Event, []byte) (*models.StripeEvent, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(stripe.Event, []byte) *models.StripeEvent); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.StripeEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(stripe.Event, []byte) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Replay provides a mock function with given fields: _a0
+func (_m *MockStripeWebhookTask) Replay(_a0 string) (*models.StripeEvent, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Replay")
+	}
+
+	var r0 *models.StripeEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*models.StripeEvent, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(string) *models.StripeEvent); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.StripeEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReplayFailed provides a mock function with given fields: _a0
+func (_m *MockStripeWebhookTask) ReplayFailed(_a0 int) ([]*models.StripeEvent, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplayFailed")
+	}
+
+	var r0 []*models.StripeEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int) ([]*models.StripeEvent, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(int) []*models.StripeEvent); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.StripeEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMockStripeWebhookTask creates a new instance of MockStripeWebhookTask. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockStripeWebhookTask(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockStripeWebhookTask {
+	mock := &MockStripeWebhookTask{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,54 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package tasks
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockReservationSweeperTask is an autogenerated mock type for the ReservationSweeperTask type
+type MockReservationSweeperTask struct {
+	mock.Mock
+}
+
+// SweepStaleReservations provides a mock function with given fields:
+func (_m *MockReservationSweeperTask) SweepStaleReservations() (*SweepReport, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for SweepStaleReservations")
+	}
+
+	var r0 *SweepReport
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (*SweepReport, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() *SweepReport); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*SweepReport)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMockReservationSweeperTask creates a new instance of MockReservationSweeperTask. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockReservationSweeperTask(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockReservationSweeperTask {
+	mock := &MockReservationSweeperTask{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,542 @@
+package tasks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"smartgas-payment/internal/dto"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/payments"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/services"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	internalWebsocket "smartgas-payment/internal/websocket"
+)
+
+const (
+	// Time a message is held by whoever claimed it before someone else can retry it
+	outboxLease = time.Minute * 5
+	// First retry delay, doubled on each attempt up to outboxMaxBackoff
+	outboxBaseBackoff = time.Second * 30
+	outboxMaxBackoff  = time.Hour
+	// A pump is not preset after this time, the customer may be gone
+	presetExpiration = time.Minute * 2
+)
+
+var ErrUnknownOutboxMessage = errors.New("Unknown outbox message type")
+
+type capturePayload struct {
+	ReservedAmount money.Amount `json:"reserved_amount"`
+	Amount         money.Amount `json:"amount"`
+}
+
+type refundPayload struct {
+	Amount money.Amount `json:"amount"`
+	Event  string       `json:"event"`
+	Reason string       `json:"reason"`
+}
+
+type sendMailPayload struct {
+	TemplatePath  string               `json:"template_path"`
+	To            string               `json:"to"`
+	Description   string               `json:"description"`
+	Data          *schemas.FuelRequest `json:"data"`
+	AttachReceipt bool                 `json:"attach_receipt"`
+}
+
+func newOutboxMessage(messageType string, paymentID uuid.UUID, payload any) *models.OutboxMessage {
+	message := &models.OutboxMessage{
+		Type:      messageType,
+		PaymentID: &paymentID,
+	}
+
+	if payload != nil {
+		data, _ := json.Marshal(payload)
+		message.Payload = string(data)
+	}
+
+	return message
+}
+
+// NewSetGasPumpMessage presets the pump, on failure the payment is given back.
+// It is attempted once, later attempts only give the payment back
+func NewSetGasPumpMessage(paymentID uuid.UUID) *models.OutboxMessage {
+	message := newOutboxMessage(models.OutboxSetGasPump, paymentID, nil)
+	message.MaxAttempts = 3
+
+	return message
+}
+
+func NewAccumPointsMessage(paymentID uuid.UUID) *models.OutboxMessage {
+	return newOutboxMessage(models.OutboxAccumPoints, paymentID, nil)
+}
+
+func NewReportTransactionMessage(paymentID uuid.UUID) *models.OutboxMessage {
+	return newOutboxMessage(models.OutboxReportTransaction, paymentID, nil)
+}
+
+func NewCapturePaymentMessage(
+	paymentID uuid.UUID,
+	reservedAmount money.Amount,
+	amount money.Amount,
+) *models.OutboxMessage {
+	return newOutboxMessage(
+		models.OutboxCapturePayment,
+		paymentID,
+		capturePayload{ReservedAmount: reservedAmount, Amount: amount},
+	)
+}
+
+func NewCancelPaymentMessage(paymentID uuid.UUID) *models.OutboxMessage {
+	return newOutboxMessage(models.OutboxCancelPayment, paymentID, nil)
+}
+
+// NewCampaignUsageMessage adds the discount given to a served payment to the counters
+// of its campaign
+func NewCampaignUsageMessage(paymentID uuid.UUID) *models.OutboxMessage {
+	return newOutboxMessage(models.OutboxCampaignUsage, paymentID, nil)
+}
+
+// NewRefundPaymentMessage refunds amount, zero or less refunds the whole payment. event is
+// recorded by providers confirming refunds asynchronously
+func NewRefundPaymentMessage(
+	paymentID uuid.UUID,
+	amount money.Amount,
+	event string,
+	reason string,
+) *models.OutboxMessage {
+	return newOutboxMessage(
+		models.OutboxRefundPayment,
+		paymentID,
+		refundPayload{Amount: amount, Event: event, Reason: reason},
+	)
+}
+
+func NewFuelRequestMailMessage(
+	payment *models.Payment,
+	description string,
+	data *schemas.FuelRequest,
+) *models.OutboxMessage {
+	return newOutboxMessage(models.OutboxSendMail, payment.ID, sendMailPayload{
+		TemplatePath: "fuel_request.html",
+		To:           payment.Customer.Email,
+		Description:  description,
+		Data:         data,
+	})
+}
+
+// NewFuelRequestReceiptMailMessage is a fuel request mail with the receipt of the load
+// attached, the receipt is rendered when the mail is sent so it has the points earned
+func NewFuelRequestReceiptMailMessage(
+	payment *models.Payment,
+	description string,
+	data *schemas.FuelRequest,
+) *models.OutboxMessage {
+	return newOutboxMessage(models.OutboxSendMail, payment.ID, sendMailPayload{
+		TemplatePath:  "fuel_request.html",
+		To:            payment.Customer.Email,
+		Description:   description,
+		Data:          data,
+		AttachReceipt: true,
+	})
+}
+
+//go:generate mockery --name OutboxTask --filename=mock_outbox.go --inpackage=true
+type OutboxTask interface {
+	Enqueue(...*models.OutboxMessage) error
+	Dispatch(...*models.OutboxMessage)
+	ProcessPending(int) (int, error)
+	GivePaymentBack(*models.Payment, string) error
+}
+
+type outboxTask struct {
+	outboxRepository   repository.OutboxRepository
+	paymentRepository  repository.PaymentRepository
+	campaignRepository repository.CampaignRepository
+	socioSmartService  services.SocioSmartService
+	mailService        services.MailService
+	receiptService     services.ReceiptService
+	providers          services.PaymentProviderRegistry
+}
+
+func ProvideOutboxTask(
+	outboxRepository repository.OutboxRepository,
+	paymentRepository repository.PaymentRepository,
+	campaignRepository repository.CampaignRepository,
+	socioSmartService services.SocioSmartService,
+	mailService services.MailService,
+	receiptService services.ReceiptService,
+	providers services.PaymentProviderRegistry,
+) *outboxTask {
+	return &outboxTask{
+		outboxRepository:   outboxRepository,
+		paymentRepository:  paymentRepository,
+		campaignRepository: campaignRepository,
+		socioSmartService:  socioSmartService,
+		mailService:        mailService,
+		receiptService:     receiptService,
+		providers:          providers,
+	}
+}
+
+// Enqueue stores messages not linked to a payment event and dispatches them
+func (ot *outboxTask) Enqueue(messages ...*models.OutboxMessage) error {
+	if err := ot.outboxRepository.Enqueue(messages...); err != nil {
+		return err
+	}
+
+	ot.Dispatch(messages...)
+
+	return nil
+}
+
+// Dispatch runs already stored messages right away, the ones failing are left for the worker
+func (ot *outboxTask) Dispatch(messages ...*models.OutboxMessage) {
+	for _, message := range messages {
+		ot.process(message)
+	}
+}
+
+// ProcessPending runs up to limit available messages, returning how many were processed
+func (ot *outboxTask) ProcessPending(limit int) (int, error) {
+	messages, err := ot.outboxRepository.ListAvailable(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, message := range messages {
+		if ot.process(message) {
+			processed++
+		}
+	}
+
+	return processed, nil
+}
+
+func (ot *outboxTask) process(message *models.OutboxMessage) bool {
+	claimed, err := ot.outboxRepository.Claim(message, outboxLease)
+	if err != nil {
+		log.Println("Outbox: error claiming message", message.ID, err)
+		return false
+	}
+
+	if !claimed {
+		return false
+	}
+
+	err = ot.handle(message)
+	if err == nil {
+		if err := ot.outboxRepository.MarkDone(message.ID); err != nil {
+			log.Println("Outbox: error marking message as done", message.ID, err)
+		}
+		return true
+	}
+
+	var retryAt *time.Time
+	if message.Attempts < message.MaxAttempts {
+		backoff := outboxBaseBackoff << (message.Attempts - 1)
+		if backoff > outboxMaxBackoff || backoff <= 0 {
+			backoff = outboxMaxBackoff
+		}
+		at := time.Now().Add(backoff)
+		retryAt = &at
+	} else {
+		log.Println("Outbox: message moved to dead letter", message.ID, message.Type, err)
+	}
+
+	if err := ot.outboxRepository.MarkFailed(message.ID, err.Error(), retryAt); err != nil {
+		log.Println("Outbox: error marking message as failed", message.ID, err)
+	}
+
+	return true
+}
+
+func (ot *outboxTask) handle(message *models.OutboxMessage) error {
+	if message.PaymentID == nil {
+		return fmt.Errorf("%w: %s without payment", ErrUnknownOutboxMessage, message.Type)
+	}
+
+	payment, err := ot.paymentRepository.GetByIDPreloaded(*message.PaymentID)
+	if err != nil {
+		return err
+	}
+
+	switch message.Type {
+	case models.OutboxSetGasPump:
+		return ot.setGasPump(message, payment)
+	case models.OutboxAccumPoints:
+		return ot.accumPoints(payment)
+	case models.OutboxCampaignUsage:
+		return ot.campaignUsage(payment)
+	case models.OutboxReportTransaction:
+		return ot.socioSmartService.ReportTransaction(services.ReportTransactionOpts{
+			Ip:     payment.GasPump.GasStation.Ip,
+			Number: payment.GasPump.Number,
+		})
+	case models.OutboxSendMail:
+		var payload sendMailPayload
+		if err := json.Unmarshal([]byte(message.Payload), &payload); err != nil {
+			return err
+		}
+
+		opts := services.SendMailOpts{
+			TemplatePath: payload.TemplatePath,
+			To:           payload.To,
+			Description:  payload.Description,
+			Data:         payload.Data,
+		}
+
+		if payload.AttachReceipt {
+			receipt, err := ot.receipt(payment)
+			if err != nil {
+				return err
+			}
+			opts.Attachments = append(opts.Attachments, *receipt)
+		}
+
+		return ot.mailService.SendMail(opts)
+	case models.OutboxCapturePayment:
+		var payload capturePayload
+		if err := json.Unmarshal([]byte(message.Payload), &payload); err != nil {
+			return err
+		}
+
+		provider, err := ot.providers.Get(payment.PaymentProvider)
+		if err != nil {
+			return err
+		}
+
+		opts := services.CaptureOpts{
+			TransactionID:  payment.ExternalTransactionID,
+			ReservedAmount: payload.ReservedAmount,
+			Amount:         payload.Amount,
+			ManualCapture:  payment.CaptureMethod == models.CaptureManual,
+		}
+
+		if err := provider.Capture(opts); err != nil {
+			return err
+		}
+
+		if opts.ManualCapture {
+			return ot.paymentRepository.ClearAuthorizationExpiry(payment.ID)
+		}
+
+		return ot.recordCaptureRefund(provider, payment, opts)
+	case models.OutboxCancelPayment:
+		provider, err := ot.providers.Get(payment.PaymentProvider)
+		if err != nil {
+			return err
+		}
+
+		return provider.Cancel(payment.ExternalTransactionID)
+	case models.OutboxRefundPayment:
+		var payload refundPayload
+		if err := json.Unmarshal([]byte(message.Payload), &payload); err != nil {
+			return err
+		}
+
+		provider, err := ot.providers.Get(payment.PaymentProvider)
+		if err != nil {
+			return err
+		}
+
+		return provider.Refund(services.RefundOpts{
+			TransactionID: payment.ExternalTransactionID,
+			Amount:        payload.Amount,
+			Event:         payload.Event,
+			Reason:        payload.Reason,
+		})
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnknownOutboxMessage, message.Type)
+}
+
+// recordCaptureRefund records the difference between the reserved and the captured
+// amount as refunded, providers confirming refunds asynchronously record it by themselves
+// and manual captures only charge the amount captured
+func (ot *outboxTask) recordCaptureRefund(
+	provider services.PaymentProvider,
+	payment *models.Payment,
+	opts services.CaptureOpts,
+) error {
+	difference := opts.ReservedAmount - opts.Amount
+	if difference <= 0 || opts.ManualCapture || provider.RefundsConfirmedAsync() {
+		return nil
+	}
+
+	err := ot.paymentRepository.CreateEventWithUpdates(
+		&models.PaymentEvent{PaymentID: payment.ID, Type: "partial_refund"},
+		map[string]any{"refunded_amount": gorm.Expr("refunded_amount + ?", difference)},
+	)
+	if err != nil {
+		return err
+	}
+
+	channel := internalWebsocket.PaymentChannels.GetChannel(payment.ID.String())
+	channel.BroadcastJson(dto.PaymentWebsocketNotification{Status: "partial_refund"})
+
+	internalWebsocket.PaymentChannels.DeleteChannel(payment.ID.String())
+
+	return nil
+}
+
+func (ot *outboxTask) receipt(payment *models.Payment) (*services.MailAttachment, error) {
+	data := &schemas.FuelRequest{}
+	data.FillData(payment)
+
+	var content bytes.Buffer
+	if err := ot.receiptService.Render(data, &content); err != nil {
+		return nil, err
+	}
+
+	return &services.MailAttachment{
+		Filename:    ReceiptFilename(payment),
+		ContentType: "application/pdf",
+		Content:     content.Bytes(),
+	}, nil
+}
+
+// ReceiptFilename is the name of the PDF receipt of a payment
+func ReceiptFilename(payment *models.Payment) string {
+	return fmt.Sprintf("ticket-%s.pdf", payment.ID.String())
+}
+
+func (ot *outboxTask) accumPoints(payment *models.Payment) error {
+	// Already accumulated by a previous attempt
+	if payment.GMID != "" {
+		return nil
+	}
+
+	points, err := ot.socioSmartService.AccumPoints(payment)
+	if err != nil {
+		return err
+	}
+
+	payment.GMPoints = points.Amount
+	payment.GMID = points.Id
+
+	_, err = ot.paymentRepository.UpdateByID(payment.ID, payment)
+
+	return err
+}
+
+func (ot *outboxTask) campaignUsage(payment *models.Payment) error {
+	if payment.CampaignID == nil {
+		return nil
+	}
+
+	// Combined discounts also hold the level discount, only the campaign part is counted
+	discountPerLiter := payment.DiscountPerLiter
+	if payment.Campaign != nil && payment.Campaign.Discount != nil {
+		discountPerLiter = math.Min(discountPerLiter, *payment.Campaign.Discount)
+	}
+
+	// The fee completing the minimum charge was not fuel
+	liters := (payment.RealAmountReported - payment.ChargeFee).Liters(payment.Price)
+
+	return ot.campaignRepository.RecordUsage(&models.CampaignUsage{
+		CampaignID: *payment.CampaignID,
+		CustomerID: payment.CustomerID,
+		PaymentID:  payment.ID,
+		Amount:     money.FromFloat(discountPerLiter * liters),
+		Liters:     liters,
+	})
+}
+
+func (ot *outboxTask) setGasPump(message *models.OutboxMessage, payment *models.Payment) error {
+	// Retries never preset the pump, the first attempt may have been interrupted
+	if message.Attempts == 1 && time.Since(message.CreatedAt) < presetExpiration {
+		data, err := ot.socioSmartService.SetGasPump(services.SetGasPumpOptions{
+			Number:    payment.GasPump.Number,
+			Ip:        payment.GasPump.GasStation.Ip,
+			FuelType:  payment.FuelType,
+			Amount:    payment.Amount,
+			PaymentID: payment.ID,
+			Discount:  payment.DiscountPerLiter,
+		})
+
+		if err == nil && data.Status != 0 {
+			err = ot.paymentRepository.CreateEvent(&models.PaymentEvent{
+				PaymentID: payment.ID,
+				Type:      "pump_ready",
+			})
+			if err != nil {
+				log.Println("Outbox: error creating pump_ready event", payment.ID, err)
+			}
+
+			channel := internalWebsocket.PaymentChannels.GetChannel(payment.ID.String())
+			channel.BroadcastJson(dto.PaymentWebsocketNotification{Status: "pump_ready"})
+
+			return nil
+		}
+
+		if err == nil {
+			err = errors.New("This pump has already a preset")
+		}
+		log.Println("Outbox: not able to preset pump", payment.ID, err)
+	}
+
+	err := ot.GivePaymentBack(payment, "Hubo un error al intentar hacer tu carga")
+	// Already canceled by a previous attempt
+	if errors.Is(err, payments.ErrInvalidTransition) {
+		return nil
+	}
+
+	return err
+}
+
+// GivePaymentBack releases the whole payment recording an internal cancellation, the
+// customer is notified with the given mail description
+func (ot *outboxTask) GivePaymentBack(payment *models.Payment, description string) error {
+	provider, err := ot.providers.Get(payment.PaymentProvider)
+	if err != nil {
+		return err
+	}
+
+	var messages []*models.OutboxMessage
+
+	// Gift cards and loads set from operations have no customer to notify
+	if payment.Customer != nil && (payment.FromOperations == nil || !*payment.FromOperations) {
+		requestFuelSchemaMail := &schemas.FuelRequest{}
+		requestFuelSchemaMail.FillData(payment)
+		requestFuelSchemaMail.RefundedAmount = payment.Amount
+		requestFuelSchemaMail.Error = true
+
+		messages = append(
+			messages,
+			NewFuelRequestMailMessage(payment, description, requestFuelSchemaMail),
+		)
+	}
+
+	// Authorizations not captured yet are canceled, there is nothing to refund. The
+	// cancellation is recorded right away either way, so the payment is not swept again
+	// while the provider webhook confirms the refund
+	giveBack := NewCancelPaymentMessage(payment.ID)
+	if provider.RefundsConfirmedAsync() && payment.CaptureMethod != models.CaptureManual {
+		giveBack = NewRefundPaymentMessage(payment.ID, 0, "internal_cancellation", description)
+	}
+
+	messages = append([]*models.OutboxMessage{giveBack}, messages...)
+
+	err = ot.paymentRepository.CreateEvent(
+		&models.PaymentEvent{PaymentID: payment.ID, Type: "internal_cancellation"},
+		messages...,
+	)
+	if err != nil {
+		return err
+	}
+
+	ot.Dispatch(messages...)
+
+	return nil
+}
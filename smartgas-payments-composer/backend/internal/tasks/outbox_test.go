@@ -0,0 +1,207 @@
+package tasks
+
+import (
+	"errors"
+	"io"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/schemas"
+	"smartgas-payment/internal/services"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type outboxTaskTest struct {
+	suite.Suite
+	paymentRepository *repository.MockPaymentRepository
+	providers         *services.MockPaymentProviderRegistry
+	mailService       *services.MockMailService
+	receiptService    *services.MockReceiptService
+	outbox            *outboxTask
+}
+
+func (suite *outboxTaskTest) SetupTest() {
+	suite.paymentRepository = &repository.MockPaymentRepository{}
+	suite.providers = &services.MockPaymentProviderRegistry{}
+	suite.mailService = &services.MockMailService{}
+	suite.receiptService = &services.MockReceiptService{}
+
+	suite.outbox = ProvideOutboxTask(
+		&repository.MockOutboxRepository{},
+		suite.paymentRepository,
+		nil,
+		nil,
+		suite.mailService,
+		suite.receiptService,
+		suite.providers,
+	)
+}
+
+func (suite *outboxTaskTest) TestCaptureRecordsDifference() {
+	testcases := []struct {
+		Name          string
+		Async         bool
+		CaptureMethod string
+		Amount        money.Amount
+		Recorded      bool
+	}{
+		{
+			Name:          "TestOutbox_CaptureSyncRecordsRefund",
+			CaptureMethod: models.CaptureAutomatic,
+			Amount:        money.FromFloat(400),
+			Recorded:      true,
+		},
+		{
+			Name:          "TestOutbox_CaptureSyncWholeAmount",
+			CaptureMethod: models.CaptureAutomatic,
+			Amount:        money.FromFloat(500),
+		},
+		{
+			Name:          "TestOutbox_CaptureAsyncLeftToWebhook",
+			Async:         true,
+			CaptureMethod: models.CaptureAutomatic,
+			Amount:        money.FromFloat(400),
+		},
+		{
+			Name:          "TestOutbox_CaptureManualNothingRefunded",
+			CaptureMethod: models.CaptureManual,
+			Amount:        money.FromFloat(400),
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			payment := &models.Payment{
+				ID:                    uuid.New(),
+				PaymentProvider:       "swit",
+				ExternalTransactionID: "tr_capture",
+				CaptureMethod:         tc.CaptureMethod,
+			}
+			suite.paymentRepository.On("GetByIDPreloaded", payment.ID).Return(payment, nil)
+
+			provider := &services.MockPaymentProvider{}
+			provider.On("RefundsConfirmedAsync").Return(tc.Async).Maybe()
+			provider.On("Capture", services.CaptureOpts{
+				TransactionID:  payment.ExternalTransactionID,
+				ReservedAmount: money.FromFloat(500),
+				Amount:         tc.Amount,
+				ManualCapture:  tc.CaptureMethod == models.CaptureManual,
+			}).Return(nil).Once()
+			suite.providers.On("Get", "swit").Return(provider, nil)
+
+			if tc.CaptureMethod == models.CaptureManual {
+				suite.paymentRepository.On("ClearAuthorizationExpiry", payment.ID).Return(nil).Once()
+			}
+
+			if tc.Recorded {
+				suite.paymentRepository.On(
+					"CreateEventWithUpdates",
+					mock.MatchedBy(func(event *models.PaymentEvent) bool {
+						return event.PaymentID == payment.ID && event.Type == "partial_refund"
+					}),
+					mock.MatchedBy(func(updates map[string]any) bool {
+						_, ok := updates["refunded_amount"]
+						return ok
+					}),
+				).Return(nil).Once()
+			}
+
+			message := NewCapturePaymentMessage(payment.ID, money.FromFloat(500), tc.Amount)
+
+			suite.Nil(suite.outbox.handle(message))
+			provider.AssertExpectations(suite.T())
+			suite.paymentRepository.AssertExpectations(suite.T())
+			if !tc.Recorded {
+				suite.paymentRepository.AssertNotCalled(
+					suite.T(), "CreateEventWithUpdates", mock.Anything, mock.Anything,
+				)
+			}
+		})
+	}
+}
+
+func (suite *outboxTaskTest) TestSendMailReceipt() {
+	testcases := []struct {
+		Name      string
+		Attach    bool
+		RenderErr error
+		Err       error
+	}{
+		{Name: "TestOutbox_MailWithReceipt", Attach: true},
+		{Name: "TestOutbox_MailWithoutReceipt"},
+		{
+			// Retried later, the mail is not sent without its receipt
+			Name:      "TestOutbox_MailReceiptFailed",
+			Attach:    true,
+			RenderErr: errors.New("font not found"),
+			Err:       errors.New("font not found"),
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			payment := &models.Payment{
+				ID:         uuid.New(),
+				GMPoints:   12.5,
+				Customer:   &models.Customer{Email: "customer@test.com"},
+				GasPump:    &models.GasPump{Number: "01", GasStation: &models.GasStation{Name: "Station"}},
+				TotalLiter: 10,
+			}
+			suite.paymentRepository.On("GetByIDPreloaded", payment.ID).Return(payment, nil)
+
+			// Rendered from the stored payment, with the points earned after the mail was queued
+			suite.receiptService.On("Render", mock.MatchedBy(func(data *schemas.FuelRequest) bool {
+				return data.TransactionID == payment.ID.String() && data.GMPoints == 12.5
+			}), mock.Anything).Run(func(args mock.Arguments) {
+				args.Get(1).(io.Writer).Write([]byte("%PDF-1.3 receipt"))
+			}).Return(tc.RenderErr).Maybe()
+			suite.mailService.On("SendMail", mock.Anything).Return(nil).Maybe()
+
+			message := NewFuelRequestMailMessage(payment, "Carga", &schemas.FuelRequest{})
+			if tc.Attach {
+				message = NewFuelRequestReceiptMailMessage(payment, "Carga", &schemas.FuelRequest{})
+			}
+
+			err := suite.outbox.handle(message)
+
+			suite.Equal(tc.Err, err)
+
+			if tc.Err != nil {
+				suite.mailService.AssertNotCalled(suite.T(), "SendMail", mock.Anything)
+				return
+			}
+
+			expected := []services.MailAttachment(nil)
+			if tc.Attach {
+				expected = []services.MailAttachment{{
+					Filename:    ReceiptFilename(payment),
+					ContentType: "application/pdf",
+					Content:     []byte("%PDF-1.3 receipt"),
+				}}
+			} else {
+				suite.receiptService.AssertNotCalled(suite.T(), "Render", mock.Anything, mock.Anything)
+			}
+
+			suite.mailService.AssertCalled(suite.T(), "SendMail", mock.MatchedBy(func(opts services.SendMailOpts) bool {
+				return opts.To == "customer@test.com" && assert.ObjectsAreEqual(expected, opts.Attachments)
+			}))
+		})
+	}
+}
+
+func TestOutboxTask(t *testing.T) {
+	suite.Run(t, new(outboxTaskTest))
+}
@@ -0,0 +1,209 @@
+package tasks
+
+import (
+	"errors"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/services"
+	"strings"
+	"time"
+)
+
+// Provider transactions are fetched with this margin around the range, a payment
+// intent can be created a moment before its payment is stored
+const reconciliationMargin = time.Hour
+
+// A reconciliation still running after this long is taken as failed, its process
+// stopped before finishing it
+const reconciliationTimeout = time.Hour * 2
+
+//go:generate mockery --name ReconciliationTask --filename=mock_reconciliation.go --inpackage=true
+type ReconciliationTask interface {
+	Reconcile(time.Time, time.Time) (*models.Reconciliation, error)
+}
+
+type reconciliationTask struct {
+	reconciliationRepository repository.ReconciliationRepository
+	paymentRepository        repository.PaymentRepository
+	providers                services.PaymentProviderRegistry
+}
+
+func ProvideReconciliationTask(
+	reconciliationRepository repository.ReconciliationRepository,
+	paymentRepository repository.PaymentRepository,
+	providers services.PaymentProviderRegistry,
+) *reconciliationTask {
+	return &reconciliationTask{
+		reconciliationRepository: reconciliationRepository,
+		paymentRepository:        paymentRepository,
+		providers:                providers,
+	}
+}
+
+// Reconcile matches the payments created in [from, to) against the transactions of
+// every provider, storing a report with the discrepancies found. Providers without a
+// way to list their transactions (Swit and Debit) are not reconciled
+func (rt *reconciliationTask) Reconcile(from time.Time, to time.Time) (*models.Reconciliation, error) {
+	reconciliation := &models.Reconciliation{From: from, To: to}
+
+	err := rt.reconciliationRepository.Start(reconciliation, time.Now().Add(-reconciliationTimeout))
+	if errors.Is(err, repository.ErrReconciliationRunning) {
+		return nil, RunningError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	providerErrors := []string{}
+
+	for _, name := range rt.providers.Names() {
+		discrepancies, matched, err := rt.reconcileProvider(name, from, to)
+		if errors.Is(err, services.ErrOperationNotSupported) {
+			continue
+		}
+		if err != nil {
+			providerErrors = append(providerErrors, name+": "+err.Error())
+			continue
+		}
+
+		for _, discrepancy := range discrepancies {
+			discrepancy.ReconciliationID = reconciliation.ID
+		}
+
+		if err := rt.reconciliationRepository.CreateBatchDiscrepancies(discrepancies); err != nil {
+			providerErrors = append(providerErrors, name+": "+err.Error())
+			continue
+		}
+
+		reconciliation.Matched += matched
+		for _, discrepancy := range discrepancies {
+			reconciliation.Discrepancies = append(reconciliation.Discrepancies, *discrepancy)
+		}
+	}
+
+	reconciliation.Status = "done"
+	reconciliation.Errors = strings.Join(providerErrors, "; ")
+	if len(reconciliation.Errors) > 1000 {
+		reconciliation.Errors = reconciliation.Errors[:1000]
+	}
+
+	if err := rt.reconciliationRepository.Finish(reconciliation); err != nil {
+		return nil, err
+	}
+
+	return reconciliation, nil
+}
+
+func (rt *reconciliationTask) reconcileProvider(
+	name string,
+	from time.Time,
+	to time.Time,
+) ([]*models.ReconciliationDiscrepancy, int, error) {
+	provider, err := rt.providers.Get(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	transactions, err := provider.ListTransactions(
+		from.Add(-reconciliationMargin),
+		to.Add(reconciliationMargin),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	payments, err := rt.paymentRepository.ListByProviderAndDate(name, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	remote := make(map[string]services.ProviderTransaction, len(transactions))
+	for _, transaction := range transactions {
+		remote[transaction.ID] = transaction
+	}
+
+	discrepancies := []*models.ReconciliationDiscrepancy{}
+	local := make(map[string]bool, len(payments))
+	matched := 0
+
+	for _, payment := range payments {
+		local[payment.ExternalTransactionID] = true
+
+		discrepancy := &models.ReconciliationDiscrepancy{
+			PaymentProvider:       name,
+			ExternalTransactionID: payment.ExternalTransactionID,
+			PaymentID:             &payment.ID,
+		}
+
+		captured := expectedCaptured(name, payment)
+
+		transaction, ok := remote[payment.ExternalTransactionID]
+		switch {
+		case !ok:
+			discrepancy.Type = models.DiscrepancyMissingRemotely
+			discrepancy.LocalAmount = captured
+		case transaction.Captured != captured:
+			discrepancy.Type = models.DiscrepancyAmount
+			discrepancy.LocalAmount = captured
+			discrepancy.RemoteAmount = transaction.Captured
+		case transaction.Refunded != payment.RefundedAmount:
+			discrepancy.Type = models.DiscrepancyRefund
+			discrepancy.LocalAmount = payment.RefundedAmount
+			discrepancy.RemoteAmount = transaction.Refunded
+		default:
+			matched++
+			continue
+		}
+
+		discrepancies = append(discrepancies, discrepancy)
+	}
+
+	for _, transaction := range transactions {
+		if local[transaction.ID] {
+			continue
+		}
+
+		// Out of range, fetched only to match payments near the limits
+		if transaction.CreatedAt.Before(from) || !transaction.CreatedAt.Before(to) {
+			continue
+		}
+
+		discrepancies = append(discrepancies, &models.ReconciliationDiscrepancy{
+			Type:                  models.DiscrepancyMissingLocally,
+			PaymentProvider:       name,
+			ExternalTransactionID: transaction.ID,
+			RemoteAmount:          transaction.Captured,
+		})
+	}
+
+	return discrepancies, matched, nil
+}
+
+// expectedCaptured is the amount the provider should have captured for a payment
+func expectedCaptured(provider string, payment *models.Payment) money.Amount {
+	reached := map[string]bool{}
+	for _, event := range payment.Events {
+		reached[event.Type] = true
+	}
+
+	switch {
+	case !reached["paid"] && !reached["funds_reserved"]:
+		return money.Zero
+	case payment.CaptureMethod == models.CaptureManual:
+		// Only the served amount is captured, authorizations not served are canceled
+		// or expire
+		if reached["served"] && !reached["authorization_expired"] {
+			return payment.RealAmountReported
+		}
+		return money.Zero
+	case provider == "stripe":
+		// Charged up front, what was not served is refunded
+		return payment.Amount
+	case reached["served"]:
+		return payment.RealAmountReported
+	}
+
+	// Reservations not served are released
+	return money.Zero
+}
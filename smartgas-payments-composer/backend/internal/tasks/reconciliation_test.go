@@ -0,0 +1,244 @@
+package tasks
+
+import (
+	"errors"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/services"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type reconciliationTaskTest struct {
+	suite.Suite
+	reconciliationRepository *repository.MockReconciliationRepository
+	paymentRepository        *repository.MockPaymentRepository
+	providers                *services.MockPaymentProviderRegistry
+	task                     *reconciliationTask
+}
+
+func (suite *reconciliationTaskTest) SetupTest() {
+	suite.reconciliationRepository = &repository.MockReconciliationRepository{}
+	suite.paymentRepository = &repository.MockPaymentRepository{}
+	suite.providers = &services.MockPaymentProviderRegistry{}
+
+	suite.reconciliationRepository.On("Start", mock.AnythingOfType("*models.Reconciliation"), mock.Anything).
+		Return(nil).Maybe()
+	suite.reconciliationRepository.On("CreateBatchDiscrepancies", mock.Anything).Return(nil)
+	suite.reconciliationRepository.On("Finish", mock.AnythingOfType("*models.Reconciliation")).Return(nil)
+
+	suite.task = ProvideReconciliationTask(
+		suite.reconciliationRepository,
+		suite.paymentRepository,
+		suite.providers,
+	)
+}
+
+func (suite *reconciliationTaskTest) TestProvidersWithoutListingAreSkipped() {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	stripeProvider := &services.MockPaymentProvider{}
+	stripeProvider.On("ListTransactions", mock.Anything, mock.Anything).
+		Return([]services.ProviderTransaction{{
+			ID:        "pi_1",
+			Captured:  money.FromFloat(500),
+			CreatedAt: from.Add(time.Hour),
+		}}, nil)
+
+	switProvider := &services.MockPaymentProvider{}
+	switProvider.On("ListTransactions", mock.Anything, mock.Anything).
+		Return(nil, services.ErrOperationNotSupported)
+
+	suite.providers.On("Names").Return([]string{"stripe", "swit"})
+	suite.providers.On("Get", "stripe").Return(stripeProvider, nil)
+	suite.providers.On("Get", "swit").Return(switProvider, nil)
+
+	suite.paymentRepository.On("ListByProviderAndDate", "stripe", from, to).
+		Return([]*models.Payment{{
+			ID:                    uuid.New(),
+			ExternalTransactionID: "pi_1",
+			Amount:                money.FromFloat(500),
+			Events:                []models.PaymentEvent{{Type: "pending"}, {Type: "paid"}},
+		}}, nil)
+
+	reconciliation, err := suite.task.Reconcile(from, to)
+
+	suite.Nil(err)
+	suite.Equal("done", reconciliation.Status)
+	suite.Empty(reconciliation.Errors)
+	suite.Equal(1, reconciliation.Matched)
+	suite.Empty(reconciliation.Discrepancies)
+	suite.paymentRepository.AssertNotCalled(
+		suite.T(), "ListByProviderAndDate", "swit", mock.Anything, mock.Anything,
+	)
+}
+
+func (suite *reconciliationTaskTest) TestCapturedAmounts() {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	testcases := []struct {
+		Name          string
+		CaptureMethod string
+		Events        []string
+		Captured      money.Amount
+		Discrepancy   string
+	}{
+		{
+			Name:          "TestReconciliation_AutomaticServed",
+			CaptureMethod: models.CaptureAutomatic,
+			Events:        []string{"pending", "paid", "served"},
+			Captured:      money.FromFloat(500),
+		},
+		{
+			Name:          "TestReconciliation_ManualServed",
+			CaptureMethod: models.CaptureManual,
+			Events:        []string{"funds_reserved", "serving", "served"},
+			Captured:      money.FromFloat(320),
+		},
+		{
+			Name:          "TestReconciliation_ManualCapturedReserved",
+			CaptureMethod: models.CaptureManual,
+			Events:        []string{"funds_reserved", "serving", "served"},
+			Captured:      money.FromFloat(500),
+			Discrepancy:   models.DiscrepancyAmount,
+		},
+		{
+			Name:          "TestReconciliation_ManualNotServed",
+			CaptureMethod: models.CaptureManual,
+			Events:        []string{"funds_reserved"},
+			Captured:      money.Zero,
+		},
+		{
+			Name:          "TestReconciliation_ManualCanceled",
+			CaptureMethod: models.CaptureManual,
+			Events:        []string{"funds_reserved", "manual_action"},
+			Captured:      money.Zero,
+		},
+		{
+			Name:          "TestReconciliation_ManualExpired",
+			CaptureMethod: models.CaptureManual,
+			Events:        []string{"funds_reserved", "authorization_expired"},
+			Captured:      money.Zero,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			events := []models.PaymentEvent{}
+			for _, event := range tc.Events {
+				events = append(events, models.PaymentEvent{Type: event})
+			}
+
+			stripeProvider := &services.MockPaymentProvider{}
+			stripeProvider.On("ListTransactions", mock.Anything, mock.Anything).
+				Return([]services.ProviderTransaction{{
+					ID:        "pi_1",
+					Captured:  tc.Captured,
+					CreatedAt: from.Add(time.Hour),
+				}}, nil)
+
+			suite.providers.On("Names").Return([]string{"stripe"})
+			suite.providers.On("Get", "stripe").Return(stripeProvider, nil)
+
+			suite.paymentRepository.On("ListByProviderAndDate", "stripe", from, to).
+				Return([]*models.Payment{{
+					ID:                    uuid.New(),
+					ExternalTransactionID: "pi_1",
+					Amount:                money.FromFloat(500),
+					RealAmountReported:    money.FromFloat(320),
+					CaptureMethod:         tc.CaptureMethod,
+					Events:                events,
+				}}, nil)
+
+			reconciliation, err := suite.task.Reconcile(from, to)
+
+			suite.Nil(err)
+			if tc.Discrepancy == "" {
+				suite.Equal(1, reconciliation.Matched)
+				suite.Empty(reconciliation.Discrepancies)
+				return
+			}
+
+			suite.Zero(reconciliation.Matched)
+			suite.Len(reconciliation.Discrepancies, 1)
+			suite.Equal(tc.Discrepancy, reconciliation.Discrepancies[0].Type)
+			suite.Equal(money.FromFloat(320), reconciliation.Discrepancies[0].LocalAmount)
+		})
+	}
+}
+
+func (suite *reconciliationTaskTest) TestStart() {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	testcases := []struct {
+		Name     string
+		StartErr error
+		Err      error
+	}{
+		{Name: "TestReconciliation_Started"},
+		{
+			Name:     "TestReconciliation_AlreadyRunning",
+			StartErr: repository.ErrReconciliationRunning,
+			Err:      RunningError,
+		},
+		{
+			Name:     "TestReconciliation_StartFailed",
+			StartErr: errors.New("connection refused"),
+			Err:      errors.New("connection refused"),
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			suite.reconciliationRepository = &repository.MockReconciliationRepository{}
+			suite.reconciliationRepository.On("Finish", mock.AnythingOfType("*models.Reconciliation")).Return(nil)
+			suite.task.reconciliationRepository = suite.reconciliationRepository
+
+			// Runs started before the timeout are taken as failed
+			suite.reconciliationRepository.On(
+				"Start",
+				mock.AnythingOfType("*models.Reconciliation"),
+				mock.MatchedBy(func(staleBefore time.Time) bool {
+					return time.Since(staleBefore) >= reconciliationTimeout &&
+						time.Since(staleBefore) < reconciliationTimeout+time.Minute
+				}),
+			).Return(tc.StartErr)
+
+			suite.providers.On("Names").Return([]string{})
+
+			reconciliation, err := suite.task.Reconcile(from, to)
+
+			if tc.Err != nil {
+				suite.Equal(tc.Err, err)
+				suite.Nil(reconciliation)
+				suite.providers.AssertNotCalled(suite.T(), "Names")
+				suite.reconciliationRepository.AssertNotCalled(suite.T(), "Finish", mock.Anything)
+				return
+			}
+
+			suite.Nil(err)
+			suite.Equal("done", reconciliation.Status)
+			suite.reconciliationRepository.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func TestReconciliationTask(t *testing.T) {
+	suite.Run(t, new(reconciliationTaskTest))
+}
@@ -0,0 +1,228 @@
+package tasks
+
+import (
+	"errors"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrSettlementOverlaps = errors.New("The period overlaps another settlement of the legal name")
+
+// providerFee is the fee a provider keeps from each captured payment, it is read
+// from the settings <provider>_fee_percentage and <provider>_fee_fixed (pesos)
+type providerFee struct {
+	percentage float64
+	fixed      money.Amount
+}
+
+func (pf providerFee) of(captured money.Amount) money.Amount {
+	return captured.Mul(pf.percentage/100) + pf.fixed
+}
+
+//go:generate mockery --name SettlementTask --filename=mock_settlement.go --inpackage=true
+type SettlementTask interface {
+	Generate(string, time.Time, time.Time) (*models.Settlement, error)
+	Close(uuid.UUID, *models.User) (*models.Settlement, error)
+}
+
+type settlementTask struct {
+	settlementRepository repository.SettlementRepository
+	settingsRepo         repository.SettingRepository
+}
+
+func ProvideSettlementTask(
+	settlementRepository repository.SettlementRepository,
+	settingsRepo repository.SettingRepository,
+) *settlementTask {
+	return &settlementTask{
+		settlementRepository: settlementRepository,
+		settingsRepo:         settingsRepo,
+	}
+}
+
+// Generate computes the settlement of a legal name for [from, to), open settlements
+// of the same period are computed again. Refunds of payments settled in closed
+// periods become adjustments of the settlement
+func (st *settlementTask) Generate(
+	legalNameID string,
+	from time.Time,
+	to time.Time,
+) (*models.Settlement, error) {
+	settlement, err := st.settlementRepository.GetByPeriod(legalNameID, from, to)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+
+		overlaps, err := st.settlementRepository.HasOverlap(legalNameID, from, to, uuid.Nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if overlaps {
+			return nil, ErrSettlementOverlaps
+		}
+
+		settlement = &models.Settlement{
+			LegalNameID: legalNameID,
+			From:        from,
+			To:          to,
+			Status:      models.SettlementOpen,
+		}
+
+		if err := st.settlementRepository.Create(settlement); err != nil {
+			// Created by a concurrent request
+			stored, getErr := st.settlementRepository.GetByPeriod(legalNameID, from, to)
+			if getErr != nil {
+				return nil, err
+			}
+			settlement = stored
+		}
+	}
+
+	if settlement.Status != models.SettlementOpen {
+		return settlement, repository.ErrSettlementClosed
+	}
+
+	if err := st.compute(settlement); err != nil {
+		return nil, err
+	}
+
+	return settlement, nil
+}
+
+// Close computes an open settlement for the last time and locks it
+func (st *settlementTask) Close(id uuid.UUID, user *models.User) (*models.Settlement, error) {
+	settlement, err := st.settlementRepository.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if settlement.Status != models.SettlementOpen {
+		return settlement, repository.ErrSettlementClosed
+	}
+
+	if err := st.compute(settlement); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	settlement.ClosedAt = &now
+	settlement.ClosedByID = &user.ID
+
+	if err := st.settlementRepository.Close(settlement); err != nil {
+		return nil, err
+	}
+
+	settlement.Status = models.SettlementClosed
+	settlement.ClosedBy = user
+
+	return settlement, nil
+}
+
+func (st *settlementTask) compute(settlement *models.Settlement) error {
+	payments, err := st.settlementRepository.ListPaymentsToSettle(settlement)
+	if err != nil {
+		return err
+	}
+
+	adjustments, err := st.settlementRepository.ListRefundAdjustments(settlement)
+	if err != nil {
+		return err
+	}
+
+	fees := map[string]providerFee{}
+
+	lines := make([]*models.SettlementLine, 0, len(payments)+len(adjustments))
+
+	settlement.Captured = money.Zero
+	settlement.Refunds = money.Zero
+	settlement.ProviderFees = money.Zero
+	settlement.Payments = len(payments)
+	settlement.Adjustments = len(adjustments)
+
+	for _, payment := range payments {
+		fee, ok := fees[payment.PaymentProvider]
+		if !ok {
+			if fee, err = st.providerFee(payment.PaymentProvider); err != nil {
+				return err
+			}
+			fees[payment.PaymentProvider] = fee
+		}
+
+		line := &models.SettlementLine{
+			SettlementID:    settlement.ID,
+			PaymentID:       payment.PaymentID,
+			Type:            models.SettlementLinePayment,
+			PaymentProvider: payment.PaymentProvider,
+			Captured:        payment.Captured,
+			Refunds:         unsettledRefunds(payment),
+			ProviderFee:     fee.of(payment.Captured),
+		}
+		lines = append(lines, line)
+	}
+
+	for _, adjustment := range adjustments {
+		line := &models.SettlementLine{
+			SettlementID:    settlement.ID,
+			PaymentID:       adjustment.PaymentID,
+			Type:            models.SettlementLineAdjustment,
+			PaymentProvider: adjustment.PaymentProvider,
+			Refunds:         unsettledRefunds(adjustment),
+		}
+		lines = append(lines, line)
+	}
+
+	for _, line := range lines {
+		line.Net = line.Captured - line.Refunds - line.ProviderFee
+
+		settlement.Captured += line.Captured
+		settlement.Refunds += line.Refunds
+		settlement.ProviderFees += line.ProviderFee
+	}
+
+	settlement.Net = settlement.Captured - settlement.Refunds - settlement.ProviderFees
+
+	return st.settlementRepository.ReplaceLines(settlement, lines)
+}
+
+func (st *settlementTask) providerFee(provider string) (providerFee, error) {
+	var fee providerFee
+
+	percentage, err := st.settingsRepo.GetByName(provider + "_fee_percentage")
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fee, err
+	}
+	if percentage != nil && percentage.Value != "" {
+		if fee.percentage, err = strconv.ParseFloat(percentage.Value, 64); err != nil {
+			return fee, err
+		}
+	}
+
+	fixed, err := st.settingsRepo.GetByName(provider + "_fee_fixed")
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fee, err
+	}
+	if fixed != nil && fixed.Value != "" {
+		if fee.fixed, err = money.Parse(fixed.Value); err != nil {
+			return fee, err
+		}
+	}
+
+	return fee, nil
+}
+
+// unsettledRefunds returns the refunds of a payment not settled yet by other settlements
+func unsettledRefunds(row *repository.SettlementPaymentRow) money.Amount {
+	if row.RefundedAmount <= row.SettledRefunds {
+		return money.Zero
+	}
+
+	return row.RefundedAmount - row.SettledRefunds
+}
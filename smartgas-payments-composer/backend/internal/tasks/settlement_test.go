@@ -0,0 +1,265 @@
+package tasks
+
+import (
+	"errors"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type settlementTaskTest struct {
+	suite.Suite
+	settlementRepository *repository.MockSettlementRepository
+	settingRepository    *repository.MockSettingRepository
+	task                 *settlementTask
+	from                 time.Time
+	to                   time.Time
+}
+
+func (suite *settlementTaskTest) SetupTest() {
+	suite.settlementRepository = &repository.MockSettlementRepository{}
+	suite.settingRepository = &repository.MockSettingRepository{}
+
+	suite.from = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	suite.to = suite.from.AddDate(0, 1, 0)
+
+	// Stripe keeps 3.6% plus 3 pesos, swit has no fee configured
+	suite.settingRepository.On("GetByName", "stripe_fee_percentage").
+		Return(&models.Setting{Name: "stripe_fee_percentage", Value: "3.6"}, nil).Once()
+	suite.settingRepository.On("GetByName", "stripe_fee_fixed").
+		Return(&models.Setting{Name: "stripe_fee_fixed", Value: "3"}, nil).Once()
+	suite.settingRepository.On("GetByName", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+	suite.task = ProvideSettlementTask(suite.settlementRepository, suite.settingRepository)
+}
+
+// mockLines registers three payments and the refunds of two payments settled in closed periods
+func (suite *settlementTaskTest) mockLines() {
+	suite.settlementRepository.On("ListPaymentsToSettle", mock.AnythingOfType("*models.Settlement")).
+		Return([]*repository.SettlementPaymentRow{
+			{
+				PaymentID:       uuid.New(),
+				PaymentProvider: "stripe",
+				Captured:        money.FromFloat(1000),
+				RefundedAmount:  money.FromFloat(100),
+			},
+			{
+				PaymentID:       uuid.New(),
+				PaymentProvider: "stripe",
+				Captured:        money.FromFloat(500),
+			},
+			{
+				PaymentID:       uuid.New(),
+				PaymentProvider: "swit",
+				Captured:        money.FromFloat(400),
+			},
+		}, nil)
+	suite.settlementRepository.On("ListRefundAdjustments", mock.AnythingOfType("*models.Settlement")).
+		Return([]*repository.SettlementPaymentRow{
+			{
+				PaymentID:       uuid.New(),
+				PaymentProvider: "stripe",
+				RefundedAmount:  money.FromFloat(200),
+				SettledRefunds:  money.FromFloat(50),
+			},
+			{
+				// Its refunds were already taken by another settlement
+				PaymentID:       uuid.New(),
+				PaymentProvider: "swit",
+				RefundedAmount:  money.FromFloat(80),
+				SettledRefunds:  money.FromFloat(80),
+			},
+		}, nil)
+	suite.settlementRepository.On("ReplaceLines", mock.AnythingOfType("*models.Settlement"), mock.Anything).Return(nil)
+}
+
+// assertTotals checks the totals of the lines registered by mockLines
+func (suite *settlementTaskTest) assertTotals(settlement *models.Settlement) {
+	// Fees: 1000 * 3.6% + 3 and 500 * 3.6% + 3, swit and adjustments have none
+	suite.Equal(money.FromFloat(1900), settlement.Captured)
+	suite.Equal(money.FromFloat(250), settlement.Refunds)
+	suite.Equal(money.FromFloat(60), settlement.ProviderFees)
+	suite.Equal(money.FromFloat(1590), settlement.Net)
+	suite.Equal(3, settlement.Payments)
+	suite.Equal(2, settlement.Adjustments)
+
+	suite.settlementRepository.AssertCalled(suite.T(), "ReplaceLines", settlement, mock.MatchedBy(func(lines []*models.SettlementLine) bool {
+		if len(lines) != 5 {
+			return false
+		}
+
+		adjustment := lines[3]
+
+		return lines[0].Type == models.SettlementLinePayment &&
+			lines[0].ProviderFee == money.FromFloat(39) &&
+			lines[0].Net == money.FromFloat(861) &&
+			lines[2].ProviderFee == money.Zero &&
+			adjustment.Type == models.SettlementLineAdjustment &&
+			adjustment.Captured == money.Zero &&
+			adjustment.Net == money.FromFloat(-150) &&
+			lines[4].Refunds == money.Zero
+	}))
+	// Fees are read once per provider
+	suite.settingRepository.AssertNumberOfCalls(suite.T(), "GetByName", 4)
+}
+
+func (suite *settlementTaskTest) TestGenerate() {
+	testcases := []struct {
+		Name string
+		Mock func() *models.Settlement
+		Err  error
+	}{
+		{
+			Name: "TestSettlement_GenerateNew",
+			Mock: func() *models.Settlement {
+				suite.settlementRepository.On("GetByPeriod", "LN1", suite.from, suite.to).Return(nil, gorm.ErrRecordNotFound)
+				suite.settlementRepository.On("HasOverlap", "LN1", suite.from, suite.to, uuid.Nil).Return(false, nil)
+				suite.settlementRepository.On("Create", mock.AnythingOfType("*models.Settlement")).Return(nil)
+				suite.mockLines()
+
+				return nil
+			},
+		},
+		{
+			// Open settlements are computed again with the refunds made since
+			Name: "TestSettlement_GenerateOpen",
+			Mock: func() *models.Settlement {
+				stored := &models.Settlement{
+					ID:          uuid.New(),
+					LegalNameID: "LN1",
+					Status:      models.SettlementOpen,
+					Captured:    money.FromFloat(10),
+				}
+				suite.settlementRepository.On("GetByPeriod", "LN1", suite.from, suite.to).Return(stored, nil)
+				suite.mockLines()
+
+				return stored
+			},
+		},
+		{
+			Name: "TestSettlement_GenerateCreatedConcurrently",
+			Mock: func() *models.Settlement {
+				stored := &models.Settlement{ID: uuid.New(), LegalNameID: "LN1", Status: models.SettlementOpen}
+				suite.settlementRepository.On("GetByPeriod", "LN1", suite.from, suite.to).Return(nil, gorm.ErrRecordNotFound).Once()
+				suite.settlementRepository.On("HasOverlap", "LN1", suite.from, suite.to, uuid.Nil).Return(false, nil)
+				suite.settlementRepository.On("Create", mock.AnythingOfType("*models.Settlement")).
+					Return(errors.New("Duplicate entry for key 'idx_settlement_period'"))
+				suite.settlementRepository.On("GetByPeriod", "LN1", suite.from, suite.to).Return(stored, nil).Once()
+				suite.mockLines()
+
+				return stored
+			},
+		},
+		{
+			Name: "TestSettlement_GenerateOverlaps",
+			Mock: func() *models.Settlement {
+				suite.settlementRepository.On("GetByPeriod", "LN1", suite.from, suite.to).Return(nil, gorm.ErrRecordNotFound)
+				suite.settlementRepository.On("HasOverlap", "LN1", suite.from, suite.to, uuid.Nil).Return(true, nil)
+
+				return nil
+			},
+			Err: ErrSettlementOverlaps,
+		},
+		{
+			Name: "TestSettlement_GenerateClosed",
+			Mock: func() *models.Settlement {
+				stored := &models.Settlement{ID: uuid.New(), LegalNameID: "LN1", Status: models.SettlementClosed}
+				suite.settlementRepository.On("GetByPeriod", "LN1", suite.from, suite.to).Return(stored, nil)
+
+				return stored
+			},
+			Err: repository.ErrSettlementClosed,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			stored := tc.Mock()
+
+			settlement, err := suite.task.Generate("LN1", suite.from, suite.to)
+
+			if tc.Err != nil {
+				suite.ErrorIs(err, tc.Err)
+				suite.settlementRepository.AssertNotCalled(suite.T(), "ReplaceLines", mock.Anything, mock.Anything)
+				suite.settlementRepository.AssertNotCalled(suite.T(), "Create", mock.Anything)
+				return
+			}
+
+			suite.Nil(err)
+			if stored != nil {
+				suite.Equal(stored.ID, settlement.ID)
+			} else {
+				suite.Equal("LN1", settlement.LegalNameID)
+				suite.Equal(models.SettlementOpen, settlement.Status)
+			}
+			suite.assertTotals(settlement)
+		})
+	}
+}
+
+func (suite *settlementTaskTest) TestClose() {
+	user := &models.User{ID: uuid.New()}
+
+	testcases := []struct {
+		Name     string
+		Status   string
+		CloseErr error
+		Err      error
+	}{
+		{Name: "TestSettlement_Close", Status: models.SettlementOpen},
+		{Name: "TestSettlement_CloseClosed", Status: models.SettlementClosed, Err: repository.ErrSettlementClosed},
+		{
+			Name:     "TestSettlement_CloseClosedMeanwhile",
+			Status:   models.SettlementOpen,
+			CloseErr: repository.ErrSettlementClosed,
+			Err:      repository.ErrSettlementClosed,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			stored := &models.Settlement{ID: uuid.New(), LegalNameID: "LN1", Status: tc.Status}
+			suite.settlementRepository.On("GetByID", stored.ID).Return(stored, nil)
+			suite.settlementRepository.On("Close", stored).Return(tc.CloseErr)
+			suite.mockLines()
+
+			settlement, err := suite.task.Close(stored.ID, user)
+
+			if tc.Err != nil {
+				suite.ErrorIs(err, tc.Err)
+				if tc.Status == models.SettlementClosed {
+					suite.settlementRepository.AssertNotCalled(suite.T(), "ReplaceLines", mock.Anything, mock.Anything)
+					suite.settlementRepository.AssertNotCalled(suite.T(), "Close", mock.Anything)
+				}
+				return
+			}
+
+			suite.Nil(err)
+			suite.Equal(models.SettlementClosed, settlement.Status)
+			suite.Equal(&user.ID, settlement.ClosedByID)
+			suite.NotNil(settlement.ClosedAt)
+			suite.Equal(user, settlement.ClosedBy)
+			// Computed a last time before it is locked
+			suite.assertTotals(settlement)
+		})
+	}
+}
+
+func TestSettlementTask(t *testing.T) {
+	suite.Run(t, new(settlementTaskTest))
+}
@@ -0,0 +1,533 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"smartgas-payment/internal/dto"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/payments"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/services"
+	"time"
+
+	"github.com/stripe/stripe-go/v72"
+	"gorm.io/gorm"
+
+	internalWebsocket "smartgas-payment/internal/websocket"
+)
+
+// Stripe keeps the funds of an uncaptured payment intent authorized for 7 days
+const stripeAuthorizationLifetime = 7 * 24 * time.Hour
+
+// An event is held by the attempt processing it for this long, an attempt that dies
+// leaves it to the next redelivery or replay once the lease ends
+const stripeEventLease = time.Minute * 5
+
+var (
+	ErrStripeEventPayload       = errors.New("Invalid stripe event payload")
+	ErrStripeEventNotReplayable = errors.New("Only failed or pending stripe events can be replayed")
+	ErrStripeEventProcessing    = errors.New("The stripe event is being processed")
+)
+
+//go:generate mockery --name StripeWebhookTask --filename=mock_stripe_webhook.go --inpackage=true
+type StripeWebhookTask interface {
+	Receive(stripe.Event, []byte) (*models.StripeEvent, error)
+	Replay(string) (*models.StripeEvent, error)
+	ReplayFailed(int) ([]*models.StripeEvent, error)
+}
+
+type stripeWebhookTask struct {
+	stripeEventRepository repository.StripeEventRepository
+	paymentRepository     repository.PaymentRepository
+	settingRepository     repository.SettingRepository
+	disputeRepository     repository.DisputeRepository
+	stripeService         services.StripeService
+	outboxTask            OutboxTask
+}
+
+func ProvideStripeWebhookTask(
+	stripeEventRepository repository.StripeEventRepository,
+	paymentRepository repository.PaymentRepository,
+	settingRepository repository.SettingRepository,
+	disputeRepository repository.DisputeRepository,
+	stripeService services.StripeService,
+	outboxTask OutboxTask,
+) *stripeWebhookTask {
+	return &stripeWebhookTask{
+		stripeEventRepository: stripeEventRepository,
+		paymentRepository:     paymentRepository,
+		settingRepository:     settingRepository,
+		disputeRepository:     disputeRepository,
+		stripeService:         stripeService,
+		outboxTask:            outboxTask,
+	}
+}
+
+// Receive stores a verified event and processes it, redeliveries of events already
+// processed are skipped. ErrStripeEventProcessing is returned while another delivery
+// of the event is processed
+func (swt *stripeWebhookTask) Receive(event stripe.Event, payload []byte) (*models.StripeEvent, error) {
+	record := &models.StripeEvent{
+		ID:      event.ID,
+		Type:    event.Type,
+		Payload: string(payload),
+		Status:  models.StripeEventPending,
+	}
+
+	created, err := swt.stripeEventRepository.Register(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		record, err = swt.stripeEventRepository.GetByID(event.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if record.Status == models.StripeEventProcessed || record.Status == models.StripeEventIgnored {
+			return record, nil
+		}
+	}
+
+	claimed, err := swt.stripeEventRepository.Claim(record, stripeEventLease)
+	if err != nil {
+		return nil, err
+	}
+
+	if !claimed {
+		return record, ErrStripeEventProcessing
+	}
+
+	return record, swt.process(record)
+}
+
+// Replay processes again a failed (or stuck pending) event
+func (swt *stripeWebhookTask) Replay(id string) (*models.StripeEvent, error) {
+	record, err := swt.stripeEventRepository.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Status != models.StripeEventFailed && record.Status != models.StripeEventPending {
+		return record, ErrStripeEventNotReplayable
+	}
+
+	claimed, err := swt.stripeEventRepository.Claim(record, stripeEventLease)
+	if err != nil {
+		return nil, err
+	}
+
+	if !claimed {
+		return record, ErrStripeEventProcessing
+	}
+
+	return record, swt.process(record)
+}
+
+// ReplayFailed replays up to limit failed events, oldest first, skipping the ones
+// being processed. The outcome of each one is stored in its status and error
+func (swt *stripeWebhookTask) ReplayFailed(limit int) ([]*models.StripeEvent, error) {
+	records, err := swt.stripeEventRepository.ListByStatus(models.StripeEventFailed, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed := []*models.StripeEvent{}
+
+	for _, record := range records {
+		claimed, err := swt.stripeEventRepository.Claim(record, stripeEventLease)
+		if err != nil {
+			log.Println("Stripe webhook: error claiming event", record.ID, err)
+			continue
+		}
+
+		if !claimed {
+			continue
+		}
+
+		if err := swt.process(record); err != nil {
+			log.Println("Stripe webhook: replay failed", record.ID, err)
+		}
+
+		replayed = append(replayed, record)
+	}
+
+	return replayed, nil
+}
+
+func (swt *stripeWebhookTask) process(record *models.StripeEvent) error {
+	var event stripe.Event
+
+	handled := false
+	err := json.Unmarshal([]byte(record.Payload), &event)
+	if err != nil || event.Data == nil {
+		err = fmt.Errorf("%w: %v", ErrStripeEventPayload, err)
+	} else {
+		handled, err = swt.handle(event)
+	}
+
+	record.Attempts++
+	record.Error = ""
+
+	switch {
+	case err != nil:
+		record.Status = models.StripeEventFailed
+		record.Error = err.Error()
+		if len(record.Error) > 1000 {
+			record.Error = record.Error[:1000]
+		}
+	case !handled:
+		record.Status = models.StripeEventIgnored
+	default:
+		now := time.Now()
+		record.Status = models.StripeEventProcessed
+		record.ProcessedAt = &now
+	}
+
+	if finishErr := swt.stripeEventRepository.Finish(record); finishErr != nil && err == nil {
+		// Left pending, the redelivery finds the event already applied
+		return finishErr
+	}
+
+	return err
+}
+
+// handle applies the event to its payment, reporting false for event types we do not use
+func (swt *stripeWebhookTask) handle(event stripe.Event) (bool, error) {
+	switch event.Type {
+	case "payment_intent.payment_failed":
+		return true, swt.paymentIntentFailed(event)
+	case "payment_intent.amount_capturable_updated":
+		return true, swt.paymentIntentAuthorized(event)
+	case "payment_intent.succeeded":
+		return true, swt.paymentIntentSucceeded(event)
+	case "payment_intent.canceled":
+		return true, swt.paymentIntentCanceled(event)
+	case "payment_intent.requires_action":
+		return true, swt.paymentIntentChanged(event, "requires_action")
+	case "payment_intent.processing":
+		return true, swt.paymentIntentChanged(event, "processing")
+	case "charge.refunded":
+		return true, swt.chargeRefunded(event)
+	case "charge.dispute.created", "charge.dispute.updated", "charge.dispute.closed":
+		return true, swt.chargeDispute(event)
+	}
+
+	return false, nil
+}
+
+func (swt *stripeWebhookTask) paymentIntentFailed(event stripe.Event) error {
+	var paymentIntent stripe.PaymentIntent
+	if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
+		return fmt.Errorf("%w: %v", ErrStripeEventPayload, err)
+	}
+
+	payment, err := swt.getPayment(paymentIntent.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = swt.createEvent(payment, "failed")
+
+	return err
+}
+
+func (swt *stripeWebhookTask) paymentIntentSucceeded(event stripe.Event) error {
+	var paymentIntent stripe.PaymentIntent
+	if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
+		return fmt.Errorf("%w: %v", ErrStripeEventPayload, err)
+	}
+
+	payment, err := swt.getPayment(paymentIntent.ID)
+	if err != nil {
+		return err
+	}
+
+	// Manually captured payments were paid once authorized, this confirms the capture
+	if payment.CaptureMethod == models.CaptureManual {
+		return nil
+	}
+
+	return swt.paymentPaid(payment)
+}
+
+// paymentIntentAuthorized marks as paid the manually captured payments once their
+// funds are held, keeping when the authorization expires
+func (swt *stripeWebhookTask) paymentIntentAuthorized(event stripe.Event) error {
+	var paymentIntent stripe.PaymentIntent
+	if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
+		return fmt.Errorf("%w: %v", ErrStripeEventPayload, err)
+	}
+
+	payment, err := swt.getPayment(paymentIntent.ID)
+	if err != nil {
+		return err
+	}
+
+	if payment.CaptureMethod != models.CaptureManual || paymentIntent.AmountCapturable <= 0 {
+		return nil
+	}
+
+	if payment.AuthorizationExpiresAt == nil {
+		expiresAt := time.Unix(paymentIntent.Created, 0).Add(stripeAuthorizationLifetime)
+		payment.AuthorizationExpiresAt = &expiresAt
+
+		if _, err := swt.paymentRepository.UpdateByID(payment.ID, payment); err != nil {
+			return err
+		}
+	}
+
+	return swt.paymentPaid(payment)
+}
+
+func (swt *stripeWebhookTask) paymentPaid(payment *models.Payment) error {
+	setting, err := swt.settingRepository.GetByName("gas_pump_status")
+	if err != nil {
+		log.Println("Stripe webhook: gas pump status not available", err)
+	}
+
+	// PRE-SET gas pump once the payment is stored, on failure the payment is given back
+	var messages []*models.OutboxMessage
+	if setting != nil && setting.Value == "enabled" {
+		messages = append(messages, NewSetGasPumpMessage(payment.ID))
+	}
+
+	// logging event, status is derived from it
+	created, err := swt.createEvent(payment, "paid", messages...)
+	if err != nil || !created {
+		return err
+	}
+
+	channel := internalWebsocket.PaymentChannels.GetChannel(payment.ID.String())
+	channel.BroadcastJson(dto.PaymentWebsocketNotification{Status: "paid"})
+
+	swt.outboxTask.Dispatch(messages...)
+
+	return nil
+}
+
+// paymentIntentCanceled tells apart the authorizations of manually captured payments
+// that Stripe canceled because they were not captured in time
+func (swt *stripeWebhookTask) paymentIntentCanceled(event stripe.Event) error {
+	var paymentIntent stripe.PaymentIntent
+	if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
+		return fmt.Errorf("%w: %v", ErrStripeEventPayload, err)
+	}
+
+	if paymentIntent.CancellationReason != stripe.PaymentIntentCancellationReasonAutomatic {
+		return swt.paymentIntentChanged(event, "canceled")
+	}
+
+	payment, err := swt.getPayment(paymentIntent.ID)
+	if err != nil {
+		return err
+	}
+
+	last, err := swt.paymentRepository.GetLastEventByPaymentID(payment.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if payment.CaptureMethod != models.CaptureManual || last == nil ||
+		(last.Type != "authorization_expired" && !payments.CanTransition(last.Type, "authorization_expired")) {
+		return swt.paymentIntentChanged(event, "canceled")
+	}
+
+	log.Println("Stripe webhook: authorization expired for payment", payment.ID)
+
+	created, err := swt.createEvent(payment, "authorization_expired")
+	if err != nil || !created {
+		return err
+	}
+
+	channel := internalWebsocket.PaymentChannels.GetChannel(payment.ID.String())
+	channel.BroadcastJson(dto.PaymentWebsocketNotification{Status: "authorization_expired"})
+
+	internalWebsocket.PaymentChannels.DeleteChannel(payment.ID.String())
+
+	return nil
+}
+
+// paymentIntentChanged records the intermediate states of an intent not yet paid
+func (swt *stripeWebhookTask) paymentIntentChanged(event stripe.Event, eventType string) error {
+	var paymentIntent stripe.PaymentIntent
+	if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
+		return fmt.Errorf("%w: %v", ErrStripeEventPayload, err)
+	}
+
+	payment, err := swt.getPayment(paymentIntent.ID)
+	if err != nil {
+		return err
+	}
+
+	last, err := swt.paymentRepository.GetLastEventByPaymentID(payment.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	// Stripe does not guarantee the order of the events, one arriving after the payment
+	// moved on (or after we canceled it) is stale
+	if last != nil && last.Type != eventType && !payments.CanTransition(last.Type, eventType) {
+		log.Println("Stripe webhook: skipping stale", eventType, "for payment", payment.ID, "after", last.Type)
+		return nil
+	}
+
+	created, err := swt.createEvent(payment, eventType)
+	if err != nil || !created {
+		return err
+	}
+
+	channel := internalWebsocket.PaymentChannels.GetChannel(payment.ID.String())
+	channel.BroadcastJson(dto.PaymentWebsocketNotification{Status: eventType})
+
+	return nil
+}
+
+func (swt *stripeWebhookTask) chargeRefunded(event stripe.Event) error {
+	var chargeBody stripe.Charge
+	if err := json.Unmarshal(event.Data.Raw, &chargeBody); err != nil {
+		return fmt.Errorf("%w: %v", ErrStripeEventPayload, err)
+	}
+
+	if chargeBody.PaymentIntent == nil {
+		return fmt.Errorf("%w: charge %s without payment intent", ErrStripeEventPayload, chargeBody.ID)
+	}
+
+	cha, err := swt.stripeService.GetCharge(chargeBody.ID)
+	if err != nil {
+		return err
+	}
+
+	status := "partial_refund"
+
+	if cha.Refunds != nil && len(cha.Refunds.Data) > 0 {
+		if sta, ok := cha.Refunds.Data[0].Metadata["status"]; ok {
+			status = sta
+		}
+	}
+
+	payment, err := swt.getPayment(chargeBody.PaymentIntent.ID)
+	if err != nil {
+		return err
+	}
+
+	created, err := swt.createEvent(payment, status)
+	if err != nil {
+		return err
+	}
+
+	if created && status == "manual_action" {
+		swt.paymentRepository.CreateEvent(&models.PaymentEvent{
+			Type:      "partial_refund",
+			PaymentID: payment.ID,
+		})
+	}
+
+	payment.RefundedAmount = money.FromCents(chargeBody.AmountRefunded)
+
+	if _, err := swt.paymentRepository.UpdateByID(payment.ID, payment); err != nil {
+		return err
+	}
+
+	// TODO: Notify user that money were refunded
+
+	channel := internalWebsocket.PaymentChannels.GetChannel(payment.ID.String())
+	channel.BroadcastJson(dto.PaymentWebsocketNotification{Status: status})
+
+	internalWebsocket.PaymentChannels.DeleteChannel(payment.ID.String())
+
+	return nil
+}
+
+func (swt *stripeWebhookTask) getPayment(paymentIntentID string) (*models.Payment, error) {
+	payment, err := swt.paymentRepository.GetPaymentByStripePaymentIntentID(paymentIntentID)
+	if err != nil {
+		return nil, fmt.Errorf("payment for payment intent %s: %w", paymentIntentID, err)
+	}
+
+	return payment, nil
+}
+
+// createEvent adds eventType to the payment, reporting false when it already was its
+// last event, so replaying an event that failed after the transition does not fail again
+func (swt *stripeWebhookTask) createEvent(
+	payment *models.Payment,
+	eventType string,
+	messages ...*models.OutboxMessage,
+) (bool, error) {
+	err := swt.paymentRepository.CreateEvent(&models.PaymentEvent{
+		PaymentID: payment.ID,
+		Type:      eventType,
+	}, messages...)
+
+	if errors.Is(err, payments.ErrInvalidTransition) {
+		last, lastErr := swt.paymentRepository.GetLastEventByPaymentID(payment.ID)
+		if lastErr == nil && last.Type == eventType {
+			return false, nil
+		}
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// chargeDispute keeps the dispute record of the payment up to date and adds the
+// dispute events to its timeline
+func (swt *stripeWebhookTask) chargeDispute(event stripe.Event) error {
+	var dispute stripe.Dispute
+	if err := json.Unmarshal(event.Data.Raw, &dispute); err != nil {
+		return fmt.Errorf("%w: %v", ErrStripeEventPayload, err)
+	}
+
+	if dispute.PaymentIntent == nil {
+		return fmt.Errorf("%w: dispute %s without payment intent", ErrStripeEventPayload, dispute.ID)
+	}
+
+	payment, err := swt.getPayment(dispute.PaymentIntent.ID)
+	if err != nil {
+		return err
+	}
+
+	record := &models.Dispute{
+		PaymentID:       payment.ID,
+		StripeDisputeID: dispute.ID,
+		Amount:          money.FromCents(dispute.Amount),
+		Reason:          string(dispute.Reason),
+		Status:          string(dispute.Status),
+	}
+
+	if dispute.EvidenceDetails != nil && dispute.EvidenceDetails.DueBy > 0 {
+		dueBy := time.Unix(dispute.EvidenceDetails.DueBy, 0)
+		record.EvidenceDueBy = &dueBy
+	}
+
+	eventType := "disputed"
+
+	if event.Type == "charge.dispute.closed" {
+		closedAt := time.Unix(event.Created, 0)
+		record.ClosedAt = &closedAt
+
+		switch dispute.Status {
+		case stripe.DisputeStatusWon, stripe.DisputeStatusWarningClosed:
+			eventType = "dispute_won"
+		default:
+			eventType = "dispute_lost"
+		}
+	}
+
+	if err := swt.disputeRepository.Save(record); err != nil {
+		return err
+	}
+
+	// A dispute opened before the load was served fails here and is replayed later
+	_, err = swt.createEvent(payment, eventType)
+
+	return err
+}
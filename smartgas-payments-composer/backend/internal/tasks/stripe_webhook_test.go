@@ -0,0 +1,543 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/payments"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/services"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/stripe/stripe-go/v72"
+	"gorm.io/gorm"
+)
+
+type stripeWebhookTaskTest struct {
+	suite.Suite
+	stripeEventRepository *repository.MockStripeEventRepository
+	paymentRepository     *repository.MockPaymentRepository
+	disputeRepository     *repository.MockDisputeRepository
+	stripeService         *services.MockStripeService
+	outboxRepository      *repository.MockOutboxRepository
+	task                  *stripeWebhookTask
+}
+
+// An event type the webhook does not use, processing it only stores it as ignored
+const ignoredStripeEvent = `{"id":"evt_1","type":"customer.created","data":{"object":{"id":"cus_1"}}}`
+
+func (suite *stripeWebhookTaskTest) SetupTest() {
+	suite.stripeEventRepository = &repository.MockStripeEventRepository{}
+	suite.paymentRepository = &repository.MockPaymentRepository{}
+	suite.disputeRepository = &repository.MockDisputeRepository{}
+	suite.stripeService = &services.MockStripeService{}
+	suite.outboxRepository = &repository.MockOutboxRepository{}
+
+	outboxTask := ProvideOutboxTask(
+		suite.outboxRepository,
+		suite.paymentRepository,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+	)
+
+	suite.task = ProvideStripeWebhookTask(
+		suite.stripeEventRepository,
+		suite.paymentRepository,
+		nil,
+		suite.disputeRepository,
+		suite.stripeService,
+		outboxTask,
+	)
+}
+
+func (suite *stripeWebhookTaskTest) stripeEvent(payload string) stripe.Event {
+	var event stripe.Event
+	suite.Require().Nil(json.Unmarshal([]byte(payload), &event))
+
+	return event
+}
+
+func (suite *stripeWebhookTaskTest) TestReceiveClaim() {
+	testcases := []struct {
+		Name    string
+		Created bool
+		Stored  string
+		Claimed bool
+		Status  string
+		Err     error
+	}{
+		{
+			Name:    "TestStripeWebhook_NewEvent",
+			Created: true,
+			Claimed: true,
+			Status:  models.StripeEventIgnored,
+		},
+		{
+			Name:   "TestStripeWebhook_RedeliveryProcessed",
+			Stored: models.StripeEventProcessed,
+			Status: models.StripeEventProcessed,
+		},
+		{
+			Name:    "TestStripeWebhook_RedeliveryFailed",
+			Stored:  models.StripeEventFailed,
+			Claimed: true,
+			Status:  models.StripeEventIgnored,
+		},
+		{
+			Name:   "TestStripeWebhook_RedeliveryBeingProcessed",
+			Stored: models.StripeEventPending,
+			Status: models.StripeEventPending,
+			Err:    ErrStripeEventProcessing,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			suite.stripeEventRepository.On("Register", mock.AnythingOfType("*models.StripeEvent")).Return(tc.Created, nil)
+			if !tc.Created {
+				suite.stripeEventRepository.On("GetByID", "evt_1").Return(&models.StripeEvent{
+					ID:      "evt_1",
+					Type:    "customer.created",
+					Payload: ignoredStripeEvent,
+					Status:  tc.Stored,
+				}, nil)
+			}
+			suite.stripeEventRepository.On("Claim", mock.AnythingOfType("*models.StripeEvent"), stripeEventLease).
+				Return(tc.Claimed, nil).Maybe()
+			suite.stripeEventRepository.On("Finish", mock.AnythingOfType("*models.StripeEvent")).Return(nil).Maybe()
+
+			record, err := suite.task.Receive(suite.stripeEvent(ignoredStripeEvent), []byte(ignoredStripeEvent))
+
+			suite.Equal(tc.Err, err)
+			suite.Equal(tc.Status, record.Status)
+
+			if tc.Stored == models.StripeEventProcessed {
+				suite.stripeEventRepository.AssertNotCalled(suite.T(), "Claim", mock.Anything, mock.Anything)
+			}
+			if tc.Claimed {
+				suite.stripeEventRepository.AssertCalled(suite.T(), "Finish", record)
+				suite.Equal(1, record.Attempts)
+			} else {
+				suite.stripeEventRepository.AssertNotCalled(suite.T(), "Finish", mock.Anything)
+			}
+		})
+	}
+}
+
+func (suite *stripeWebhookTaskTest) TestReplayClaim() {
+	testcases := []struct {
+		Name    string
+		Stored  string
+		Claimed bool
+		Status  string
+		Err     error
+	}{
+		{
+			Name:    "TestStripeWebhook_ReplayFailed",
+			Stored:  models.StripeEventFailed,
+			Claimed: true,
+			Status:  models.StripeEventIgnored,
+		},
+		{
+			Name:   "TestStripeWebhook_ReplayBeingProcessed",
+			Stored: models.StripeEventFailed,
+			Status: models.StripeEventFailed,
+			Err:    ErrStripeEventProcessing,
+		},
+		{
+			Name:   "TestStripeWebhook_ReplayProcessed",
+			Stored: models.StripeEventProcessed,
+			Status: models.StripeEventProcessed,
+			Err:    ErrStripeEventNotReplayable,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			suite.stripeEventRepository.On("GetByID", "evt_1").Return(&models.StripeEvent{
+				ID:      "evt_1",
+				Type:    "customer.created",
+				Payload: ignoredStripeEvent,
+				Status:  tc.Stored,
+			}, nil)
+			suite.stripeEventRepository.On("Claim", mock.AnythingOfType("*models.StripeEvent"), stripeEventLease).
+				Return(tc.Claimed, nil).Maybe()
+			suite.stripeEventRepository.On("Finish", mock.AnythingOfType("*models.StripeEvent")).Return(nil).Maybe()
+
+			record, err := suite.task.Replay("evt_1")
+
+			suite.Equal(tc.Err, err)
+			suite.Equal(tc.Status, record.Status)
+			if !tc.Claimed {
+				suite.stripeEventRepository.AssertNotCalled(suite.T(), "Finish", mock.Anything)
+			}
+		})
+	}
+}
+
+func (suite *stripeWebhookTaskTest) TestReplayFailedSkipsClaimed() {
+	free := &models.StripeEvent{ID: "evt_1", Payload: ignoredStripeEvent, Status: models.StripeEventFailed}
+	taken := &models.StripeEvent{ID: "evt_2", Payload: ignoredStripeEvent, Status: models.StripeEventFailed}
+
+	suite.stripeEventRepository.On("ListByStatus", models.StripeEventFailed, 10).
+		Return([]*models.StripeEvent{free, taken}, nil)
+	suite.stripeEventRepository.On("Claim", free, stripeEventLease).Return(true, nil)
+	suite.stripeEventRepository.On("Claim", taken, stripeEventLease).Return(false, nil)
+	suite.stripeEventRepository.On("Finish", free).Return(nil)
+
+	records, err := suite.task.ReplayFailed(10)
+
+	suite.Nil(err)
+	suite.Equal([]*models.StripeEvent{free}, records)
+	suite.Equal(models.StripeEventIgnored, free.Status)
+	suite.Equal(models.StripeEventFailed, taken.Status)
+	suite.stripeEventRepository.AssertNotCalled(suite.T(), "Finish", taken)
+}
+
+func (suite *stripeWebhookTaskTest) TestReceiveStoresEvent() {
+	suite.stripeEventRepository.On("Register", mock.AnythingOfType("*models.StripeEvent")).Return(true, nil)
+	suite.stripeEventRepository.On("Claim", mock.AnythingOfType("*models.StripeEvent"), stripeEventLease).Return(true, nil)
+	suite.stripeEventRepository.On("Finish", mock.AnythingOfType("*models.StripeEvent")).Return(nil)
+
+	_, err := suite.task.Receive(suite.stripeEvent(ignoredStripeEvent), []byte(ignoredStripeEvent))
+
+	suite.Nil(err)
+	// Stored as received before it is processed
+	suite.stripeEventRepository.AssertCalled(suite.T(), "Register", mock.MatchedBy(func(record *models.StripeEvent) bool {
+		return record.ID == "evt_1" && record.Type == "customer.created" && record.Payload == ignoredStripeEvent
+	}))
+}
+
+func (suite *stripeWebhookTaskTest) TestReplayOutcome() {
+	failed := `{"id":"evt_1","type":"payment_intent.payment_failed","data":{"object":{"id":"pi_1"}}}`
+	finishErr := errors.New("connection refused")
+
+	testcases := []struct {
+		Name      string
+		Payload   string
+		Mock      func(payment *models.Payment)
+		FinishErr error
+		Status    string
+		Error     string
+		Err       error
+	}{
+		{
+			Name:    "TestStripeWebhook_ReplayProcessed",
+			Payload: failed,
+			Mock: func(payment *models.Payment) {
+				suite.paymentRepository.On("GetPaymentByStripePaymentIntentID", "pi_1").Return(payment, nil)
+				suite.paymentRepository.On("CreateEvent", mock.MatchedBy(func(event *models.PaymentEvent) bool {
+					return event.PaymentID == payment.ID && event.Type == "failed"
+				})).Return(nil)
+			},
+			Status: models.StripeEventProcessed,
+		},
+		{
+			Name:    "TestStripeWebhook_ReplayIgnored",
+			Payload: ignoredStripeEvent,
+			Mock:    func(payment *models.Payment) {},
+			Status:  models.StripeEventIgnored,
+		},
+		{
+			Name:    "TestStripeWebhook_ReplayPaymentNotFound",
+			Payload: failed,
+			Mock: func(payment *models.Payment) {
+				suite.paymentRepository.On("GetPaymentByStripePaymentIntentID", "pi_1").Return(nil, gorm.ErrRecordNotFound)
+			},
+			Status: models.StripeEventFailed,
+			Error:  "payment for payment intent pi_1: record not found",
+			Err:    gorm.ErrRecordNotFound,
+		},
+		{
+			Name:    "TestStripeWebhook_ReplayInvalidPayload",
+			Payload: `{"id":"evt_1"`,
+			Mock:    func(payment *models.Payment) {},
+			Status:  models.StripeEventFailed,
+			Error:   "Invalid stripe event payload: unexpected end of JSON input",
+			Err:     ErrStripeEventPayload,
+		},
+		{
+			// Left pending, the next delivery finds the payment event already created
+			Name:      "TestStripeWebhook_ReplayNotFinished",
+			Payload:   ignoredStripeEvent,
+			Mock:      func(payment *models.Payment) {},
+			FinishErr: finishErr,
+			Status:    models.StripeEventIgnored,
+			Err:       finishErr,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			payment := &models.Payment{ID: uuid.New(), ExternalTransactionID: "pi_1"}
+			stored := &models.StripeEvent{
+				ID:       "evt_1",
+				Payload:  tc.Payload,
+				Status:   models.StripeEventFailed,
+				Error:    "previous error",
+				Attempts: 2,
+			}
+
+			suite.stripeEventRepository.On("GetByID", "evt_1").Return(stored, nil)
+			suite.stripeEventRepository.On("Claim", stored, stripeEventLease).Return(true, nil)
+			suite.stripeEventRepository.On("Finish", stored).Return(tc.FinishErr)
+			tc.Mock(payment)
+
+			record, err := suite.task.Replay("evt_1")
+
+			if tc.Err == nil {
+				suite.Nil(err)
+			} else {
+				suite.ErrorIs(err, tc.Err)
+			}
+			suite.Equal(tc.Status, record.Status)
+			suite.Equal(tc.Error, record.Error)
+			suite.Equal(3, record.Attempts)
+			suite.Equal(tc.Status == models.StripeEventProcessed, record.ProcessedAt != nil)
+			suite.paymentRepository.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func (suite *stripeWebhookTaskTest) TestChargeDispute() {
+	dispute := func(eventType string, status string) string {
+		return `{"id":"evt_1","type":"` + eventType + `","created":1710000000,"data":{"object":{` +
+			`"id":"dp_1","amount":35050,"reason":"fraudulent","status":"` + status + `",` +
+			`"payment_intent":"pi_1","evidence_details":{"due_by":1710500000}}}}`
+	}
+
+	testcases := []struct {
+		Name      string
+		Payload   string
+		Status    string
+		EventType string
+		Closed    bool
+	}{
+		{
+			Name:      "TestStripeWebhook_DisputeCreated",
+			Payload:   dispute("charge.dispute.created", "needs_response"),
+			Status:    "needs_response",
+			EventType: "disputed",
+		},
+		{
+			Name:      "TestStripeWebhook_DisputeUpdated",
+			Payload:   dispute("charge.dispute.updated", "under_review"),
+			Status:    "under_review",
+			EventType: "disputed",
+		},
+		{
+			Name:      "TestStripeWebhook_DisputeWon",
+			Payload:   dispute("charge.dispute.closed", "won"),
+			Status:    "won",
+			EventType: "dispute_won",
+			Closed:    true,
+		},
+		{
+			// Inquiries closed without a chargeback keep the funds as well
+			Name:      "TestStripeWebhook_DisputeWarningClosed",
+			Payload:   dispute("charge.dispute.closed", "warning_closed"),
+			Status:    "warning_closed",
+			EventType: "dispute_won",
+			Closed:    true,
+		},
+		{
+			Name:      "TestStripeWebhook_DisputeLost",
+			Payload:   dispute("charge.dispute.closed", "lost"),
+			Status:    "lost",
+			EventType: "dispute_lost",
+			Closed:    true,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			payment := &models.Payment{ID: uuid.New(), ExternalTransactionID: "pi_1"}
+
+			suite.paymentRepository.On("GetPaymentByStripePaymentIntentID", "pi_1").Return(payment, nil)
+			suite.disputeRepository.On("Save", mock.AnythingOfType("*models.Dispute")).Return(nil)
+			suite.paymentRepository.On("CreateEvent", mock.MatchedBy(func(event *models.PaymentEvent) bool {
+				return event.PaymentID == payment.ID && event.Type == tc.EventType
+			})).Return(nil)
+
+			handled, err := suite.task.handle(suite.stripeEvent(tc.Payload))
+
+			suite.True(handled)
+			suite.Nil(err)
+			suite.disputeRepository.AssertCalled(suite.T(), "Save", mock.MatchedBy(func(record *models.Dispute) bool {
+				closed := record.ClosedAt != nil && record.ClosedAt.Equal(time.Unix(1710000000, 0))
+
+				return record.PaymentID == payment.ID &&
+					record.StripeDisputeID == "dp_1" &&
+					record.Amount == money.FromFloat(350.50) &&
+					record.Reason == "fraudulent" &&
+					record.Status == tc.Status &&
+					record.EvidenceDueBy.Equal(time.Unix(1710500000, 0)) &&
+					closed == tc.Closed && (tc.Closed || record.ClosedAt == nil)
+			}))
+			suite.paymentRepository.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func (suite *stripeWebhookTaskTest) TestChargeDisputeFailures() {
+	testcases := []struct {
+		Name    string
+		Payload string
+		Mock    func(payment *models.Payment)
+		Err     error
+	}{
+		{
+			Name:    "TestStripeWebhook_DisputeWithoutPaymentIntent",
+			Payload: `{"id":"evt_1","type":"charge.dispute.created","data":{"object":{"id":"dp_1","status":"needs_response"}}}`,
+			Mock:    func(payment *models.Payment) {},
+			Err:     ErrStripeEventPayload,
+		},
+		{
+			Name:    "TestStripeWebhook_DisputeOfUnknownPayment",
+			Payload: `{"id":"evt_1","type":"charge.dispute.created","data":{"object":{"id":"dp_1","payment_intent":"pi_1"}}}`,
+			Mock: func(payment *models.Payment) {
+				suite.paymentRepository.On("GetPaymentByStripePaymentIntentID", "pi_1").Return(nil, gorm.ErrRecordNotFound)
+			},
+			Err: gorm.ErrRecordNotFound,
+		},
+		{
+			// Opened before the load was served, the event is replayed later
+			Name:    "TestStripeWebhook_DisputeNotAllowedYet",
+			Payload: `{"id":"evt_1","type":"charge.dispute.created","data":{"object":{"id":"dp_1","payment_intent":"pi_1"}}}`,
+			Mock: func(payment *models.Payment) {
+				suite.paymentRepository.On("GetPaymentByStripePaymentIntentID", "pi_1").Return(payment, nil)
+				suite.disputeRepository.On("Save", mock.AnythingOfType("*models.Dispute")).Return(nil)
+				suite.paymentRepository.On("CreateEvent", mock.AnythingOfType("*models.PaymentEvent")).
+					Return(&payments.TransitionError{From: "pending", To: "disputed"})
+				suite.paymentRepository.On("GetLastEventByPaymentID", payment.ID).
+					Return(&models.PaymentEvent{PaymentID: payment.ID, Type: "pending"}, nil)
+			},
+			Err: payments.ErrInvalidTransition,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			payment := &models.Payment{ID: uuid.New(), ExternalTransactionID: "pi_1"}
+			tc.Mock(payment)
+
+			handled, err := suite.task.handle(suite.stripeEvent(tc.Payload))
+
+			suite.True(handled)
+			suite.ErrorIs(err, tc.Err)
+			if tc.Err != payments.ErrInvalidTransition {
+				suite.disputeRepository.AssertNotCalled(suite.T(), "Save", mock.Anything)
+			}
+		})
+	}
+}
+
+func (suite *stripeWebhookTaskTest) TestPaymentIntentCanceled() {
+	canceled := func(reason string) string {
+		return `{"id":"evt_1","type":"payment_intent.canceled","data":{"object":{` +
+			`"id":"pi_1","cancellation_reason":"` + reason + `"}}}`
+	}
+
+	testcases := []struct {
+		Name          string
+		Payload       string
+		CaptureMethod string
+		Last          string
+		EventType     string
+	}{
+		{
+			Name:          "TestStripeWebhook_CanceledByCustomer",
+			Payload:       canceled("requested_by_customer"),
+			CaptureMethod: models.CaptureAutomatic,
+			Last:          "pending",
+			EventType:     "canceled",
+		},
+		{
+			Name:          "TestStripeWebhook_CanceledWhileRequiringAction",
+			Payload:       canceled("abandoned"),
+			CaptureMethod: models.CaptureAutomatic,
+			Last:          "requires_action",
+			EventType:     "canceled",
+		},
+		{
+			// Stripe does not keep the order of the events, the payment already moved on
+			Name:          "TestStripeWebhook_CanceledStale",
+			Payload:       canceled("requested_by_customer"),
+			CaptureMethod: models.CaptureAutomatic,
+			Last:          "paid",
+		},
+		{
+			Name:          "TestStripeWebhook_CanceledAuthorizationExpired",
+			Payload:       canceled("automatic"),
+			CaptureMethod: models.CaptureManual,
+			Last:          "paid",
+			EventType:     "authorization_expired",
+		},
+		{
+			Name:          "TestStripeWebhook_CanceledAutomaticallyNotAuthorized",
+			Payload:       canceled("automatic"),
+			CaptureMethod: models.CaptureAutomatic,
+			Last:          "pending",
+			EventType:     "canceled",
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			payment := &models.Payment{ID: uuid.New(), ExternalTransactionID: "pi_1", CaptureMethod: tc.CaptureMethod}
+
+			suite.paymentRepository.On("GetPaymentByStripePaymentIntentID", "pi_1").Return(payment, nil)
+			suite.paymentRepository.On("GetLastEventByPaymentID", payment.ID).
+				Return(&models.PaymentEvent{PaymentID: payment.ID, Type: tc.Last}, nil)
+			suite.paymentRepository.On("CreateEvent", mock.AnythingOfType("*models.PaymentEvent")).Return(nil).Maybe()
+
+			handled, err := suite.task.handle(suite.stripeEvent(tc.Payload))
+
+			suite.True(handled)
+			suite.Nil(err)
+			if tc.EventType == "" {
+				suite.paymentRepository.AssertNotCalled(suite.T(), "CreateEvent", mock.Anything)
+				return
+			}
+			suite.paymentRepository.AssertCalled(suite.T(), "CreateEvent", mock.MatchedBy(func(event *models.PaymentEvent) bool {
+				return event.PaymentID == payment.ID && event.Type == tc.EventType
+			}))
+		})
+	}
+}
+
+func TestStripeWebhookTask(t *testing.T) {
+	suite.Run(t, new(stripeWebhookTaskTest))
+}
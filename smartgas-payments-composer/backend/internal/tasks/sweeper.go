@@ -0,0 +1,262 @@
+package tasks
+
+import (
+	"errors"
+	"log"
+	"smartgas-payment/config"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/payments"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/services"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Minutes used when the timeout of a state is not configured
+var defaultStaleTimeouts = map[string]uint{
+	"funds_reserved":  15,
+	"paid":            15,
+	"pump_ready":      30,
+	"pending":         60,
+	"requires_action": 60,
+}
+
+// unpaidEvents are the states of the payments the customer never confirmed, nothing was
+// charged so their intent is only canceled, releasing the promo code they reserved
+var unpaidEvents = map[string]bool{
+	"pending":         true,
+	"requires_action": true,
+}
+
+// Authorizations not captured are captured or released this long before they expire
+const authorizationExpiryMargin = time.Hour * 6
+
+// Idempotency keys are replayed to the retries sent within this time, older ones are deleted
+const idempotencyKeyRetention = time.Hour * 24
+
+// SweptPayment is a stale payment found by the sweeper and what happened to it
+type SweptPayment struct {
+	PaymentID       uuid.UUID
+	LastEvent       string
+	PaymentProvider string
+	Amount          money.Amount
+	Error           string
+}
+
+// SweepReport lists the payments given back by a sweep, provider cancellations and
+// refunds of the canceled ones are completed (and retried) through the outbox
+type SweepReport struct {
+	StartedAt time.Time
+	Canceled  []SweptPayment
+	// Served loads whose authorization was about to expire before being captured
+	Captured []SweptPayment
+	Failed   []SweptPayment
+	// Idempotency keys deleted once their retention passed
+	ExpiredIdempotencyKeys int64
+}
+
+//go:generate mockery --name ReservationSweeperTask --filename=mock_sweeper.go --inpackage=true
+type ReservationSweeperTask interface {
+	SweepStaleReservations() (*SweepReport, error)
+}
+
+type reservationSweeperTask struct {
+	paymentRepository     repository.PaymentRepository
+	idempotencyRepository repository.IdempotencyRepository
+	outboxTask            OutboxTask
+	providers             services.PaymentProviderRegistry
+	config                config.Config
+}
+
+func ProvideReservationSweeperTask(
+	paymentRepository repository.PaymentRepository,
+	idempotencyRepository repository.IdempotencyRepository,
+	outboxTask OutboxTask,
+	providers services.PaymentProviderRegistry,
+	config config.Config,
+) *reservationSweeperTask {
+	return &reservationSweeperTask{
+		paymentRepository:     paymentRepository,
+		idempotencyRepository: idempotencyRepository,
+		outboxTask:            outboxTask,
+		providers:             providers,
+		config:                config,
+	}
+}
+
+func (rst *reservationSweeperTask) timeouts() map[string]time.Duration {
+	configured := map[string]uint{
+		"funds_reserved":  rst.config.Sweeper.FundsReservedMinutes,
+		"paid":            rst.config.Sweeper.PaidMinutes,
+		"pump_ready":      rst.config.Sweeper.PumpReadyMinutes,
+		"pending":         rst.config.Sweeper.PendingMinutes,
+		"requires_action": rst.config.Sweeper.PendingMinutes,
+	}
+
+	timeouts := map[string]time.Duration{}
+	for state, minutes := range configured {
+		if minutes == 0 {
+			minutes = defaultStaleTimeouts[state]
+		}
+		timeouts[state] = time.Minute * time.Duration(minutes)
+	}
+
+	return timeouts
+}
+
+// SweepStaleReservations gives back the funds of the payments that never got a served
+// event from the forecourt, canceling or refunding them through their provider. Payments
+// never confirmed by the customer are canceled and expired idempotency keys are deleted
+func (rst *reservationSweeperTask) SweepStaleReservations() (*SweepReport, error) {
+	report := &SweepReport{
+		StartedAt: time.Now(),
+		Canceled:  []SweptPayment{},
+		Captured:  []SweptPayment{},
+		Failed:    []SweptPayment{},
+	}
+
+	for state, timeout := range rst.timeouts() {
+		stale, err := rst.paymentRepository.ListStaleByLastEvent(state, report.StartedAt.Add(-timeout))
+		if err != nil {
+			return report, err
+		}
+
+		for _, payment := range stale {
+			swept := SweptPayment{
+				PaymentID:       payment.ID,
+				LastEvent:       state,
+				PaymentProvider: payment.PaymentProvider,
+				Amount:          payment.Amount,
+			}
+
+			var err error
+			if unpaidEvents[state] {
+				err = rst.cancelUnpaid(payment)
+			} else {
+				err = rst.outboxTask.GivePaymentBack(
+					payment,
+					"Tu carga fue cancelada por inactividad, tu dinero ha sido devuelto",
+				)
+			}
+			if err != nil {
+				log.Println("Sweeper: not able to give back payment", payment.ID, err)
+				swept.Error = err.Error()
+				report.Failed = append(report.Failed, swept)
+				continue
+			}
+
+			report.Canceled = append(report.Canceled, swept)
+		}
+	}
+
+	if err := rst.sweepExpiringAuthorizations(report); err != nil {
+		return report, err
+	}
+
+	expired, err := rst.idempotencyRepository.DeleteCreatedBefore(
+		report.StartedAt.Add(-idempotencyKeyRetention),
+	)
+	report.ExpiredIdempotencyKeys = expired
+
+	return report, err
+}
+
+// sweepExpiringAuthorizations captures the served loads whose authorization is about to
+// expire and gives back the ones not served, so Stripe never releases the funds by itself
+func (rst *reservationSweeperTask) sweepExpiringAuthorizations(report *SweepReport) error {
+	expiring, err := rst.paymentRepository.ListExpiringAuthorizations(
+		report.StartedAt.Add(authorizationExpiryMargin),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, payment := range expiring {
+		last, err := rst.paymentRepository.GetLastEventByPaymentID(payment.ID)
+		if err != nil {
+			return err
+		}
+
+		swept := SweptPayment{
+			PaymentID:       payment.ID,
+			LastEvent:       last.Type,
+			PaymentProvider: payment.PaymentProvider,
+			Amount:          payment.Amount,
+		}
+
+		switch last.Type {
+		case "served":
+			err = rst.captureServed(payment)
+		case "paid", "pump_ready":
+			err = rst.outboxTask.GivePaymentBack(
+				payment,
+				"Tu carga no fue completada a tiempo, tu dinero ha sido devuelto",
+			)
+		default:
+			// Loads still serving are left to the forecourt
+			continue
+		}
+
+		if err != nil {
+			log.Println("Sweeper: not able to settle expiring authorization", payment.ID, err)
+			swept.Error = err.Error()
+			report.Failed = append(report.Failed, swept)
+			continue
+		}
+
+		if last.Type == "served" {
+			report.Captured = append(report.Captured, swept)
+		} else {
+			report.Canceled = append(report.Canceled, swept)
+		}
+	}
+
+	return nil
+}
+
+// captureServed captures the amount served of a load whose capture did not go through
+func (rst *reservationSweeperTask) captureServed(payment *models.Payment) error {
+	provider, err := rst.providers.Get(payment.PaymentProvider)
+	if err != nil {
+		return err
+	}
+
+	err = provider.Capture(services.CaptureOpts{
+		TransactionID:  payment.ExternalTransactionID,
+		ReservedAmount: payment.Amount,
+		Amount:         payment.RealAmountReported,
+		ManualCapture:  true,
+	})
+	if err != nil {
+		return err
+	}
+
+	return rst.paymentRepository.ClearAuthorizationExpiry(payment.ID)
+}
+
+// cancelUnpaid cancels the intent of a payment never confirmed and records it as canceled.
+// The intent is canceled first, so a payment confirmed meanwhile is never recorded as canceled
+func (rst *reservationSweeperTask) cancelUnpaid(payment *models.Payment) error {
+	if payment.ExternalTransactionID != "" {
+		provider, err := rst.providers.Get(payment.PaymentProvider)
+		if err != nil {
+			return err
+		}
+
+		if err := provider.Cancel(payment.ExternalTransactionID); err != nil {
+			return err
+		}
+	}
+
+	err := rst.paymentRepository.CreateEvent(
+		&models.PaymentEvent{PaymentID: payment.ID, Type: "canceled"},
+	)
+	// Already recorded by the provider webhook
+	if errors.Is(err, payments.ErrInvalidTransition) {
+		return nil
+	}
+
+	return err
+}
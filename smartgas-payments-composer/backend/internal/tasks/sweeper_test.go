@@ -0,0 +1,284 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"smartgas-payment/config"
+	"smartgas-payment/internal/models"
+	"smartgas-payment/internal/money"
+	"smartgas-payment/internal/repository"
+	"smartgas-payment/internal/services"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type sweeperTaskTest struct {
+	suite.Suite
+	paymentRepository     *repository.MockPaymentRepository
+	idempotencyRepository *repository.MockIdempotencyRepository
+	outboxRepository      *repository.MockOutboxRepository
+	providers             *services.MockPaymentProviderRegistry
+	stripeProvider        *services.MockPaymentProvider
+	sweeper               *reservationSweeperTask
+}
+
+func (suite *sweeperTaskTest) SetupTest() {
+	suite.paymentRepository = &repository.MockPaymentRepository{}
+	suite.idempotencyRepository = &repository.MockIdempotencyRepository{}
+	suite.outboxRepository = &repository.MockOutboxRepository{}
+	suite.providers = &services.MockPaymentProviderRegistry{}
+
+	suite.stripeProvider = &services.MockPaymentProvider{}
+	suite.stripeProvider.On("RefundsConfirmedAsync").Return(true).Maybe()
+	suite.providers.On("Get", "stripe").Return(suite.stripeProvider, nil)
+
+	suite.idempotencyRepository.On("DeleteCreatedBefore", mock.Anything).Return(int64(0), nil).Maybe()
+
+	// Messages are left to the worker
+	suite.outboxRepository.On("Claim", mock.Anything, outboxLease).Return(false, nil)
+
+	outboxTask := ProvideOutboxTask(
+		suite.outboxRepository,
+		suite.paymentRepository,
+		nil,
+		nil,
+		nil,
+		nil,
+		suite.providers,
+	)
+
+	suite.sweeper = ProvideReservationSweeperTask(
+		suite.paymentRepository,
+		suite.idempotencyRepository,
+		outboxTask,
+		suite.providers,
+		config.Config{},
+	)
+}
+
+// stale returns payment as the only stale one, in the given state
+func (suite *sweeperTaskTest) stale(state string, payment *models.Payment) {
+	suite.paymentRepository.On("ListStaleByLastEvent", state, mock.Anything).
+		Return([]*models.Payment{payment}, nil)
+	suite.paymentRepository.On("ListStaleByLastEvent", mock.Anything, mock.Anything).
+		Return([]*models.Payment{}, nil)
+	suite.paymentRepository.On("ListExpiringAuthorizations", mock.Anything).
+		Return([]*models.Payment{}, nil).Maybe()
+}
+
+func (suite *sweeperTaskTest) TestSweepRecordsCancellation() {
+	testcases := []struct {
+		Name          string
+		CaptureMethod string
+		MessageType   string
+	}{
+		{
+			Name:          "TestSweeper_CapturedIsRefunded",
+			CaptureMethod: models.CaptureAutomatic,
+			MessageType:   models.OutboxRefundPayment,
+		},
+		{
+			Name:          "TestSweeper_AuthorizedIsCanceled",
+			CaptureMethod: models.CaptureManual,
+			MessageType:   models.OutboxCancelPayment,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		suite.Run(tc.Name, func() {
+			suite.SetupTest()
+
+			payment := &models.Payment{
+				ID:              uuid.New(),
+				Amount:          money.FromFloat(500),
+				PaymentProvider: "stripe",
+				CaptureMethod:   tc.CaptureMethod,
+			}
+			suite.stale("paid", payment)
+
+			suite.paymentRepository.On(
+				"CreateEvent",
+				mock.MatchedBy(func(event *models.PaymentEvent) bool {
+					return event.PaymentID == payment.ID && event.Type == "internal_cancellation"
+				}),
+				mock.MatchedBy(func(message *models.OutboxMessage) bool {
+					return message.Type == tc.MessageType
+				}),
+			).Return(nil).Once()
+
+			report, err := suite.sweeper.SweepStaleReservations()
+
+			suite.Nil(err)
+			suite.Len(report.Canceled, 1)
+			suite.Empty(report.Failed)
+			suite.paymentRepository.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func (suite *sweeperTaskTest) TestRefundKeepsCancellationEvent() {
+	payment := &models.Payment{
+		ID:              uuid.New(),
+		PaymentProvider: "stripe",
+		CaptureMethod:   models.CaptureAutomatic,
+	}
+	suite.stale("paid", payment)
+
+	var refund *models.OutboxMessage
+	suite.paymentRepository.On("CreateEvent", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			refund = args.Get(1).(*models.OutboxMessage)
+		}).
+		Return(nil).
+		Once()
+
+	_, err := suite.sweeper.SweepStaleReservations()
+	suite.Nil(err)
+
+	var payload refundPayload
+	suite.Nil(json.Unmarshal([]byte(refund.Payload), &payload))
+	suite.Equal("internal_cancellation", payload.Event)
+	suite.Equal(money.Zero, payload.Amount)
+}
+
+func (suite *sweeperTaskTest) TestSweepCancelsUnpaid() {
+	for _, state := range []string{"pending", "requires_action"} {
+		suite.Run("TestSweeper_"+state, func() {
+			suite.SetupTest()
+
+			payment := &models.Payment{
+				ID:                    uuid.New(),
+				PaymentProvider:       "stripe",
+				ExternalTransactionID: "pi_" + state,
+			}
+			suite.stale(state, payment)
+
+			suite.stripeProvider.On("Cancel", payment.ExternalTransactionID).Return(nil).Once()
+			suite.paymentRepository.On("CreateEvent", mock.MatchedBy(func(event *models.PaymentEvent) bool {
+				return event.PaymentID == payment.ID && event.Type == "canceled"
+			})).Return(nil).Once()
+
+			report, err := suite.sweeper.SweepStaleReservations()
+
+			suite.Nil(err)
+			suite.Len(report.Canceled, 1)
+			suite.stripeProvider.AssertExpectations(suite.T())
+			suite.paymentRepository.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func (suite *sweeperTaskTest) TestSweepKeepsUnpaidConfirmedMeanwhile() {
+	payment := &models.Payment{
+		ID:                    uuid.New(),
+		PaymentProvider:       "stripe",
+		ExternalTransactionID: "pi_confirmed",
+	}
+	suite.stale("pending", payment)
+
+	suite.stripeProvider.On("Cancel", payment.ExternalTransactionID).
+		Return(errors.New("payment intent already succeeded")).
+		Once()
+
+	report, err := suite.sweeper.SweepStaleReservations()
+
+	suite.Nil(err)
+	suite.Len(report.Failed, 1)
+	suite.paymentRepository.AssertNotCalled(suite.T(), "CreateEvent", mock.Anything)
+}
+
+func (suite *sweeperTaskTest) TestSweepExpiringAuthorizations() {
+	served := &models.Payment{
+		ID:                    uuid.New(),
+		Amount:                money.FromFloat(500),
+		RealAmountReported:    money.FromFloat(350),
+		PaymentProvider:       "stripe",
+		ExternalTransactionID: "pi_served",
+		CaptureMethod:         models.CaptureManual,
+	}
+	paid := &models.Payment{
+		ID:                    uuid.New(),
+		Amount:                money.FromFloat(500),
+		PaymentProvider:       "stripe",
+		ExternalTransactionID: "pi_paid",
+		CaptureMethod:         models.CaptureManual,
+	}
+	serving := &models.Payment{
+		ID:                    uuid.New(),
+		PaymentProvider:       "stripe",
+		ExternalTransactionID: "pi_serving",
+		CaptureMethod:         models.CaptureManual,
+	}
+
+	suite.paymentRepository.On("ListStaleByLastEvent", mock.Anything, mock.Anything).
+		Return([]*models.Payment{}, nil)
+	suite.paymentRepository.On("ListExpiringAuthorizations", mock.Anything).
+		Return([]*models.Payment{served, paid, serving}, nil)
+
+	for payment, last := range map[*models.Payment]string{
+		served:  "served",
+		paid:    "paid",
+		serving: "serving",
+	} {
+		suite.paymentRepository.On("GetLastEventByPaymentID", payment.ID).
+			Return(&models.PaymentEvent{PaymentID: payment.ID, Type: last}, nil)
+	}
+
+	suite.stripeProvider.On("Capture", services.CaptureOpts{
+		TransactionID:  served.ExternalTransactionID,
+		ReservedAmount: served.Amount,
+		Amount:         served.RealAmountReported,
+		ManualCapture:  true,
+	}).Return(nil).Once()
+	suite.paymentRepository.On("ClearAuthorizationExpiry", served.ID).Return(nil).Once()
+
+	suite.paymentRepository.On(
+		"CreateEvent",
+		mock.MatchedBy(func(event *models.PaymentEvent) bool {
+			return event.PaymentID == paid.ID && event.Type == "internal_cancellation"
+		}),
+		mock.MatchedBy(func(message *models.OutboxMessage) bool {
+			return message.Type == models.OutboxCancelPayment
+		}),
+	).Return(nil).Once()
+
+	report, err := suite.sweeper.SweepStaleReservations()
+
+	suite.Nil(err)
+	suite.Len(report.Captured, 1)
+	suite.Equal(served.ID, report.Captured[0].PaymentID)
+	suite.Len(report.Canceled, 1)
+	suite.Equal(paid.ID, report.Canceled[0].PaymentID)
+	suite.Empty(report.Failed)
+	suite.stripeProvider.AssertExpectations(suite.T())
+	suite.paymentRepository.AssertExpectations(suite.T())
+}
+
+func (suite *sweeperTaskTest) TestSweepDeletesExpiredIdempotencyKeys() {
+	suite.paymentRepository.On("ListStaleByLastEvent", mock.Anything, mock.Anything).
+		Return([]*models.Payment{}, nil)
+	suite.paymentRepository.On("ListExpiringAuthorizations", mock.Anything).
+		Return([]*models.Payment{}, nil)
+
+	suite.idempotencyRepository = &repository.MockIdempotencyRepository{}
+	suite.idempotencyRepository.On("DeleteCreatedBefore", mock.MatchedBy(func(date time.Time) bool {
+		return time.Since(date) >= idempotencyKeyRetention && time.Since(date) < idempotencyKeyRetention+time.Minute
+	})).Return(int64(3), nil).Once()
+	suite.sweeper.idempotencyRepository = suite.idempotencyRepository
+
+	report, err := suite.sweeper.SweepStaleReservations()
+
+	suite.Nil(err)
+	suite.Equal(int64(3), report.ExpiredIdempotencyKeys)
+	suite.idempotencyRepository.AssertExpectations(suite.T())
+}
+
+func TestSweeperTask(t *testing.T) {
+	suite.Run(t, new(sweeperTaskTest))
+}
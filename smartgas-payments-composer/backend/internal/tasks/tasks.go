@@ -4,6 +4,18 @@ import "github.com/google/wire"
 
 var TasksSet = wire.NewSet(
 	ProvideSynchronizationTask,
+	ProvideOutboxTask,
+	ProvideReservationSweeperTask,
+	ProvideReconciliationTask,
+	ProvideStripeWebhookTask,
+	ProvideSettlementTask,
+	ProvideFraudTask,
 
 	wire.Bind(new(SynchronizationTask), new(*synchronizationTask)),
+	wire.Bind(new(OutboxTask), new(*outboxTask)),
+	wire.Bind(new(ReservationSweeperTask), new(*reservationSweeperTask)),
+	wire.Bind(new(ReconciliationTask), new(*reconciliationTask)),
+	wire.Bind(new(StripeWebhookTask), new(*stripeWebhookTask)),
+	wire.Bind(new(SettlementTask), new(*settlementTask)),
+	wire.Bind(new(FraudTask), new(*fraudTask)),
 )
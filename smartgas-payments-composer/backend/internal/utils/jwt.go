@@ -32,3 +32,23 @@ func ParseJwtToken(tokenString string, isRefreshToken bool) (*schemas.JwtClaims,
 
 	return claims, nil
 }
+
+// ParseQuoteToken returns the claims of a quote signed by us, jwt.ErrTokenExpired is
+// wrapped when it expired
+func ParseQuoteToken(tokenString string) (*schemas.QuoteClaims, error) {
+	cfg := config.ConfigSettings
+
+	token, err := jwt.ParseWithClaims(tokenString, &schemas.QuoteClaims{}, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+
+		return []byte(cfg.SecretKeyQuote), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return token.Claims.(*schemas.QuoteClaims), nil
+}
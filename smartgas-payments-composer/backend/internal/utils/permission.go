@@ -36,6 +36,35 @@ func GatherGasStationIds(user *models.User) (gasStations []string) {
 	return
 }
 
+// GatherLegalNameIds returns the legal names of the stations of the user
+func GatherLegalNameIds(user *models.User) (legalNames []string) {
+	seen := map[string]bool{}
+	for _, station := range user.GasStations {
+		if station.LegalNameID == "" || seen[station.LegalNameID] {
+			continue
+		}
+		seen[station.LegalNameID] = true
+		legalNames = append(legalNames, station.LegalNameID)
+	}
+
+	return
+}
+
+// CanSeeLegalName reports whether the user is admin or has a station of the legal name
+func CanSeeLegalName(user *models.User, legalNameID string) bool {
+	if *user.IsAdmin {
+		return true
+	}
+
+	for _, legalName := range GatherLegalNameIds(user) {
+		if legalName == legalNameID {
+			return true
+		}
+	}
+
+	return false
+}
+
 func AddStationsFilter(user *models.User, filters map[string]any) {
 	if !*user.IsAdmin {
 		filters["stations"] = GatherGasStationIds(user)
@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"smartgas-payment/internal/money"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// XLSXMaxRows is the most rows of a xlsx file, the whole file is kept until it is
+// closed since the format can not be written in pieces
+const XLSXMaxRows = 100000
+
+// ErrTooManyRows is returned when a xlsx file reaches XLSXMaxRows, nothing was
+// written to the output yet
+var ErrTooManyRows = errors.New("the file exceeds the rows allowed")
+
+// TabularWriter writes the rows of a report one by one. csv rows are streamed as
+// they are written, xlsx files are written when closed
+type TabularWriter interface {
+	WriteRow(values ...any) error
+	Close() error
+}
+
+// TabularContentTypes are the content types of the supported formats
+var TabularContentTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// NewTabularWriter returns a writer of the given format (csv or xlsx) over w
+func NewTabularWriter(format string, w io.Writer) (TabularWriter, error) {
+	switch format {
+	case "csv":
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	case "xlsx":
+		file := excelize.NewFile()
+		stream, err := file.NewStreamWriter("Sheet1")
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		return &xlsxWriter{file: file, stream: stream, output: w}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported tabular format %q", format)
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	rows   int
+}
+
+func (cw *csvWriter) WriteRow(values ...any) error {
+	record := make([]string, 0, len(values))
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			record = append(record, "")
+		case time.Time:
+			record = append(record, v.Format(time.RFC3339))
+		default:
+			record = append(record, fmt.Sprint(v))
+		}
+	}
+
+	if err := cw.writer.Write(record); err != nil {
+		return err
+	}
+
+	// Flushing from time to time so the rows reach the client while the report is built
+	cw.rows++
+	if cw.rows%500 == 0 {
+		cw.writer.Flush()
+	}
+
+	return cw.writer.Error()
+}
+
+func (cw *csvWriter) Close() error {
+	cw.writer.Flush()
+
+	return cw.writer.Error()
+}
+
+type xlsxWriter struct {
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	output io.Writer
+	rows   int
+}
+
+func (xw *xlsxWriter) WriteRow(values ...any) error {
+	row := make([]any, 0, len(values))
+	for _, value := range values {
+		// Amounts are stored in centavos, spreadsheets need pesos
+		if amount, ok := value.(money.Amount); ok {
+			value = amount.Float64()
+		}
+		row = append(row, value)
+	}
+
+	if xw.rows == XLSXMaxRows {
+		// The file is discarded, it will not be closed
+		xw.file.Close()
+		return ErrTooManyRows
+	}
+
+	xw.rows++
+
+	cell, err := excelize.CoordinatesToCellName(1, xw.rows)
+	if err != nil {
+		return err
+	}
+
+	return xw.stream.SetRow(cell, row)
+}
+
+func (xw *xlsxWriter) Close() error {
+	defer xw.file.Close()
+
+	if err := xw.stream.Flush(); err != nil {
+		return err
+	}
+
+	return xw.file.Write(xw.output)
+}
@@ -13,3 +13,19 @@ func InitTimezone(timezone string) {
 
 	time.Local = loc
 }
+
+// ParseDateRange parses two dates (2006-01-02) in the local timezone, returning the
+// range [from, to + 1 day) so the last day is included
+func ParseDateRange(from string, to string) (time.Time, time.Time, error) {
+	low, err := time.ParseInLocation("2006-01-02", from, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	high, err := time.ParseInLocation("2006-01-02", to, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return low, high.AddDate(0, 0, 1), nil
+}
@@ -47,3 +47,7 @@ func (ch Channel) DeleteChannel(channelId string) {
 func InitChannels() Channel {
 	return make(Channel)
 }
+
+// PaymentChannels are the channels used to notify payment changes, shared between
+// controllers and tasks running in the same process
+var PaymentChannels = InitChannels()
@@ -1,4 +1,12 @@
-import { Alert, Badge, Box, Chip, Typography, Button } from "@mui/material";
+import {
+  Alert,
+  Badge,
+  Box,
+  Chip,
+  Typography,
+  Button,
+  TextField,
+} from "@mui/material";
 import { useEffect, useMemo, useState } from "react";
 import { useDispatch } from "react-redux";
 import Table from "../components/Table";
@@ -291,14 +299,35 @@ export default function Payment() {
                     variant="text"
                     sx={{ color: "white" }}
                     onClick={() => {
+                      // The reason is required by the API and kept with the refund
+                      let reason = "";
                       dispatch(
                         openDialog({
-                          content:
-                            "¿Estas seguro que deseas emitir un reembolso?",
+                          content: (
+                            <Box>
+                              <Typography sx={{ mb: 2 }}>
+                                ¿Estas seguro que deseas emitir un reembolso?
+                              </Typography>
+                              <TextField
+                                label="Motivo del reembolso"
+                                fullWidth
+                                multiline
+                                inputProps={{ maxLength: 255 }}
+                                onChange={(e) => {
+                                  reason = e.target.value;
+                                }}
+                              />
+                            </Box>
+                          ),
                           title: "Accion",
                           customButtonText: "Si",
                           customButtonAction: () => {
-                            doActionMutation({ id: row.id, action: "refund" });
+                            if (!reason.trim()) return;
+                            doActionMutation({
+                              id: row.id,
+                              action: "refund",
+                              reason: reason.trim(),
+                            });
                             dispatch(closeDialog());
                           },
                         }),